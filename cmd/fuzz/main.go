@@ -2,14 +2,19 @@ package fuzz
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,6 +27,7 @@ import (
 	"github.com/RedTeamPentesting/monsoon/shell"
 	"github.com/fd0/termstatus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -29,34 +35,117 @@ import (
 type Options struct {
 	Range       []string
 	RangeFormat string
-	Filename    string
+	RangeStep   int
+	DateRange   string
+	DateFormat  string
+	DateStep    time.Duration
+	dateRange   producer.DateRange
+	IPRange     []string
+	IPHostsOnly bool
+	Charset     string
+	Length      string
+	length      producer.Range
+	ValuesRegex string
+	RegexLimit  int
+	valuesRegex *producer.Regex
+	Rules       string
+	rules       []producer.Rule
+	ValuesExec  string
+	valuesExec  []string
+	Plugin      string
+	plugin      []string
+	Combine     []string
+	CombineSep  []string
+	Usernames   string
+	UserFormats []string
+	UserDomain  string
+	Extensions  []string
+	Prefixes    []string
+	Suffixes    []string
+	Dedup       bool
+	DedupCase   bool
+	DedupMemory int
+	SkipRegex   []string
+	SkipFile    []string
+	exclude     *producer.FilterExclude
+	Filenames   []string
+	FilePrio    []int
+	Values      []string
+	valueFiles  []string
+	MethodList  bool
+	Mode        string
 	Logfile     string
 	Logdir      string
 	Threads     int
 
+	MaxConcurrentPerHost int
+	Warmup               int
+
 	RequestsPerSecond float64
 
 	BufferSize int
 	Skip       int
 	Limit      int
-
-	Request        *request.Request // the template for the HTTP request
-	FollowRedirect int
+	Shard      string
+	shard      *producer.FilterShard
+	Shuffle    string
+	shuffle    *producer.FilterShuffle
+
+	Request         *request.Request // the template for the HTTP request
+	hostFilter      *request.HostFilter
+	oauth           *response.OAuthClient
+	primer          *response.Primer
+	Methods         []string
+	FollowRedirect  int
+	LocationTrusted bool
+	CookieJar       bool
+	CookieFile      string
+	cookieJar       *response.PersistentJar
+	Timing          bool
 
 	HideStatusCodes []string
 	ShowStatusCodes []string
+	HideWSClose     []string
+	ShowWSClose     []string
+	HideHeader      []string
+	ShowHeader      []string
 	HideHeaderSize  []string
 	HideBodySize    []string
+	HideTime        []string
+	ShowTime        []string
+	HideWords       []string
+	HideLines       []string
 	HidePattern     []string
 	hidePattern     []*regexp.Regexp
 	ShowPattern     []string
 	showPattern     []*regexp.Regexp
 
+	AutoCalibrate       bool
+	AutoCalibrateValues []string
+	HideSimilar         float64
+
 	Extract        []string
 	extract        []*regexp.Regexp
 	ExtractPipe    []string
 	extractPipe    [][]string
+	ExtractJSON    []string
+	ExtractXPath   []string
+	extractXPath   []*response.XPath
+	MatchJSON      []string
+	matchJSON      []response.JSONMatch
+	MatchXPath     []string
+	matchXPath     []response.XPathMatch
 	BodyBufferSize int
+
+	FollowUp            string
+	FollowUpPlaceholder string
+
+	State        string
+	Resume       string
+	stateCommand string
+	stateArgs    []string
+	stateSecrets []request.ArgSecret
+	resumed      *State
 }
 
 var opts Options
@@ -89,20 +178,246 @@ func splitShell(cmds []string) ([][]string, error) {
 	return data, nil
 }
 
+// sources returns the names of the sources for values which are configured.
+func (opts *Options) sources() (list []string) {
+	add := func(set bool, name string) {
+		if set {
+			list = append(list, name)
+		}
+	}
+
+	add(len(opts.Range) > 0, "range")
+	add(opts.DateRange != "", "--date-range")
+	add(len(opts.IPRange) > 0, "--ip-range")
+	add(len(opts.Filenames) > 0, "filename")
+	add(len(opts.Values) > 0, "--value")
+	add(opts.MethodList, "--method-list")
+	add(opts.Charset != "", "--charset")
+	add(opts.ValuesRegex != "", "--values-regex")
+	add(opts.ValuesExec != "", "--values-exec")
+	add(opts.Plugin != "", "--producer-plugin")
+	add(len(opts.Combine) > 0, "--combine")
+	add(opts.Usernames != "", "--usernames")
+
+	return list
+}
+
 // valid validates the options and returns an error if something is invalid.
 func (opts *Options) valid() (err error) {
 	if opts.Threads <= 0 {
 		return errors.New("invalid number of threads")
 	}
 
-	if len(opts.Range) > 0 && opts.Filename != "" {
+	if len(opts.Methods) > 0 && opts.Request.Method != "" {
+		return errors.New("--method and --methods cannot be used together")
+	}
+
+	if opts.Warmup < 0 {
+		return errors.New("invalid number of warmup requests")
+	}
+
+	if opts.CookieJar && opts.CookieFile != "" {
+		return errors.New("--cookie-jar and --cookie-file cannot be used together")
+	}
+
+	if opts.HideSimilar < 0 || opts.HideSimilar > 1 {
+		return errors.New("invalid ratio for --hide-similar, must be between 0 and 1")
+	}
+
+	if opts.MaxConcurrentPerHost < 0 {
+		return errors.New("invalid number of concurrent requests per host")
+	}
+
+	if len(opts.Range) > 0 && len(opts.Filenames) > 0 {
 		return errors.New("only one source allowed but both range and filename specified")
 	}
 
-	if len(opts.Range) == 0 && opts.Filename == "" {
+	if opts.MethodList && (len(opts.Range) > 0 || len(opts.Filenames) > 0 || len(opts.Values) > 0) {
+		return errors.New("only one source allowed but --method-list and range, filename or --value specified")
+	}
+
+	if len(opts.Values) > 0 && (len(opts.Range) > 0 || len(opts.Filenames) > 0) {
+		return errors.New("only one source allowed but --value and range or filename specified")
+	}
+
+	if sources := opts.sources(); len(sources) > 1 {
+		return fmt.Errorf("only one source allowed but %v specified", strings.Join(sources, " and "))
+	}
+
+	if opts.DateRange != "" {
+		if opts.DateStep <= 0 {
+			return errors.New("invalid step for date range")
+		}
+
+		opts.dateRange, err = producer.ParseDateRange(opts.DateRange)
+		if err != nil {
+			return err
+		}
+		opts.dateRange.Step = opts.DateStep
+	}
+
+	if opts.Charset != "" {
+		opts.length, err = producer.ParseRange(opts.Length)
+		if err != nil {
+			return fmt.Errorf("--length: %v", err)
+		}
+
+		_, err = producer.CharsetCount(opts.Charset, opts.length)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.ValuesRegex != "" {
+		opts.valuesRegex, err = producer.ParseRegex(opts.ValuesRegex, opts.RegexLimit)
+		if err != nil {
+			return fmt.Errorf("--values-regex: %v", err)
+		}
+	}
+
+	if opts.ValuesExec != "" {
+		opts.valuesExec, err = shell.Split(opts.ValuesExec)
+		if err != nil {
+			return fmt.Errorf("--values-exec: %v", err)
+		}
+
+		if len(opts.valuesExec) == 0 {
+			return fmt.Errorf("invalid command for --values-exec: %q", opts.ValuesExec)
+		}
+	}
+
+	if opts.Plugin != "" {
+		opts.plugin, err = shell.Split(opts.Plugin)
+		if err != nil {
+			return fmt.Errorf("--producer-plugin: %v", err)
+		}
+
+		if len(opts.plugin) == 0 {
+			return fmt.Errorf("invalid command for --producer-plugin: %q", opts.Plugin)
+		}
+	}
+
+	if opts.Rules != "" {
+		if len(opts.Filenames) == 0 && len(opts.Values) == 0 && opts.ValuesExec == "" && opts.Plugin == "" && len(opts.Combine) == 0 {
+			return errors.New("--rules can only be used together with --file, --value, --values-exec, --producer-plugin or --combine")
+		}
+
+		f, err := os.Open(opts.Rules)
+		if err != nil {
+			return err
+		}
+
+		opts.rules, err = producer.ReadRules(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("read %v: %v", opts.Rules, err)
+		}
+	}
+
+	if (len(opts.Extensions) > 0 || len(opts.Prefixes) > 0 || len(opts.Suffixes) > 0) && len(opts.Values) > 0 {
+		return errors.New("--extensions, --prefix and --suffix cannot be used together with --value")
+	}
+
+	if len(opts.FilePrio) > 0 && len(opts.FilePrio) != len(opts.Filenames) {
+		return fmt.Errorf("--file-priority needs one priority for each file (%d), got %d", len(opts.Filenames), len(opts.FilePrio))
+	}
+
+	if opts.Usernames != "" {
+		if len(opts.UserFormats) == 0 {
+			return errors.New("no format for --usernames specified")
+		}
+
+		for _, format := range opts.UserFormats {
+			err = producer.CheckUsernameFormat(format)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(opts.Combine) > 0 && len(opts.Combine) != 2 {
+		return errors.New("--combine needs exactly two files")
+	}
+
+	if (opts.Dedup || opts.DedupCase) && opts.DedupMemory <= 0 {
+		return errors.New("invalid memory size for --dedup")
+	}
+
+	if len(opts.SkipRegex) > 0 || len(opts.SkipFile) > 0 {
+		opts.exclude = &producer.FilterExclude{Values: make(map[string]struct{})}
+		opts.exclude.Regexps, err = compileRegexps(opts.SkipRegex)
+		if err != nil {
+			return err
+		}
+
+		for _, filename := range opts.SkipFile {
+			f, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+
+			err = producer.ReadExcludeList(f, opts.exclude.Values)
+			_ = f.Close()
+			if err != nil {
+				return fmt.Errorf("read %v: %v", filename, err)
+			}
+		}
+	}
+
+	if opts.Shard != "" {
+		opts.shard, err = producer.ParseShard(opts.Shard)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Shuffle != "" {
+		opts.shuffle = &producer.FilterShuffle{Window: opts.BufferSize}
+		switch {
+		case opts.Shuffle != "random":
+			opts.shuffle.Seed, err = strconv.ParseInt(opts.Shuffle, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid seed for --shuffle: %q", opts.Shuffle)
+			}
+		case opts.Request.Seed != 0:
+			opts.shuffle.Seed = opts.Request.Seed
+		default:
+			opts.shuffle.Seed = time.Now().UnixNano()
+		}
+	}
+
+	if len(opts.sources()) == 0 {
 		return errors.New("neither file nor range specified, nothing to do")
 	}
 
+	switch opts.Mode {
+	case "clusterbomb", "pitchfork":
+	default:
+		return fmt.Errorf("unknown mode %q, valid modes are clusterbomb and pitchfork", opts.Mode)
+	}
+
+	opts.Request.Placeholders = nil
+	opts.valueFiles = nil
+	for _, v := range opts.Values {
+		data := strings.SplitN(v, ":", 2)
+		if len(data) != 2 || data[0] == "" || data[1] == "" {
+			return fmt.Errorf("invalid value source %q, expected placeholder:filename", v)
+		}
+
+		for _, name := range opts.Request.Placeholders {
+			if name == data[0] {
+				return fmt.Errorf("placeholder %q specified more than once", name)
+			}
+		}
+
+		if data[1] == "-" && (stdinValues(opts.Filenames) || stdinValues(opts.valueFiles)) {
+			return errors.New("values can be read from stdin only once")
+		}
+
+		opts.Request.Placeholders = append(opts.Request.Placeholders, data[0])
+		opts.valueFiles = append(opts.valueFiles, data[1])
+	}
+
 	opts.extract, err = compileRegexps(opts.Extract)
 	if err != nil {
 		return err
@@ -113,6 +428,40 @@ func (opts *Options) valid() (err error) {
 		return err
 	}
 
+	for _, path := range opts.ExtractJSON {
+		err = request.CheckJSONPath(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts.extractXPath = nil
+	for _, expr := range opts.ExtractXPath {
+		x, err := response.ParseXPath(expr)
+		if err != nil {
+			return err
+		}
+		opts.extractXPath = append(opts.extractXPath, x)
+	}
+
+	opts.matchJSON = nil
+	for _, expr := range opts.MatchJSON {
+		m, err := response.ParseJSONMatch(expr)
+		if err != nil {
+			return err
+		}
+		opts.matchJSON = append(opts.matchJSON, m)
+	}
+
+	opts.matchXPath = nil
+	for _, expr := range opts.MatchXPath {
+		m, err := response.ParseXPathMatch(expr)
+		if err != nil {
+			return err
+		}
+		opts.matchXPath = append(opts.matchXPath, m)
+	}
+
 	opts.hidePattern, err = compileRegexps(opts.HidePattern)
 	if err != nil {
 		return err
@@ -135,8 +484,18 @@ var cmd = &cobra.Command{
 	Example: helpExamples,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
+		args, err := PrepareResume(cmd, &opts, args)
+		if err != nil {
+			return err
+		}
+
+		args, err = request.ProcessConfig(opts.Request, cmd.Flags(), args)
+		if err != nil {
+			return err
+		}
+
 		return cli.WithContext(func(ctx context.Context, g *errgroup.Group) error {
-			return run(ctx, g, &opts, args)
+			return Run(ctx, g, &opts, args)
 		})
 	},
 }
@@ -148,35 +507,105 @@ func AddCommand(c *cobra.Command) {
 	fs := cmd.Flags()
 	fs.SortFlags = false
 
+	AddFlags(&opts, fs)
+}
+
+// AddFlags adds the options for a run to fs, so that they can be reused by
+// other commands.
+func AddFlags(opts *Options, fs *pflag.FlagSet) {
 	fs.StringSliceVarP(&opts.Range, "range", "r", nil, "set range `from-to`")
 	fs.StringVar(&opts.RangeFormat, "range-format", "%d", "set `format` for range")
-
-	fs.StringVarP(&opts.Filename, "file", "f", "", "read values from `filename`")
+	fs.IntVar(&opts.RangeStep, "range-step", 1, "use every `n`th value of the range")
+	fs.StringVar(&opts.DateRange, "date-range", "", "use all dates in `first:last` (e.g. 2023-01-01:2023-12-31) as values")
+	fs.StringVar(&opts.DateFormat, "date-format", "2006-01-02", "format the dates with Go time `layout`, or as seconds (unix) or milliseconds (unixms) since the epoch")
+	fs.DurationVar(&opts.DateStep, "date-step", 24*time.Hour, "use a date every `duration` (e.g. 1h)")
+	fs.StringSliceVar(&opts.IPRange, "ip-range", nil, "use the IP addresses in `net,[from-to],[...]` (e.g. 10.0.0.0/24) as values")
+	fs.BoolVar(&opts.IPHostsOnly, "ip-hosts-only", false, "exclude the network and broadcast addresses of IPv4 networks for --ip-range")
+	fs.StringVar(&opts.Charset, "charset", "", "use all combinations of the characters in `chars` as values")
+	fs.StringVar(&opts.Length, "length", "1-4", "set `from-to` for the length of the values built from --charset")
+	fs.StringVar(&opts.ValuesRegex, "values-regex", "", "use all strings matching `regex` as values")
+	fs.IntVar(&opts.RegexLimit, "values-regex-limit", 100000, "refuse to generate more than `n` values from --values-regex")
+
+	fs.StringSliceVarP(&opts.Filenames, "file", "f", nil, "read values from `filename,[filename],[...]`, one after another")
+	fs.IntSliceVar(&opts.FilePrio, "file-priority", nil, "read the files for --file ordered by `n,[n],[...]` (highest first), files with the same priority alternately")
+	fs.StringArrayVar(&opts.Values, "value", nil, "read values for `placeholder:filename` (can be specified multiple times)")
+	fs.StringVar(&opts.ValuesExec, "values-exec", "", "run `command` and use the lines it prints as values")
+	fs.StringVar(&opts.Plugin, "producer-plugin", "", "run the producer plugin `command` and use the values it generates (see the help)")
+	fs.StringSliceVar(&opts.Combine, "combine", nil, "use all combinations of a value from `file1,file2` joined with each separator")
+	fs.StringVar(&opts.Usernames, "usernames", "", "use user names built from the names (\"first last\") in `file` as values")
+	fs.StringSliceVar(&opts.UserFormats, "username-format", producer.DefaultUsernameFormats, "build the user names for --usernames with `format,[format],[...]` using {first}, {last}, {f} and {l}")
+	fs.StringVar(&opts.UserDomain, "username-domain", "", "append @`domain` to the user names for --usernames")
+	fs.StringSliceVar(&opts.CombineSep, "combine-separator", []string{".", "-", "_", ""}, "join the values for --combine with `sep,[sep],[...]` (the empty string joins them directly)")
+	fs.StringVar(&opts.Rules, "rules", "", "apply the word mangling rules from `file` to each value read from a file")
+	fs.StringSliceVar(&opts.Extensions, "extensions", nil, "also send each value with the `ext,[ext],[...]` appended (e.g. .php,.bak)")
+	fs.StringSliceVar(&opts.Prefixes, "prefix", nil, "send each value with the `prefix,[prefix],[...]` prepended")
+	fs.StringSliceVar(&opts.Suffixes, "suffix", nil, "send each value with the `suffix,[suffix],[...]` appended")
+	fs.BoolVar(&opts.MethodList, "method-list", false, "use the built-in list of HTTP methods as values (e.g. with --method FUZZ)")
+	fs.StringVar(&opts.Mode, "mode", "clusterbomb", "combine the values from several --value files as `mode` (clusterbomb: all combinations, pitchfork: line by line)")
 	fs.StringVar(&opts.Logfile, "logfile", "", "write copy of printed messages to `filename`.log")
 	fs.StringVar(&opts.Logdir, "logdir", os.Getenv("MONSOON_LOG_DIR"), "automatically log all output to files in `dir`")
 
 	fs.IntVarP(&opts.Threads, "threads", "t", 5, "make as many as `n` parallel requests")
+	fs.IntVar(&opts.MaxConcurrentPerHost, "max-concurrent-per-host", 0, "make at most `n` parallel requests to the same host (0: no limit)")
+	fs.IntVar(&opts.Warmup, "warmup", 0, "send `n` requests with the first value before the run and discard the responses")
 	fs.IntVar(&opts.BufferSize, "buffer-size", 100000, "set number of buffered items to `n`")
 	fs.IntVar(&opts.Skip, "skip", 0, "skip the first `n` requests")
 	fs.IntVar(&opts.Limit, "limit", 0, "only run `n` requests, then exit")
+	fs.BoolVar(&opts.Dedup, "dedup", false, "do not use values which have been used before, e.g. when they are contained in several files")
+	fs.BoolVar(&opts.DedupCase, "dedup-ignore-case", false, "ignore the case for --dedup (implies --dedup)")
+	fs.IntVar(&opts.DedupMemory, "dedup-memory", 64, "use at most `n` MiB for --dedup, for more values a bloom filter is used which may drop a few values")
+	fs.StringSliceVar(&opts.SkipRegex, "skip-values-regex", nil, "do not use values matching `regexp`")
+	fs.StringSliceVar(&opts.SkipFile, "skip-values-file", nil, "do not use the values listed in `file` (one per line)")
+	fs.StringVar(&opts.Shuffle, "shuffle", "", "send the values in a pseudo-random order, using `seed`")
+	fs.Lookup("shuffle").NoOptDefVal = "random"
+	fs.StringVar(&opts.Shard, "shard", "", "only use the values whose index modulo total is n, given as `n/total` (e.g. 3/10)")
 	fs.Float64Var(&opts.RequestsPerSecond, "requests-per-second", 0, "do at most `n` requests per second (e.g. 0.5)")
 
 	// add all options to define a request
 	opts.Request = request.New("")
 	request.AddFlags(opts.Request, fs)
 
+	fs.StringSliceVar(&opts.Methods, "methods", nil, "send a request with each `method,[method],[...]` for every value")
+
 	fs.IntVar(&opts.FollowRedirect, "follow-redirect", 0, "follow `n` redirects")
+	fs.BoolVar(&opts.LocationTrusted, "location-trusted", false, "send the Authorization and Cookie headers to other hosts when following redirects (dangerous)")
+	fs.BoolVar(&opts.CookieJar, "cookie-jar", false, "send cookies set by the server in the following requests of a redirect chain")
+	fs.StringVar(&opts.CookieFile, "cookie-file", "", "send cookies set by the server in all following requests, load them from and save them to `file`")
 
 	fs.StringSliceVar(&opts.HideStatusCodes, "hide-status", nil, "hide responses with this status `code,[code-code],[-code],[...]`")
 	fs.StringSliceVar(&opts.ShowStatusCodes, "show-status", nil, "show only responses with this status `code,[code-code],[code-],[...]`")
+	fs.StringSliceVar(&opts.HideWSClose, "hide-ws-close", nil, "hide responses with this WebSocket close `code,[code-code],[-code],[...]`")
+	fs.StringSliceVar(&opts.ShowWSClose, "show-ws-close", nil, "show only responses with this WebSocket close `code,[code-code],[code-],[...]`")
+	fs.StringArrayVar(&opts.HideHeader, "hide-header", nil, "hide responses with a header matching `'name: regex'` (or only 'name', can be specified multiple times)")
+	fs.StringArrayVar(&opts.ShowHeader, "show-header", nil, "show only responses with a header matching `'name: regex'` (or only 'name', can be specified multiple times)")
 	fs.StringSliceVar(&opts.HideHeaderSize, "hide-header-size", nil, "hide responses with this header size (`size,from-to,from-,-to`)")
 	fs.StringSliceVar(&opts.HideBodySize, "hide-body-size", nil, "hide responses with this body size (`size,from-to,from-,-to`)")
+	fs.StringSliceVar(&opts.HideTime, "hide-time", nil, "hide responses which took this long (`>d,<d,>=d,<=d,from-to`, e.g. <100ms)")
+	fs.StringSliceVar(&opts.ShowTime, "show-time", nil, "show only responses which took this long (`>d,<d,>=d,<=d,from-to`, e.g. >2s)")
+	fs.StringSliceVar(&opts.HideWords, "hide-words", nil, "hide responses with this number of words in the body (`n,from-to,from-,-to`)")
+	fs.StringSliceVar(&opts.HideLines, "hide-lines", nil, "hide responses with this number of lines in the body (`n,from-to,from-,-to`)")
 	fs.StringArrayVar(&opts.HidePattern, "hide-pattern", nil, "hide responses containing `regex` in response header or body (can be specified multiple times)")
 	fs.StringArrayVar(&opts.ShowPattern, "show-pattern", nil, "show only responses containing `regex` in response header or body (can be specified multiple times)")
+	fs.StringArrayVar(&opts.MatchJSON, "match-jsonpath", nil, "show only responses with a JSON body matching `expr`, e.g. '$.error == null' (can be specified multiple times)")
+	fs.StringArrayVar(&opts.MatchXPath, "match-xpath", nil, "show only responses with an XML or HTML body matching `expr`, e.g. \"//status == 'ok'\" (can be specified multiple times)")
+
+	fs.BoolVar(&opts.AutoCalibrate, "auto-calibrate", false, "send requests with random values first and hide responses similar to them")
+	fs.StringArrayVar(&opts.AutoCalibrateValues, "auto-calibrate-value", nil, "use `value` for calibration instead of random values (can be specified multiple times, implies --auto-calibrate)")
+	fs.Float64Var(&opts.HideSimilar, "hide-similar", 0, "also hide responses whose body is at least `ratio` (e.g. 0.95) similar to a calibration response (implies --auto-calibrate)")
 
 	fs.StringArrayVar(&opts.Extract, "extract", nil, "extract `regex` from response body (can be specified multiple times)")
 	fs.StringArrayVar(&opts.ExtractPipe, "extract-pipe", nil, "pipe response body to `cmd` to extract data (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ExtractJSON, "extract-jsonpath", nil, "extract the value at `path` (e.g. $.user.id) from a JSON response body (can be specified multiple times)")
+	fs.StringArrayVar(&opts.ExtractXPath, "extract-xpath", nil, "extract the values selected by `xpath` (e.g. //user/@id) from an XML or HTML response body (can be specified multiple times)")
 	fs.IntVar(&opts.BodyBufferSize, "body-buffer-size", 5, "use `n` MiB as the buffer size for extracting strings from a response body")
+
+	fs.StringVar(&opts.FollowUp, "follow-up", "", "send the request template from `file` for each response which is shown")
+	fs.StringVar(&opts.FollowUpPlaceholder, "follow-up-placeholder", "DATA", "insert the extracted data into the follow-up request at `prefix`1, prefix2, ...")
+
+	fs.BoolVar(&opts.Timing, "timing", false, "record and display the duration of the phases of each request (DNS, connect, TLS, time to first byte, total)")
+
+	fs.StringVar(&opts.State, "state", "", "write the progress to `file` regularly, so the run can be resumed with --resume")
+	fs.StringVar(&opts.Resume, "resume", "", "continue the interrupted run from the state `file`")
 }
 
 // logfilePath returns the prefix for the logfiles, if any.
@@ -196,6 +625,47 @@ func logfilePath(opts *Options, inputURL string) (prefix string, err error) {
 	return opts.Logfile, nil
 }
 
+// fileGroups sorts the files by priority (highest first). Files with the same
+// priority are put in the same group, so they are read alternately. Without
+// priorities, the files are read one after another.
+func fileGroups(files []io.ReadCloser, prio []int) [][]io.ReadCloser {
+	if len(prio) == 0 {
+		groups := make([][]io.ReadCloser, 0, len(files))
+		for _, file := range files {
+			groups = append(groups, []io.ReadCloser{file})
+		}
+		return groups
+	}
+
+	index := make([]int, len(files))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		return prio[index[i]] > prio[index[j]]
+	})
+
+	var groups [][]io.ReadCloser
+	for i, idx := range index {
+		if i > 0 && prio[idx] == prio[index[i-1]] {
+			groups[len(groups)-1] = append(groups[len(groups)-1], files[idx])
+			continue
+		}
+		groups = append(groups, []io.ReadCloser{files[idx]})
+	}
+	return groups
+}
+
+// stdinValues returns true if one of the files is stdin ("-").
+func stdinValues(files []string) bool {
+	for _, filename := range files {
+		if filename == "-" {
+			return true
+		}
+	}
+	return false
+}
+
 func setupProducer(ctx context.Context, g *errgroup.Group, opts *Options, ch chan<- string, count chan<- int) error {
 	switch {
 	case len(opts.Range) > 0:
@@ -205,6 +675,10 @@ func setupProducer(ctx context.Context, g *errgroup.Group, opts *Options, ch cha
 			if err != nil {
 				return err
 			}
+			err = rng.SetStep(opts.RangeStep)
+			if err != nil {
+				return err
+			}
 
 			ranges = append(ranges, rng)
 		}
@@ -214,20 +688,159 @@ func setupProducer(ctx context.Context, g *errgroup.Group, opts *Options, ch cha
 		})
 		return nil
 
-	case opts.Filename == "-":
+	case len(opts.valueFiles) == 1 && opts.valueFiles[0] == "-":
+		// a single list from stdin is used as the values arrive
 		g.Go(func() error {
 			return producer.Reader(ctx, os.Stdin, ch, count)
 		})
 		return nil
 
-	case opts.Filename != "":
-		file, err := os.Open(opts.Filename)
+	case len(opts.valueFiles) > 0:
+		var lists [][]string
+		for _, filename := range opts.valueFiles {
+			var file io.ReadCloser = os.Stdin
+			if filename != "-" {
+				var err error
+				file, err = os.Open(filename)
+				if err != nil {
+					return err
+				}
+			}
+
+			lines, err := producer.ReadLines(file)
+			_ = file.Close()
+			if err != nil {
+				return fmt.Errorf("read %v: %v", filename, err)
+			}
+
+			lists = append(lists, lines)
+		}
+
+		combine := producer.Product
+		if opts.Mode == "pitchfork" {
+			combine = producer.Zip
+		}
+
+		g.Go(func() error {
+			return combine(ctx, lists, request.ValueSeparator, ch, count)
+		})
+		return nil
+
+	case len(opts.IPRange) > 0:
+		var ranges []producer.IPRange
+		for _, r := range opts.IPRange {
+			rng, err := producer.ParseIPRange(r, opts.IPHostsOnly)
+			if err != nil {
+				return err
+			}
+
+			ranges = append(ranges, rng)
+		}
+
+		g.Go(func() error {
+			return producer.IPRanges(ctx, ranges, ch, count)
+		})
+		return nil
+
+	case opts.DateRange != "":
+		g.Go(func() error {
+			return producer.Dates(ctx, opts.dateRange, opts.DateFormat, ch, count)
+		})
+		return nil
+
+	case len(opts.Combine) > 0:
+		var lists [][]string
+		for _, filename := range opts.Combine {
+			file, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+
+			lines, err := producer.ReadLines(file)
+			_ = file.Close()
+			if err != nil {
+				return fmt.Errorf("read %v: %v", filename, err)
+			}
+
+			lists = append(lists, lines)
+		}
+
+		// the separator is a list of its own between the two lists
+		lists = [][]string{lists[0], opts.CombineSep, lists[1]}
+		g.Go(func() error {
+			return producer.Product(ctx, lists, "", ch, count)
+		})
+		return nil
+
+	case opts.Usernames != "":
+		file, err := os.Open(opts.Usernames)
 		if err != nil {
 			return err
 		}
 
+		names, err := producer.ReadNames(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("read %v: %v", opts.Usernames, err)
+		}
+
+		list := producer.Usernames(names, opts.UserFormats, opts.UserDomain)
+		g.Go(func() error {
+			return producer.Product(ctx, [][]string{list}, "", ch, count)
+		})
+		return nil
+
+	case len(opts.valuesExec) > 0:
 		g.Go(func() error {
-			return producer.Reader(ctx, file, ch, count)
+			return producer.Command(ctx, opts.valuesExec, ch, count)
+		})
+		return nil
+
+	case len(opts.plugin) > 0:
+		g.Go(func() error {
+			return producer.Plugin(ctx, opts.plugin, ch, count)
+		})
+		return nil
+
+	case opts.valuesRegex != nil:
+		g.Go(func() error {
+			return producer.Regexes(ctx, opts.valuesRegex, ch, count)
+		})
+		return nil
+
+	case opts.Charset != "":
+		g.Go(func() error {
+			return producer.Charset(ctx, opts.Charset, opts.length, ch, count)
+		})
+		return nil
+
+	case opts.MethodList:
+		g.Go(func() error {
+			return producer.Product(ctx, [][]string{producer.HTTPMethods}, request.ValueSeparator, ch, count)
+		})
+		return nil
+
+	case len(opts.Filenames) > 0:
+		var files []io.ReadCloser
+		for _, filename := range opts.Filenames {
+			if filename == "-" {
+				files = append(files, os.Stdin)
+				continue
+			}
+
+			file, err := os.Open(filename)
+			if err != nil {
+				for _, f := range files {
+					_ = f.Close()
+				}
+				return err
+			}
+			files = append(files, file)
+		}
+
+		groups := fileGroups(files, opts.FilePrio)
+		g.Go(func() error {
+			return producer.ReaderGroups(ctx, groups, ch, count)
 		})
 		return nil
 
@@ -280,8 +893,32 @@ func setupResponseFilters(opts *Options) ([]response.Filter, error) {
 
 	filters = append(filters, filter)
 
-	if len(opts.HideHeaderSize) > 0 || len(opts.HideBodySize) > 0 {
-		f, err := response.NewFilterSize(opts.HideHeaderSize, opts.HideBodySize)
+	if len(opts.HideWSClose) > 0 || len(opts.ShowWSClose) > 0 {
+		f, err := response.NewFilterWebSocketClose(opts.HideWSClose, opts.ShowWSClose)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.HideHeader) > 0 || len(opts.ShowHeader) > 0 {
+		f, err := response.NewFilterHeader(opts.HideHeader, opts.ShowHeader)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.HideTime) > 0 || len(opts.ShowTime) > 0 {
+		f, err := response.NewFilterTime(opts.HideTime, opts.ShowTime)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(opts.HideHeaderSize) > 0 || len(opts.HideBodySize) > 0 || len(opts.HideWords) > 0 || len(opts.HideLines) > 0 {
+		f, err := response.NewFilterSize(opts.HideHeaderSize, opts.HideBodySize, opts.HideWords, opts.HideLines)
 		if err != nil {
 			return nil, err
 		}
@@ -296,10 +933,60 @@ func setupResponseFilters(opts *Options) ([]response.Filter, error) {
 		filters = append(filters, response.FilterAcceptPattern{Pattern: opts.showPattern})
 	}
 
+	if len(opts.matchJSON) > 0 || len(opts.matchXPath) > 0 {
+		filters = append(filters, response.FilterMatch{JSON: opts.matchJSON, XPath: opts.matchXPath})
+	}
+
 	return filters, nil
 }
 
 func setupValueFilters(ctx context.Context, opts *Options, valueCh <-chan string, countCh <-chan int) (<-chan string, <-chan int) {
+	if len(opts.rules) > 0 {
+		f := &producer.FilterRules{Rules: opts.rules}
+		if len(opts.Values) > 0 {
+			f.Separator = request.ValueSeparator
+		}
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
+	if len(opts.Extensions) > 0 || len(opts.Prefixes) > 0 || len(opts.Suffixes) > 0 {
+		f := &producer.FilterExpand{
+			Prefixes:   opts.Prefixes,
+			Suffixes:   opts.Suffixes,
+			Extensions: opts.Extensions,
+		}
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
+	if opts.Dedup || opts.DedupCase {
+		f := &producer.FilterDedup{
+			IgnoreCase: opts.DedupCase,
+			Memory:     opts.DedupMemory * 1024 * 1024,
+		}
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
+	if opts.exclude != nil {
+		f := opts.exclude
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
+	if opts.shard != nil {
+		f := opts.shard
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
+	if opts.shuffle != nil {
+		f := opts.shuffle
+		countCh = f.Count(ctx, countCh)
+		valueCh = f.Select(ctx, valueCh)
+	}
+
 	if opts.Skip > 0 {
 		f := &producer.FilterSkip{Skip: opts.Skip}
 		countCh = f.Count(ctx, countCh)
@@ -315,27 +1002,121 @@ func setupValueFilters(ctx context.Context, opts *Options, valueCh <-chan string
 	return valueCh, countCh
 }
 
-func startRunners(ctx context.Context, opts *Options, in <-chan string) (<-chan response.Response, error) {
-	out := make(chan response.Response)
+// newRunner returns a runner configured from opts.
+func newRunner(opts *Options, transport *http.Transport, in <-chan string, out chan<- response.Response) *response.Runner {
+	runner := response.NewRunner(transport, opts.Request, in, out)
+	runner.BodyBufferSize = opts.BodyBufferSize * 1024 * 1024
+	runner.Extract = opts.extract
 
-	var wg sync.WaitGroup
-	transport, err := response.NewTransport(opts.Request.Insecure, opts.Request.TLSClientKeyCertFile,
-		opts.Request.DisableHTTP2, opts.Threads)
-	if err != nil {
-		return nil, err
+	runner.HostFilter = opts.hostFilter
+	runner.Client.CheckRedirect = response.CheckRedirect(opts.FollowRedirect, opts.LocationTrusted, func(req *http.Request) error {
+		return response.CheckTarget(opts.Request, opts.hostFilter, req)
+	})
+	runner.CookieJar = opts.CookieJar
+	if opts.cookieJar != nil {
+		runner.Client.Jar = opts.cookieJar
+	}
+	if opts.oauth != nil {
+		runner.Client.Transport = opts.oauth.Transport(runner.Client.Transport)
 	}
+	runner.Timing = opts.Timing
+	runner.Methods = opts.Methods
+	runner.Primer = opts.primer
 
-	for i := 0; i < opts.Threads; i++ {
-		runner := response.NewRunner(transport, opts.Request, in, out)
-		runner.BodyBufferSize = opts.BodyBufferSize * 1024 * 1024
-		runner.Extract = opts.extract
+	return runner
+}
+
+// warmup sends the warmup requests with the first value read from in. The
+// value is then returned first by the returned channel, followed by all other
+// values from in.
+func warmup(ctx context.Context, opts *Options, transport *http.Transport, in <-chan string, onError func(error)) <-chan string {
+	var first string
+	select {
+	case v, ok := <-in:
+		if !ok {
+			return in
+		}
+		first = v
+	case <-ctx.Done():
+		return in
+	}
+
+	err := newRunner(opts, transport, nil, nil).Warmup(ctx, first, opts.Warmup)
+	if err != nil && ctx.Err() == nil {
+		onError(err)
+	}
 
-		runner.Client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			if len(via) <= opts.FollowRedirect {
-				return nil
+	out := make(chan string)
+	go func() {
+		defer close(out)
+
+		select {
+		case out <- first:
+		case <-ctx.Done():
+			return
+		}
+
+		for v := range in {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
 			}
-			return http.ErrUseLastResponse
 		}
+	}()
+
+	return out
+}
+
+// calibrate sends the calibration requests and returns the filter.
+func calibrate(ctx context.Context, opts *Options, transport *http.Transport) (*response.FilterCalibration, error) {
+	values := opts.AutoCalibrateValues
+	if len(values) == 0 {
+		var err error
+		values, err = response.CalibrationValues(3)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Request.Placeholders) > 0 {
+		// use the same value for all placeholders
+		joined := make([]string, 0, len(values))
+		for _, v := range values {
+			list := make([]string, len(opts.Request.Placeholders))
+			for i := range list {
+				list[i] = v
+			}
+			joined = append(joined, request.JoinValues(list))
+		}
+		values = joined
+	}
+
+	f, err := newRunner(opts, transport, nil, nil).Calibrate(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Similarity = opts.HideSimilar
+	return f, nil
+}
+
+// startRunners starts the runners, which read the values from in, or from
+// items if it is not nil.
+func startRunners(ctx context.Context, opts *Options, transport *http.Transport, in <-chan string, items <-chan response.Item) <-chan response.Response {
+	out := make(chan response.Response)
+
+	var wg sync.WaitGroup
+
+	var hostLimit *response.HostLimiter
+	if opts.MaxConcurrentPerHost > 0 {
+		hostLimit = response.NewHostLimiter(opts.MaxConcurrentPerHost)
+	}
+
+	for i := 0; i < opts.Threads; i++ {
+		runner := newRunner(opts, transport, in, out)
+		runner.HostLimit = hostLimit
+		runner.Items = items
 		wg.Add(1)
 		go func() {
 			runner.Run(ctx)
@@ -349,10 +1130,12 @@ func startRunners(ctx context.Context, opts *Options, in <-chan string) (<-chan
 		close(out)
 	}()
 
-	return out, nil
+	return out
 }
 
-func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) error {
+// Run sends the requests for the options and the target URL in args and
+// displays the responses.
+func Run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) error {
 	// make sure the options and arguments are valid
 	if len(args) == 0 {
 		return errors.New("last argument needs to be the URL")
@@ -394,32 +1177,144 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 	cch := make(chan int, 1)
 	var countCh <-chan int = cch
 
-	// start a producer from the options
-	err = setupProducer(ctx, g, opts, vch, cch)
+	// start a producer from the options, it is stopped when the run is done
+	// (e.g. with --limit) even if it has more values
+	producerCtx, cancelProducer := context.WithCancel(ctx)
+	defer cancelProducer()
+
+	err = setupProducer(producerCtx, g, opts, vch, cch)
 	if err != nil {
 		return err
 	}
 
 	// filter values (skip, limit)
-	valueCh, countCh = setupValueFilters(ctx, opts, valueCh, countCh)
+	valueCh, countCh = setupValueFilters(producerCtx, opts, valueCh, countCh)
+
+	// with several methods, more than one request is sent for each value
+	if len(opts.Methods) > 1 {
+		countCh = producer.MultiplyCount(ctx, countCh, len(opts.Methods))
+	}
 
 	// limit the throughput (if requested)
 	if opts.RequestsPerSecond > 0 {
 		valueCh = producer.Limit(ctx, opts.RequestsPerSecond, valueCh)
 	}
 
-	// start the runners
-	responseCh, err := startRunners(ctx, opts, valueCh)
+	// load rotating headers and run the pre-request command once before any
+	// request is sent
+	err = opts.Request.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	transport, err := response.NewTransport(opts.Request, opts.Threads)
+	if err != nil {
+		return err
+	}
+
+	opts.hostFilter, err = response.NewHostFilter(opts.Request)
+	if err != nil {
+		return err
+	}
+
+	// share the cookies between all requests
+	if opts.CookieFile != "" {
+		opts.cookieJar, err = response.NewPersistentJar(opts.CookieFile)
+		if err != nil {
+			return fmt.Errorf("load cookies: %v", err)
+		}
+	}
+
+	// send the prime request once before any request is sent
+	opts.primer, err = response.NewPrimer(opts.Request, transport)
 	if err != nil {
 		return err
 	}
 
+	if opts.primer != nil {
+		opts.primer.HostFilter = opts.hostFilter
+		err = opts.primer.Run(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// request the OAuth token once before any request is sent
+	opts.oauth, err = response.NewOAuthClient(opts.Request, transport)
+	if err != nil {
+		return err
+	}
+
+	if opts.oauth != nil {
+		opts.oauth.HostFilter = opts.hostFilter
+		opts.oauth.Error = func(err error) {
+			term.Printf("%v\n", err)
+		}
+
+		err = opts.oauth.Run(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// refresh the output of the pre-request command, the value from the
+	// prime request and the OAuth token regularly until all requests are done
+	refreshCtx, cancelRefresh := context.WithCancel(ctx)
+	defer cancelRefresh()
+	go opts.Request.RefreshPreRequestCommand(refreshCtx, func(err error) {
+		term.Printf("%v\n", err)
+	})
+
+	if opts.primer != nil {
+		go opts.primer.Refresh(refreshCtx, func(err error) {
+			term.Printf("%v\n", err)
+		})
+	}
+
+	if opts.oauth != nil {
+		go opts.oauth.Refresh(refreshCtx, func(err error) {
+			term.Printf("%v\n", err)
+		})
+	}
+
+	// establish the connection (and a TLS session to resume) before the run
+	if opts.Warmup > 0 {
+		transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(opts.Threads)
+		valueCh = warmup(ctx, opts, transport, valueCh, func(err error) {
+			term.Printf("warmup: %v\n", err)
+		})
+	}
+
+	// hide responses which are similar to the ones for bogus values
+	if opts.AutoCalibrate || len(opts.AutoCalibrateValues) > 0 || opts.HideSimilar > 0 {
+		f, err := calibrate(ctx, opts, transport)
+		if err != nil {
+			return err
+		}
+
+		term.Printf("calibration: hiding responses with %v\n", f)
+		responseFilters = append(responseFilters, f)
+	}
+
+	// track the progress for resuming the run
+	var checkpoint *checkpoint
+	var itemCh <-chan response.Item
+	if opts.State != "" {
+		checkpoint = newCheckpoint(opts, inputURL)
+		itemCh = checkpoint.Values(ctx, valueCh)
+	}
+
+	// start the runners
+	responseCh := startRunners(ctx, opts, transport, valueCh, itemCh)
+
 	// filter the responses
 	responseCh = response.Mark(responseCh, responseFilters)
 
 	// extract data from all interesting (non-hidden) responses
 	extracter := &response.Extracter{
 		Pattern:  opts.extract,
+		JSONPath: opts.ExtractJSON,
+		XPath:    opts.extractXPath,
 		Commands: opts.extractPipe,
 		Error: func(err error) {
 			term.Printf("%v", err)
@@ -427,6 +1322,26 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 	}
 	responseCh = extracter.Run(responseCh)
 
+	// send the follow-up request for all interesting responses
+	if opts.FollowUp != "" {
+		followUp, err := response.NewFollowUp(opts.Request, opts.FollowUp, opts.FollowUpPlaceholder, transport)
+		if err != nil {
+			return err
+		}
+
+		if opts.cookieJar != nil {
+			followUp.Client.Jar = opts.cookieJar
+		}
+
+		responseCh = followUp.Run(ctx, responseCh)
+	}
+
+	if checkpoint != nil {
+		responseCh = checkpoint.Responses(ctx, responseCh, func(err error) {
+			term.Printf("%v\n", err)
+		})
+	}
+
 	if logfilePrefix != "" {
 		rec, err := recorder.New(logfilePrefix+".json", opts.Request)
 		if err != nil {
@@ -434,11 +1349,58 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 		}
 
 		// fill in information for generating the request
-		rec.Data.InputFile = opts.Filename
+		if len(opts.Filenames) == 1 {
+			rec.Data.InputFile = opts.Filenames[0]
+		} else {
+			rec.Data.InputFiles = opts.Filenames
+			rec.Data.FilePrio = opts.FilePrio
+		}
+		rec.Data.Values = opts.Values
+		if len(opts.Values) > 0 {
+			rec.Data.Mode = opts.Mode
+		}
 		rec.Data.Ranges = opts.Range
 		rec.Data.RangeFormat = opts.RangeFormat
+		if opts.RangeStep > 1 {
+			rec.Data.RangeStep = opts.RangeStep
+		}
+		rec.Data.IPRange = opts.IPRange
+		if opts.DateRange != "" {
+			rec.Data.DateRange = opts.DateRange
+			rec.Data.DateFormat = opts.DateFormat
+		}
+		if opts.Charset != "" {
+			rec.Data.Charset = opts.Charset
+			rec.Data.Length = opts.Length
+		}
+		rec.Data.ValuesRegex = opts.ValuesRegex
+		rec.Data.ValuesExec = opts.ValuesExec
+		rec.Data.Plugin = opts.Plugin
+		if len(opts.Combine) > 0 {
+			rec.Data.Combine = opts.Combine
+			rec.Data.CombineSep = opts.CombineSep
+		}
+		if opts.Usernames != "" {
+			rec.Data.Usernames = opts.Usernames
+			rec.Data.UserFormats = opts.UserFormats
+			rec.Data.UserDomain = opts.UserDomain
+		}
+		rec.Data.Rules = opts.Rules
+		rec.Data.Extensions = opts.Extensions
+		rec.Data.Prefixes = opts.Prefixes
+		rec.Data.Suffixes = opts.Suffixes
+		rec.Data.Dedup = opts.Dedup || opts.DedupCase
+		rec.Data.DedupCase = opts.DedupCase
+		rec.Data.SkipRegex = opts.SkipRegex
+		rec.Data.SkipFile = opts.SkipFile
+		rec.Data.Shard = opts.Shard
+		if opts.shuffle != nil {
+			rec.Data.ShuffleSeed = strconv.FormatInt(opts.shuffle.Seed, 10)
+		}
 		rec.Data.Extract = opts.Extract
 		rec.Data.ExtractPipe = opts.ExtractPipe
+		rec.Data.ExtractJSON = opts.ExtractJSON
+		rec.Data.ExtractXP = opts.ExtractXPath
 
 		out := make(chan response.Response)
 		in := responseCh
@@ -455,6 +1417,26 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 
 	// run the reporter
 	term.Printf("input URL %v\n\n", inputURL)
+	if opts.shuffle != nil {
+		term.Printf("shuffling values with seed %d\n\n", opts.shuffle.Seed)
+	}
+	if opts.resumed != nil {
+		term.Printf("resuming at value %d, %d responses were shown before:\n", opts.resumed.Done, len(opts.resumed.Shown))
+		for _, line := range opts.resumed.Shown {
+			term.Printf("%v\n", line)
+		}
+		term.Printf("\n")
+	}
 	reporter := reporter.New(term)
-	return reporter.Display(responseCh, countCh)
+	err = reporter.Display(responseCh, countCh)
+
+	// the runners are done when all responses have been displayed
+	if opts.cookieJar != nil {
+		saveErr := opts.cookieJar.Save(opts.CookieFile)
+		if saveErr != nil && err == nil {
+			err = fmt.Errorf("save cookies: %v", saveErr)
+		}
+	}
+
+	return err
 }
@@ -0,0 +1,98 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestFilePriority(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-files-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	var filenames []string
+	for i, data := range []string{"a1\na2\na3\n", "b1\nb2\n", "c1\nc2\nc3\n", "d1\n"} {
+		filename := filepath.Join(tempdir, fmt.Sprintf("file%d.txt", i))
+		err := ioutil.WriteFile(filename, []byte(data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filenames = append(filenames, filename)
+	}
+
+	var tests = []struct {
+		prio []int
+		want []string
+	}{
+		{
+			// without priorities the files are read one after another
+			prio: nil,
+			want: []string{"a1", "a2", "a3", "b1", "b2", "c1", "c2", "c3", "d1"},
+		},
+		{
+			prio: []int{1, 1, 1, 1},
+			want: []string{"a1", "b1", "c1", "d1", "a2", "b2", "c2", "a3", "c3"},
+		},
+		{
+			prio: []int{1, 2, 3, 4},
+			want: []string{"d1", "c1", "c2", "c3", "b1", "b2", "a1", "a2", "a3"},
+		},
+		{
+			// files with the same priority are read alternately, in the
+			// order in which they were specified
+			prio: []int{1, 10, 1, 10},
+			want: []string{"b1", "d1", "b2", "a1", "c1", "a2", "c2", "a3", "c3"},
+		},
+		{
+			prio: []int{5, -1, 0, 5},
+			want: []string{"a1", "d1", "a2", "a3", "c1", "c2", "c3", "b1", "b2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			opts := &Options{Filenames: filenames, FilePrio: test.prio}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			g, ctx := errgroup.WithContext(ctx)
+			ch := make(chan string)
+			count := make(chan int, 1)
+
+			err := setupProducer(ctx, g, opts, ch, count)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var values []string
+			for v := range ch {
+				values = append(values, v)
+			}
+
+			err = g.Wait()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if n := <-count; n != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), n)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package fuzz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/RedTeamPentesting/monsoon/response"
+	"github.com/spf13/cobra"
+)
+
+// stateInterval is the time between two writes of the state file.
+const stateInterval = 5 * time.Second
+
+// State is the progress of a run, which is written to the state file, so that
+// an interrupted run can be resumed with --resume.
+type State struct {
+	Command     string    `json:"command"`
+	Args        []string  `json:"args"`
+	OptionsHash string    `json:"options_hash"`
+	Target      string    `json:"target"`
+	Updated     time.Time `json:"updated"`
+	Finished    bool      `json:"finished"`
+
+	// Done is the number of values (from the start of the list, including
+	// skipped ones) for which all requests have been sent and the responses
+	// have been received.
+	Done int `json:"done"`
+
+	// Shown contains the responses which have been displayed so far.
+	Shown []string `json:"shown,omitempty"`
+
+	// Secrets references the sensitive values which have been removed from
+	// Args, they are read from environment variables when the run is
+	// resumed.
+	Secrets []request.ArgSecret `json:"secrets,omitempty"`
+}
+
+// optionsHash returns a hash over the command line arguments.
+func optionsHash(command string, args []string) string {
+	h := sha256.Sum256([]byte(command + "\x00" + strings.Join(args, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// commandArgs returns the arguments following the command name in os.Args.
+func commandArgs(name string) []string {
+	for i, arg := range os.Args[1:] {
+		if arg == name {
+			return append([]string{}, os.Args[i+2:]...)
+		}
+	}
+	return nil
+}
+
+// ReadState loads the state from filename and checks that it has not been
+// modified.
+func ReadState(filename string) (*State, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	err = json.Unmarshal(buf, &state)
+	if err != nil {
+		return nil, fmt.Errorf("parse state file %v: %v", filename, err)
+	}
+
+	if state.OptionsHash != optionsHash(state.Command, state.Args) {
+		return nil, fmt.Errorf("state file %v: the options have been modified", filename)
+	}
+
+	return &state, nil
+}
+
+// PrepareResume loads the state file from opts.Resume and parses the command
+// line arguments of the interrupted run stored in it into the flags of c. The
+// values which have been processed are skipped. It returns the positional
+// arguments. If opts.Resume is not set, the command line arguments for the
+// state file are recorded (without sensitive values) and args is returned
+// unchanged.
+func PrepareResume(c *cobra.Command, opts *Options, args []string) ([]string, error) {
+	opts.stateCommand = c.Name()
+	if opts.Resume == "" {
+		var env map[string]string
+		opts.stateArgs, opts.stateSecrets, env = request.RedactArgs(opts.Request, c.Flags(), commandArgs(c.Name()))
+
+		if opts.State != "" && len(env) > 0 {
+			names := make([]string, 0, len(env))
+			for name := range env {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Fprintf(os.Stderr, "sensitive values are not written to the state file %v, set the following environment variables before resuming:\n", opts.State)
+			for _, name := range names {
+				fmt.Fprintf(os.Stderr, "  %v\n", name)
+			}
+		}
+
+		return args, nil
+	}
+
+	if c.Flags().NFlag() > 1 || len(args) > 0 {
+		return nil, errors.New("--resume cannot be used together with other options or arguments")
+	}
+
+	state, err := ReadState(opts.Resume)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Command != c.Name() {
+		return nil, fmt.Errorf("state file %v was written by the %q command, use 'monsoon %v --resume'", opts.Resume, state.Command, state.Command)
+	}
+
+	if state.Finished {
+		return nil, fmt.Errorf("the run in state file %v has already finished", opts.Resume)
+	}
+
+	stateArgs, err := request.InsertArgSecrets(state.Args, state.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("state file %v: %v", opts.Resume, err)
+	}
+
+	resume := opts.Resume
+	err = c.Flags().Parse(stateArgs)
+	if err != nil {
+		return nil, fmt.Errorf("state file %v: %v", resume, err)
+	}
+
+	// continue with the first value which has not been processed
+	if opts.Limit > 0 {
+		opts.Limit -= state.Done - opts.Skip
+		if opts.Limit <= 0 {
+			return nil, fmt.Errorf("the run in state file %v has already finished", resume)
+		}
+	}
+	opts.Skip = state.Done
+
+	opts.Resume = resume
+	opts.stateArgs = state.Args
+	opts.stateSecrets = state.Secrets
+	opts.resumed = state
+
+	return c.Flags().Args(), nil
+}
+
+// checkpoint tracks the values for which all responses have been received
+// and writes the state file regularly. The values are numbered in the order
+// in which they are sent, so the same value at several positions in the list
+// is tracked separately.
+type checkpoint struct {
+	filename string
+	perValue int // number of responses for each value
+
+	mu      sync.Mutex
+	state   State
+	seq     int   // the sequence number for the next value
+	first   int   // the sequence number of the first pending value
+	pending []int // number of responses received for the values sent, starting with first
+}
+
+// shuffleArgs returns args with --shuffle without a seed replaced by the
+// seed which is used, so that a resumed run sends the values in the same
+// order.
+func shuffleArgs(args []string, seed int64) []string {
+	res := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--shuffle" || arg == "--shuffle=random" {
+			arg = "--shuffle=" + strconv.FormatInt(seed, 10)
+		}
+		res = append(res, arg)
+	}
+	return append(res, args[len(res):]...)
+}
+
+func newCheckpoint(opts *Options, target string) *checkpoint {
+	args := opts.stateArgs
+	if opts.shuffle != nil {
+		args = shuffleArgs(args, opts.shuffle.Seed)
+	}
+
+	c := &checkpoint{
+		filename: opts.State,
+		perValue: 1,
+		state: State{
+			Command:     opts.stateCommand,
+			Args:        args,
+			OptionsHash: optionsHash(opts.stateCommand, args),
+			Target:      request.RedactURL(target),
+			Done:        opts.Skip,
+			Secrets:     opts.stateSecrets,
+		},
+	}
+
+	if len(opts.Methods) > 1 {
+		c.perValue = len(opts.Methods)
+	}
+
+	if opts.resumed != nil {
+		c.state.Shown = opts.resumed.Shown
+	}
+
+	return c
+}
+
+// Values records the values sent to the runners and numbers them.
+func (c *checkpoint) Values(ctx context.Context, in <-chan string) <-chan response.Item {
+	out := make(chan response.Item)
+
+	go func() {
+		defer close(out)
+		for v := range in {
+			c.mu.Lock()
+			item := response.Item{Value: v, Seq: c.seq}
+			c.seq++
+			c.pending = append(c.pending, 0)
+			c.mu.Unlock()
+
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// record marks the value of res as processed, if all its responses have been
+// received.
+func (c *checkpoint) record(res response.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// requests which have been cancelled need to be sent again
+	if res.Error != nil && errors.Is(res.Error, context.Canceled) {
+		return
+	}
+
+	i := res.Seq - c.first
+	if i >= 0 && i < len(c.pending) && c.pending[i] < c.perValue {
+		c.pending[i]++
+	}
+
+	for len(c.pending) > 0 && c.pending[0] == c.perValue {
+		c.pending = c.pending[1:]
+		c.first++
+		c.state.Done++
+	}
+
+	if !res.Hide {
+		c.state.Shown = append(c.state.Shown, res.String())
+	}
+}
+
+// write saves the state to the file.
+func (c *checkpoint) write(finished bool) error {
+	c.mu.Lock()
+	c.state.Updated = time.Now()
+	c.state.Finished = finished
+	buf, err := json.MarshalIndent(c.state, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so the state is never lost, it is
+	// created with mode 0600 and only readable by the current user
+	tmp, err := ioutil.TempFile(filepath.Dir(c.filename), filepath.Base(c.filename)+".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(append(buf, '\n'))
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.filename)
+}
+
+// Responses records the responses and writes the state file every
+// stateInterval and when in is closed. Errors are passed to onError.
+func (c *checkpoint) Responses(ctx context.Context, in <-chan response.Response, onError func(error)) <-chan response.Response {
+	out := make(chan response.Response)
+
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(stateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case res, ok := <-in:
+				if !ok {
+					err := c.write(ctx.Err() == nil)
+					if err != nil {
+						onError(fmt.Errorf("write state file: %v", err))
+					}
+					return
+				}
+
+				c.record(res)
+				out <- res
+
+			case <-ticker.C:
+				err := c.write(false)
+				if err != nil {
+					onError(fmt.Errorf("write state file: %v", err))
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -2,12 +2,14 @@ package show
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"net/http/httputil"
+	"net"
 	"os"
 
 	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/RedTeamPentesting/monsoon/response"
 	"github.com/spf13/cobra"
 )
 
@@ -41,6 +43,11 @@ var cmd = &cobra.Command{
 	Example: helpExamples,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
+		args, err := request.ProcessConfig(opts.Request, cmd.Flags(), args)
+		if err != nil {
+			return err
+		}
+
 		if len(args) == 0 {
 			return errors.New("last argument needs to be the URL")
 		}
@@ -51,7 +58,35 @@ var cmd = &cobra.Command{
 
 		opts.Request.URL = args[0]
 
-		req, err := opts.Request.Apply(opts.Value)
+		err = opts.Request.Prepare(context.Background())
+		if err != nil {
+			return err
+		}
+
+		// the prime request is sent so that the extracted value can be shown
+		if opts.Request.PrimeRequestFile != "" {
+			tr, err := response.NewTransport(opts.Request, 1)
+			if err != nil {
+				return err
+			}
+
+			primer, err := response.NewPrimer(opts.Request, tr)
+			if err != nil {
+				return err
+			}
+
+			primer.HostFilter, err = response.NewHostFilter(opts.Request)
+			if err != nil {
+				return err
+			}
+
+			err = primer.Run(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+
+		req, err := opts.Request.ApplyNext(opts.Value)
 		if err != nil {
 			return err
 		}
@@ -61,11 +96,26 @@ var cmd = &cobra.Command{
 			return err
 		}
 
+		addr, err := opts.Request.DialAddress(opts.Value, net.JoinHostPort(host, port))
+		if err != nil {
+			return err
+		}
+
+		host, port, err = net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+
 		// remote server
 		fmt.Printf("remote %v, port %v\n\n", host, port)
 
+		// the fragment is not part of the request sent to the server
+		if req.URL.Fragment != "" {
+			fmt.Printf("fragment %v (not sent)\n\n", req.URL.Fragment)
+		}
+
 		// print request with body
-		buf, err := httputil.DumpRequestOut(req, true)
+		buf, err := response.DumpRequest(opts.Request, opts.Value, req)
 		if err != nil {
 			return err
 		}
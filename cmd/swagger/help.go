@@ -0,0 +1,48 @@
+package swagger
+
+import (
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+const helpShort = "Build request templates from an OpenAPI or Swagger description"
+
+var helpLong = strings.TrimSpace(`
+The 'swagger' command reads an API description in the OpenAPI 3 or Swagger
+2.0 format (JSON only, YAML needs to be converted first) and builds a request
+template for each operation. The placeholder is inserted into all parameters:
+the path parameters, the query string, the header and cookie parameters and all
+strings in the JSON body, numbers and booleans are set to their example
+values. Forms are sent URL encoded or as multipart/form-data, depending on the
+description.
+
+The templates are printed, or written to a directory with --output so that
+they can be used with 'monsoon fuzz --template-file'. With --run the request
+for each operation is sent once (with the value from --value) to the server
+from the description or the URL passed as the last argument, which must not
+contain a path. The other options are the same as for the 'fuzz' command and
+apply to all requests.
+` + request.LongHelp)
+
+const helpExamples = `
+Print the templates for all operations in the file 'api.json':
+
+    monsoon swagger --spec api.json
+
+Write the templates to the directory 'templates' and fuzz the first one:
+
+    monsoon swagger --spec api.json --output templates
+    monsoon fuzz --template-file templates/001-get-listPets.txt \
+      --file values.txt https://api.example.com
+
+Send each operation once to a test server with an API key:
+
+    monsoon swagger --spec api.json --run --value 1 \
+      --header "X-API-Key: secret" \
+      http://localhost:8080
+
+A YAML description can be converted with yq beforehand:
+
+    yq -o json api.yaml > api.json
+`
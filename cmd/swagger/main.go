@@ -0,0 +1,230 @@
+package swagger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/openapi"
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/RedTeamPentesting/monsoon/response"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// Options collect options for the command.
+type Options struct {
+	Request *request.Request // the template for the HTTP request
+	Spec    string
+	Output  string
+	Run     bool
+	Value   string
+}
+
+var opts Options
+
+// AddCommand adds the command to c.
+func AddCommand(c *cobra.Command) {
+	c.AddCommand(cmd)
+
+	fs := cmd.Flags()
+	fs.SortFlags = false
+
+	fs.StringVar(&opts.Spec, "spec", "", "read the API description from `file` (OpenAPI 3 or Swagger 2.0, JSON)")
+	fs.StringVar(&opts.Spec, "openapi", "", "same as --spec")
+	fs.StringVar(&opts.Output, "output", "", "write one template file for each operation to `dir`")
+	fs.BoolVar(&opts.Run, "run", false, "send the request for each operation once and print the responses")
+	fs.StringVarP(&opts.Value, "value", "v", "test", "use `string` for the placeholder with --run")
+
+	opts.Request = request.New("")
+	request.AddFlags(opts.Request, fs)
+}
+
+func header(name string) string {
+	if len(name) > 70 {
+		return name
+	}
+
+	return fmt.Sprintf("---- %s %s", name, strings.Repeat("-", 80-5-len(name)))
+}
+
+var cmd = &cobra.Command{
+	Use:                   "swagger [options] --spec file [URL]",
+	DisableFlagsInUseLine: true,
+
+	Short:   helpShort,
+	Long:    helpLong,
+	Example: helpExamples,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		args, err := request.ProcessConfig(opts.Request, cmd.Flags(), args)
+		if err != nil {
+			return err
+		}
+
+		return cli.WithContext(func(ctx context.Context, g *errgroup.Group) error {
+			return run(ctx, g, &opts, args)
+		})
+	},
+}
+
+func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) error {
+	if opts.Spec == "" {
+		return errors.New("no API description specified (--spec)")
+	}
+
+	if len(args) > 1 {
+		return errors.New("more than one target URL specified")
+	}
+
+	if opts.Request.TemplateFile != "" {
+		return errors.New("--template-file cannot be used, the templates are built from the API description")
+	}
+
+	buf, err := ioutil.ReadFile(opts.Spec)
+	if err != nil {
+		return err
+	}
+
+	spec, err := openapi.Parse(buf, opts.Request.Replace)
+	if err != nil {
+		return fmt.Errorf("%v: %v", opts.Spec, err)
+	}
+
+	if len(spec.Operations) == 0 {
+		return fmt.Errorf("%v: no operations found", opts.Spec)
+	}
+
+	if opts.Output != "" {
+		err = writeTemplates(opts.Output, spec.Operations)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Run {
+		baseURL := spec.BaseURL
+		if len(args) == 1 {
+			baseURL = args[0]
+		}
+
+		if baseURL == "" {
+			return errors.New("the API description does not contain the server URL, pass it as the last argument")
+		}
+
+		return runOperations(ctx, opts, baseURL, spec.Operations)
+	}
+
+	if opts.Output == "" {
+		for _, op := range spec.Operations {
+			fmt.Println(header(op.Method + " " + op.Path))
+			fmt.Printf("%s\n\n", bytes.TrimRight(op.Template, "\r\n"))
+		}
+	}
+
+	return nil
+}
+
+// writeTemplates writes a file for each operation to dir.
+func writeTemplates(dir string, ops []openapi.Operation) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for i, op := range ops {
+		filename := filepath.Join(dir, fmt.Sprintf("%03d-%s.txt", i+1, op.Name()))
+		err = ioutil.WriteFile(filename, op.Template, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-7s %-40s %v\n", op.Method, op.Path, filename)
+	}
+
+	fmt.Printf("wrote %d templates to %v\n", len(ops), dir)
+	return nil
+}
+
+// runOperations sends the request for each operation to baseURL once.
+func runOperations(ctx context.Context, opts *Options, baseURL string, ops []openapi.Operation) error {
+	opts.Request.URL = baseURL
+
+	err := opts.Request.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	tr, err := response.NewTransport(opts.Request, 1)
+	if err != nil {
+		return err
+	}
+
+	filter, err := response.NewHostFilter(opts.Request)
+	if err != nil {
+		return err
+	}
+
+	primer, err := response.NewPrimer(opts.Request, tr)
+	if err != nil {
+		return err
+	}
+
+	if primer != nil {
+		primer.HostFilter = filter
+		err = primer.Run(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	oauth, err := response.NewOAuthClient(opts.Request, tr)
+	if err != nil {
+		return err
+	}
+
+	if oauth != nil {
+		oauth.HostFilter = filter
+		err = oauth.Run(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%7s %8s %8s   %v\n", "status", "header", "body", "operation")
+	for _, op := range ops {
+		opts.Request.TemplateData = op.Template
+
+		input := make(chan string, 1)
+		input <- opts.Value
+		close(input)
+
+		output := make(chan response.Response, 1)
+
+		runner := response.NewRunner(tr, opts.Request, input, output)
+		runner.HostFilter = filter
+		if oauth != nil {
+			runner.Client.Transport = oauth.Transport(runner.Client.Transport)
+		}
+		runner.Run(ctx)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		res := <-output
+		if res.Error != nil {
+			fmt.Printf("%7s %18s   %v %v\n", "error", res.Error, op.Method, op.Path)
+			continue
+		}
+
+		fmt.Printf("%7d %8d %8d   %v %v\n", res.HTTPResponse.StatusCode, res.Header.Bytes, res.Body.Bytes, op.Method, op.Path)
+	}
+
+	return nil
+}
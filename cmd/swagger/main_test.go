@@ -0,0 +1,246 @@
+package swagger
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sync/errgroup"
+)
+
+// recorder records the requests it receives.
+type recorder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	line := r.Method + " " + r.RequestURI
+	if r.Header.Get("X-Request-ID") != "" {
+		line += " X-Request-ID: " + r.Header.Get("X-Request-ID")
+	}
+	if c, err := r.Cookie("session"); err == nil {
+		line += " session=" + c.Value
+	}
+	if len(body) > 0 {
+		line += " " + strings.Join(strings.Fields(string(body)), " ")
+	}
+
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, line)
+	rec.mu.Unlock()
+
+	_, _ = w.Write([]byte("ok"))
+}
+
+// runSwagger runs the command with opts and args.
+func runSwagger(opts *Options, args ...string) error {
+	if opts.Request == nil {
+		opts.Request = request.New("")
+	}
+
+	var g errgroup.Group
+	return run(context.Background(), &g, opts, args)
+}
+
+func TestRunTemplates(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-swagger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	err = runSwagger(&Options{
+		Spec:   filepath.Join("testdata", "petstore.json"),
+		Output: tempdir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ioutil.ReadDir(tempdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	wantNames := []string{
+		"001-get-listPets.txt",
+		"002-post-createPet.txt",
+		"003-get-pets_id.txt",
+		"004-delete-pets_id.txt",
+	}
+	if !cmp.Equal(wantNames, names) {
+		t.Error(cmp.Diff(wantNames, names))
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(tempdir, "003-get-pets_id.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "GET /v1/pets/FUZZ HTTP/1.1\r\n" +
+		"Host: api.example.com\r\n" +
+		"X-Request-ID: FUZZ\r\n" +
+		"\r\n"
+	if string(buf) != want {
+		t.Errorf("wrong template, want:\n%q\ngot:\n%q", want, buf)
+	}
+}
+
+func TestRunOperations(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	// the base path from the description is kept, only scheme and host are
+	// taken from the URL
+	err := runSwagger(&Options{
+		Spec:  filepath.Join("testdata", "petstore.json"),
+		Run:   true,
+		Value: "23",
+	}, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /v1/pets?limit=23",
+		`POST /v1/pets { "name": "23" }`,
+		"GET /v1/pets/23 X-Request-ID: 23",
+		"DELETE /v1/pets/23",
+	}
+	if !cmp.Equal(want, rec.requests) {
+		t.Error(cmp.Diff(want, rec.requests))
+	}
+}
+
+func TestRunServerURL(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-swagger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	buf, err := ioutil.ReadFile(filepath.Join("testdata", "openapi.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// point the default value of the server variable to the test server
+	host := strings.TrimPrefix(srv.URL, "http://")
+	buf = []byte(strings.Replace(string(buf), `"api.example.com"`, `"`+host+`"`, 1))
+
+	spec := filepath.Join(tempdir, "openapi.json")
+	err = ioutil.WriteFile(spec, buf, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runSwagger(&Options{
+		Spec:  spec,
+		Run:   true,
+		Value: "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"GET /api/users/admin session=admin"}
+	if !cmp.Equal(want, rec.requests) {
+		t.Error(cmp.Diff(want, rec.requests))
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-swagger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	// a description without a server URL
+	noServer := filepath.Join(tempdir, "noserver.json")
+	err = ioutil.WriteFile(noServer, []byte(`{"openapi": "3.0.0", "paths": {"/": {"get": {}}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a description with a path, but without operations
+	noOps := filepath.Join(tempdir, "noops.json")
+	err = ioutil.WriteFile(noOps, []byte(`{"openapi": "3.0.0", "paths": {"/": {}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		opts Options
+		args []string
+		err  string
+	}{
+		{
+			err: "no API description specified",
+		},
+		{
+			opts: Options{Spec: filepath.Join("testdata", "petstore.json")},
+			args: []string{"http://one.example.com", "http://two.example.com"},
+			err:  "more than one target URL",
+		},
+		{
+			opts: Options{
+				Spec:    filepath.Join("testdata", "petstore.json"),
+				Request: &request.Request{TemplateFile: "request.txt"},
+			},
+			err: "--template-file cannot be used",
+		},
+		{
+			opts: Options{Spec: filepath.Join(tempdir, "missing.json")},
+			err:  "missing.json",
+		},
+		{
+			opts: Options{Spec: noOps},
+			err:  "no operations found",
+		},
+		{
+			opts: Options{Spec: noServer, Run: true},
+			err:  "does not contain the server URL",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			err := runSwagger(&test.opts, test.args...)
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("wrong error, want %q, got %q", test.err, err)
+			}
+		})
+	}
+}
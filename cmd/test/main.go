@@ -5,7 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http/httputil"
+	"net"
 	"os"
 	"strings"
 
@@ -60,6 +60,11 @@ var cmd = &cobra.Command{
 	Example: helpExamples,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
+		args, err := request.ProcessConfig(opts.Request, cmd.Flags(), args)
+		if err != nil {
+			return err
+		}
+
 		return cli.WithContext(func(ctx context.Context, g *errgroup.Group) error {
 			return run(ctx, g, &opts, args)
 		})
@@ -77,7 +82,48 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 
 	opts.Request.URL = args[0]
 
-	req, err := opts.Request.Apply(opts.Value)
+	err := opts.Request.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	tr, err := response.NewTransport(opts.Request, 1)
+	if err != nil {
+		return err
+	}
+
+	filter, err := response.NewHostFilter(opts.Request)
+	if err != nil {
+		return err
+	}
+
+	primer, err := response.NewPrimer(opts.Request, tr)
+	if err != nil {
+		return err
+	}
+
+	if primer != nil {
+		primer.HostFilter = filter
+		err = primer.Run(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	oauth, err := response.NewOAuthClient(opts.Request, tr)
+	if err != nil {
+		return err
+	}
+
+	if oauth != nil {
+		oauth.HostFilter = filter
+		err = oauth.Run(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := opts.Request.ApplyNext(opts.Value)
 	if err != nil {
 		return err
 	}
@@ -87,13 +133,28 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 		return err
 	}
 
+	addr, err := opts.Request.DialAddress(opts.Value, net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+
+	host, port, err = net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
 	// remote server
 	fmt.Printf("remote %v, port %v\n\n", host, port)
 
+	// the token is added by the transport, show it in the request
+	if oauth != nil {
+		oauth.Authorize(req)
+	}
+
 	if opts.ShowRequest {
 		fmt.Println(header("request"))
 		// print request with body
-		buf, err := httputil.DumpRequestOut(req, true)
+		buf, err := response.DumpRequest(opts.Request, opts.Value, req)
 		if err != nil {
 			return err
 		}
@@ -115,13 +176,11 @@ func run(ctx context.Context, g *errgroup.Group, opts *Options, args []string) e
 
 	output := make(chan response.Response, 1)
 
-	tr, err := response.NewTransport(opts.Request.Insecure, opts.Request.TLSClientKeyCertFile,
-		opts.Request.DisableHTTP2, 1)
-	if err != nil {
-		return err
-	}
-
 	runner := response.NewRunner(tr, opts.Request, input, output)
+	runner.HostFilter = filter
+	if oauth != nil {
+		runner.Client.Transport = oauth.Transport(runner.Client.Transport)
+	}
 	runner.Run(ctx)
 	close(output)
 
@@ -0,0 +1,42 @@
+package vhost
+
+import (
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+const helpShort = "Discover virtual hosts by fuzzing the Host header"
+
+var helpLong = strings.TrimSpace(`
+The 'vhost' command sends requests to a fixed URL (usually an IP address) with
+each value inserted into the Host header, in order to find the virtual hosts
+served by a web server. With --domain, the domain is appended to each value, so
+that a list of subdomains can be used.
+
+Before the run, requests with random host names are sent to establish the
+response for unknown hosts (like --auto-calibrate for the 'fuzz' command), and
+only responses which deviate from it are shown. The calibration values can be
+set with --auto-calibrate-value, the responses can be filtered further with
+the options of the 'fuzz' command, which are all available.
+
+For HTTPS, the server name sent in the TLS handshake (SNI) is still taken from
+the URL, a fixed one can be set with --sni. Since the certificate is verified
+for the host in the URL, --insecure is usually needed when the URL contains an
+IP address.
+` + request.LongHelp)
+
+const helpExamples = `
+Find subdomains of example.com served by the web server at 192.0.2.10:
+
+    monsoon vhost --file subdomains.txt \
+      --domain example.com \
+      http://192.0.2.10/
+
+Try the host names in hosts.txt over HTTPS and hide the redirects:
+
+    monsoon vhost --file hosts.txt \
+      --insecure \
+      --hide-status 300-399 \
+      https://192.0.2.10/
+`
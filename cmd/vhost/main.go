@@ -0,0 +1,92 @@
+package vhost
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/cmd/fuzz"
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// Options collect options for the command.
+type Options struct {
+	fuzz.Options
+	Domain string
+}
+
+var opts Options
+
+// AddCommand adds the command to c.
+func AddCommand(c *cobra.Command) {
+	c.AddCommand(cmd)
+
+	fs := cmd.Flags()
+	fs.SortFlags = false
+
+	fs.StringVar(&opts.Domain, "domain", "", "append `domain` to all values (e.g. FUZZ.example.com)")
+	fuzz.AddFlags(&opts.Options, fs)
+}
+
+var cmd = &cobra.Command{
+	Use:                   "vhost [options] URL",
+	DisableFlagsInUseLine: true,
+
+	Short:   helpShort,
+	Long:    helpLong,
+	Example: helpExamples,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		args, err := fuzz.PrepareResume(cmd, &opts.Options, args)
+		if err != nil {
+			return err
+		}
+
+		args, err = request.ProcessConfig(opts.Request, cmd.Flags(), args)
+		if err != nil {
+			return err
+		}
+
+		err = setup(&opts)
+		if err != nil {
+			return err
+		}
+
+		return cli.WithContext(func(ctx context.Context, g *errgroup.Group) error {
+			return fuzz.Run(ctx, g, &opts.Options, args)
+		})
+	},
+}
+
+// setup inserts the placeholder into the Host header and enables the
+// calibration, so that only responses which differ from the one for a random
+// host name are shown.
+func setup(opts *Options) error {
+	for name := range opts.Request.Header.Header {
+		if textproto.CanonicalMIMEHeaderKey(name) == "Host" {
+			return errors.New("the Host header is set by the vhost command and cannot be passed with --header")
+		}
+	}
+
+	// the placeholders for --value are only parsed later, so check the option
+	if len(opts.Values) > 0 {
+		return errors.New("the vhost command only supports a single placeholder, --value cannot be used")
+	}
+
+	host := opts.Request.Replace
+	if opts.Domain != "" {
+		host += "." + strings.TrimPrefix(opts.Domain, ".")
+	}
+
+	err := opts.Request.Header.Set("Host: " + host)
+	if err != nil {
+		return err
+	}
+
+	opts.AutoCalibrate = true
+	return nil
+}
@@ -0,0 +1,123 @@
+package vhost
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/cmd/fuzz"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+// newOptions returns the options after parsing args.
+func newOptions(t testing.TB, args ...string) *Options {
+	opts := &Options{}
+	fs := pflag.NewFlagSet("vhost", pflag.ContinueOnError)
+	fs.StringVar(&opts.Domain, "domain", "", "")
+	fuzz.AddFlags(&opts.Options, fs)
+
+	err := fs.Parse(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return opts
+}
+
+func TestSetup(t *testing.T) {
+	var tests = []struct {
+		args []string
+		host string
+	}{
+		{
+			args: []string{"--file", "names.txt"},
+			host: "FUZZ",
+		},
+		{
+			args: []string{"--file", "names.txt", "--domain", "example.com"},
+			host: "FUZZ.example.com",
+		},
+		{
+			args: []string{"--file", "names.txt", "--domain", ".example.com"},
+			host: "FUZZ.example.com",
+		},
+		{
+			// other headers are kept
+			args: []string{"--file", "names.txt", "--header", "X-Foo: bar"},
+			host: "FUZZ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			opts := newOptions(t, test.args...)
+
+			err := setup(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{test.host}
+			if !cmp.Equal(want, opts.Request.Header.Header["Host"]) {
+				t.Error(cmp.Diff(want, opts.Request.Header.Header["Host"]))
+			}
+
+			if !opts.AutoCalibrate {
+				t.Error("auto calibration not enabled")
+			}
+		})
+	}
+}
+
+func TestSetupHostInserted(t *testing.T) {
+	opts := newOptions(t, "--file", "names.txt", "--domain", "example.com")
+	err := setup(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Request.URL = "http://192.0.2.1/"
+	req, err := opts.Request.Apply("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Host != "admin.example.com" {
+		t.Errorf("wrong host, want %q, got %q", "admin.example.com", req.Host)
+	}
+}
+
+func TestSetupInvalid(t *testing.T) {
+	var tests = []struct {
+		args []string
+		err  string
+	}{
+		{
+			args: []string{"--file", "names.txt", "--header", "Host: www.example.com"},
+			err:  "the Host header is set by the vhost command",
+		},
+		{
+			args: []string{"--file", "names.txt", "--header", "host: www.example.com"},
+			err:  "the Host header is set by the vhost command",
+		},
+		{
+			args: []string{"--file", "names.txt", "--value", "A:other.txt"},
+			err:  "--value cannot be used",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			opts := newOptions(t, test.args...)
+
+			err := setup(opts)
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("wrong error, want %q, got %q", test.err, err)
+			}
+		})
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/RedTeamPentesting/monsoon/cmd/fuzz"
 	"github.com/RedTeamPentesting/monsoon/cmd/list"
 	"github.com/RedTeamPentesting/monsoon/cmd/show"
+	"github.com/RedTeamPentesting/monsoon/cmd/swagger"
 	"github.com/RedTeamPentesting/monsoon/cmd/test"
+	"github.com/RedTeamPentesting/monsoon/cmd/vhost"
 	"github.com/spf13/cobra"
 )
 
@@ -24,6 +26,8 @@ func init() {
 	show.AddCommand(cmdRoot)
 	test.AddCommand(cmdRoot)
 	list.AddCommand(cmdRoot)
+	swagger.AddCommand(cmdRoot)
+	vhost.AddCommand(cmdRoot)
 }
 
 func injectDefaultCommand(args []string) []string {
@@ -0,0 +1,562 @@
+// Package openapi builds request templates from API descriptions in the
+// OpenAPI 3 and Swagger 2.0 formats.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxDepth is the maximal nesting depth for references and schemas, so that
+// recursive schemas terminate.
+const maxDepth = 16
+
+// methods are the operations of a path item in the order they are returned.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// multipartBoundary is used for multipart bodies, so that the templates are
+// reproducible.
+const multipartBoundary = "monsoon-boundary"
+
+// Spec is the result of parsing an API description.
+type Spec struct {
+	// BaseURL is the scheme and host of the first server (e.g.
+	// "https://api.example.com"), empty if the description does not contain
+	// an absolute URL.
+	BaseURL    string
+	Operations []Operation
+}
+
+// Operation is a request template for one operation.
+type Operation struct {
+	Method   string // e.g. "GET"
+	Path     string // the path as in the description, e.g. "/pets/{id}"
+	ID       string // operationId, may be empty
+	Template []byte // the HTTP request with the placeholder in all parameters
+}
+
+// Name returns a name for the operation which can be used as a file name.
+func (op Operation) Name() string {
+	name := op.ID
+	if name == "" {
+		name = op.Path
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(op.Method))
+	sb.WriteByte('-')
+	underscore := false
+	for _, c := range name {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' {
+			sb.WriteRune(c)
+			underscore = false
+			continue
+		}
+		if !underscore {
+			sb.WriteByte('_')
+			underscore = true
+		}
+	}
+
+	return strings.TrimRight(strings.Replace(sb.String(), "-_", "-", 1), "_")
+}
+
+type object = map[string]interface{}
+
+// parser holds the state while the description is parsed.
+type parser struct {
+	doc         object
+	version     int    // 2 or 3
+	placeholder string // inserted into all parameters
+	host        string // for the Host header
+	basePath    string // prepended to all paths
+}
+
+// Parse reads the API description from buf (in JSON format) and returns a
+// template for each operation, with placeholder inserted into all parameters.
+func Parse(buf []byte, placeholder string) (*Spec, error) {
+	trimmed := bytes.TrimSpace(buf)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, errors.New("only API descriptions in JSON format are supported, convert YAML to JSON first")
+	}
+
+	p := &parser{placeholder: placeholder}
+	err := json.Unmarshal(trimmed, &p.doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API description: %v", err)
+	}
+
+	swagger, _ := p.doc["swagger"].(string)
+	openapi, _ := p.doc["openapi"].(string)
+	switch {
+	case strings.HasPrefix(swagger, "2."):
+		p.version = 2
+	case strings.HasPrefix(openapi, "3."):
+		p.version = 3
+	default:
+		return nil, errors.New("unsupported API description, neither Swagger 2.0 nor OpenAPI 3")
+	}
+
+	spec := &Spec{}
+	spec.BaseURL, err = p.server()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, _ := p.doc["paths"].(object)
+	if len(paths) == 0 {
+		return nil, errors.New("API description does not contain any paths")
+	}
+
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, path := range names {
+		item, _ := p.resolve(paths[path]).(object)
+		for _, method := range methods {
+			op, ok := item[method].(object)
+			if !ok {
+				continue
+			}
+
+			id, _ := op["operationId"].(string)
+			tmpl, err := p.template(strings.ToUpper(method), path, item, op)
+			if err != nil {
+				return nil, fmt.Errorf("%v %v: %v", strings.ToUpper(method), path, err)
+			}
+
+			spec.Operations = append(spec.Operations, Operation{
+				Method:   strings.ToUpper(method),
+				Path:     path,
+				ID:       id,
+				Template: tmpl,
+			})
+		}
+	}
+
+	return spec, nil
+}
+
+// server returns the base URL and sets the host and base path from the
+// description.
+func (p *parser) server() (string, error) {
+	var rawURL string
+
+	if p.version == 2 {
+		host, _ := p.doc["host"].(string)
+		p.basePath, _ = p.doc["basePath"].(string)
+		if host == "" {
+			return "", nil
+		}
+
+		scheme := "https"
+		if schemes, ok := p.doc["schemes"].([]interface{}); ok && len(schemes) > 0 {
+			if s, ok := schemes[0].(string); ok {
+				scheme = s
+			}
+		}
+		rawURL = scheme + "://" + host
+	} else {
+		servers, _ := p.doc["servers"].([]interface{})
+		if len(servers) == 0 {
+			return "", nil
+		}
+		server, _ := servers[0].(object)
+		rawURL, _ = server["url"].(string)
+
+		// replace the variables with their default values
+		vars, _ := server["variables"].(object)
+		for name, v := range vars {
+			v, _ := v.(object)
+			def, _ := v["default"].(string)
+			rawURL = strings.Replace(rawURL, "{"+name+"}", def, -1)
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q: %v", rawURL, err)
+	}
+
+	if p.version == 3 {
+		p.basePath = u.Path
+	}
+
+	if u.Host == "" {
+		return "", nil
+	}
+
+	p.host = u.Host
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// resolve follows references to other parts of the description.
+func (p *parser) resolve(v interface{}) interface{} {
+	for i := 0; i < maxDepth; i++ {
+		obj, ok := v.(object)
+		if !ok {
+			return v
+		}
+
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return v
+		}
+
+		v = p.lookup(ref)
+	}
+
+	return nil
+}
+
+// lookup returns the value for the local reference ref (e.g.
+// "#/components/schemas/Pet"), or nil if it does not exist.
+func (p *parser) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+
+	var v interface{} = p.doc
+	for _, name := range strings.Split(ref[2:], "/") {
+		name = strings.Replace(strings.Replace(name, "~1", "/", -1), "~0", "~", -1)
+		obj, ok := v.(object)
+		if !ok {
+			return nil
+		}
+		v = obj[name]
+	}
+
+	return v
+}
+
+// parameter is a parameter of an operation.
+type parameter struct {
+	Name   string
+	In     string
+	Type   string      // type of Swagger 2.0 parameters
+	Schema interface{} // schema of the body for Swagger 2.0
+}
+
+// parameters returns the parameters for the operation, the ones of the
+// operation take precedence over the ones of the path item.
+func (p *parser) parameters(item, op object) []parameter {
+	var list []parameter
+	index := make(map[string]int)
+
+	for _, src := range []object{item, op} {
+		params, _ := src["parameters"].([]interface{})
+		for _, v := range params {
+			param, ok := p.resolve(v).(object)
+			if !ok {
+				continue
+			}
+
+			name, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			typ, _ := param["type"].(string)
+			if name == "" || in == "" {
+				continue
+			}
+
+			par := parameter{Name: name, In: in, Type: typ, Schema: param["schema"]}
+			if i, ok := index[in+":"+name]; ok {
+				list[i] = par
+				continue
+			}
+
+			index[in+":"+name] = len(list)
+			list = append(list, par)
+		}
+	}
+
+	return list
+}
+
+// consumes returns the content types the operation accepts (Swagger 2.0).
+func (p *parser) consumes(op object) []string {
+	list, ok := op["consumes"].([]interface{})
+	if !ok {
+		list, _ = p.doc["consumes"].([]interface{})
+	}
+
+	var types []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			types = append(types, s)
+		}
+	}
+	return types
+}
+
+// template returns the HTTP request for the operation.
+func (p *parser) template(method, path string, item, op object) ([]byte, error) {
+	var query, cookies, headers, form []string
+	var body interface{}
+	hasBody, hasFile := false, false
+
+	for _, param := range p.parameters(item, op) {
+		switch param.In {
+		case "path":
+			path = strings.Replace(path, "{"+param.Name+"}", p.placeholder, -1)
+		case "query":
+			query = append(query, url.QueryEscape(param.Name)+"="+p.placeholder)
+		case "header":
+			// these headers are described elsewhere in OpenAPI 3
+			switch strings.ToLower(param.Name) {
+			case "accept", "content-type", "authorization":
+				continue
+			}
+			headers = append(headers, param.Name+": "+p.placeholder)
+		case "cookie":
+			cookies = append(cookies, param.Name+"="+p.placeholder)
+		case "formData":
+			form = append(form, param.Name)
+			hasFile = hasFile || param.Type == "file"
+		case "body":
+			body = p.example(param.Schema, make(map[string]bool))
+			hasBody = true
+		}
+	}
+
+	contentType := ""
+	var buf []byte
+	var err error
+
+	// Swagger 2.0 describes the body with parameters
+	switch {
+	case hasBody:
+		contentType = "application/json"
+		for _, t := range p.consumes(op) {
+			if isJSON(t) {
+				contentType = t
+				break
+			}
+		}
+		buf, err = json.MarshalIndent(body, "", "  ")
+	case len(form) > 0:
+		contentType = "application/x-www-form-urlencoded"
+		if consumes := p.consumes(op); hasFile || len(consumes) == 1 && consumes[0] == "multipart/form-data" {
+			contentType = "multipart/form-data"
+		}
+		buf, contentType, err = p.formBody(contentType, form)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// OpenAPI 3 uses requestBody instead
+	if reqBody, ok := p.resolve(op["requestBody"]).(object); ok {
+		contentType, buf, err = p.requestBody(reqBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sb strings.Builder
+	target := strings.TrimSuffix(p.basePath, "/") + path
+	if !strings.HasPrefix(target, "/") {
+		target = "/" + target
+	}
+	if len(query) > 0 {
+		target += "?" + strings.Join(query, "&")
+	}
+
+	fmt.Fprintf(&sb, "%s %s HTTP/1.1\r\n", method, target)
+	if p.host != "" {
+		fmt.Fprintf(&sb, "Host: %s\r\n", p.host)
+	}
+	for _, h := range headers {
+		sb.WriteString(h + "\r\n")
+	}
+	if len(cookies) > 0 {
+		sb.WriteString("Cookie: " + strings.Join(cookies, "; ") + "\r\n")
+	}
+	if contentType != "" {
+		sb.WriteString("Content-Type: " + contentType + "\r\n")
+	}
+	sb.WriteString("\r\n")
+	sb.Write(buf)
+
+	return []byte(sb.String()), nil
+}
+
+// requestBody returns the content type and body for an OpenAPI 3 request
+// body. JSON is preferred over forms, and forms over all other types.
+func (p *parser) requestBody(reqBody object) (contentType string, body []byte, err error) {
+	content, _ := reqBody["content"].(object)
+	if len(content) == 0 {
+		return "", nil, nil
+	}
+
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if bodyRank(types[i]) != bodyRank(types[j]) {
+			return bodyRank(types[i]) < bodyRank(types[j])
+		}
+		return types[i] < types[j]
+	})
+
+	contentType = types[0]
+	media, _ := content[contentType].(object)
+	schema := media["schema"]
+
+	switch {
+	case isJSON(contentType):
+		body, err = json.MarshalIndent(p.example(schema, make(map[string]bool)), "", "  ")
+		return contentType, body, err
+	case contentType == "application/x-www-form-urlencoded" || contentType == "multipart/form-data":
+		body, contentType, err = p.formBody(contentType, p.properties(schema))
+		return contentType, body, err
+	}
+
+	return contentType, []byte(p.placeholder), nil
+}
+
+// bodyRank orders the content types for requestBody.
+func bodyRank(contentType string) int {
+	switch {
+	case isJSON(contentType):
+		return 0
+	case contentType == "application/x-www-form-urlencoded":
+		return 1
+	case contentType == "multipart/form-data":
+		return 2
+	}
+	return 3
+}
+
+func isJSON(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+// properties returns the sorted names of the properties of an object schema.
+func (p *parser) properties(schema interface{}) []string {
+	s, _ := p.resolve(schema).(object)
+	props, _ := s["properties"].(object)
+
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formBody returns a form with the placeholder as the value for all fields,
+// either URL encoded or as multipart/form-data.
+func (p *parser) formBody(contentType string, fields []string) ([]byte, string, error) {
+	if contentType != "multipart/form-data" {
+		values := make([]string, 0, len(fields))
+		for _, name := range fields {
+			values = append(values, url.QueryEscape(name)+"="+p.placeholder)
+		}
+		return []byte(strings.Join(values, "&")), contentType, nil
+	}
+
+	var buf bytes.Buffer
+	wr := multipart.NewWriter(&buf)
+	err := wr.SetBoundary(multipartBoundary)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, name := range fields {
+		err = wr.WriteField(name, p.placeholder)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err = wr.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), wr.FormDataContentType(), nil
+}
+
+// example returns a value for schema, which contains the placeholder for all
+// strings. Numbers and booleans are set to their example or default value,
+// or to zero and false. Recursive references are set to null, seen contains
+// the references which are currently expanded.
+func (p *parser) example(schema interface{}, seen map[string]bool) interface{} {
+	if obj, ok := schema.(object); ok {
+		if ref, ok := obj["$ref"].(string); ok {
+			if seen[ref] || len(seen) > maxDepth {
+				return nil
+			}
+
+			seen[ref] = true
+			defer delete(seen, ref)
+			return p.example(p.lookup(ref), seen)
+		}
+	}
+
+	s, ok := schema.(object)
+	if !ok {
+		return p.placeholder
+	}
+
+	if list, ok := s["allOf"].([]interface{}); ok {
+		res := make(object)
+		for _, sub := range list {
+			if obj, ok := p.example(sub, seen).(object); ok {
+				for k, v := range obj {
+					res[k] = v
+				}
+			}
+		}
+		return res
+	}
+
+	for _, name := range []string{"oneOf", "anyOf"} {
+		if list, ok := s[name].([]interface{}); ok && len(list) > 0 {
+			return p.example(list[0], seen)
+		}
+	}
+
+	typ, _ := s["type"].(string)
+	if _, ok := s["properties"]; ok && typ == "" {
+		typ = "object"
+	}
+
+	switch typ {
+	case "object":
+		res := make(object)
+		props, _ := s["properties"].(object)
+		for name, prop := range props {
+			res[name] = p.example(prop, seen)
+		}
+		return res
+	case "array":
+		return []interface{}{p.example(s["items"], seen)}
+	case "integer", "number":
+		for _, name := range []string{"example", "default"} {
+			if v, ok := s[name].(float64); ok {
+				return v
+			}
+		}
+		return 0
+	case "boolean":
+		for _, name := range []string{"example", "default"} {
+			if v, ok := s[name].(bool); ok {
+				return v
+			}
+		}
+		return false
+	}
+
+	return p.placeholder
+}
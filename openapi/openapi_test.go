@@ -0,0 +1,250 @@
+package openapi
+
+import (
+	"testing"
+)
+
+const swaggerSpec = `{
+  "swagger": "2.0",
+  "host": "api.example.com",
+  "basePath": "/v1",
+  "schemes": ["http"],
+  "paths": {
+    "/pets/{id}": {
+      "parameters": [{"name": "id", "in": "path", "type": "integer"}],
+      "get": {
+        "operationId": "getPet",
+        "parameters": [
+          {"name": "fields", "in": "query", "type": "string"},
+          {"name": "X-Trace", "in": "header", "type": "string"}
+        ]
+      },
+      "put": {
+        "parameters": [
+          {"name": "pet", "in": "body", "schema": {"$ref": "#/definitions/Pet"}}
+        ]
+      }
+    },
+    "/upload": {
+      "post": {
+        "parameters": [
+          {"name": "name", "in": "formData", "type": "string"},
+          {"name": "age", "in": "formData", "type": "integer"}
+        ]
+      }
+    }
+  },
+  "definitions": {
+    "Pet": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "age": {"type": "integer", "example": 3},
+        "tags": {"type": "array", "items": {"type": "string"}},
+        "parent": {"$ref": "#/definitions/Pet"}
+      }
+    }
+  }
+}`
+
+const openapiSpec = `{
+  "openapi": "3.0.0",
+  "servers": [{"url": "https://{host}/api", "variables": {"host": {"default": "example.com"}}}],
+  "paths": {
+    "/login": {
+      "post": {
+        "parameters": [
+          {"name": "session", "in": "cookie"},
+          {"name": "Content-Type", "in": "header"}
+        ],
+        "requestBody": {
+          "content": {
+            "text/plain": {},
+            "application/x-www-form-urlencoded": {
+              "schema": {"properties": {"user": {"type": "string"}, "password": {"type": "string"}}}
+            }
+          }
+        }
+      }
+    },
+    "/avatar": {
+      "put": {
+        "requestBody": {
+          "content": {
+            "multipart/form-data": {"schema": {"properties": {"file": {"type": "string"}}}}
+          }
+        }
+      }
+    },
+    "/users": {
+      "post": {
+        "operationId": "create user",
+        "requestBody": {"$ref": "#/components/requestBodies/User"}
+      }
+    }
+  },
+  "components": {
+    "requestBodies": {
+      "User": {
+        "content": {
+          "application/json": {
+            "schema": {
+              "allOf": [
+                {"properties": {"name": {"type": "string"}}},
+                {"properties": {"admin": {"type": "boolean"}}}
+              ]
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type operation struct {
+	method, path, name string
+	template           string
+}
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		spec    string
+		baseURL string
+		ops     []operation
+	}{
+		{
+			spec:    swaggerSpec,
+			baseURL: "http://api.example.com",
+			ops: []operation{
+				{
+					method: "GET",
+					path:   "/pets/{id}",
+					name:   "get-getPet",
+					template: "GET /v1/pets/FUZZ?fields=FUZZ HTTP/1.1\r\n" +
+						"Host: api.example.com\r\n" +
+						"X-Trace: FUZZ\r\n" +
+						"\r\n",
+				},
+				{
+					method: "PUT",
+					path:   "/pets/{id}",
+					name:   "put-pets_id",
+					template: "PUT /v1/pets/FUZZ HTTP/1.1\r\n" +
+						"Host: api.example.com\r\n" +
+						"Content-Type: application/json\r\n" +
+						"\r\n" +
+						"{\n" +
+						"  \"age\": 3,\n" +
+						"  \"name\": \"FUZZ\",\n" +
+						"  \"parent\": null,\n" +
+						"  \"tags\": [\n" +
+						"    \"FUZZ\"\n" +
+						"  ]\n" +
+						"}",
+				},
+				{
+					method: "POST",
+					path:   "/upload",
+					name:   "post-upload",
+					template: "POST /v1/upload HTTP/1.1\r\n" +
+						"Host: api.example.com\r\n" +
+						"Content-Type: application/x-www-form-urlencoded\r\n" +
+						"\r\n" +
+						"name=FUZZ&age=FUZZ",
+				},
+			},
+		},
+		{
+			spec:    openapiSpec,
+			baseURL: "https://example.com",
+			ops: []operation{
+				{
+					method: "PUT",
+					path:   "/avatar",
+					name:   "put-avatar",
+					template: "PUT /api/avatar HTTP/1.1\r\n" +
+						"Host: example.com\r\n" +
+						"Content-Type: multipart/form-data; boundary=monsoon-boundary\r\n" +
+						"\r\n" +
+						"--monsoon-boundary\r\n" +
+						"Content-Disposition: form-data; name=\"file\"\r\n" +
+						"\r\n" +
+						"FUZZ\r\n" +
+						"--monsoon-boundary--\r\n",
+				},
+				{
+					method: "POST",
+					path:   "/login",
+					name:   "post-login",
+					template: "POST /api/login HTTP/1.1\r\n" +
+						"Host: example.com\r\n" +
+						"Cookie: session=FUZZ\r\n" +
+						"Content-Type: application/x-www-form-urlencoded\r\n" +
+						"\r\n" +
+						"password=FUZZ&user=FUZZ",
+				},
+				{
+					method: "POST",
+					path:   "/users",
+					name:   "post-create_user",
+					template: "POST /api/users HTTP/1.1\r\n" +
+						"Host: example.com\r\n" +
+						"Content-Type: application/json\r\n" +
+						"\r\n" +
+						"{\n" +
+						"  \"admin\": false,\n" +
+						"  \"name\": \"FUZZ\"\n" +
+						"}",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			spec, err := Parse([]byte(test.spec), "FUZZ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if spec.BaseURL != test.baseURL {
+				t.Errorf("wrong base URL, want %q, got %q", test.baseURL, spec.BaseURL)
+			}
+
+			if len(spec.Operations) != len(test.ops) {
+				t.Fatalf("wrong number of operations, want %d, got %d", len(test.ops), len(spec.Operations))
+			}
+
+			for i, op := range spec.Operations {
+				want := test.ops[i]
+				if op.Method != want.method || op.Path != want.path {
+					t.Errorf("operation %d: want %v %v, got %v %v", i, want.method, want.path, op.Method, op.Path)
+				}
+
+				if op.Name() != want.name {
+					t.Errorf("operation %d: wrong name, want %q, got %q", i, want.name, op.Name())
+				}
+
+				if string(op.Template) != want.template {
+					t.Errorf("operation %d: wrong template, want:\n%q\ngot:\n%q", i, want.template, op.Template)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []string{
+		"openapi: 3.0.0\npaths: {}\n",
+		`{"openapi": "4.0.0", "paths": {"/": {"get": {}}}}`,
+		`{"swagger": "2.0", "paths": {}}`,
+		`{"swagger": "2.0"`,
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte(test), "FUZZ")
+		if err == nil {
+			t.Errorf("expected error not returned for %q", test)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package producer
+
+import (
+	"context"
+	"errors"
+)
+
+// maxCount is the largest number of items which can be reported.
+const maxCount = int(^uint(0) >> 1)
+
+// CharsetCount returns the number of strings consisting of the characters in
+// chars with a length within lengths. An error is returned if the number is
+// too large.
+func CharsetCount(chars string, lengths Range) (int, error) {
+	n := len([]rune(chars))
+	if n == 0 {
+		return 0, errors.New("character set is empty")
+	}
+
+	if lengths.First < 1 {
+		return 0, errors.New("length must be at least 1")
+	}
+
+	total := 0
+	for length := lengths.First; length <= lengths.Last; length++ {
+		c := 1
+		for i := 0; i < length; i++ {
+			if c > maxCount/n {
+				return 0, errors.New("too many combinations for character set and length")
+			}
+			c *= n
+		}
+
+		if total > maxCount-c {
+			return 0, errors.New("too many combinations for character set and length")
+		}
+		total += c
+	}
+
+	return total, nil
+}
+
+// Charset sends all strings consisting of the characters in chars with a
+// length within lengths to the channel ch, shorter strings first, and the
+// number of items to the channel count. The strings are generated one after
+// another, so memory usage does not depend on the number of strings. Sending
+// stops and ch and count are closed when an error occurs or the context is
+// cancelled.
+func Charset(ctx context.Context, chars string, lengths Range, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	total, err := CharsetCount(chars, lengths)
+	if err != nil {
+		return err
+	}
+
+	count <- total
+
+	set := []rune(chars)
+	for length := lengths.First; length <= lengths.Last; length++ {
+		// the indexes into set for all positions, the last position changes first
+		idx := make([]int, length)
+		buf := make([]rune, length)
+
+		for {
+			for i, j := range idx {
+				buf[i] = set[j]
+			}
+
+			select {
+			case ch <- string(buf):
+			case <-ctx.Done():
+				return nil
+			}
+
+			pos := length - 1
+			for pos >= 0 {
+				idx[pos]++
+				if idx[pos] < len(set) {
+					break
+				}
+				idx[pos] = 0
+				pos--
+			}
+
+			if pos < 0 {
+				break
+			}
+		}
+	}
+
+	return nil
+}
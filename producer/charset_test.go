@@ -0,0 +1,98 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCharset(t *testing.T) {
+	var tests = []struct {
+		chars   string
+		lengths Range
+		want    []string
+	}{
+		{
+			chars:   "ab",
+			lengths: Range{First: 1, Last: 1},
+			want:    []string{"a", "b"},
+		},
+		{
+			chars:   "ab",
+			lengths: Range{First: 1, Last: 2},
+			want:    []string{"a", "b", "aa", "ab", "ba", "bb"},
+		},
+		{
+			chars:   "xyz",
+			lengths: Range{First: 2, Last: 2},
+			want:    []string{"xx", "xy", "xz", "yx", "yy", "yz", "zx", "zy", "zz"},
+		},
+		{
+			chars:   "01",
+			lengths: Range{First: 3, Last: 3},
+			want:    []string{"000", "001", "010", "011", "100", "101", "110", "111"},
+		},
+		{
+			// characters are runes, not bytes
+			chars:   "äö",
+			lengths: Range{First: 2, Last: 2},
+			want:    []string{"ää", "äö", "öä", "öö"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Charset(ctx, test.chars, test.lengths, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestCharsetCount(t *testing.T) {
+	var tests = []struct {
+		chars   string
+		lengths Range
+		want    int
+		err     bool
+	}{
+		{"abc", Range{First: 1, Last: 3}, 3 + 9 + 27, false},
+		{"0123456789", Range{First: 4, Last: 4}, 10000, false},
+		{"abcdefghijklmnopqrstuvwxyz0123456789", Range{First: 1, Last: 5}, 36 + 1296 + 46656 + 1679616 + 60466176, false},
+		{"", Range{First: 1, Last: 2}, 0, true},
+		{"ab", Range{First: 0, Last: 2}, 0, true},
+		{"abcdefghijklmnopqrstuvwxyz", Range{First: 1, Last: 20}, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			n, err := CharsetCount(test.chars, test.lengths)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error not returned, got count %d", n)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n != test.want {
+				t.Errorf("wrong count, want %d, got %d", test.want, n)
+			}
+		})
+	}
+}
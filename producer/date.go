@@ -0,0 +1,115 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are accepted for the first and last date of a range.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// DateRange defines a range of dates (in UTC) which should be tested.
+type DateRange struct {
+	First, Last time.Time
+	Step        time.Duration // distance between two dates, one day is used if it is not set
+}
+
+func parseDate(s string) (t time.Time, err error) {
+	for _, layout := range dateLayouts {
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// ParseDateRange parses a range from the string s in the format
+// `first:last`, e.g. "2023-01-01:2023-12-31" or
+// "2023-01-01T08:00:2023-01-01T18:00".
+func ParseDateRange(s string) (r DateRange, err error) {
+	// the times may contain colons, so try all of them as the separator
+	found := false
+	for i := strings.Index(s, ":"); i >= 0 && i < len(s); {
+		first, errFirst := parseDate(s[:i])
+		last, errLast := parseDate(s[i+1:])
+		if errFirst == nil && errLast == nil {
+			r.First, r.Last = first, last
+			found = true
+			break
+		}
+
+		next := strings.Index(s[i+1:], ":")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	if !found {
+		return DateRange{}, fmt.Errorf("wrong format for date range, expected: first:last (e.g. 2023-01-01:2023-12-31), got: %q", s)
+	}
+
+	if r.First.After(r.Last) {
+		return DateRange{}, fmt.Errorf("last date is before first date for date range %q", s)
+	}
+
+	return r, nil
+}
+
+// step returns the distance between two dates.
+func (r DateRange) step() time.Duration {
+	if r.Step <= 0 {
+		return 24 * time.Hour
+	}
+	return r.Step
+}
+
+// Count returns the number of items in the range.
+func (r DateRange) Count() int {
+	return int(r.Last.Sub(r.First)/r.step()) + 1
+}
+
+// FormatDate returns t formatted with the layout format (see time.Format). The
+// special formats "unix" and "unixms" return the seconds or milliseconds since
+// the epoch.
+func FormatDate(t time.Time, format string) string {
+	switch format {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unixms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+	return t.Format(format)
+}
+
+// Dates sends all dates in the range formatted with format (see FormatDate) to
+// the channel ch, and the number of items to the channel count. Sending stops
+// and ch is closed when the context is cancelled.
+func Dates(ctx context.Context, r DateRange, format string, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	if format == "" {
+		return errors.New("date format is empty")
+	}
+
+	count <- r.Count()
+
+	for t := r.First; !t.After(r.Last); t = t.Add(r.step()) {
+		select {
+		case ch <- FormatDate(t, format):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package producer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDates(t *testing.T) {
+	var tests = []struct {
+		rng    string
+		step   time.Duration
+		format string
+		want   []string
+	}{
+		{
+			// both bounds are included
+			rng:    "2023-02-27:2023-03-02",
+			format: "2006-01-02",
+			want:   []string{"2023-02-27", "2023-02-28", "2023-03-01", "2023-03-02"},
+		},
+		{
+			rng:    "2024-02-28:2024-03-01",
+			format: "02.01.2006",
+			want:   []string{"28.02.2024", "29.02.2024", "01.03.2024"},
+		},
+		{
+			rng:    "2023-01-01:2023-01-01",
+			format: "20060102",
+			want:   []string{"20230101"},
+		},
+		{
+			rng:    "2023-01-01T08:00:2023-01-01T10:00",
+			step:   30 * time.Minute,
+			format: "15:04",
+			want:   []string{"08:00", "08:30", "09:00", "09:30", "10:00"},
+		},
+		{
+			// the step does not divide the range, the last date is not sent
+			rng:    "2023-01-01:2023-01-10",
+			step:   4 * 24 * time.Hour,
+			format: "2006-01-02",
+			want:   []string{"2023-01-01", "2023-01-05", "2023-01-09"},
+		},
+		{
+			rng:    "2023-01-01T00:00:00:2023-01-01T00:00:02",
+			step:   time.Second,
+			format: "unix",
+			want:   []string{"1672531200", "1672531201", "1672531202"},
+		},
+		{
+			rng:    "2023-01-01:2023-01-02",
+			format: "unixms",
+			want:   []string{"1672531200000", "1672617600000"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rng, func(t *testing.T) {
+			rng, err := ParseDateRange(test.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rng.Step = test.step
+
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Dates(ctx, rng, test.format, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestDateRangeInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"2023-01-01",
+		"2023-01-01:",
+		"2023-13-01:2023-12-31",
+		"2023-12-31:2023-01-01",
+		"yesterday:today",
+	} {
+		_, err := ParseDateRange(s)
+		if err == nil {
+			t.Errorf("expected error for date range %q not returned", s)
+		}
+	}
+
+	rng, err := ParseDateRange("2023-01-01:2023-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+		return Dates(ctx, rng, "", ch, count)
+	})
+	if err == nil {
+		t.Error("expected error for empty format not returned")
+	}
+}
@@ -0,0 +1,113 @@
+package producer
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+)
+
+// bytesPerHash is the estimated memory used for each hash in the exact set.
+const bytesPerHash = 40
+
+// bloomHashes is the number of bits set in the bloom filter for each value.
+const bloomHashes = 7
+
+// FilterDedup drops all values which have been seen before, optionally
+// ignoring the case. The hashes of the values are kept in a set until it would
+// need more than Memory bytes, then a bloom filter of that size is used
+// instead. With the bloom filter, a small fraction of values which have not
+// been seen before may be dropped as well.
+type FilterDedup struct {
+	IgnoreCase bool
+	Memory     int
+
+	seen  map[uint64]struct{}
+	bloom []uint64
+
+	dropCounter
+}
+
+// hash returns the 64 bit FNV-1a hash of v.
+func hash(v string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(v))
+	return h.Sum64()
+}
+
+// bloomPositions returns the bits of the bloom filter for h, derived via
+// double hashing.
+func (f *FilterDedup) bloomPositions(h uint64, fn func(uint64)) {
+	bits := uint64(len(f.bloom)) * 64
+
+	// mix the hash (splitmix64) to get a second, independent one
+	h2 := h + 0x9e3779b97f4a7c15
+	h2 = (h2 ^ (h2 >> 30)) * 0xbf58476d1ce4e5b9
+	h2 = (h2 ^ (h2 >> 27)) * 0x94d049bb133111eb
+	h2 ^= h2 >> 31
+
+	for i := uint64(0); i < bloomHashes; i++ {
+		fn((h + i*h2) % bits)
+	}
+}
+
+// bloomAdd adds h to the bloom filter and returns true if it was already
+// contained.
+func (f *FilterDedup) bloomAdd(h uint64) bool {
+	found := true
+	f.bloomPositions(h, func(pos uint64) {
+		word, bit := pos/64, uint64(1)<<(pos%64)
+		if f.bloom[word]&bit == 0 {
+			found = false
+			f.bloom[word] |= bit
+		}
+	})
+	return found
+}
+
+// duplicate returns true if v has been seen before.
+func (f *FilterDedup) duplicate(v string) bool {
+	if f.IgnoreCase {
+		v = strings.ToLower(v)
+	}
+	h := hash(v)
+
+	if f.bloom != nil {
+		return f.bloomAdd(h)
+	}
+
+	if f.seen == nil {
+		f.seen = make(map[uint64]struct{})
+	}
+
+	if _, ok := f.seen[h]; ok {
+		return true
+	}
+
+	// switch to the bloom filter when the set gets too large
+	if (len(f.seen)+1)*bytesPerHash > f.Memory {
+		words := f.Memory / 8
+		if words < 1 {
+			words = 1
+		}
+		f.bloom = make([]uint64, words)
+		for old := range f.seen {
+			f.bloomAdd(old)
+		}
+		f.seen = nil
+		f.bloomAdd(h)
+		return false
+	}
+
+	f.seen[h] = struct{}{}
+	return false
+}
+
+// Count filters the number of values.
+func (f *FilterDedup) Count(ctx context.Context, in <-chan int) <-chan int {
+	return f.count(ctx, in)
+}
+
+// Select filters values sent over ch.
+func (f *FilterDedup) Select(ctx context.Context, in <-chan string) <-chan string {
+	return f.filter(ctx, in, f.duplicate)
+}
@@ -0,0 +1,107 @@
+package producer
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterDedup(t *testing.T) {
+	var tests = []struct {
+		ignoreCase bool
+		input      []string
+		want       []string
+	}{
+		{
+			input: []string{"a", "b", "c"},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			input: []string{"a", "b", "a", "c", "b", "a"},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			input: []string{"Admin", "admin", "ADMIN", "root"},
+			want:  []string{"Admin", "admin", "ADMIN", "root"},
+		},
+		{
+			ignoreCase: true,
+			input:      []string{"Admin", "admin", "ADMIN", "root", "Root"},
+			want:       []string{"Admin", "root"},
+		},
+		{
+			input: []string{"", "a", ""},
+			want:  []string{"", "a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f := &FilterDedup{IgnoreCase: test.ignoreCase, Memory: 1 << 20}
+			res, total := runFilter(t, f, test.input, len(test.input))
+			if !cmp.Equal(test.want, res) {
+				t.Error(cmp.Diff(test.want, res))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestFilterDedupBloom(t *testing.T) {
+	var unique []string
+	for i := 0; i < 1000; i++ {
+		unique = append(unique, strconv.Itoa(i))
+	}
+
+	// send all values twice
+	input := append(append([]string(nil), unique...), unique...)
+
+	// the memory is only enough for a few hashes in the set, so the bloom
+	// filter (with 2560 bits) is used, which is too small for 1000 values
+	f := &FilterDedup{Memory: 8 * bytesPerHash}
+	res, total := runFilter(t, f, input, len(input))
+
+	if f.bloom == nil {
+		t.Fatal("bloom filter not used")
+	}
+
+	if total != len(res) {
+		t.Errorf("wrong count, want %d, got %d", len(res), total)
+	}
+
+	// no duplicates are sent
+	seen := make(map[string]struct{})
+	for _, v := range res {
+		if _, ok := seen[v]; ok {
+			t.Errorf("duplicate value %q sent", v)
+		}
+		seen[v] = struct{}{}
+	}
+
+	// the first values are sent before the set is replaced by the bloom filter
+	want := unique[:8]
+	if !cmp.Equal(want, res[:len(want)]) {
+		t.Error(cmp.Diff(want, res[:len(want)]))
+	}
+
+	// values which have not been seen before are dropped by the bloom filter
+	if len(res) >= len(unique) {
+		t.Errorf("bloom filter did not drop any unique values, %d values sent", len(res))
+	}
+}
+
+func TestFilterDedupBloomSwitch(t *testing.T) {
+	// values seen before the switch to the bloom filter are still dropped
+	f := &FilterDedup{Memory: 3 * bytesPerHash}
+	input := []string{"a", "b", "c", "d", "a", "b", "c", "d"}
+	res, _ := runFilter(t, f, input, len(input))
+
+	want := []string{"a", "b", "c", "d"}
+	if !cmp.Equal(want, res) {
+		t.Error(cmp.Diff(want, res))
+	}
+}
@@ -0,0 +1,151 @@
+package producer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ReadExcludeList reads the values from rd (one per line) into a set, empty
+// lines are ignored.
+func ReadExcludeList(rd io.Reader, list map[string]struct{}) error {
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		v := strings.TrimRight(sc.Text(), "\r")
+		if v == "" {
+			continue
+		}
+		list[v] = struct{}{}
+	}
+	return sc.Err()
+}
+
+// dropCounter filters values with a function and sends the total count
+// corrected by the number of dropped values after the last value has been
+// received.
+type dropCounter struct {
+	once    sync.Once
+	dropped chan int
+}
+
+func (d *dropCounter) init() {
+	d.once.Do(func() {
+		d.dropped = make(chan int, 1)
+	})
+}
+
+// count receives the total count from in and sends it, minus the number of
+// dropped values, to the returned channel.
+func (d *dropCounter) count(ctx context.Context, in <-chan int) <-chan int {
+	d.init()
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		var ok bool
+		select {
+		case total, ok = <-in:
+			// the total is not known, e.g. for values read from stdin
+			if !ok {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		// wait until all values have been filtered
+		select {
+		case n := <-d.dropped:
+			total -= n
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case out <- total:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// filter sends all values received from in for which drop returns false to
+// the returned channel.
+func (d *dropCounter) filter(ctx context.Context, in <-chan string, drop func(string) bool) <-chan string {
+	d.init()
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		var dropped int
+		for {
+			var v string
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				// when the input channel is closed we're done
+				if !ok {
+					d.dropped <- dropped
+					return
+				}
+			}
+
+			if drop(v) {
+				dropped++
+				// drop value, receive next
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+
+	return out
+}
+
+// FilterExclude drops all values which match one of the regular expressions
+// or are contained in Values. Since the number of dropped values is only known
+// at the end, the corrected total count is sent after the last value has been
+// received.
+type FilterExclude struct {
+	Regexps []*regexp.Regexp
+	Values  map[string]struct{}
+
+	dropCounter
+}
+
+// exclude returns true if v should be dropped.
+func (f *FilterExclude) exclude(v string) bool {
+	if _, ok := f.Values[v]; ok {
+		return true
+	}
+
+	for _, re := range f.Regexps {
+		if re.MatchString(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count filters the number of values.
+func (f *FilterExclude) Count(ctx context.Context, in <-chan int) <-chan int {
+	return f.count(ctx, in)
+}
+
+// Select filters values sent over ch.
+func (f *FilterExclude) Select(ctx context.Context, in <-chan string) <-chan string {
+	return f.filter(ctx, in, f.exclude)
+}
@@ -0,0 +1,105 @@
+package producer
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadExcludeList(t *testing.T) {
+	list := make(map[string]struct{})
+	err := ReadExcludeList(strings.NewReader("foo\r\n\nbar\nfoo\n  baz\n"), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct{}{
+		"foo":   {},
+		"bar":   {},
+		"  baz": {},
+	}
+	if !cmp.Equal(want, list) {
+		t.Error(cmp.Diff(want, list))
+	}
+}
+
+func TestFilterExclude(t *testing.T) {
+	var tests = []struct {
+		regexps []string
+		values  []string
+		input   []string
+		total   int
+
+		want      []string
+		wantTotal int
+	}{
+		{
+			input:     []string{"a", "b", "c"},
+			total:     3,
+			want:      []string{"a", "b", "c"},
+			wantTotal: 3,
+		},
+		{
+			values:    []string{"b", "d"},
+			input:     []string{"a", "b", "c", "b"},
+			total:     4,
+			want:      []string{"a", "c"},
+			wantTotal: 2,
+		},
+		{
+			regexps:   []string{`\.bak$`, `^tmp`},
+			input:     []string{"index.php", "index.php.bak", "tmpfile", "file.tmp"},
+			total:     4,
+			want:      []string{"index.php", "file.tmp"},
+			wantTotal: 2,
+		},
+		{
+			regexps:   []string{`^x`},
+			values:    []string{"a"},
+			input:     []string{"a", "b", "xa", "x"},
+			total:     4,
+			want:      []string{"b"},
+			wantTotal: 1,
+		},
+		{
+			// the total is corrected even if it was larger than the number
+			// of values received
+			values:    []string{"a"},
+			input:     []string{"a", "b"},
+			total:     10,
+			want:      []string{"b"},
+			wantTotal: 9,
+		},
+		{
+			// the total stays unknown
+			values:    []string{"a"},
+			input:     []string{"a", "b"},
+			total:     -1,
+			want:      []string{"b"},
+			wantTotal: -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f := &FilterExclude{Values: make(map[string]struct{})}
+			for _, v := range test.values {
+				f.Values[v] = struct{}{}
+			}
+			for _, s := range test.regexps {
+				f.Regexps = append(f.Regexps, regexp.MustCompile(s))
+			}
+
+			res, total := runFilter(t, f, test.input, test.total)
+			if !cmp.Equal(test.want, res) {
+				t.Error(cmp.Diff(test.want, res))
+			}
+
+			if total != test.wantTotal {
+				t.Errorf("wrong count, want %d, got %d", test.wantTotal, total)
+			}
+		})
+	}
+}
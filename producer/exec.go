@@ -0,0 +1,55 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Command runs the program in args and sends all lines it writes to stdout to
+// the channel ch, and the number of items to the channel count. Since the
+// lines are read only when they are sent, the program blocks when writing
+// while the values are not yet needed. The program is killed when the context
+// is cancelled.
+func Command(ctx context.Context, args []string, ch chan<- string, count chan<- int) error {
+	if len(args) == 0 {
+		close(ch)
+		return errors.New("no command specified")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		close(ch)
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		close(ch)
+		return fmt.Errorf("values command: %v", err)
+	}
+
+	err = Reader(ctx, stdout, ch, count)
+	waitErr := cmd.Wait()
+	if err != nil {
+		return fmt.Errorf("values command: %v", err)
+	}
+
+	// the program is killed when the context is cancelled, that's not an error
+	if waitErr != nil && ctx.Err() == nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return fmt.Errorf("values command %q failed: %v: %s", strings.Join(args, " "), waitErr, msg)
+		}
+		return fmt.Errorf("values command %q failed: %v", strings.Join(args, " "), waitErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package producer
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func needShell(t testing.TB) {
+	_, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	needShell(t)
+
+	var tests = []struct {
+		script string
+		want   []string
+	}{
+		{`printf 'admin\nroot\n'`, []string{"admin", "root"}},
+		{`printf 'a b\n\nc'`, []string{"a b", "", "c"}},
+		{`true`, nil},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Command(ctx, []string{"sh", "-c", test.script}, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	needShell(t)
+
+	values, _, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+		return Command(ctx, []string{"sh", "-c", "echo foo; echo broken >&2; exit 3"}, ch, count)
+	})
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+
+	if !strings.Contains(err.Error(), "broken") {
+		t.Errorf("error does not contain the message from stderr: %v", err)
+	}
+
+	want := []string{"foo"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	_, _, err = collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+		return Command(ctx, nil, ch, count)
+	})
+	if err == nil {
+		t.Fatal("expected error for missing command not returned")
+	}
+}
+
+func TestCommandCancel(t *testing.T) {
+	needShell(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		// the program writes values forever
+		errCh <- Command(ctx, []string{"sh", "-c", "while true; do echo x; done"}, ch, count)
+	}()
+
+	for i := 0; i < 3; i++ {
+		<-ch
+	}
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("cancellation returned an error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not stopped")
+	}
+}
@@ -0,0 +1,111 @@
+package producer
+
+import (
+	"context"
+	"strings"
+)
+
+// FilterExpand replaces each value by one value for each combination of the
+// prefixes and suffixes. If Extensions is set, the value is also sent with each
+// extension appended. An extension without a leading dot receives one.
+type FilterExpand struct {
+	Prefixes   []string
+	Suffixes   []string
+	Extensions []string
+}
+
+// candidates returns the number of values sent for a single value.
+func (f *FilterExpand) candidates() int {
+	n := 1
+	if len(f.Prefixes) > 0 {
+		n *= len(f.Prefixes)
+	}
+	if len(f.Suffixes) > 0 {
+		n *= len(f.Suffixes)
+	}
+	return n * (1 + len(f.Extensions))
+}
+
+// expand returns all values built from v.
+func (f *FilterExpand) expand(v string) []string {
+	prefixes, suffixes := f.Prefixes, f.Suffixes
+	if len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+	if len(suffixes) == 0 {
+		suffixes = []string{""}
+	}
+
+	list := make([]string, 0, f.candidates())
+	for _, prefix := range prefixes {
+		for _, suffix := range suffixes {
+			base := prefix + v + suffix
+			list = append(list, base)
+
+			for _, ext := range f.Extensions {
+				if !strings.HasPrefix(ext, ".") {
+					ext = "." + ext
+				}
+				list = append(list, base+ext)
+			}
+		}
+	}
+
+	return list
+}
+
+// Count filters the number of values.
+func (f *FilterExpand) Count(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		select {
+		case total = <-in:
+		case <-ctx.Done():
+		}
+
+		// calculate the correct total count
+		total *= f.candidates()
+
+		select {
+		case out <- total:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// Select filters values sent over ch.
+func (f *FilterExpand) Select(ctx context.Context, in <-chan string) <-chan string {
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		for {
+			var v string
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				// when the input channel is closed we're done
+				if !ok {
+					return
+				}
+			}
+
+			for _, value := range f.expand(v) {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- value:
+				}
+			}
+		}
+	}()
+
+	return out
+}
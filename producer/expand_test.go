@@ -0,0 +1,63 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterExpand(t *testing.T) {
+	var tests = []struct {
+		filter FilterExpand
+		values []string
+		want   []string
+	}{
+		{
+			filter: FilterExpand{},
+			values: []string{"admin", "login"},
+			want:   []string{"admin", "login"},
+		},
+		{
+			filter: FilterExpand{Extensions: []string{"php", ".bak"}},
+			values: []string{"admin", "login"},
+			want:   []string{"admin", "admin.php", "admin.bak", "login", "login.php", "login.bak"},
+		},
+		{
+			filter: FilterExpand{Prefixes: []string{"", "old_"}},
+			values: []string{"admin"},
+			want:   []string{"admin", "old_admin"},
+		},
+		{
+			filter: FilterExpand{Suffixes: []string{"~", "1"}},
+			values: []string{"index"},
+			want:   []string{"index~", "index1"},
+		},
+		{
+			filter: FilterExpand{
+				Prefixes:   []string{"a", "b"},
+				Suffixes:   []string{"1", "2"},
+				Extensions: []string{"txt"},
+			},
+			values: []string{"x"},
+			want: []string{
+				"ax1", "ax1.txt", "ax2", "ax2.txt",
+				"bx1", "bx1.txt", "bx2", "bx2.txt",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f := test.filter
+			values, total := runFilter(t, &f, test.values, len(test.values))
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
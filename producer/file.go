@@ -11,27 +11,66 @@ import (
 // an error occurs or the context is cancelled. The reader is closed when this
 // function returns.
 func Reader(ctx context.Context, rd io.ReadCloser, ch chan<- string, count chan<- int) (err error) {
+	return Readers(ctx, []io.ReadCloser{rd}, ch, count)
+}
+
+// Readers sends all lines read from the readers one after another to the
+// channel ch, and the total number of items to the channel count when the
+// last reader is done. Sending stops and ch and count are closed when an error
+// occurs or the context is cancelled. The readers are closed when this
+// function returns.
+func Readers(ctx context.Context, readers []io.ReadCloser, ch chan<- string, count chan<- int) (err error) {
+	groups := make([][]io.ReadCloser, 0, len(readers))
+	for _, rd := range readers {
+		groups = append(groups, []io.ReadCloser{rd})
+	}
+	return ReaderGroups(ctx, groups, ch, count)
+}
+
+// ReaderGroups works like Readers, but the readers are organized in groups.
+// The groups are read one after another, the lines of the readers within a
+// group are sent alternately until all readers of the group are done.
+func ReaderGroups(ctx context.Context, groups [][]io.ReadCloser, ch chan<- string, count chan<- int) (err error) {
 	defer close(ch)
 	defer func() {
-		// ignore error
-		_ = rd.Close()
+		for _, group := range groups {
+			for _, rd := range group {
+				// ignore error
+				_ = rd.Close()
+			}
+		}
 	}()
 
-	sc := bufio.NewScanner(rd)
 	num := 0
-	for sc.Scan() {
-		if sc.Err() != nil {
-			return sc.Err()
+	for _, group := range groups {
+		scanners := make([]*bufio.Scanner, 0, len(group))
+		for _, rd := range group {
+			scanners = append(scanners, bufio.NewScanner(rd))
 		}
 
-		num++
+		for len(scanners) > 0 {
+			active := scanners[:0]
+			for _, sc := range scanners {
+				if !sc.Scan() {
+					if sc.Err() != nil {
+						return sc.Err()
+					}
+					continue
+				}
+				active = append(active, sc)
 
-		select {
-		case ch <- sc.Text():
-		case <-ctx.Done():
-			return nil
+				num++
+
+				select {
+				case ch <- sc.Text():
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			scanners = active
 		}
 	}
+
 	count <- num
 	return nil
 }
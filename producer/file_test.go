@@ -0,0 +1,162 @@
+package producer
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReaderStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rd, wr := io.Pipe()
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Reader(ctx, rd, ch, count)
+	}()
+
+	// values are sent as soon as they are read, e.g. from stdin
+	for _, v := range []string{"foo", "bar"} {
+		go func(v string) {
+			_, _ = io.WriteString(wr, v+"\n")
+		}(v)
+
+		select {
+		case got := <-ch:
+			if got != v {
+				t.Fatalf("wrong value, want %q, got %q", v, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("value %q not received before the end of the input", v)
+		}
+
+		select {
+		case n := <-count:
+			t.Fatalf("count %d sent before the end of the input", n)
+		default:
+		}
+	}
+
+	_ = wr.Close()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel not closed at the end of the input")
+	}
+
+	err := <-errCh
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := <-count; n != 2 {
+		t.Errorf("wrong count, want 2, got %d", n)
+	}
+}
+
+func TestReaderCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rd, wr := io.Pipe()
+	defer func() {
+		_ = wr.Close()
+	}()
+
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Reader(ctx, rd, ch, count)
+	}()
+
+	go func() {
+		_, _ = io.WriteString(wr, "foo\nbar\n")
+	}()
+
+	<-ch
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reader was not stopped")
+	}
+
+	// the total is unknown
+	select {
+	case n := <-count:
+		t.Errorf("count %d sent for a cancelled reader", n)
+	default:
+	}
+}
+
+// readers returns an io.ReadCloser for each string.
+func readers(data ...string) []io.ReadCloser {
+	var res []io.ReadCloser
+	for _, s := range data {
+		res = append(res, ioutil.NopCloser(strings.NewReader(s)))
+	}
+	return res
+}
+
+func TestReaderGroups(t *testing.T) {
+	var tests = []struct {
+		groups [][]io.ReadCloser
+		want   []string
+	}{
+		{
+			groups: [][]io.ReadCloser{readers("a\nb\n")},
+			want:   []string{"a", "b"},
+		},
+		{
+			// groups are read one after another
+			groups: [][]io.ReadCloser{readers("a\nb\n"), readers("c\nd")},
+			want:   []string{"a", "b", "c", "d"},
+		},
+		{
+			// readers in a group are read alternately
+			groups: [][]io.ReadCloser{readers("a1\na2\na3\n", "b1\n", "c1\nc2\n")},
+			want:   []string{"a1", "b1", "c1", "a2", "c2", "a3"},
+		},
+		{
+			groups: [][]io.ReadCloser{
+				readers("x1\n"),
+				readers("a1\na2\n", "b1\nb2\nb3\n"),
+				readers("", "y1\n"),
+			},
+			want: []string{"x1", "a1", "b1", "a2", "b2", "b3", "y1"},
+		},
+		{
+			groups: [][]io.ReadCloser{readers("", "")},
+			want:   nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return ReaderGroups(ctx, test.groups, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package producer
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // Filter selects/rejects items received from a producer.
 type Filter interface {
@@ -81,7 +86,110 @@ func (f *FilterSkip) Select(ctx context.Context, in <-chan string) <-chan string
 	return out
 }
 
-// FilterLimit passes through at most Max values.
+// FilterShard passes through only the values whose index (starting at zero)
+// modulo Shards is Shard. Since the order of the values is the same for each
+// run, running all shards from 0 to Shards-1 sends each value exactly once.
+type FilterShard struct {
+	Shard  int
+	Shards int
+}
+
+// ParseShard parses a shard specification like "3/10".
+func ParseShard(s string) (*FilterShard, error) {
+	data := strings.SplitN(s, "/", 2)
+	if len(data) != 2 {
+		return nil, fmt.Errorf("wrong format for shard %q, expected n/total", s)
+	}
+
+	shard, err := strconv.Atoi(data[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard %q: %v", s, err)
+	}
+
+	shards, err := strconv.Atoi(data[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard %q: %v", s, err)
+	}
+
+	if shards <= 0 {
+		return nil, fmt.Errorf("invalid shard %q: number of shards must be positive", s)
+	}
+
+	if shard < 0 || shard >= shards {
+		return nil, fmt.Errorf("invalid shard %q: shard must be between 0 and %d", s, shards-1)
+	}
+
+	return &FilterShard{Shard: shard, Shards: shards}, nil
+}
+
+// Count filters the number of values.
+func (f *FilterShard) Count(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		select {
+		case total = <-in:
+		case <-ctx.Done():
+		}
+
+		// calculate the correct total count
+		if total > f.Shard {
+			total = (total-f.Shard-1)/f.Shards + 1
+		} else {
+			total = 0
+		}
+
+		select {
+		case out <- total:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// Select filters values sent over ch.
+func (f *FilterShard) Select(ctx context.Context, in <-chan string) <-chan string {
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		var cur int
+		for {
+			var v string
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				// when the input channel is closed we're done
+				if !ok {
+					return
+				}
+			}
+
+			cur++
+			if (cur-1)%f.Shards != f.Shard {
+				// drop value, receive next
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+
+	return out
+}
+
+// FilterLimit passes through at most Max values. The output channel is closed
+// as soon as Max values have been sent, values received afterwards are not
+// read.
 type FilterLimit struct {
 	Max int
 }
@@ -119,7 +227,7 @@ func (f *FilterLimit) Select(ctx context.Context, in <-chan string) <-chan strin
 	go func() {
 		defer close(out)
 		var cur int
-		for {
+		for cur < f.Max {
 			var v string
 			var ok bool
 			select {
@@ -131,11 +239,6 @@ func (f *FilterLimit) Select(ctx context.Context, in <-chan string) <-chan strin
 					return
 				}
 			}
-
-			if cur >= f.Max {
-				// drop value, receive next
-				continue
-			}
 			cur++
 
 			select {
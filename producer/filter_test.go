@@ -0,0 +1,188 @@
+package producer
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// count the values the filter reads from the producer
+	in := make(chan string)
+	sent := make(chan int, 1)
+	go func() {
+		defer close(in)
+		n := 0
+		defer func() {
+			sent <- n
+		}()
+
+		for i := 0; i < 1000000; i++ {
+			select {
+			case in <- strconv.Itoa(i):
+				n++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	f := &FilterLimit{Max: 5}
+	var values []string
+	for v := range f.Select(ctx, in) {
+		values = append(values, v)
+	}
+
+	want := []string{"0", "1", "2", "3", "4"}
+	if !cmp.Equal(want, values) {
+		t.Error(cmp.Diff(want, values))
+	}
+
+	// the producer is stopped by cancelling the context
+	cancel()
+
+	select {
+	case n := <-sent:
+		if n != f.Max {
+			t.Errorf("filter read %d values from the producer, want %d", n, f.Max)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("producer was not stopped")
+	}
+}
+
+func TestFilterLimitCount(t *testing.T) {
+	var tests = []struct {
+		max, total, want int
+	}{
+		{5, 100, 5},
+		{5, 3, 3},
+		{5, 5, 5},
+		{5, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var values []string
+			for i := 0; i < test.total; i++ {
+				values = append(values, strconv.Itoa(i))
+			}
+
+			res, total := runFilter(t, &FilterLimit{Max: test.max}, values, test.total)
+			if total != test.want {
+				t.Errorf("wrong count, want %d, got %d", test.want, total)
+			}
+
+			if len(res) != test.want {
+				t.Errorf("wrong number of values, want %d, got %d", test.want, len(res))
+			}
+		})
+	}
+}
+
+func TestFilterShard(t *testing.T) {
+	var tests = []struct {
+		shard, shards int
+		total         int
+		want          []string
+	}{
+		{0, 1, 5, []string{"0", "1", "2", "3", "4"}},
+		{0, 2, 6, []string{"0", "2", "4"}},
+		{1, 2, 6, []string{"1", "3", "5"}},
+		{0, 3, 10, []string{"0", "3", "6", "9"}},
+		{1, 3, 10, []string{"1", "4", "7"}},
+		{2, 3, 10, []string{"2", "5", "8"}},
+		{2, 3, 2, nil},
+		{3, 4, 3, nil},
+		{0, 4, 0, nil},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var values []string
+			for i := 0; i < test.total; i++ {
+				values = append(values, strconv.Itoa(i))
+			}
+
+			res, total := runFilter(t, &FilterShard{Shard: test.shard, Shards: test.shards}, values, test.total)
+			if !cmp.Equal(test.want, res) {
+				t.Error(cmp.Diff(test.want, res))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestFilterShardAll(t *testing.T) {
+	// all shards together send each value exactly once
+	var values []string
+	for i := 0; i < 23; i++ {
+		values = append(values, strconv.Itoa(i))
+	}
+
+	seen := make(map[string]int)
+	var sum int
+	for shard := 0; shard < 5; shard++ {
+		res, total := runFilter(t, &FilterShard{Shard: shard, Shards: 5}, values, len(values))
+		for _, v := range res {
+			seen[v]++
+		}
+		sum += total
+	}
+
+	if sum != len(values) {
+		t.Errorf("wrong sum of counts, want %d, got %d", len(values), sum)
+	}
+
+	for _, v := range values {
+		if seen[v] != 1 {
+			t.Errorf("value %v sent %d times", v, seen[v])
+		}
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	var tests = []struct {
+		shard string
+		want  *FilterShard
+		err   bool
+	}{
+		{"0/1", &FilterShard{Shard: 0, Shards: 1}, false},
+		{"3/10", &FilterShard{Shard: 3, Shards: 10}, false},
+		{"10/10", nil, true},
+		{"-1/10", nil, true},
+		{"0/0", nil, true},
+		{"1", nil, true},
+		{"a/10", nil, true},
+		{"1/b", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f, err := ParseShard(test.shard)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error not returned, got %v", f)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, f) {
+				t.Error(cmp.Diff(test.want, f))
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+)
+
+// maxIPRange is the largest number of addresses in a single IP range.
+const maxIPRange = 1 << 24
+
+// IPRange defines a range of IP addresses which should be tested.
+type IPRange struct {
+	First, Last net.IP
+}
+
+// ParseIPRange parses a range from the string s. Valid formats are an address
+// (`10.0.0.1`), a network (`10.0.0.0/24`) and a range of addresses
+// (`10.0.0.1-10.0.0.20`). If hostsOnly is set, the network and broadcast
+// addresses of an IPv4 network are excluded.
+func ParseIPRange(s string, hostsOnly bool) (r IPRange, err error) {
+	switch {
+	case strings.Contains(s, "/"):
+		ip, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return IPRange{}, fmt.Errorf("invalid IP range %q: %v", s, err)
+		}
+
+		r.First = network.IP
+		r.Last = make(net.IP, len(network.IP))
+		for i := range network.IP {
+			r.Last[i] = network.IP[i] | ^network.Mask[i]
+		}
+
+		ones, bits := network.Mask.Size()
+		if hostsOnly && ip.To4() != nil && bits-ones >= 2 {
+			r.First = addIP(r.First, 1)
+			r.Last = addIP(r.Last, -1)
+		}
+
+	case strings.Contains(s, "-"):
+		data := strings.SplitN(s, "-", 2)
+		r.First, r.Last = parseIP(data[0]), parseIP(data[1])
+		if r.First == nil || r.Last == nil || len(r.First) != len(r.Last) {
+			return IPRange{}, fmt.Errorf("wrong format for IP range, expected: first-last, got: %q", s)
+		}
+
+	default:
+		r.First = parseIP(s)
+		if r.First == nil {
+			return IPRange{}, fmt.Errorf("invalid IP address %q", s)
+		}
+		r.Last = r.First
+	}
+
+	if ipInt(r.First).Cmp(ipInt(r.Last)) > 0 {
+		return IPRange{}, fmt.Errorf("last address is smaller than first address for IP range %q", s)
+	}
+
+	size := new(big.Int).Sub(ipInt(r.Last), ipInt(r.First))
+	if size.Cmp(big.NewInt(maxIPRange)) >= 0 {
+		return IPRange{}, fmt.Errorf("IP range %q contains more than %d addresses", s, maxIPRange)
+	}
+
+	return r, nil
+}
+
+// parseIP returns the IP address in s, IPv4 addresses are returned with four
+// bytes.
+func parseIP(s string) net.IP {
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+func ipInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip)
+}
+
+// addIP returns the address n after ip.
+func addIP(ip net.IP, n int64) net.IP {
+	v := new(big.Int).Add(ipInt(ip), big.NewInt(n))
+	buf := v.Bytes()
+
+	res := make(net.IP, len(ip))
+	copy(res[len(res)-len(buf):], buf)
+	return res
+}
+
+// Count returns the number of addresses in the range.
+func (r IPRange) Count() int {
+	return int(new(big.Int).Sub(ipInt(r.Last), ipInt(r.First)).Int64()) + 1
+}
+
+// IPRanges sends all addresses in the ranges to the channel ch, and the number
+// of items to the channel count. Sending stops and ch is closed when the
+// context is cancelled.
+func IPRanges(ctx context.Context, ranges []IPRange, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	var total int
+	for _, r := range ranges {
+		total += r.Count()
+	}
+
+	count <- total
+
+	for _, r := range ranges {
+		ip := r.First
+		n := r.Count()
+		for i := 0; i < n; i++ {
+			select {
+			case ch <- ip.String():
+			case <-ctx.Done():
+				return nil
+			}
+
+			// the address after the last one may overflow (e.g. for
+			// 255.255.255.255), so it is not computed
+			if i+1 < n {
+				ip = addIP(ip, 1)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIPRanges(t *testing.T) {
+	var tests = []struct {
+		ranges    []string
+		hostsOnly bool
+		want      []string
+	}{
+		{
+			ranges: []string{"10.0.0.1"},
+			want:   []string{"10.0.0.1"},
+		},
+		{
+			ranges: []string{"192.168.1.0/30"},
+			want:   []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"},
+		},
+		{
+			ranges:    []string{"192.168.1.0/30"},
+			hostsOnly: true,
+			want:      []string{"192.168.1.1", "192.168.1.2"},
+		},
+		{
+			// the address does not need to be the network address
+			ranges:    []string{"10.1.2.3/29"},
+			hostsOnly: true,
+			want:      []string{"10.1.2.1", "10.1.2.2", "10.1.2.3", "10.1.2.4", "10.1.2.5", "10.1.2.6"},
+		},
+		{
+			// networks without room for network and broadcast addresses
+			ranges:    []string{"10.0.0.4/31", "10.0.0.9/32"},
+			hostsOnly: true,
+			want:      []string{"10.0.0.4", "10.0.0.5", "10.0.0.9"},
+		},
+		{
+			ranges: []string{"10.0.0.254-10.0.1.1"},
+			want:   []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"},
+		},
+		{
+			ranges: []string{"255.255.255.254-255.255.255.255"},
+			want:   []string{"255.255.255.254", "255.255.255.255"},
+		},
+		{
+			ranges: []string{"2001:db8::/126"},
+			want:   []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"},
+		},
+		{
+			// the network and broadcast addresses are only excluded for IPv4
+			ranges:    []string{"2001:db8::/127"},
+			hostsOnly: true,
+			want:      []string{"2001:db8::", "2001:db8::1"},
+		},
+		{
+			ranges: []string{"2001:db8::ffff-2001:db8::1:1"},
+			want:   []string{"2001:db8::ffff", "2001:db8::1:0", "2001:db8::1:1"},
+		},
+		{
+			ranges: []string{"::1", "127.0.0.1"},
+			want:   []string{"::1", "127.0.0.1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var ranges []IPRange
+			for _, s := range test.ranges {
+				rng, err := ParseIPRange(s, test.hostsOnly)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ranges = append(ranges, rng)
+			}
+
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return IPRanges(ctx, ranges, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestIPRangeInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"10.0.0.256",
+		"10.0.0.0/33",
+		"10.0.0.5-10.0.0.1",
+		"10.0.0.1-2001:db8::1",
+		"10.0.0.1-",
+		"foo",
+		"10.0.0.0/7",
+		"2001:db8::/64",
+	} {
+		_, err := ParseIPRange(s, false)
+		if err == nil {
+			t.Errorf("expected error for IP range %q not returned", s)
+		}
+	}
+
+	// the largest allowed range
+	rng, err := ParseIPRange("10.0.0.0/8", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rng.Count() != maxIPRange {
+		t.Errorf("wrong count, want %d, got %d", maxIPRange, rng.Count())
+	}
+}
@@ -0,0 +1,41 @@
+package producer
+
+import "context"
+
+// HTTPMethods is the built-in list of methods for --method-list: the methods
+// from RFC 9110 and RFC 5789, the WebDAV methods and some which are only
+// supported by particular servers or proxies.
+var HTTPMethods = []string{
+	"GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH",
+	"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK", "SEARCH",
+	"REPORT", "MKACTIVITY", "CHECKOUT", "MERGE", "ACL", "BIND", "UNBIND", "REBIND",
+	"PURGE", "DEBUG", "TRACK", "LINK", "UNLINK", "VIEW", "QUERY",
+}
+
+// MultiplyCount multiplies the total count read from in by n, e.g. when a
+// request is sent with n methods for each value. If the total is not known
+// (in is closed without a value), the returned channel is closed as well.
+func MultiplyCount(ctx context.Context, in <-chan int, n int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		var ok bool
+		select {
+		case total, ok = <-in:
+			if !ok {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case out <- total * n:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,73 @@
+package producer
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHTTPMethods(t *testing.T) {
+	// methods are tokens (RFC 9110, section 5.6.2), the built-in ones are
+	// all upper case
+	token := regexp.MustCompile(`^[A-Z]+$`)
+
+	seen := make(map[string]struct{})
+	for _, method := range HTTPMethods {
+		if !token.MatchString(method) {
+			t.Errorf("invalid method %q", method)
+		}
+
+		if _, ok := seen[method]; ok {
+			t.Errorf("duplicate method %q", method)
+		}
+		seen[method] = struct{}{}
+	}
+
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "TRACE", "PATCH", "PROPFIND"} {
+		if _, ok := seen[method]; !ok {
+			t.Errorf("method %v is missing", method)
+		}
+	}
+}
+
+func TestHTTPMethodsValues(t *testing.T) {
+	values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+		return Product(ctx, [][]string{HTTPMethods}, "\x00", ch, count)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(HTTPMethods, values) {
+		t.Error(cmp.Diff(HTTPMethods, values))
+	}
+
+	if total != len(HTTPMethods) {
+		t.Errorf("wrong count, want %d, got %d", len(HTTPMethods), total)
+	}
+}
+
+func TestMultiplyCount(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan int, 1)
+	in <- 7
+	close(in)
+
+	n, ok := <-MultiplyCount(ctx, in, 3)
+	if !ok || n != 21 {
+		t.Errorf("wrong count, want 21, got %v (ok %v)", n, ok)
+	}
+
+	// an unknown total stays unknown
+	unknown := make(chan int)
+	close(unknown)
+
+	n, ok = <-MultiplyCount(ctx, unknown, 3)
+	if ok {
+		t.Errorf("count %d sent for unknown total", n)
+	}
+}
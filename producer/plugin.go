@@ -0,0 +1,183 @@
+package producer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// PluginProtocol is the version of the protocol spoken with producer plugins.
+const PluginProtocol = 1
+
+// pluginWindow is the maximum number of values a plugin may send before
+// monsoon requests more.
+const pluginWindow = 1000
+
+// pluginRequest is sent from monsoon to the plugin.
+type pluginRequest struct {
+	Protocol int `json:"protocol,omitempty"`
+	Next     int `json:"next"`
+}
+
+// pluginMessage is sent from the plugin to monsoon.
+type pluginMessage struct {
+	Value *string `json:"value"`
+	Count *int    `json:"count"`
+	Error string  `json:"error"`
+}
+
+// Plugin runs the producer plugin program in args and sends the values it
+// generates to the channel ch, and the number of items to the channel count.
+// The program is killed when the context is cancelled.
+//
+// The plugin and monsoon exchange JSON objects, one per line. Monsoon writes
+// requests for more values to the plugin's stdin, the first one also contains
+// the protocol version:
+//
+//	{"protocol": 1, "next": 1000}
+//	{"next": 500}
+//
+// The plugin must not send more values than requested in total. It writes the
+// following messages to stdout:
+//
+//	{"count": 5000}      the total number of values (optional, at most once)
+//	{"value": "admin"}   a value
+//	{"error": "..."}     an error, which aborts the run
+//
+// When all values have been sent, the plugin exits. The environment variable
+// MONSOON_PRODUCER_PROTOCOL is set to the protocol version, messages written
+// to stderr are shown when the plugin fails.
+func Plugin(ctx context.Context, args []string, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	if len(args) == 0 {
+		return errors.New("no producer plugin specified")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), fmt.Sprintf("MONSOON_PRODUCER_PROTOCOL=%d", PluginProtocol))
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("producer plugin: %v", err)
+	}
+
+	err = runPlugin(ctx, stdin, stdout, ch, count)
+	_ = stdin.Close()
+	if err != nil {
+		// make sure the plugin terminates
+		_ = cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+	if err != nil {
+		return fmt.Errorf("producer plugin: %v", err)
+	}
+
+	// the program is killed when the context is cancelled, that's not an error
+	if waitErr != nil && ctx.Err() == nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return fmt.Errorf("producer plugin %q failed: %v: %s", strings.Join(args, " "), waitErr, msg)
+		}
+		return fmt.Errorf("producer plugin %q failed: %v", strings.Join(args, " "), waitErr)
+	}
+
+	return nil
+}
+
+// runPlugin speaks the plugin protocol via wr and rd.
+func runPlugin(ctx context.Context, wr io.Writer, rd io.Reader, ch chan<- string, count chan<- int) error {
+	enc := json.NewEncoder(wr)
+
+	// the number of values the plugin may still send, write errors are
+	// ignored because the plugin may exit without reading all requests, but
+	// the credit is only increased when the request has been written
+	credit := pluginWindow
+	_ = enc.Encode(pluginRequest{Protocol: PluginProtocol, Next: pluginWindow})
+
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(nil, 1024*1024)
+
+	num := 0
+	countSent := false
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+
+		var msg pluginMessage
+		err := json.Unmarshal(sc.Bytes(), &msg)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+
+		switch {
+		case msg.Error != "":
+			return errors.New(msg.Error)
+
+		case msg.Count != nil:
+			if countSent {
+				continue
+			}
+			countSent = true
+
+			select {
+			case count <- *msg.Count:
+			case <-ctx.Done():
+				return nil
+			}
+
+		case msg.Value != nil:
+			if credit == 0 {
+				return fmt.Errorf("line %d: more values sent than requested", line)
+			}
+			credit--
+			num++
+
+			select {
+			case ch <- *msg.Value:
+			case <-ctx.Done():
+				return nil
+			}
+
+			// request more values when half of the window has been used
+			if credit <= pluginWindow/2 {
+				if enc.Encode(pluginRequest{Next: pluginWindow - credit}) == nil {
+					credit = pluginWindow
+				}
+			}
+
+		default:
+			return fmt.Errorf("line %d: unknown message %s", line, sc.Bytes())
+		}
+	}
+
+	if sc.Err() != nil {
+		return sc.Err()
+	}
+
+	if !countSent {
+		count <- num
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// pluginConn is the plugin side of the connection to runPlugin.
+type pluginConn struct {
+	// requests receives the requests sent by monsoon
+	requests <-chan pluginRequest
+	stdin    io.Closer
+	stdout   io.Writer
+}
+
+// send writes a message to monsoon.
+func (p pluginConn) send(msg string) {
+	_, _ = io.WriteString(p.stdout, msg+"\n")
+}
+
+// sendValue writes a value to monsoon.
+func (p pluginConn) sendValue(v string) {
+	buf, _ := json.Marshal(pluginMessage{Value: &v})
+	_, _ = p.stdout.Write(append(buf, '\n'))
+}
+
+// startPlugin connects runPlugin to a fake plugin running plugin, which is
+// done when the function returns.
+func startPlugin(ctx context.Context, plugin func(pluginConn), ch chan<- string, count chan<- int) error {
+	stdinRd, stdinWr := io.Pipe()
+	stdoutRd, stdoutWr := io.Pipe()
+
+	requests := make(chan pluginRequest, 100)
+	go func() {
+		defer close(requests)
+		dec := json.NewDecoder(stdinRd)
+		for {
+			var req pluginRequest
+			if dec.Decode(&req) != nil {
+				return
+			}
+			requests <- req
+		}
+	}()
+
+	go func() {
+		defer func() {
+			_ = stdoutWr.Close()
+		}()
+		plugin(pluginConn{requests: requests, stdin: stdinRd, stdout: stdoutWr})
+	}()
+
+	err := runPlugin(ctx, stdinWr, stdoutRd, ch, count)
+	_ = stdinWr.Close()
+	_ = stdoutRd.Close()
+	return err
+}
+
+// collectPlugin runs the fake plugin and returns the values, count and error.
+func collectPlugin(t testing.TB, plugin func(pluginConn)) ([]string, int, error) {
+	return collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+		defer close(ch)
+		return startPlugin(ctx, plugin, ch, count)
+	})
+}
+
+// sendRequested sends n values, but only as many as monsoon requested, and
+// returns the requests it received.
+func sendRequested(p pluginConn, n int) []pluginRequest {
+	var requests []pluginRequest
+	credit := 0
+	for i := 0; i < n; i++ {
+		for credit == 0 {
+			req, ok := <-p.requests
+			if !ok {
+				return requests
+			}
+			requests = append(requests, req)
+			credit += req.Next
+		}
+		p.sendValue(strconv.Itoa(i))
+		credit--
+	}
+	return requests
+}
+
+func TestPlugin(t *testing.T) {
+	var tests = []struct {
+		plugin func(pluginConn)
+		want   []string
+		count  int
+	}{
+		{
+			plugin: func(p pluginConn) {
+				p.send(`{"value": "foo"}`)
+				p.send(`{"value": ""}`)
+				p.send(``)
+				p.send(`{"value": "bar baz"}`)
+			},
+			want:  []string{"foo", "", "bar baz"},
+			count: 3,
+		},
+		{
+			// the count sent by the plugin is used
+			plugin: func(p pluginConn) {
+				p.send(`{"count": 10}`)
+				p.send(`{"value": "foo"}`)
+				p.send(`{"count": 20}`)
+				p.send(`{"value": "bar"}`)
+			},
+			want:  []string{"foo", "bar"},
+			count: 10,
+		},
+		{
+			plugin: func(p pluginConn) {},
+			want:   nil,
+			count:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, total, err := collectPlugin(t, test.plugin)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != test.count {
+				t.Errorf("wrong count, want %d, got %d", test.count, total)
+			}
+		})
+	}
+}
+
+func TestPluginRequests(t *testing.T) {
+	var requests []pluginRequest
+	values, total, err := collectPlugin(t, func(p pluginConn) {
+		requests = sendRequested(p, 2500)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 2500 || total != 2500 {
+		t.Errorf("wrong number of values, want 2500, got %d (count %d)", len(values), total)
+	}
+
+	// more values are requested when half of the window has been used
+	want := []pluginRequest{
+		{Protocol: PluginProtocol, Next: pluginWindow},
+		{Next: pluginWindow / 2},
+		{Next: pluginWindow / 2},
+		{Next: pluginWindow / 2},
+	}
+	if !cmp.Equal(want, requests) {
+		t.Error(cmp.Diff(want, requests))
+	}
+}
+
+func TestPluginError(t *testing.T) {
+	var tests = []struct {
+		plugin func(pluginConn)
+		err    string
+	}{
+		{
+			plugin: func(p pluginConn) {
+				p.send(`{"value": "foo"}`)
+				p.send(`{"error": "wordlist not found"}`)
+				p.send(`{"value": "bar"}`)
+			},
+			err: "wordlist not found",
+		},
+		{
+			plugin: func(p pluginConn) {
+				p.send(`{"value": "foo"}`)
+				p.send(`foo bar`)
+			},
+			err: "line 2:",
+		},
+		{
+			plugin: func(p pluginConn) {
+				p.send(`{"foo": "bar"}`)
+			},
+			err: "unknown message",
+		},
+		{
+			// the plugin does not read any more requests and sends more
+			// values than requested
+			plugin: func(p pluginConn) {
+				<-p.requests
+				_ = p.stdin.Close()
+				for i := 0; i < pluginWindow+1; i++ {
+					p.sendValue(strconv.Itoa(i))
+				}
+			},
+			err: fmt.Sprintf("line %d: more values sent than requested", pluginWindow+1),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			_, _, err := collectPlugin(t, test.plugin)
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("wrong error, want %q, got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestPluginCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- startPlugin(ctx, func(p pluginConn) {
+			sendRequested(p, 100)
+		}, ch, count)
+	}()
+
+	<-ch
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("plugin was not stopped")
+	}
+
+	select {
+	case n := <-count:
+		t.Errorf("count %d sent for a cancelled plugin", n)
+	default:
+	}
+}
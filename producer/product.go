@@ -0,0 +1,100 @@
+package producer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// ReadLines returns all lines read from rd.
+func ReadLines(rd io.Reader) (lines []string, err error) {
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+
+	return lines, sc.Err()
+}
+
+// Product sends all combinations of one value from each list to the channel
+// ch, the values of a combination are joined with sep. The last list is
+// iterated fastest. The number of items is sent to the channel count first.
+// Sending stops and ch is closed when the context is cancelled.
+func Product(ctx context.Context, lists [][]string, sep string, ch chan<- string, count chan<- int) error {
+	total := 1
+	for _, list := range lists {
+		total *= len(list)
+	}
+	if len(lists) == 0 {
+		total = 0
+	}
+
+	count <- total
+
+	defer close(ch)
+
+	if total == 0 {
+		return nil
+	}
+
+	index := make([]int, len(lists))
+	values := make([]string, len(lists))
+	for {
+		for i, list := range lists {
+			values[i] = list[index[i]]
+		}
+
+		select {
+		case ch <- strings.Join(values, sep):
+		case <-ctx.Done():
+			return nil
+		}
+
+		// advance to the next combination, starting with the last list
+		i := len(lists) - 1
+		for ; i >= 0; i-- {
+			index[i]++
+			if index[i] < len(lists[i]) {
+				break
+			}
+			index[i] = 0
+		}
+
+		if i < 0 {
+			return nil
+		}
+	}
+}
+
+// Zip sends the values with the same index from all lists to the channel ch,
+// joined with sep, until the shortest list is exhausted. The number of items
+// is sent to the channel count first. Sending stops and ch is closed when the
+// context is cancelled.
+func Zip(ctx context.Context, lists [][]string, sep string, ch chan<- string, count chan<- int) error {
+	total := 0
+	for i, list := range lists {
+		if i == 0 || len(list) < total {
+			total = len(list)
+		}
+	}
+
+	count <- total
+
+	defer close(ch)
+
+	values := make([]string, len(lists))
+	for n := 0; n < total; n++ {
+		for i, list := range lists {
+			values[i] = list[n]
+		}
+
+		select {
+		case ch <- strings.Join(values, sep):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// collect runs the producer and returns all values it sent, the count (or -1
+// if no count was sent) and the error.
+func collect(t testing.TB, run func(ctx context.Context, ch chan<- string, count chan<- int) error) (values []string, total int, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan string)
+	count := make(chan int, 1)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- run(ctx, ch, count)
+	}()
+
+	for v := range ch {
+		values = append(values, v)
+	}
+	err = <-errCh
+
+	select {
+	case total = <-count:
+	default:
+		total = -1
+	}
+
+	return values, total, err
+}
+
+func TestZip(t *testing.T) {
+	var tests = []struct {
+		lists [][]string
+		sep   string
+		want  []string
+	}{
+		{
+			lists: [][]string{{"a", "b", "c"}, {"1", "2", "3"}},
+			sep:   ":",
+			want:  []string{"a:1", "b:2", "c:3"},
+		},
+		{
+			lists: [][]string{{"a", "b", "c"}, {"1"}, {"x", "y"}},
+			sep:   "",
+			want:  []string{"a1x"},
+		},
+		{
+			lists: [][]string{{"admin", "root"}, {"secret", "toor", "unused"}},
+			sep:   "\x00",
+			want:  []string{"admin\x00secret", "root\x00toor"},
+		},
+		{
+			lists: [][]string{{"a", "b"}, {}},
+			want:  nil,
+		},
+		{
+			lists: nil,
+			want:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Zip(ctx, test.lists, test.sep, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestProduct(t *testing.T) {
+	var tests = []struct {
+		lists [][]string
+		sep   string
+		want  []string
+	}{
+		{
+			lists: [][]string{{"a", "b"}, {"1", "2", "3"}},
+			sep:   ":",
+			want:  []string{"a:1", "a:2", "a:3", "b:1", "b:2", "b:3"},
+		},
+		{
+			// the last list is iterated fastest
+			lists: [][]string{{"x", "y"}, {"1"}, {"a", "b"}},
+			sep:   "",
+			want:  []string{"x1a", "x1b", "y1a", "y1b"},
+		},
+		{
+			// --combine uses the separators as a list of their own
+			lists: [][]string{{"john", "jane"}, {".", "_", ""}, {"doe"}},
+			sep:   "",
+			want:  []string{"john.doe", "john_doe", "johndoe", "jane.doe", "jane_doe", "janedoe"},
+		},
+		{
+			lists: [][]string{{"a", "b", "c"}},
+			sep:   "\x00",
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			lists: [][]string{{"a", "b"}, {}},
+			want:  nil,
+		},
+		{
+			lists: nil,
+			want:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Product(ctx, test.lists, test.sep, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestProductZipCount(t *testing.T) {
+	lists := [][]string{{"a", "b", "c"}, {"1", "2"}, {"x", "y", "z", "w"}}
+
+	_, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+		return Product(ctx, lists, "", ch, count)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 3*2*4 {
+		t.Errorf("wrong count for product, want %d, got %d", 3*2*4, total)
+	}
+
+	_, total, err = collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+		return Zip(ctx, lists, "", ch, count)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the shortest list determines the number of values
+	if total != 2 {
+		t.Errorf("wrong count for zip, want 2, got %d", total)
+	}
+}
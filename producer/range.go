@@ -9,6 +9,7 @@ import (
 // Range defines a range of values which should be tested.
 type Range struct {
 	First, Last int
+	Step        int // distance between two values, 1 is used if it is not set
 }
 
 // ParseRange parses a range from the string s. Valid formats are `n` and `n-m`.
@@ -32,9 +33,27 @@ func ParseRange(s string) (r Range, err error) {
 	return r, nil
 }
 
+// SetStep sets the distance between two values of the range.
+func (r *Range) SetStep(step int) error {
+	if step <= 0 {
+		return fmt.Errorf("invalid step %d for range, must be positive", step)
+	}
+
+	r.Step = step
+	return nil
+}
+
+// step returns the distance between two values.
+func (r Range) step() int {
+	if r.Step <= 0 {
+		return 1
+	}
+	return r.Step
+}
+
 // Count returns the number of items in the range.
 func (r Range) Count() int {
-	return r.Last - r.First + 1
+	return (r.Last-r.First)/r.step() + 1
 }
 
 // Ranges sends all range values to the channel ch, and the number of items to
@@ -56,7 +75,7 @@ func Ranges(ctx context.Context, ranges []Range, format string, ch chan<- string
 	defer close(ch)
 
 	for _, r := range ranges {
-		for i := r.First; i <= r.Last; i++ {
+		for i := r.First; i <= r.Last; i += r.step() {
 			v := fmt.Sprintf(format, i)
 			select {
 			case ch <- v:
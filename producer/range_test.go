@@ -0,0 +1,105 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRanges(t *testing.T) {
+	var tests = []struct {
+		ranges []string
+		step   int
+		format string
+		want   []string
+	}{
+		{
+			ranges: []string{"1-5"},
+			step:   1,
+			want:   []string{"1", "2", "3", "4", "5"},
+		},
+		{
+			ranges: []string{"3", "7-8"},
+			step:   1,
+			format: "%03d",
+			want:   []string{"003", "007", "008"},
+		},
+		{
+			ranges: []string{"0-10"},
+			step:   5,
+			want:   []string{"0", "5", "10"},
+		},
+		{
+			// the step does not divide the range, the last value is not sent
+			ranges: []string{"1-10"},
+			step:   4,
+			want:   []string{"1", "5", "9"},
+		},
+		{
+			ranges: []string{"1-3", "10-20"},
+			step:   7,
+			want:   []string{"1", "10", "17"},
+		},
+		{
+			ranges: []string{"5"},
+			step:   100,
+			want:   []string{"5"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var ranges []Range
+			for _, s := range test.ranges {
+				rng, err := ParseRange(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = rng.SetStep(test.step)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ranges = append(ranges, rng)
+			}
+
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Ranges(ctx, ranges, test.format, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.want) {
+				t.Errorf("wrong count, want %d, got %d", len(test.want), total)
+			}
+		})
+	}
+}
+
+func TestRangeInvalid(t *testing.T) {
+	for _, s := range []string{"", "a-b", "5-1", "1-"} {
+		_, err := ParseRange(s)
+		if err == nil {
+			t.Errorf("expected error for range %q not returned", s)
+		}
+	}
+
+	rng, err := ParseRange("1-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []int{0, -1} {
+		err = rng.SetStep(step)
+		if err == nil {
+			t.Errorf("expected error for step %d not returned", step)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp/syntax"
+	"unicode"
+)
+
+// maxUnboundedRepeat is used as the maximum number of repetitions for *, +
+// and {n,} in a regular expression.
+const maxUnboundedRepeat = 3
+
+// Printable ASCII characters, used for . and to limit negated character
+// classes.
+const (
+	firstPrintable = 0x20
+	lastPrintable  = 0x7e
+)
+
+// Regex generates all strings which match a regular expression. Unbounded
+// repetitions are limited to maxUnboundedRepeat, the dot and negated
+// character classes only match printable ASCII characters. Anchors and word
+// boundaries are ignored.
+type Regex struct {
+	re    *syntax.Regexp
+	count int
+
+	// the characters for each position of literals and character classes
+	chars map[*syntax.Regexp][][]rune
+}
+
+// ParseRegex parses the regular expression pattern. An error is returned if it
+// matches more than limit strings.
+func ParseRegex(pattern string, limit int) (*Regex, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+
+	n := regexCount(re, limit)
+	if n > limit {
+		return nil, fmt.Errorf("regex %q matches more than %d values", pattern, limit)
+	}
+
+	if n == 0 {
+		return nil, fmt.Errorf("regex %q does not match any value", pattern)
+	}
+
+	r := &Regex{re: re, count: n, chars: make(map[*syntax.Regexp][][]rune)}
+	r.prepare(re)
+	return r, nil
+}
+
+// prepare computes the characters for all literals and character classes in
+// re, so they are not computed again for each generated string.
+func (r *Regex) prepare(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		r.chars[re] = literal(re)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		r.chars[re] = [][]rune{charClass(re)}
+	}
+
+	for _, sub := range re.Sub {
+		r.prepare(sub)
+	}
+}
+
+// Count returns the number of strings generated for the regular expression.
+// Strings which can be generated in several ways (e.g. for "a|a") are counted
+// and sent several times.
+func (r *Regex) Count() int {
+	return r.count
+}
+
+// classRanges returns the ranges of characters (pairs of the first and last
+// character) matched by a character class or a dot.
+func classRanges(re *syntax.Regexp) []rune {
+	switch re.Op {
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return []rune{firstPrintable, lastPrintable}
+	}
+
+	var list []rune
+	for i := 0; i+1 < len(re.Rune); i += 2 {
+		lo, hi := re.Rune[i], re.Rune[i+1]
+
+		// ranges which start in ASCII are limited to the printable characters
+		// (e.g. for negated classes), others like [ä-ö] are used as they are
+		if lo <= lastPrintable {
+			if hi > lastPrintable {
+				hi = lastPrintable
+			}
+			if lo < firstPrintable {
+				lo = firstPrintable
+			}
+		}
+
+		// ranges up to the last Unicode character are only created for
+		// negated classes (e.g. [^ä]), they are limited to ASCII as well
+		if lo > lastPrintable && hi == unicode.MaxRune {
+			continue
+		}
+
+		if lo <= hi {
+			list = append(list, lo, hi)
+		}
+	}
+
+	return list
+}
+
+// classSize returns the number of characters matched by a character class or
+// a dot, without building the list of characters.
+func classSize(re *syntax.Regexp) int {
+	ranges := classRanges(re)
+	n := 0
+	for i := 0; i < len(ranges); i += 2 {
+		n += int(ranges[i+1]-ranges[i]) + 1
+	}
+	return n
+}
+
+// charClass returns the characters matched by a character class or a dot.
+func charClass(re *syntax.Regexp) []rune {
+	ranges := classRanges(re)
+	list := make([]rune, 0, classSize(re))
+	for i := 0; i < len(ranges); i += 2 {
+		list = charRange(list, ranges[i], ranges[i+1])
+	}
+	return list
+}
+
+func charRange(list []rune, lo, hi rune) []rune {
+	for r := lo; r <= hi; r++ {
+		list = append(list, r)
+	}
+	return list
+}
+
+// literal returns the characters for each position of a literal, both cases
+// are returned for case insensitive literals. For ASCII characters, only the
+// ASCII variants are used (e.g. not the Kelvin sign for k).
+func literal(re *syntax.Regexp) [][]rune {
+	list := make([][]rune, 0, len(re.Rune))
+	for _, r := range re.Rune {
+		chars := []rune{r}
+		if re.Flags&syntax.FoldCase != 0 {
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				if r <= lastPrintable && f > lastPrintable {
+					continue
+				}
+				chars = append(chars, f)
+			}
+		}
+		list = append(list, chars)
+	}
+	return list
+}
+
+// repeatRange returns the minimal and maximal number of repetitions.
+func repeatRange(re *syntax.Regexp) (min, max int) {
+	switch re.Op {
+	case syntax.OpStar:
+		return 0, maxUnboundedRepeat
+	case syntax.OpPlus:
+		return 1, 1 + maxUnboundedRepeat
+	case syntax.OpQuest:
+		return 0, 1
+	}
+
+	if re.Max < 0 {
+		return re.Min, re.Min + maxUnboundedRepeat
+	}
+	return re.Min, re.Max
+}
+
+// regexCount returns the number of strings generated for re, values larger
+// than limit are returned as limit+1.
+func regexCount(re *syntax.Regexp, limit int) int {
+	mul := func(a, b int) int {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		if a > limit || b > limit || a > (limit+1)/b {
+			return limit + 1
+		}
+		return a * b
+	}
+
+	add := func(a, b int) int {
+		if a+b > limit {
+			return limit + 1
+		}
+		return a + b
+	}
+
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return 0
+
+	case syntax.OpLiteral:
+		n := 1
+		for _, chars := range literal(re) {
+			n = mul(n, len(chars))
+		}
+		return n
+
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return mul(1, classSize(re))
+
+	case syntax.OpCapture:
+		return regexCount(re.Sub[0], limit)
+
+	case syntax.OpConcat:
+		n := 1
+		for _, sub := range re.Sub {
+			n = mul(n, regexCount(sub, limit))
+		}
+		return n
+
+	case syntax.OpAlternate:
+		n := 0
+		for _, sub := range re.Sub {
+			n = add(n, regexCount(sub, limit))
+		}
+		return n
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := repeatRange(re)
+		sub := regexCount(re.Sub[0], limit)
+
+		n := 0
+		for i := min; i <= max; i++ {
+			c := 1
+			for j := 0; j < i; j++ {
+				c = mul(c, sub)
+			}
+			n = add(n, c)
+		}
+		return n
+	}
+
+	// empty match, anchors and word boundaries
+	return 1
+}
+
+// generate calls emit for each string matching re, which is appended to
+// prefix. It returns false if emit returned false.
+func (r *Regex) generate(re *syntax.Regexp, prefix string, emit func(string) bool) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return true
+
+	case syntax.OpLiteral, syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return generateChars(r.chars[re], prefix, emit)
+
+	case syntax.OpCapture:
+		return r.generate(re.Sub[0], prefix, emit)
+
+	case syntax.OpConcat:
+		return r.generateList(re.Sub, prefix, emit)
+
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !r.generate(sub, prefix, emit) {
+				return false
+			}
+		}
+		return true
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := repeatRange(re)
+		for i := min; i <= max; i++ {
+			list := make([]*syntax.Regexp, i)
+			for j := range list {
+				list[j] = re.Sub[0]
+			}
+
+			if !r.generateList(list, prefix, emit) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// empty match, anchors and word boundaries
+	return emit(prefix)
+}
+
+// generateList generates the strings for the concatenation of list.
+func (r *Regex) generateList(list []*syntax.Regexp, prefix string, emit func(string) bool) bool {
+	if len(list) == 0 {
+		return emit(prefix)
+	}
+
+	return r.generate(list[0], prefix, func(s string) bool {
+		return r.generateList(list[1:], s, emit)
+	})
+}
+
+// generateChars generates the strings with one of the characters in chars[i]
+// at position i.
+func generateChars(chars [][]rune, prefix string, emit func(string) bool) bool {
+	if len(chars) == 0 {
+		return emit(prefix)
+	}
+
+	for _, c := range chars[0] {
+		if !generateChars(chars[1:], prefix+string(c), emit) {
+			return false
+		}
+	}
+	return true
+}
+
+// Regexes sends all strings matching re to the channel ch, and the number of
+// items to the channel count. The strings are generated one after another.
+// Sending stops and ch is closed when the context is cancelled.
+func Regexes(ctx context.Context, re *Regex, ch chan<- string, count chan<- int) error {
+	defer close(ch)
+
+	if re == nil {
+		return errors.New("no regex specified")
+	}
+
+	count <- re.count
+
+	re.generate(re.re, "", func(s string) bool {
+		select {
+		case ch <- s:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+
+	return nil
+}
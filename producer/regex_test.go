@@ -0,0 +1,131 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRegex(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		want    []string
+	}{
+		{`admin`, []string{"admin"}},
+		{`a|b|cd`, []string{"a", "b", "cd"}},
+		{`(foo|bar)[0-1]`, []string{"foo0", "foo1", "bar0", "bar1"}},
+		{`ab?`, []string{"a", "ab"}},
+		{`a*`, []string{"", "a", "aa", "aaa"}},
+		{`x+`, []string{"x", "xx", "xxx", "xxxx"}},
+		{`[ab]{2}`, []string{"aa", "ab", "ba", "bb"}},
+		{`a{1,3}`, []string{"a", "aa", "aaa"}},
+		{`a{2,}`, []string{"aa", "aaa", "aaaa", "aaaaa"}},
+		{`^id=\d$`, []string{"id=0", "id=1", "id=2", "id=3", "id=4", "id=5", "id=6", "id=7", "id=8", "id=9"}},
+		{`(?i)ab`, []string{"AB", "Ab", "aB", "ab"}},
+		// no Kelvin sign (U+212A) or long s (U+017F)
+		{`(?i)ks`, []string{"KS", "Ks", "kS", "ks"}},
+		{`[ä-ö]`, []string{"ä", "å", "æ", "ç", "è", "é", "ê", "ë", "ì", "í", "î", "ï", "ð", "ñ", "ò", "ó", "ô", "õ", "ö"}},
+		{`a|[^ -~]`, []string{"a"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			re, err := ParseRegex(test.pattern, 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Regexes(ctx, re, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(values) || re.Count() != len(values) {
+				t.Errorf("wrong count, want %d, got %d (Count() %d)", len(values), total, re.Count())
+			}
+		})
+	}
+}
+
+func TestRegexCount(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		want    int
+	}{
+		{`.`, lastPrintable - firstPrintable + 1},
+		{`[^a]`, lastPrintable - firstPrintable},
+		{`[^ä]`, lastPrintable - firstPrintable + 1},
+		{`\D`, lastPrintable - firstPrintable + 1 - 10},
+		{`[a-z]{2,3}`, 26*26 + 26*26*26},
+		{`(a|b)*c+`, (1 + 2 + 4 + 8) * 4},
+		{`(?i)[a-c]?x`, 2 + 6*2},
+		{`user[0-9]{0,}`, 1 + 10 + 100 + 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			re, err := ParseRegex(test.pattern, 100000)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if re.Count() != test.want {
+				t.Errorf("wrong count, want %d, got %d", test.want, re.Count())
+			}
+
+			values, total, err := collect(t, func(ctx context.Context, ch chan<- string, count chan<- int) error {
+				return Regexes(ctx, re, ch, count)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(values) != test.want || total != test.want {
+				t.Errorf("wrong number of values, want %d, got %d (count %d)", test.want, len(values), total)
+			}
+
+			for _, v := range values {
+				for _, r := range v {
+					if r > lastPrintable && !strings.ContainsRune(test.pattern, r) {
+						t.Errorf("value %q contains character %q outside of printable ASCII", v, r)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRegexInvalid(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		limit   int
+		err     string
+	}{
+		{`[a-z]{5}`, 1000, "matches more than 1000 values"},
+		{`.+`, 100, "matches more than 100 values"},
+		{`\pL{3}`, 1000000, "matches more than 1000000 values"},
+		{`[^ -~]`, 1000, "does not match any value"},
+		{`(foo`, 1000, "missing closing )"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			_, err := ParseRegex(test.pattern, test.limit)
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("wrong error, want %q, got %q", test.err, err)
+			}
+		})
+	}
+}
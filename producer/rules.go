@@ -0,0 +1,406 @@
+package producer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Rule is a word mangling rule, which consists of several functions applied to
+// a word one after another. The syntax is a subset of the rules supported by
+// hashcat and John the Ripper.
+type Rule struct {
+	text  string
+	funcs []func([]rune) []rune
+}
+
+func (r Rule) String() string {
+	return r.text
+}
+
+// Apply returns word modified by all functions of the rule.
+func (r Rule) Apply(word string) string {
+	w := []rune(word)
+	for _, f := range r.funcs {
+		w = f(w)
+	}
+	return string(w)
+}
+
+// rulePosition returns the position encoded in c (0-9, A-Z for 10-35).
+func rulePosition(c rune) (int, error) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), nil
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, nil
+	}
+	return 0, fmt.Errorf("invalid position %q", c)
+}
+
+func toggle(r rune) rune {
+	if unicode.IsUpper(r) {
+		return unicode.ToLower(r)
+	}
+	return unicode.ToUpper(r)
+}
+
+// mapRunes returns a function which applies f to all characters.
+func mapRunes(f func(rune) rune) func([]rune) []rune {
+	return func(w []rune) []rune {
+		for i := range w {
+			w[i] = f(w[i])
+		}
+		return w
+	}
+}
+
+// ParseRule parses a single rule, the functions may be separated by spaces.
+func ParseRule(text string) (Rule, error) {
+	rule := Rule{text: text}
+	s := []rune(text)
+
+	// arg returns the n characters following the function at position i
+	arg := func(i, n int) ([]rune, error) {
+		if i+n >= len(s) {
+			return nil, fmt.Errorf("rule %q: function %q at position %d needs %d arguments", text, s[i], i, n)
+		}
+		return s[i+1 : i+1+n], nil
+	}
+
+	// pos returns the position which is the first argument of the function
+	pos := func(i int) (int, error) {
+		a, err := arg(i, 1)
+		if err != nil {
+			return 0, err
+		}
+		n, err := rulePosition(a[0])
+		if err != nil {
+			return 0, fmt.Errorf("rule %q: %v", text, err)
+		}
+		return n, nil
+	}
+
+	for i := 0; i < len(s); i++ {
+		var f func([]rune) []rune
+		var err error
+
+		switch s[i] {
+		case ' ', '\t', ':':
+			continue
+
+		case 'l':
+			f = mapRunes(unicode.ToLower)
+		case 'u':
+			f = mapRunes(unicode.ToUpper)
+		case 't':
+			f = mapRunes(toggle)
+		case 'c', 'C':
+			first, rest := unicode.ToUpper, unicode.ToLower
+			if s[i] == 'C' {
+				first, rest = unicode.ToLower, unicode.ToUpper
+			}
+			f = func(w []rune) []rune {
+				for i := range w {
+					if i == 0 {
+						w[i] = first(w[i])
+					} else {
+						w[i] = rest(w[i])
+					}
+				}
+				return w
+			}
+		case 'r':
+			f = func(w []rune) []rune {
+				for i, j := 0, len(w)-1; i < j; i, j = i+1, j-1 {
+					w[i], w[j] = w[j], w[i]
+				}
+				return w
+			}
+		case 'd':
+			f = func(w []rune) []rune {
+				return append(w, w...)
+			}
+		case 'f':
+			f = func(w []rune) []rune {
+				for i := len(w) - 1; i >= 0; i-- {
+					w = append(w, w[i])
+				}
+				return w
+			}
+		case 'q':
+			f = func(w []rune) []rune {
+				res := make([]rune, 0, 2*len(w))
+				for _, r := range w {
+					res = append(res, r, r)
+				}
+				return res
+			}
+		case '{':
+			f = func(w []rune) []rune {
+				if len(w) == 0 {
+					return w
+				}
+				return append(w[1:], w[0])
+			}
+		case '}':
+			f = func(w []rune) []rune {
+				if len(w) == 0 {
+					return w
+				}
+				return append([]rune{w[len(w)-1]}, w[:len(w)-1]...)
+			}
+		case '[':
+			f = func(w []rune) []rune {
+				if len(w) == 0 {
+					return w
+				}
+				return w[1:]
+			}
+		case ']':
+			f = func(w []rune) []rune {
+				if len(w) == 0 {
+					return w
+				}
+				return w[:len(w)-1]
+			}
+
+		case '$', '^', '@':
+			var a []rune
+			a, err = arg(i, 1)
+			if err != nil {
+				break
+			}
+			c := a[0]
+			switch s[i] {
+			case '$':
+				f = func(w []rune) []rune { return append(w, c) }
+			case '^':
+				f = func(w []rune) []rune { return append([]rune{c}, w...) }
+			case '@':
+				f = func(w []rune) []rune {
+					res := w[:0]
+					for _, r := range w {
+						if r != c {
+							res = append(res, r)
+						}
+					}
+					return res
+				}
+			}
+			i++
+
+		case 's':
+			var a []rune
+			a, err = arg(i, 2)
+			if err != nil {
+				break
+			}
+			from, to := a[0], a[1]
+			f = mapRunes(func(r rune) rune {
+				if r == from {
+					return to
+				}
+				return r
+			})
+			i += 2
+
+		case 'T', 'D', '\'', 'z', 'Z':
+			var n int
+			n, err = pos(i)
+			if err != nil {
+				break
+			}
+			switch s[i] {
+			case 'T':
+				f = func(w []rune) []rune {
+					if n < len(w) {
+						w[n] = toggle(w[n])
+					}
+					return w
+				}
+			case 'D':
+				f = func(w []rune) []rune {
+					if n < len(w) {
+						w = append(w[:n], w[n+1:]...)
+					}
+					return w
+				}
+			case '\'':
+				f = func(w []rune) []rune {
+					if n < len(w) {
+						w = w[:n]
+					}
+					return w
+				}
+			case 'z':
+				f = func(w []rune) []rune {
+					if len(w) == 0 {
+						return w
+					}
+					return append([]rune(strings.Repeat(string(w[0]), n)), w...)
+				}
+			case 'Z':
+				f = func(w []rune) []rune {
+					if len(w) == 0 {
+						return w
+					}
+					return append(w, []rune(strings.Repeat(string(w[len(w)-1]), n))...)
+				}
+			}
+			i++
+
+		case 'i', 'o':
+			var n int
+			n, err = pos(i)
+			if err != nil {
+				break
+			}
+			var a []rune
+			a, err = arg(i, 2)
+			if err != nil {
+				break
+			}
+			c := a[1]
+			if s[i] == 'i' {
+				f = func(w []rune) []rune {
+					if n > len(w) {
+						return w
+					}
+					res := make([]rune, 0, len(w)+1)
+					res = append(res, w[:n]...)
+					res = append(res, c)
+					return append(res, w[n:]...)
+				}
+			} else {
+				f = func(w []rune) []rune {
+					if n < len(w) {
+						w[n] = c
+					}
+					return w
+				}
+			}
+			i += 2
+
+		default:
+			err = fmt.Errorf("rule %q: unsupported function %q at position %d", text, s[i], i)
+		}
+
+		if err != nil {
+			return Rule{}, err
+		}
+
+		rule.funcs = append(rule.funcs, f)
+	}
+
+	return rule, nil
+}
+
+// ReadRules reads the rules from rd, one per line. Empty lines and lines
+// starting with # are ignored.
+func ReadRules(rd io.Reader) (rules []Rule, err error) {
+	sc := bufio.NewScanner(rd)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if sc.Err() != nil {
+		return nil, sc.Err()
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules found")
+	}
+
+	return rules, nil
+}
+
+// FilterRules applies all rules to each value, so that a value is replaced by
+// one value for each rule. If Separator is set, the value is split at the
+// separator and the rules are applied to each part.
+type FilterRules struct {
+	Rules     []Rule
+	Separator string
+}
+
+// Count filters the number of values.
+func (f *FilterRules) Count(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int, 1)
+
+	go func() {
+		defer close(out)
+		var total int
+		select {
+		case total = <-in:
+		case <-ctx.Done():
+		}
+
+		// calculate the correct total count
+		total *= len(f.Rules)
+
+		select {
+		case out <- total:
+		case <-ctx.Done():
+		}
+	}()
+
+	return out
+}
+
+// apply returns v modified by rule.
+func (f *FilterRules) apply(rule Rule, v string) string {
+	if f.Separator == "" {
+		return rule.Apply(v)
+	}
+
+	parts := strings.Split(v, f.Separator)
+	for i := range parts {
+		parts[i] = rule.Apply(parts[i])
+	}
+	return strings.Join(parts, f.Separator)
+}
+
+// Select filters values sent over ch.
+func (f *FilterRules) Select(ctx context.Context, in <-chan string) <-chan string {
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		for {
+			var v string
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				// when the input channel is closed we're done
+				if !ok {
+					return
+				}
+			}
+
+			for _, rule := range f.Rules {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- f.apply(rule, v):
+				}
+			}
+		}
+	}()
+
+	return out
+}
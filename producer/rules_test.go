@@ -0,0 +1,233 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRule(t *testing.T) {
+	var tests = []struct {
+		rule string
+		word string
+		want string
+	}{
+		{":", "Password", "Password"},
+		{"l", "PassWord", "password"},
+		{"u", "PassWord", "PASSWORD"},
+		{"c", "pASSWORD", "Password"},
+		{"C", "password", "pASSWORD"},
+		{"t", "PassWord", "pASSwORD"},
+		{"r", "abc", "cba"},
+		{"d", "abc", "abcabc"},
+		{"f", "abc", "abccba"},
+		{"q", "abc", "aabbcc"},
+		{"{", "abc", "bca"},
+		{"}", "abc", "cab"},
+		{"[", "abc", "bc"},
+		{"]", "abc", "ab"},
+		{"$1", "abc", "abc1"},
+		{"^1", "abc", "1abc"},
+		{"@s", "password", "paword"},
+		{"sa@", "banana", "b@n@n@"},
+		{"T0", "abc", "Abc"},
+		{"T5", "abc", "abc"},
+		{"D1", "abc", "ac"},
+		{"D3", "abc", "abc"},
+		{"'2", "abcdef", "ab"},
+		{"'9", "abc", "abc"},
+		{"z2", "abc", "aaabc"},
+		{"Z2", "abc", "abccc"},
+		{"i1-", "abc", "a-bc"},
+		{"i3-", "abc", "abc-"},
+		{"i4-", "abc", "abc"},
+		{"o0X", "abc", "Xbc"},
+		{"o3X", "abc", "abc"},
+
+		// positions 10-35 are encoded as A-Z
+		{"TA", "abcdefghijkl", "abcdefghijKl"},
+		{"DB", "abcdefghijkl", "abcdefghijk"},
+		{"iZ!", "abc", "abc"},
+
+		// several functions, optionally separated by spaces
+		{"c $2 $0", "password", "Password20"},
+		{"c$2$0", "password", "Password20"},
+		{"u r ]", "abc", "CB"},
+		{"sa4 se3 so0", "password", "p4ssw0rd"},
+
+		// empty words
+		{"{ } [ ] z2 Z2", "", ""},
+
+		// characters are runes, not bytes
+		{"u r", "äöü", "ÜÖÄ"},
+		{"$ä", "abc", "abcä"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			rule, err := ParseRule(test.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Apply(test.word)
+			if got != test.want {
+				t.Errorf("wrong result for %q, want %q, got %q", test.word, test.want, got)
+			}
+
+			if rule.String() != test.rule {
+				t.Errorf("wrong String(), want %q, got %q", test.rule, rule.String())
+			}
+		})
+	}
+}
+
+func TestRuleInvalid(t *testing.T) {
+	var tests = []struct {
+		rule string
+		err  string
+	}{
+		{"$", "needs 1 arguments"},
+		{"^", "needs 1 arguments"},
+		{"@", "needs 1 arguments"},
+		{"sa", "needs 2 arguments"},
+		{"T", "needs 1 arguments"},
+		{"D", "needs 1 arguments"},
+		{"'", "needs 1 arguments"},
+		{"z", "needs 1 arguments"},
+		{"Z", "needs 1 arguments"},
+		{"i1", "needs 2 arguments"},
+		{"o", "needs 1 arguments"},
+		{"c $", "needs 1 arguments"},
+		{"Ta", "invalid position"},
+		{"i#x", "invalid position"},
+		{"x", "unsupported function"},
+		{"c X12", "unsupported function"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			_, err := ParseRule(test.rule)
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("wrong error, want %q, got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	input := "# comment\n:\n\nc $1\r\n   \nu\n#r\n"
+	rules, err := ReadRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, rule := range rules {
+		got = append(got, rule.String())
+	}
+
+	want := []string{":", "c $1", "u"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+
+	_, err = ReadRules(strings.NewReader("# only comments\n\n"))
+	if err == nil {
+		t.Error("expected error for empty rules file not returned")
+	}
+
+	_, err = ReadRules(strings.NewReader("c\nu\n$\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected error for line 3 not returned, got %v", err)
+	}
+}
+
+// runFilter sends values and the count through the filter and returns the
+// values and the count it sent. A negative total (or count returned) means the
+// total is not known.
+func runFilter(t testing.TB, f Filter, values []string, total int) ([]string, int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan string)
+	inCount := make(chan int, 1)
+	if total >= 0 {
+		inCount <- total
+	}
+	close(inCount)
+
+	out := f.Select(ctx, in)
+	outCount := f.Count(ctx, inCount)
+
+	go func() {
+		defer close(in)
+		for _, v := range values {
+			select {
+			case in <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var res []string
+	for v := range out {
+		res = append(res, v)
+	}
+
+	n, ok := <-outCount
+	if !ok {
+		n = -1
+	}
+
+	return res, n
+}
+
+func TestFilterRules(t *testing.T) {
+	var rules []Rule
+	for _, text := range []string{":", "u", "$1"} {
+		rule, err := ParseRule(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+
+	var tests = []struct {
+		sep    string
+		values []string
+		want   []string
+	}{
+		{
+			values: []string{"foo", "bar"},
+			want:   []string{"foo", "FOO", "foo1", "bar", "BAR", "bar1"},
+		},
+		{
+			sep:    "\x00",
+			values: []string{"admin\x00secret"},
+			want:   []string{"admin\x00secret", "ADMIN\x00SECRET", "admin1\x00secret1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			f := &FilterRules{Rules: rules, Separator: test.sep}
+			values, total := runFilter(t, f, test.values, len(test.values))
+
+			if !cmp.Equal(test.want, values) {
+				t.Error(cmp.Diff(test.want, values))
+			}
+
+			if total != len(test.values)*len(rules) {
+				t.Errorf("wrong count, want %d, got %d", len(test.values)*len(rules), total)
+			}
+		})
+	}
+}
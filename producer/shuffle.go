@@ -0,0 +1,89 @@
+package producer
+
+import (
+	"context"
+	"math/rand"
+)
+
+// FilterShuffle sends the values in a pseudo-random order, which is the same
+// for each run with the same Seed and input. The values are collected in a
+// window of Window values, from which a random one is sent whenever a new
+// value is received. Lists with at most Window values are therefore shuffled
+// completely. For longer lists a value is never sent more than Window
+// positions earlier than in the input, but it may be sent arbitrarily later.
+//
+// No value is sent before Window values have been received (or the input is
+// closed), so for slow sources like stdin or a program nothing happens until
+// the window is full.
+type FilterShuffle struct {
+	Seed   int64
+	Window int
+}
+
+// Count filters the number of values.
+func (f *FilterShuffle) Count(ctx context.Context, in <-chan int) <-chan int {
+	// shuffling does not change the number of values
+	return in
+}
+
+// Select filters values sent over ch.
+func (f *FilterShuffle) Select(ctx context.Context, in <-chan string) <-chan string {
+	out := make(chan string)
+
+	window := f.Window
+	if window <= 0 {
+		window = 1
+	}
+
+	go func() {
+		defer close(out)
+		rnd := rand.New(rand.NewSource(f.Seed))
+		buf := make([]string, 0, window)
+
+		send := func(v string) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- v:
+				return true
+			}
+		}
+
+		for {
+			var v string
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+			}
+
+			// when the input channel is closed, send the remaining values
+			if !ok {
+				rnd.Shuffle(len(buf), func(i, j int) {
+					buf[i], buf[j] = buf[j], buf[i]
+				})
+				for _, v := range buf {
+					if !send(v) {
+						return
+					}
+				}
+				return
+			}
+
+			if len(buf) < window {
+				buf = append(buf, v)
+				continue
+			}
+
+			// send a random value from the window and replace it
+			i := rnd.Intn(len(buf))
+			if !send(buf[i]) {
+				return
+			}
+			buf[i] = v
+		}
+	}()
+
+	return out
+}
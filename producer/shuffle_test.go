@@ -0,0 +1,89 @@
+package producer
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterShuffle(t *testing.T) {
+	var tests = []struct {
+		total  int
+		window int
+	}{
+		{0, 10},
+		{1, 10},
+		{5, 10},
+		{10, 10},
+		{100, 10},
+		{100, 1},
+		{100, 0},
+		{1000, 100},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var values []string
+			for i := 0; i < test.total; i++ {
+				values = append(values, strconv.Itoa(i))
+			}
+
+			f := &FilterShuffle{Seed: 23, Window: test.window}
+			res, total := runFilter(t, f, values, test.total)
+
+			if total != test.total {
+				t.Errorf("wrong count, want %d, got %d", test.total, total)
+			}
+
+			// the same seed results in the same order
+			res2, _ := runFilter(t, f, values, test.total)
+			if !cmp.Equal(res, res2) {
+				t.Errorf("order differs for the same seed:\n%v\n%v", res, res2)
+			}
+
+			// all values are sent exactly once
+			sorted := append([]string(nil), res...)
+			sort.Slice(sorted, func(i, j int) bool {
+				a, _ := strconv.Atoi(sorted[i])
+				b, _ := strconv.Atoi(sorted[j])
+				return a < b
+			})
+			if !cmp.Equal(values, sorted) {
+				t.Error(cmp.Diff(values, sorted))
+			}
+
+			// values are never sent more than window positions early
+			window := test.window
+			if window <= 0 {
+				window = 1
+			}
+			for pos, v := range res {
+				i, _ := strconv.Atoi(v)
+				if i > pos+window {
+					t.Errorf("value %v sent at position %d", v, pos)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterShuffleSeed(t *testing.T) {
+	var values []string
+	for i := 0; i < 50; i++ {
+		values = append(values, strconv.Itoa(i))
+	}
+
+	// input shorter than the window is shuffled completely
+	res1, _ := runFilter(t, &FilterShuffle{Seed: 1, Window: 100}, values, len(values))
+	res2, _ := runFilter(t, &FilterShuffle{Seed: 2, Window: 100}, values, len(values))
+
+	if cmp.Equal(values, res1) {
+		t.Errorf("values not shuffled: %v", res1)
+	}
+
+	if cmp.Equal(res1, res2) {
+		t.Errorf("different seeds returned the same order: %v", res1)
+	}
+}
@@ -0,0 +1,129 @@
+package producer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DefaultUsernameFormats are the formats used for user names if none are
+// specified, e.g. jsmith, j.smith, smithj, john.smith, johnsmith, john_smith,
+// john and smith for "John Smith".
+var DefaultUsernameFormats = []string{
+	"{f}{last}", "{f}.{last}", "{last}{f}", "{first}.{last}",
+	"{first}{last}", "{first}_{last}", "{first}", "{last}",
+}
+
+// Name is the first and last name of a person.
+type Name struct {
+	First, Last string
+}
+
+// ReadNames reads the names from rd, one per line, either as "first last" or
+// as "last, first". Middle names are ignored, a single word is used as the
+// first name. Empty lines and lines starting with # are ignored.
+func ReadNames(rd io.Reader) (names []Name, err error) {
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var name Name
+		if i := strings.Index(line, ","); i >= 0 {
+			last := strings.Fields(line[:i])
+			first := strings.Fields(line[i+1:])
+			if len(first) > 0 {
+				name.First = first[0]
+			}
+			if len(last) > 0 {
+				name.Last = last[len(last)-1]
+			}
+		} else {
+			fields := strings.Fields(line)
+			name.First = fields[0]
+			if len(fields) > 1 {
+				name.Last = fields[len(fields)-1]
+			}
+		}
+
+		if name.First == "" {
+			name.First, name.Last = name.Last, ""
+		}
+		if name.First == "" {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	if sc.Err() != nil {
+		return nil, sc.Err()
+	}
+
+	return names, nil
+}
+
+var usernameToken = regexp.MustCompile(`\{[^}]*\}`)
+
+// CheckUsernameFormat returns an error if format contains an unknown token.
+// Valid tokens are {first}, {last}, {f} and {l} (the first character of the
+// first and last name).
+func CheckUsernameFormat(format string) error {
+	for _, token := range usernameToken.FindAllString(format, -1) {
+		switch token {
+		case "{first}", "{last}", "{f}", "{l}":
+		default:
+			return fmt.Errorf("unknown token %v in user name format %q", token, format)
+		}
+	}
+	return nil
+}
+
+// firstChar returns the first character of s.
+func firstChar(s string) string {
+	for _, r := range s {
+		return string(r)
+	}
+	return ""
+}
+
+// Usernames returns the user names in all formats for each name, in lower
+// case. If domain is not empty, it is appended as "@domain". Formats which
+// need the last name are skipped for names without one, duplicate user names
+// for the same name are only returned once.
+func Usernames(names []Name, formats []string, domain string) []string {
+	var list []string
+	for _, name := range names {
+		first, last := strings.ToLower(name.First), strings.ToLower(name.Last)
+		r := strings.NewReplacer(
+			"{first}", first,
+			"{last}", last,
+			"{f}", firstChar(first),
+			"{l}", firstChar(last),
+		)
+
+		seen := make(map[string]struct{})
+		for _, format := range formats {
+			if last == "" && (strings.Contains(format, "{last}") || strings.Contains(format, "{l}")) {
+				continue
+			}
+
+			user := r.Replace(format)
+			if domain != "" {
+				user += "@" + domain
+			}
+
+			if _, ok := seen[user]; ok {
+				continue
+			}
+			seen[user] = struct{}{}
+			list = append(list, user)
+		}
+	}
+
+	return list
+}
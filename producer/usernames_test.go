@@ -0,0 +1,127 @@
+package producer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadNames(t *testing.T) {
+	input := `# employees
+John Smith
+  Jane   Ann Doe  
+
+Miller, Bob
+Müller , Jürgen Karl
+Alice
+Bob,
+, Carol
+,
+`
+
+	names, err := ReadNames(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Name{
+		{"John", "Smith"},
+		{"Jane", "Doe"},
+		{"Bob", "Miller"},
+		{"Jürgen", "Müller"},
+		{"Alice", ""},
+		{"Bob", ""},
+		{"Carol", ""},
+	}
+	if !cmp.Equal(want, names) {
+		t.Error(cmp.Diff(want, names))
+	}
+}
+
+func TestUsernames(t *testing.T) {
+	var tests = []struct {
+		names   []Name
+		formats []string
+		domain  string
+		want    []string
+	}{
+		{
+			names:   []Name{{"John", "Smith"}},
+			formats: DefaultUsernameFormats,
+			want: []string{
+				"jsmith", "j.smith", "smithj", "john.smith",
+				"johnsmith", "john_smith", "john", "smith",
+			},
+		},
+		{
+			names:   []Name{{"John", "Smith"}, {"Jane", "Doe"}},
+			formats: []string{"{f}{l}", "{last}.{first}", "x-{first}"},
+			want:    []string{"js", "smith.john", "x-john", "jd", "doe.jane", "x-jane"},
+		},
+		{
+			names:   []Name{{"John", "Smith"}},
+			formats: []string{"{f}{last}", "{first}"},
+			domain:  "example.com",
+			want:    []string{"jsmith@example.com", "john@example.com"},
+		},
+		{
+			// formats with the last name are skipped for names without one
+			names:   []Name{{"Alice", ""}},
+			formats: DefaultUsernameFormats,
+			want:    []string{"alice"},
+		},
+		{
+			// duplicates for the same name are only returned once, but they
+			// are kept for different names
+			names:   []Name{{"Al", "Al"}, {"Al", "Bert"}},
+			formats: []string{"{first}", "{last}", "{f}{l}"},
+			want:    []string{"al", "aa", "al", "bert", "ab"},
+		},
+		{
+			names:   []Name{{"Émile", "Ölberg"}},
+			formats: []string{"{f}{last}", "{first}.{l}"},
+			want:    []string{"éölberg", "émile.ö"},
+		},
+		{
+			names:   nil,
+			formats: DefaultUsernameFormats,
+			want:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			list := Usernames(test.names, test.formats, test.domain)
+			if !cmp.Equal(test.want, list) {
+				t.Error(cmp.Diff(test.want, list))
+			}
+		})
+	}
+}
+
+func TestCheckUsernameFormat(t *testing.T) {
+	var tests = []struct {
+		format string
+		err    bool
+	}{
+		{"{first}.{last}", false},
+		{"{f}{l}", false},
+		{"admin", false},
+		{"{first}{middle}", true},
+		{"{}", true},
+		{"{First}", true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			err := CheckUsernameFormat(test.format)
+			if test.err && err == nil {
+				t.Fatal("expected error not returned")
+			}
+			if !test.err && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
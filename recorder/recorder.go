@@ -29,24 +29,67 @@ type Data struct {
 
 	Template    Template   `json:"template"`
 	InputFile   string     `json:"input_file,omitempty"`
+	InputFiles  []string   `json:"input_files,omitempty"`
+	FilePrio    []int      `json:"file_priority,omitempty"`
+	Values      []string   `json:"values,omitempty"`
+	Mode        string     `json:"mode,omitempty"`
 	Ranges      []string   `json:"ranges,omitempty"`
 	RangeFormat string     `json:"range_format,omitempty"`
+	RangeStep   int        `json:"range_step,omitempty"`
+	IPRange     []string   `json:"ip_range,omitempty"`
+	DateRange   string     `json:"date_range,omitempty"`
+	DateFormat  string     `json:"date_format,omitempty"`
+	Charset     string     `json:"charset,omitempty"`
+	Length      string     `json:"length,omitempty"`
+	ValuesRegex string     `json:"values_regex,omitempty"`
+	ValuesExec  string     `json:"values_exec,omitempty"`
+	Plugin      string     `json:"producer_plugin,omitempty"`
+	Combine     []string   `json:"combine,omitempty"`
+	CombineSep  []string   `json:"combine_separator,omitempty"`
+	Usernames   string     `json:"usernames,omitempty"`
+	UserFormats []string   `json:"username_formats,omitempty"`
+	UserDomain  string     `json:"username_domain,omitempty"`
+	Rules       string     `json:"rules,omitempty"`
+	Extensions  []string   `json:"extensions,omitempty"`
+	Prefixes    []string   `json:"prefixes,omitempty"`
+	Suffixes    []string   `json:"suffixes,omitempty"`
+	Dedup       bool       `json:"dedup,omitempty"`
+	DedupCase   bool       `json:"dedup_ignore_case,omitempty"`
+	SkipRegex   []string   `json:"skip_values_regex,omitempty"`
+	SkipFile    []string   `json:"skip_values_file,omitempty"`
+	Shard       string     `json:"shard,omitempty"`
+	ShuffleSeed string     `json:"shuffle_seed,omitempty"`
 	Responses   []Response `json:"responses"`
 	Extract     []string   `json:"extract,omitempty"`
 	ExtractPipe []string   `json:"extract_pipe,omitempty"`
+	ExtractJSON []string   `json:"extract_jsonpath,omitempty"`
+	ExtractXP   []string   `json:"extract_xpath,omitempty"`
 }
 
 // Response is the result of a request sent to the target.
 type Response struct {
 	Item     string  `json:"item"`
+	Method   string  `json:"method,omitempty"`
+	URL      string  `json:"url,omitempty"`
 	Error    string  `json:"error,omitempty"`
 	Duration float64 `json:"duration"`
+	Timing   *Timing `json:"timing,omitempty"`
 
 	StatusCode    int                `json:"status_code"`
 	StatusText    string             `json:"status_text"`
 	Header        response.TextStats `json:"header"`
 	Body          response.TextStats `json:"body"`
 	ExtractedData []string           `json:"extracted_data,omitempty"`
+	FollowUp      *Response          `json:"follow_up,omitempty"`
+}
+
+// Timing contains the duration of the phases of a request in seconds.
+type Timing struct {
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	TLS     float64 `json:"tls"`
+	TTFB    float64 `json:"ttfb"`
+	Total   float64 `json:"total"`
 }
 
 // New creates a new  recorder.
@@ -104,10 +147,14 @@ loop:
 				break loop
 			}
 
-		case total := <-inCount:
-			data.TotalRequests = total
+		case total, ok := <-inCount:
 			// disable receiving on the in count channel
 			inCount = nil
+			if !ok {
+				// the total is not known, don't forward it
+				continue loop
+			}
+			data.TotalRequests = total
 			// enable sending by setting countCh to outCount (which is not nil)
 			countCh = outCount
 			continue loop
@@ -162,9 +209,23 @@ func (r *Recorder) dump(data Data) error {
 // NewResponse builds a Response struct for serialization with JSON.
 func NewResponse(r response.Response) (res Response) {
 	res.Item = r.Item
+	res.Method = r.Method
+	res.URL = r.URL
 	if r.Duration != 0 {
 		res.Duration = float64(r.Duration) / float64(time.Second)
 	}
+	if r.Timing != nil {
+		seconds := func(d time.Duration) float64 {
+			return float64(d) / float64(time.Second)
+		}
+		res.Timing = &Timing{
+			DNS:     seconds(r.Timing.DNS),
+			Connect: seconds(r.Timing.Connect),
+			TLS:     seconds(r.Timing.TLS),
+			TTFB:    seconds(r.Timing.TTFB),
+			Total:   seconds(r.Timing.Total),
+		}
+	}
 	if r.Error != nil {
 		res.Error = r.Error.Error()
 	}
@@ -177,5 +238,10 @@ func NewResponse(r response.Response) (res Response) {
 	res.Body = r.Body
 	res.ExtractedData = r.Extract
 
+	if r.FollowUp != nil {
+		followUp := NewResponse(*r.FollowUp)
+		res.FollowUp = &followUp
+	}
+
 	return res
 }
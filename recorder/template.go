@@ -16,13 +16,13 @@ type Template struct {
 }
 
 // NewTemplate builds a template to write to the JSON data file.
-func NewTemplate(request *request.Request) (t Template, err error) {
-	req, err := request.Apply(request.Replace)
+func NewTemplate(template *request.Request) (t Template, err error) {
+	req, err := template.Apply(template.PlaceholderValue())
 	if err != nil {
 		return Template{}, err
 	}
 
-	t.URL = req.URL.String()
+	t.URL = request.URLString(req.URL)
 	t.Method = req.Method
 	t.Header = req.Header
 
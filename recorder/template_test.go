@@ -94,6 +94,31 @@ foobar`)
 				},
 			},
 		},
+		{
+			request: func() *request.Request {
+				req := request.New("")
+				req.URL = "https://localhost:8443/?bar#FUZZ"
+				return req
+			},
+			want: Template{
+				URL:    "https://localhost:8443/?bar#FUZZ",
+				Method: "GET",
+				Header: request.DefaultHeader,
+			},
+		},
+		{
+			request: func() *request.Request {
+				req := request.New("")
+				req.TemplateData = []byte("GET /?x=y HTTP/1.1\r\n\r\n")
+				req.URL = "https://host#FUZZ"
+				return req
+			},
+			want: Template{
+				URL:    "https://host/?x=y#FUZZ",
+				Method: "GET",
+				Header: request.DefaultHeader,
+			},
+		},
 	}
 
 	for _, test := range tests {
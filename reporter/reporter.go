@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/RedTeamPentesting/monsoon/cli"
+	"github.com/RedTeamPentesting/monsoon/request"
 	"github.com/RedTeamPentesting/monsoon/response"
 )
 
@@ -23,10 +24,12 @@ func New(term cli.Terminal) *Reporter {
 type HTTPStats struct {
 	Start          time.Time
 	StatusCodes    map[int]int
+	MethodCodes    map[string]map[int]int // status codes per method, if methods are used
 	Errors         int
 	Responses      int
 	ShownResponses int
 	Count          int
+	CountKnown     bool // the total number of requests has been received
 
 	lastRPS time.Time
 	rps     float64
@@ -62,7 +65,9 @@ func (h *HTTPStats) Report(current string) (res []string) {
 	}
 
 	todo := h.Count - h.Responses
-	if todo > 0 {
+	if !h.CountKnown {
+		status += ", total unknown"
+	} else if todo > 0 {
 		status += fmt.Sprintf(", %d todo", todo)
 
 		if h.rps > 0 {
@@ -77,8 +82,16 @@ func (h *HTTPStats) Report(current string) (res []string) {
 
 	res = append(res, status)
 
-	for code, count := range h.StatusCodes {
-		res = append(res, fmt.Sprintf("%v: %v", code, count))
+	if len(h.MethodCodes) > 0 {
+		for method, codes := range h.MethodCodes {
+			for code, count := range codes {
+				res = append(res, fmt.Sprintf("%v %v: %v", method, code, count))
+			}
+		}
+	} else {
+		for code, count := range h.StatusCodes {
+			res = append(res, fmt.Sprintf("%v: %v", code, count))
+		}
 	}
 
 	sort.Strings(res[2:])
@@ -93,12 +106,20 @@ func (r *Reporter) Display(ch <-chan response.Response, countChannel <-chan int)
 	stats := &HTTPStats{
 		Start:       time.Now(),
 		StatusCodes: make(map[int]int),
+		MethodCodes: make(map[string]map[int]int),
 	}
 
 	for response := range ch {
 		select {
-		case c := <-countChannel:
-			stats.Count = c
+		case c, ok := <-countChannel:
+			if ok {
+				stats.Count = c
+				stats.CountKnown = true
+			} else {
+				// the total is not known, e.g. when the values are read
+				// from stdin and the run is cancelled
+				countChannel = nil
+			}
 		default:
 		}
 
@@ -108,6 +129,15 @@ func (r *Reporter) Display(ch <-chan response.Response, countChannel <-chan int)
 			stats.Errors++
 		} else {
 			stats.StatusCodes[response.HTTPResponse.StatusCode]++
+
+			if response.Method != "" {
+				codes, ok := stats.MethodCodes[response.Method]
+				if !ok {
+					codes = make(map[int]int)
+					stats.MethodCodes[response.Method] = codes
+				}
+				codes[response.HTTPResponse.StatusCode]++
+			}
 		}
 
 		if !response.Hide {
@@ -115,9 +145,12 @@ func (r *Reporter) Display(ch <-chan response.Response, countChannel <-chan int)
 			stats.ShownResponses++
 		}
 
-		r.term.SetStatus(stats.Report(response.Item))
+		r.term.SetStatus(stats.Report(request.DisplayValue(response.Item)))
 	}
 
+	// all requests are done, so the total is known now
+	stats.CountKnown = true
+
 	r.term.Print("\n")
 	r.term.Printf("processed %d HTTP requests in %v\n", stats.Responses, formatSeconds(time.Since(stats.Start).Seconds()))
 
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultChunkSize is the size of the chunks the body is split into when a
+// delay between chunks but no chunk size is configured.
+const defaultChunkSize = 1024
+
+// chunkedBody returns data in chunks of size bytes from each call to Read,
+// and waits for delay before returning the next chunk. When the http.Client
+// sends the body with chunked encoding, each Read results in a separate chunk.
+type chunkedBody struct {
+	data  []byte
+	size  int
+	delay time.Duration
+
+	started bool
+}
+
+func (b *chunkedBody) Read(p []byte) (int, error) {
+	if len(b.data) == 0 {
+		return 0, io.EOF
+	}
+
+	if b.started && b.delay > 0 {
+		time.Sleep(b.delay)
+	}
+	b.started = true
+
+	n := b.size
+	if n > len(p) {
+		n = len(p)
+	}
+	if n > len(b.data) {
+		n = len(b.data)
+	}
+
+	copy(p, b.data[:n])
+	b.data = b.data[n:]
+
+	return n, nil
+}
+
+// applyChunks replaces the body of req so that it is sent in chunks of
+// ChunkSize bytes with ChunkDelay in between.
+func (r *Request) applyChunks(req *http.Request) error {
+	if !r.ForceChunkedEncoding && len(r.Trailer) == 0 {
+		return errors.New("a chunk size or delay requires chunked encoding (--force-chunked-encoding)")
+	}
+
+	if r.ChunkSize < 0 {
+		return fmt.Errorf("invalid chunk size %d", r.ChunkSize)
+	}
+
+	size := r.ChunkSize
+	if size == 0 {
+		size = defaultChunkSize
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(&chunkedBody{
+			data:  body,
+			size:  size,
+			delay: r.ChunkDelay,
+		}), nil
+	}
+	req.Body, _ = req.GetBody()
+
+	return nil
+}
+
+// applyTrailer sets the trailer headers for req, which is then sent with
+// chunked encoding.
+func (r *Request) applyTrailer(req *http.Request, insertValue func(string) string) error {
+	if r.RawWriter() {
+		return errors.New("--trailer cannot be used together with --preserve-length, --preserve-header-order, --raw-request-line, --raw-header, multiple User-Agent headers or a removed Host header")
+	}
+
+	req.Trailer = make(http.Header)
+	for _, s := range r.Trailer {
+		data := strings.SplitN(s, ":", 2)
+		name := strings.TrimSpace(insertValue(data[0]))
+		if len(data) != 2 || name == "" {
+			return fmt.Errorf("invalid trailer %q, format is \"name: value\"", s)
+		}
+
+		req.Trailer.Add(name, strings.TrimSpace(insertValue(data[1])))
+	}
+
+	// trailers are only sent with chunked encoding, which requires a body.
+	// For methods which usually lack a body, the Go stdlib does not use
+	// chunked encoding if the body is empty.
+	if req.Body == nil || req.Body == http.NoBody {
+		switch req.Method {
+		case "GET", "HEAD", "DELETE", "OPTIONS", "PROPFIND", "SEARCH":
+			return fmt.Errorf("--trailer requires a body for %v requests", req.Method)
+		}
+
+		req.Body = ioutil.NopCloser(strings.NewReader(""))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("")), nil
+		}
+	}
+	req.ContentLength = -1
+
+	return nil
+}
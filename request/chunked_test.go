@@ -0,0 +1,235 @@
+package request
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunkDelay(t *testing.T) {
+	const chunks = 4
+	const delay = 50 * time.Millisecond
+
+	var arrived []time.Time
+	var received int
+	var transferEncoding []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		transferEncoding = r.TransferEncoding
+
+		buf := make([]byte, 100)
+		for {
+			n, err := r.Body.Read(buf)
+			// record the time when a chunk has been received completely
+			if n > 0 && (received+n)/defaultChunkSize > received/defaultChunkSize {
+				arrived = append(arrived, time.Now())
+			}
+			received += n
+
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	r := New("")
+	r.URL = srv.URL
+	r.Method = "POST"
+	r.Body = strings.Repeat("x", chunks*defaultChunkSize)
+	r.ForceChunkedEncoding = true
+	r.ChunkDelay = delay
+
+	req, err := r.Apply("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	if len(transferEncoding) != 1 || transferEncoding[0] != "chunked" {
+		t.Errorf("body was not sent with chunked encoding: %v", transferEncoding)
+	}
+
+	if received != chunks*defaultChunkSize {
+		t.Errorf("wrong body size received, want %v, got %v", chunks*defaultChunkSize, received)
+	}
+
+	if len(arrived) != chunks {
+		t.Fatalf("wrong number of chunks received, want %v, got %v", chunks, len(arrived))
+	}
+
+	for i := 1; i < len(arrived); i++ {
+		gap := arrived[i].Sub(arrived[i-1])
+		if gap < delay*8/10 || gap > 10*delay {
+			t.Errorf("gap between chunk %d and %d is %v, want approximately %v", i-1, i, gap, delay)
+		}
+	}
+}
+
+func TestChunkDelayRequiresChunkedEncoding(t *testing.T) {
+	r := New("")
+	r.URL = "http://www.example.com"
+	r.Body = "foo"
+	r.ChunkDelay = time.Second
+
+	_, err := r.Apply("")
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+}
+
+func TestTrailer(t *testing.T) {
+	var tests = []struct {
+		method string
+		body   string
+	}{
+		{"POST", "data=FUZZ"},
+		{"POST", ""},
+		{"PUT", "x"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			var trailer http.Header
+			var transferEncoding []string
+			var body string
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				transferEncoding = r.TransferEncoding
+				buf, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				body = string(buf)
+				trailer = r.Trailer
+			}))
+			defer srv.Close()
+
+			r := New("")
+			r.URL = srv.URL
+			r.Method = test.method
+			r.Body = test.body
+			r.Trailer = []string{"X-Checksum: FUZZ", "X-Other:  value "}
+
+			req, err := r.Apply("foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = res.Body.Close()
+
+			if len(transferEncoding) != 1 || transferEncoding[0] != "chunked" {
+				t.Errorf("body was not sent with chunked encoding: %v", transferEncoding)
+			}
+
+			if want := strings.Replace(test.body, "FUZZ", "foo", -1); body != want {
+				t.Errorf("wrong body, want %q, got %q", want, body)
+			}
+
+			if trailer.Get("X-Checksum") != "foo" || trailer.Get("X-Other") != "value" {
+				t.Errorf("wrong trailer received: %v", trailer)
+			}
+		})
+	}
+}
+
+func TestTrailerInvalid(t *testing.T) {
+	var tests = []struct {
+		method  string
+		body    string
+		trailer string
+	}{
+		{"POST", "x", "no colon"},
+		{"POST", "x", ": value"},
+		{"GET", "", "X-Foo: bar"},
+	}
+
+	for _, test := range tests {
+		r := New("")
+		r.URL = "http://www.example.com"
+		r.Method = test.method
+		r.Body = test.body
+		r.Trailer = []string{test.trailer}
+
+		_, err := r.Apply("")
+		if err == nil {
+			t.Errorf("%v: expected error not returned", test)
+		}
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+			close(received)
+			return
+		}
+		defer conn.Close()
+
+		// read until the end of the chunked body
+		var buf []byte
+		tmp := make([]byte, 256)
+		for !strings.HasSuffix(string(buf), "0\r\n\r\n") {
+			n, err := conn.Read(tmp)
+			buf = append(buf, tmp[:n]...)
+			if err != nil {
+				t.Error(err)
+				break
+			}
+		}
+
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+		received <- string(buf)
+	}()
+
+	r := New("")
+	r.URL = "http://" + l.Addr().String()
+	r.Method = "POST"
+	r.Body = "abcFUZZ"
+	r.ForceChunkedEncoding = true
+	r.ChunkSize = 3
+
+	req, err := r.Apply("defg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	want := "\r\n\r\n3\r\nabc\r\n3\r\ndef\r\n1\r\ng\r\n0\r\n\r\n"
+	if got := <-received; !strings.HasSuffix(got, want) {
+		t.Errorf("wrong chunks sent, want suffix %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,110 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/shell"
+)
+
+// RunPreRequestCommand runs the pre-request command and stores its output
+// (with trailing whitespace removed) as the value for the pre-request
+// placeholder. If no command is configured, nothing is done.
+func (r *Request) RunPreRequestCommand(ctx context.Context) error {
+	if r.PreRequestCommand == "" {
+		return nil
+	}
+
+	if r.PreRequestPlaceholder == "" {
+		return errors.New("pre-request placeholder is empty")
+	}
+
+	err := r.checkPreRequestPlaceholder()
+	if err != nil {
+		return err
+	}
+
+	args, err := shell.Split(r.PreRequestCommand)
+	if err != nil {
+		return fmt.Errorf("pre-request command: %v", err)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("invalid pre-request command: %q", r.PreRequestCommand)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return fmt.Errorf("pre-request command %q failed: %v: %s", r.PreRequestCommand, err, msg)
+		}
+		return fmt.Errorf("pre-request command %q failed: %v", r.PreRequestCommand, err)
+	}
+
+	if r.Vars == nil {
+		r.Vars = NewVars()
+	}
+	r.Vars.Set(r.PreRequestPlaceholder, strings.TrimRight(stdout.String(), " \t\r\n"))
+
+	return nil
+}
+
+// checkPreRequestPlaceholder returns an error if the pre-request placeholder
+// is also used for the values or for the value extracted from the prime
+// response, the other source of variables.
+func (r *Request) checkPreRequestPlaceholder() error {
+	name := r.PreRequestPlaceholder
+
+	if name == r.Replace {
+		return fmt.Errorf("pre-request placeholder %q is the same as the placeholder for values", name)
+	}
+
+	for _, placeholder := range r.Placeholders {
+		if name == placeholder {
+			return fmt.Errorf("pre-request placeholder %q is the same as a placeholder for values", name)
+		}
+	}
+
+	if r.PrimeRequestFile != "" && name == r.PrimePlaceholder {
+		return fmt.Errorf("pre-request placeholder %q is the same as the placeholder for the prime request", name)
+	}
+
+	return nil
+}
+
+// RefreshPreRequestCommand runs the pre-request command every
+// PreRequestInterval until ctx is cancelled. Errors are passed to onError and
+// the previous value is kept. It returns immediately if no command or interval
+// is configured.
+func (r *Request) RefreshPreRequestCommand(ctx context.Context, onError func(error)) {
+	if r.PreRequestCommand == "" || r.PreRequestInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.PreRequestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := r.RunPreRequestCommand(ctx)
+		if err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+}
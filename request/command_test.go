@@ -0,0 +1,191 @@
+package request
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeStubScript(t testing.TB, dir, content string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	filename := filepath.Join(dir, "stub.sh")
+	err := ioutil.WriteFile(filename, []byte("#!/bin/sh\n"+content), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestPreRequestCommand(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-command-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	script := writeStubScript(t, tempdir, "echo secret-token-FUZZ\n")
+
+	r, _ := newTestFlags(t, []string{
+		"--pre-request-cmd", script,
+		"--header", "Authorization: Bearer TOKEN",
+		"--data", "value=FUZZ",
+	})
+	r.URL = "http://www.example.com/FUZZ"
+
+	err = r.RunPreRequestCommand(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := r.Apply("xxx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the output of the command must not be processed again, so FUZZ in the
+	// token is kept as is
+	want := "Bearer secret-token-FUZZ"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("wrong Authorization header, want %q, got %q", want, got)
+	}
+
+	if req.URL.Path != "/xxx" {
+		t.Errorf("wrong path, want %q, got %q", "/xxx", req.URL.Path)
+	}
+}
+
+func TestPreRequestCommandFailure(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-command-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	script := writeStubScript(t, tempdir, "echo login failed >&2\nexit 1\n")
+
+	r, _ := newTestFlags(t, []string{"--pre-request-cmd", script})
+
+	err = r.RunPreRequestCommand(context.Background())
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+
+	if !strings.Contains(err.Error(), "login failed") {
+		t.Errorf("error does not contain the output of the command: %v", err)
+	}
+}
+
+func TestPreRequestCommandRefresh(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-command-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	counter := filepath.Join(tempdir, "counter")
+	script := writeStubScript(t, tempdir, "echo x >> "+counter+"\nwc -l < "+counter+"\n")
+
+	r, _ := newTestFlags(t, []string{
+		"--pre-request-cmd", script,
+		"--pre-request-interval", "10ms",
+	})
+
+	err = r.RunPreRequestCommand(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.RefreshPreRequestCommand(ctx, func(err error) {
+			t.Error(err)
+		})
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		v, _ := r.Vars.Get("TOKEN")
+		if strings.TrimSpace(v) != "1" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("value was not refreshed")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestPreRequestCommandPlaceholderCollision(t *testing.T) {
+	var tests = []struct {
+		args         []string
+		replace      string
+		placeholders []string
+	}{
+		{
+			args:    []string{"--pre-request-placeholder", "FUZZ"},
+			replace: "FUZZ",
+		},
+		{
+			args:         []string{"--pre-request-placeholder", "PASS"},
+			placeholders: []string{"USER", "PASS"},
+		},
+		{
+			args: []string{"--pre-request-placeholder", "PRIME", "--prime-request", "prime.txt", "--prime-extract", "x"},
+		},
+		{
+			args: []string{"--pre-request-placeholder", "VALUE", "--prime-request", "prime.txt",
+				"--prime-extract", "x", "--prime-placeholder", "VALUE"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			// the command is never started
+			r, _ := newTestFlags(t, append(test.args, "--pre-request-cmd", "/does/not/exist"))
+			if test.replace != "" {
+				r.Replace = test.replace
+			}
+			r.Placeholders = test.placeholders
+
+			err := r.RunPreRequestCommand(context.Background())
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+
+			if !strings.Contains(err.Error(), "is the same as") {
+				t.Errorf("wrong error returned: %v", err)
+			}
+		})
+	}
+}
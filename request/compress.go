@@ -0,0 +1,56 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// compressBody compresses the body of req with CompressBody and sets the
+// Content-Encoding header. An empty body is sent unchanged.
+func (r *Request) compressBody(req *http.Request) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	var wr io.WriteCloser
+	switch r.CompressBody {
+	case "gzip":
+		wr = gzip.NewWriter(&buf)
+	case "deflate":
+		// the "deflate" content coding is the zlib format (RFC 9110, section 8.4.1.2)
+		wr = zlib.NewWriter(&buf)
+	default:
+		return fmt.Errorf("unknown compression %q for the body", r.CompressBody)
+	}
+
+	_, err = wr.Write(body)
+	if err != nil {
+		return err
+	}
+
+	err = wr.Close()
+	if err != nil {
+		return err
+	}
+
+	compressed := buf.Bytes()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", r.CompressBody)
+
+	return nil
+}
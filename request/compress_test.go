@@ -0,0 +1,98 @@
+package request
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompressBody(t *testing.T) {
+	var tests = []struct {
+		algorithm string
+		reader    func(io.Reader) (io.Reader, error)
+	}{
+		{"gzip", func(rd io.Reader) (io.Reader, error) { return gzip.NewReader(rd) }},
+		{"deflate", func(rd io.Reader) (io.Reader, error) { return zlib.NewReader(rd) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com"
+			r.Method = "POST"
+			r.Body = "data=FUZZ"
+			r.CompressBody = test.algorithm
+
+			req, err := r.Apply("foobar")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Header.Get("Content-Encoding") != test.algorithm {
+				t.Errorf("wrong Content-Encoding header %q", req.Header.Get("Content-Encoding"))
+			}
+
+			body, err := readBody(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.ContentLength != int64(len(body)) {
+				t.Errorf("wrong content length, want %v, got %v", len(body), req.ContentLength)
+			}
+
+			rd, err := req.GetBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dec, err := test.reader(rd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(dec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != "data=foobar" {
+				t.Errorf("wrong body, want %q, got %q", "data=foobar", buf)
+			}
+		})
+	}
+}
+
+func TestCompressBodyInvalid(t *testing.T) {
+	r := New("")
+	r.URL = "http://www.example.com"
+	r.Body = "foo"
+	r.CompressBody = "br"
+
+	_, err := r.Apply("")
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+}
+
+func TestCompressBodyHeader(t *testing.T) {
+	r := New("")
+	r.URL = "http://www.example.com"
+	r.Body = "foo"
+	r.CompressBody = "gzip"
+	err := r.Header.Set("Content-Encoding")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := r.Apply("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := req.Header["Content-Encoding"]; ok {
+		t.Errorf("Content-Encoding header was not removed")
+	}
+}
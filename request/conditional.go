@@ -0,0 +1,68 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// parseHTTPTime parses s either as an HTTP date (e.g. RFC1123) or as a
+// duration relative to the current time (e.g. "-1h").
+func parseHTTPTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range []string{http.TimeFormat, time.RFC1123, time.RFC1123Z, time.RFC850, time.ANSIC} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err == nil {
+		return now().Add(d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, expected an HTTP date (RFC1123) or a relative time like -1h", s)
+}
+
+// formatETags formats a comma separated list of entity tags so that each one
+// is quoted, "*" is passed through unmodified.
+func formatETags(s string) string {
+	if strings.TrimSpace(s) == "*" {
+		return "*"
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) && !strings.HasPrefix(tag, `W/"`) {
+			tag = `"` + tag + `"`
+		}
+		tags = append(tags, tag)
+	}
+
+	return strings.Join(tags, ", ")
+}
+
+// applyConditional sets the conditional request headers in hdr.
+func (r *Request) applyConditional(hdr http.Header, insertValue func(string) string) error {
+	if r.IfModifiedSince != "" {
+		t, err := parseHTTPTime(insertValue(r.IfModifiedSince))
+		if err != nil {
+			return err
+		}
+		hdr.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+	}
+
+	if r.IfNoneMatch != "" {
+		hdr.Set("If-None-Match", formatETags(insertValue(r.IfNoneMatch)))
+	}
+
+	if r.IfMatch != "" {
+		hdr.Set("If-Match", formatETags(insertValue(r.IfMatch)))
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package request
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConditionalHeaders(t *testing.T) {
+	var tests = []struct {
+		setup func(*Request)
+		value string
+		want  map[string]string
+		err   bool
+	}{
+		{
+			setup: func(r *Request) {
+				r.IfModifiedSince = "Sat, 29 Oct 1994 19:43:31 GMT"
+			},
+			want: map[string]string{
+				"If-Modified-Since": "Sat, 29 Oct 1994 19:43:31 GMT",
+			},
+		},
+		{
+			// other time zones are converted to GMT
+			setup: func(r *Request) {
+				r.IfModifiedSince = "Sat, 29 Oct 1994 21:43:31 +0200"
+			},
+			want: map[string]string{
+				"If-Modified-Since": "Sat, 29 Oct 1994 19:43:31 GMT",
+			},
+		},
+		{
+			setup: func(r *Request) {
+				r.IfModifiedSince = "FUZZ"
+			},
+			value: "-1h30m",
+			want: map[string]string{
+				"If-Modified-Since": "Fri, 14 Jul 2017 01:10:00 GMT",
+			},
+		},
+		{
+			setup: func(r *Request) {
+				r.IfModifiedSince = "yesterday"
+			},
+			err: true,
+		},
+		{
+			setup: func(r *Request) {
+				r.IfNoneMatch = "FUZZ"
+				r.IfMatch = `"foo", W/"bar"`
+			},
+			value: "xyz",
+			want: map[string]string{
+				"If-None-Match": `"xyz"`,
+				"If-Match":      `"foo", W/"bar"`,
+			},
+		},
+		{
+			setup: func(r *Request) {
+				r.IfNoneMatch = "*"
+				r.IfMatch = "a,b"
+			},
+			want: map[string]string{
+				"If-None-Match": "*",
+				"If-Match":      `"a", "b"`,
+			},
+		},
+		{
+			// explicit headers have priority
+			setup: func(r *Request) {
+				r.IfNoneMatch = "foo"
+				_ = r.Header.Set("if-none-match: bar")
+			},
+			want: map[string]string{
+				"If-None-Match": "bar",
+			},
+		},
+	}
+
+	now = func() time.Time {
+		return time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC)
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com"
+			test.setup(r)
+
+			req, err := r.Apply(test.value)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected error not found")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range test.want {
+				got := req.Header.Get(name)
+				if got != want {
+					t.Errorf("wrong value for header %v, want %q, got %q", name, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,558 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Config contains all options set via flags for a command, so that a run can
+// be reproduced later.
+type Config struct {
+	URL      string                   `json:"url,omitempty"`
+	URLUser  *ConfigValue             `json:"url_userinfo,omitempty"` // credentials removed from the URL
+	Flags    map[string][]ConfigValue `json:"flags"`
+	Template string                   `json:"template,omitempty"` // contents of the template file
+}
+
+// urlUserinfoEnv is the environment variable used to reference the
+// credentials from the URL.
+const urlUserinfoEnv = "MONSOON_URL_USERINFO"
+
+// splitURLUserinfo removes the userinfo (e.g. "user:password") from the URL
+// s and returns the URL and the userinfo. The URL is processed as a string
+// since it may contain placeholders.
+func splitURLUserinfo(s string) (target, userinfo string) {
+	i := strings.Index(s, "://")
+	if i < 0 {
+		return s, ""
+	}
+
+	rest := s[i+3:]
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+
+	at := strings.LastIndex(rest[:end], "@")
+	if at < 0 {
+		return s, ""
+	}
+
+	return s[:i+3] + rest[at+1:], rest[:at]
+}
+
+// RedactURL returns the URL s without the userinfo (e.g. "user:password").
+func RedactURL(s string) string {
+	target, _ := splitURLUserinfo(s)
+	return target
+}
+
+// insertURLUserinfo adds userinfo to the URL s.
+func insertURLUserinfo(s, userinfo string) string {
+	i := strings.Index(s, "://")
+	if i < 0 {
+		return s
+	}
+
+	return s[:i+3] + userinfo + "@" + s[i+3:]
+}
+
+// TargetURL returns the URL, with the credentials inserted again if they
+// were saved separately.
+func (cfg Config) TargetURL() (string, error) {
+	if cfg.URLUser == nil || cfg.URL == "" {
+		return cfg.URL, nil
+	}
+
+	userinfo, err := cfg.URLUser.resolve()
+	if err != nil {
+		return "", err
+	}
+
+	return insertURLUserinfo(cfg.URL, userinfo), nil
+}
+
+// ConfigValue is a value for a flag. Secrets are not stored in the file
+// directly, instead the name of an environment variable which contains the
+// value is stored.
+type ConfigValue struct {
+	Value string
+	Env   string
+}
+
+// MarshalJSON encodes the value as a string, or an object with the name of
+// the environment variable.
+func (v ConfigValue) MarshalJSON() ([]byte, error) {
+	if v.Env != "" {
+		return json.Marshal(struct {
+			Env string `json:"env"`
+		}{v.Env})
+	}
+
+	return json.Marshal(v.Value)
+}
+
+// UnmarshalJSON decodes either a string or an object with the name of an
+// environment variable.
+func (v *ConfigValue) UnmarshalJSON(buf []byte) error {
+	if len(buf) > 0 && buf[0] == '"' {
+		*v = ConfigValue{}
+		return json.Unmarshal(buf, &v.Value)
+	}
+
+	var data struct {
+		Env string `json:"env"`
+	}
+
+	err := json.Unmarshal(buf, &data)
+	if err != nil {
+		return err
+	}
+
+	if data.Env == "" {
+		return errors.New("config value is neither a string nor references an environment variable")
+	}
+
+	*v = ConfigValue{Env: data.Env}
+	return nil
+}
+
+// resolve returns the value, reading it from the environment if necessary.
+func (v ConfigValue) resolve() (string, error) {
+	if v.Env == "" {
+		return v.Value, nil
+	}
+
+	s, ok := os.LookupEnv(v.Env)
+	if !ok {
+		return "", fmt.Errorf("environment variable %v referenced in config file is not set", v.Env)
+	}
+
+	return s, nil
+}
+
+// secretFlags contains the names of flags with sensitive values and the
+// environment variable used to reference them.
+var secretFlags = map[string]string{
+	"user":        "MONSOON_USER",
+	"auth-digest": "MONSOON_AUTH_DIGEST",
+	"auth-ntlm":   "MONSOON_AUTH_NTLM",
+	"hmac-secret": "MONSOON_HMAC_SECRET",
+	"proxy":       "MONSOON_PROXY",
+	"proxy-chain": "MONSOON_PROXY_CHAIN",
+
+	"client-p12-password": "MONSOON_CLIENT_P12_PASSWORD",
+	"oauth-client-secret": "MONSOON_OAUTH_CLIENT_SECRET",
+}
+
+// secretHeaders contains the names of headers with sensitive values.
+var secretHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"Cookie":              {},
+}
+
+// configFlags are the flags to manage the config file, they are not saved.
+var configFlags = map[string]struct{}{
+	"curl":                     {},
+	"curl-file":                {},
+	"config":                   {},
+	"save-config":              {},
+	"save-config-with-secrets": {},
+}
+
+func flagValues(f *pflag.Flag) []string {
+	if v, ok := f.Value.(pflag.SliceValue); ok {
+		return v.GetSlice()
+	}
+	return []string{f.Value.String()}
+}
+
+// headerEnvName returns the name of the environment variable for the nth
+// secret header with the given name.
+func headerEnvName(name string, n int) string {
+	return numberedEnvName("MONSOON_HEADER_"+strings.ToUpper(strings.Replace(name, "-", "_", -1)), n)
+}
+
+// numberedEnvName returns the name of the environment variable for the nth
+// value of a secret.
+func numberedEnvName(env string, n int) string {
+	if n > 1 {
+		env += fmt.Sprintf("_%d", n)
+	}
+	return env
+}
+
+// secretEnv returns the name of the environment variable used to reference
+// the value v for the flag f if the value is sensitive. The number of
+// sensitive values seen so far is tracked in count, so that each value gets
+// its own variable.
+func (r *Request) secretEnv(f *pflag.Flag, v string, count map[string]int) (string, bool) {
+	if name, ok := secretFlags[f.Name]; ok {
+		count[f.Name]++
+		return numberedEnvName(name, count[f.Name]), true
+	}
+
+	if f.Value == pflag.Value(r.Header) {
+		name := textproto.CanonicalMIMEHeaderKey(strings.SplitN(v, ":", 2)[0])
+		if _, ok := secretHeaders[name]; ok && strings.Contains(v, ":") {
+			count[name]++
+			return headerEnvName(name, count[name]), true
+		}
+	}
+
+	return "", false
+}
+
+// NewConfig builds a config from the flags which have been set in fs. Unless
+// withSecrets is true, sensitive values (including credentials in the URL)
+// are replaced with references to environment variables, which are returned
+// in env. The template file is only embedded into the config when withSecrets
+// is true, since it may contain credentials.
+func NewConfig(r *Request, fs *pflag.FlagSet, url string, withSecrets bool) (cfg Config, env map[string]string, err error) {
+	cfg = Config{
+		URL:   url,
+		Flags: make(map[string][]ConfigValue),
+	}
+	env = make(map[string]string)
+
+	if !withSecrets {
+		target, userinfo := splitURLUserinfo(url)
+		if userinfo != "" {
+			cfg.URL = target
+			cfg.URLUser = &ConfigValue{Env: urlUserinfoEnv}
+			env[urlUserinfoEnv] = userinfo
+		}
+	}
+
+	fs.Visit(func(f *pflag.Flag) {
+		if _, ok := configFlags[f.Name]; ok {
+			return
+		}
+
+		secretCount := make(map[string]int)
+
+		for _, v := range flagValues(f) {
+			value := ConfigValue{Value: v}
+
+			if !withSecrets {
+				if name, ok := r.secretEnv(f, v, secretCount); ok {
+					value = ConfigValue{Env: name}
+					env[name] = v
+				}
+			}
+
+			cfg.Flags[f.Name] = append(cfg.Flags[f.Name], value)
+		}
+	})
+
+	if withSecrets && r.TemplateFile != "" {
+		buf := r.TemplateData
+		if buf == nil {
+			buf, err = ioutil.ReadFile(r.TemplateFile)
+			if err != nil {
+				return Config{}, nil, err
+			}
+		}
+
+		cfg.Template = string(buf)
+	}
+
+	return cfg, env, nil
+}
+
+// Apply sets all flags in fs which have not been set explicitly on the
+// command line to the values from the config. Flags which are unknown to fs
+// are ignored, so a config written by one command can be used for another one.
+func (cfg Config) Apply(r *Request, fs *pflag.FlagSet) error {
+	if cfg.Template != "" && !fs.Changed("template-file") {
+		r.TemplateData = []byte(cfg.Template)
+	}
+
+	names := make([]string, 0, len(cfg.Flags))
+	for name := range cfg.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+
+		var values []string
+		for _, v := range cfg.Flags[name] {
+			s, err := v.resolve()
+			if err != nil {
+				return err
+			}
+			values = append(values, s)
+		}
+
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			err := v.Replace(values)
+			if err != nil {
+				return fmt.Errorf("config: invalid value for flag %v: %v", name, err)
+			}
+		} else {
+			if len(values) != 1 {
+				return fmt.Errorf("config: flag %v needs exactly one value, got %d", name, len(values))
+			}
+
+			err := f.Value.Set(values[0])
+			if err != nil {
+				return fmt.Errorf("config: invalid value for flag %v: %v", name, err)
+			}
+		}
+
+		f.Changed = true
+	}
+
+	return nil
+}
+
+// LoadConfig reads a config from a file.
+func LoadConfig(filename string) (cfg Config, err error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = json.Unmarshal(buf, &cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse config file %v: %v", filename, err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes the config to a file.
+func (cfg Config) Save(filename string) error {
+	buf, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+
+	return ioutil.WriteFile(filename, buf, 0600)
+}
+
+// ProcessConfig applies the curl command line and the config file and saves the
+// config if requested. It returns the arguments for the command, with the URL
+// from the curl command or the config file appended if none was passed on the
+// command line.
+func ProcessConfig(r *Request, fs *pflag.FlagSet, args []string) ([]string, error) {
+	if r.CurlFile != "" {
+		if r.Curl != "" {
+			return nil, errors.New("--curl and --curl-file cannot be used together")
+		}
+
+		buf, err := ioutil.ReadFile(r.CurlFile)
+		if err != nil {
+			return nil, err
+		}
+		r.Curl = string(buf)
+	}
+
+	if r.Curl != "" {
+		url, err := ApplyCurl(r, fs, r.Curl)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(args) == 0 {
+			args = []string{url}
+		}
+	}
+
+	if r.ConfigFile != "" {
+		cfg, err := LoadConfig(r.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+
+		err = cfg.Apply(r, fs)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(args) == 0 && cfg.URL != "" {
+			url, err := cfg.TargetURL()
+			if err != nil {
+				return nil, err
+			}
+			args = []string{url}
+		}
+	}
+
+	if r.SaveConfigFile != "" {
+		var url string
+		if len(args) > 0 {
+			url = args[0]
+		}
+
+		cfg, env, err := NewConfig(r, fs, url, r.SaveConfigWithSecrets)
+		if err != nil {
+			return nil, err
+		}
+
+		err = cfg.Save(r.SaveConfigFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(env) > 0 {
+			names := make([]string, 0, len(env))
+			for name := range env {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Fprintf(os.Stderr, "config written to %v, set the following environment variables before loading it:\n", r.SaveConfigFile)
+			for _, name := range names {
+				fmt.Fprintf(os.Stderr, "  %v\n", name)
+			}
+		}
+	}
+
+	return args, nil
+}
+
+// ArgSecret references a sensitive value which has been removed from a
+// command line argument by RedactArgs.
+type ArgSecret struct {
+	Arg int    `json:"arg"`           // index of the argument
+	Env string `json:"env"`           // environment variable which contains the value
+	URL bool   `json:"url,omitempty"` // the value is the userinfo of the URL in the argument
+}
+
+// RedactArgs returns a copy of args (the command line arguments parsed by fs)
+// with the sensitive values removed, in the same way as for a config file
+// (including credentials in URLs). The removed values are returned in env,
+// secrets records where they need to be inserted again.
+func RedactArgs(r *Request, fs *pflag.FlagSet, args []string) (redacted []string, secrets []ArgSecret, env map[string]string) {
+	redacted = append([]string{}, args...)
+	env = make(map[string]string)
+	count := make(map[string]int)
+
+	// remove replaces the value at the end of the argument at index i, which
+	// starts at offset
+	remove := func(f *pflag.Flag, i, offset int) {
+		v := redacted[i][offset:]
+		name, ok := r.secretEnv(f, v, count)
+		if !ok {
+			return
+		}
+
+		redacted[i] = redacted[i][:offset]
+		secrets = append(secrets, ArgSecret{Arg: i, Env: name})
+		env[name] = v
+	}
+
+	positional := func(i int) {
+		target, userinfo := splitURLUserinfo(redacted[i])
+		if userinfo == "" {
+			return
+		}
+
+		name := numberedEnvName(urlUserinfoEnv, count[urlUserinfoEnv]+1)
+		count[urlUserinfoEnv]++
+		redacted[i] = target
+		secrets = append(secrets, ArgSecret{Arg: i, Env: name, URL: true})
+		env[name] = userinfo
+	}
+
+	for i := 0; i < len(redacted); i++ {
+		arg := redacted[i]
+
+		switch {
+		case arg == "--":
+			for j := i + 1; j < len(redacted); j++ {
+				positional(j)
+			}
+			return redacted, secrets, env
+
+		case strings.HasPrefix(arg, "--"):
+			name := arg[2:]
+			if j := strings.Index(name, "="); j >= 0 {
+				if f := fs.Lookup(name[:j]); f != nil {
+					remove(f, i, 2+j+1)
+				}
+				continue
+			}
+
+			f := fs.Lookup(name)
+			if f == nil || f.NoOptDefVal != "" || i+1 >= len(redacted) {
+				continue
+			}
+
+			// the value is the next argument
+			i++
+			remove(f, i, 0)
+
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// shorthand flags can be combined, the first one which takes a
+			// value consumes the rest of the argument or the next one
+			for j := 1; j < len(arg); j++ {
+				f := fs.ShorthandLookup(arg[j : j+1])
+				if f == nil {
+					break
+				}
+
+				if f.NoOptDefVal != "" {
+					continue
+				}
+
+				switch {
+				case j+1 < len(arg) && arg[j+1] == '=':
+					remove(f, i, j+2)
+				case j+1 < len(arg):
+					remove(f, i, j+1)
+				case i+1 < len(redacted):
+					i++
+					remove(f, i, 0)
+				}
+				break
+			}
+
+		default:
+			positional(i)
+		}
+	}
+
+	return redacted, secrets, env
+}
+
+// InsertArgSecrets returns a copy of args with the values of the environment
+// variables referenced in secrets inserted again.
+func InsertArgSecrets(args []string, secrets []ArgSecret) ([]string, error) {
+	res := append([]string{}, args...)
+	for _, secret := range secrets {
+		if secret.Arg < 0 || secret.Arg >= len(res) {
+			return nil, fmt.Errorf("invalid argument index %d for secret %v", secret.Arg, secret.Env)
+		}
+
+		v, ok := os.LookupEnv(secret.Env)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %v is not set", secret.Env)
+		}
+
+		if secret.URL {
+			res[secret.Arg] = insertURLUserinfo(res[secret.Arg], v)
+			continue
+		}
+
+		res[secret.Arg] += v
+	}
+
+	return res, nil
+}
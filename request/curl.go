@@ -0,0 +1,467 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+// splitCurl splits a curl command line as written by the "Copy as cURL"
+// function of browsers into arguments. It supports the quoting rules of a
+// POSIX shell (single quotes, double quotes, backslash escapes and line
+// continuations) and ANSI-C quoting ($'...') used by Chrome.
+func splitCurl(s string) (args []string, err error) {
+	var (
+		arg    strings.Builder
+		inArg  bool
+		runes  = []rune(s)
+		finish = func() {
+			if inArg {
+				args = append(args, arg.String())
+			}
+			arg.Reset()
+			inArg = false
+		}
+	)
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(runes) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			if runes[i] == '\n' {
+				// line continuation
+				continue
+			}
+			if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+				continue
+			}
+			arg.WriteRune(runes[i])
+			inArg = true
+
+		case c == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("single-quoted string not terminated")
+			}
+			arg.WriteString(string(runes[i+1 : end]))
+			inArg = true
+			i = end
+
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			n, err := ansiCQuoted(&arg, runes[i+2:])
+			if err != nil {
+				return nil, err
+			}
+			inArg = true
+			i += 2 + n
+
+		case c == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				arg.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("double-quoted string not terminated")
+			}
+			inArg = true
+
+		case unicode.IsSpace(c):
+			finish()
+
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	finish()
+
+	if len(args) == 0 {
+		return nil, errors.New("command string is empty")
+	}
+
+	return args, nil
+}
+
+func indexRune(runes []rune, start int, c rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiCQuoted decodes the contents of a $'...' string to arg. The runes start
+// after the opening quote, the returned number is the index of the closing
+// quote.
+func ansiCQuoted(arg *strings.Builder, runes []rune) (int, error) {
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if c == '\'' {
+			return i, nil
+		}
+
+		if c != '\\' {
+			arg.WriteRune(c)
+			continue
+		}
+
+		i++
+		if i >= len(runes) {
+			break
+		}
+
+		switch runes[i] {
+		case 'n':
+			arg.WriteByte('\n')
+		case 'r':
+			arg.WriteByte('\r')
+		case 't':
+			arg.WriteByte('\t')
+		case '\\', '\'', '"', '?':
+			arg.WriteRune(runes[i])
+		case 'x', 'u', 'U':
+			size := map[rune]int{'x': 2, 'u': 4, 'U': 8}[runes[i]]
+			end := i + 1
+			for end < len(runes) && end < i+1+size && strings.ContainsRune("0123456789abcdefABCDEF", runes[end]) {
+				end++
+			}
+			if end == i+1 {
+				return 0, fmt.Errorf("invalid escape sequence \\%c", runes[i])
+			}
+			v, err := strconv.ParseUint(string(runes[i+1:end]), 16, 32)
+			if err != nil {
+				return 0, err
+			}
+			if runes[i] == 'x' {
+				arg.WriteByte(byte(v))
+			} else {
+				arg.WriteRune(rune(v))
+			}
+			i = end - 1
+		default:
+			arg.WriteByte('\\')
+			arg.WriteRune(runes[i])
+		}
+	}
+
+	return 0, errors.New("single-quoted string not terminated")
+}
+
+// curlArgOptions are the supported curl options which take an argument,
+// mapped to the long name.
+var curlArgOptions = map[string]string{
+	"-X":               "--request",
+	"--request":        "--request",
+	"-H":               "--header",
+	"--header":         "--header",
+	"-d":               "--data",
+	"--data":           "--data",
+	"--data-ascii":     "--data",
+	"--data-binary":    "--data-binary",
+	"--data-raw":       "--data-raw",
+	"--data-urlencode": "--data-urlencode",
+	"-u":               "--user",
+	"--user":           "--user",
+	"-A":               "--user-agent",
+	"--user-agent":     "--user-agent",
+	"-e":               "--referer",
+	"--referer":        "--referer",
+	"-b":               "--cookie",
+	"--cookie":         "--cookie",
+	"--url":            "--url",
+}
+
+// curlBoolOptions are the supported curl options without an argument, mapped
+// to the long name. An empty name means the option is ignored.
+var curlBoolOptions = map[string]string{
+	"-k":           "--insecure",
+	"--insecure":   "--insecure",
+	"-G":           "--get",
+	"--get":        "--get",
+	"-I":           "--head",
+	"--head":       "--head",
+	"--http1.1":    "--http1.1",
+	"--http2":      "",
+	"--compressed": "", // Go requests and decodes gzip compressed responses by default
+	"-s":           "",
+	"--silent":     "",
+	"-S":           "",
+	"--show-error": "",
+	"-v":           "",
+	"--verbose":    "",
+	"-i":           "",
+	"--include":    "",
+	"-g":           "",
+	"--globoff":    "",
+}
+
+// expandCurlArgs splits combined short options (e.g. "-sk" or "-XPOST") and
+// long options with a value (e.g. "--request=POST").
+func expandCurlArgs(args []string) (res []string) {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") && strings.Contains(arg, "=") {
+			data := strings.SplitN(arg, "=", 2)
+			if _, ok := curlArgOptions[data[0]]; ok {
+				res = append(res, data[0], data[1])
+				continue
+			}
+		}
+
+		if len(arg) <= 2 || arg[0] != '-' || arg[1] == '-' {
+			res = append(res, arg)
+			continue
+		}
+
+		for i := 1; i < len(arg); i++ {
+			opt := "-" + string(arg[i])
+			if _, ok := curlArgOptions[opt]; ok {
+				res = append(res, opt)
+				if i+1 < len(arg) {
+					res = append(res, arg[i+1:])
+				}
+				break
+			}
+			res = append(res, opt)
+		}
+	}
+
+	return res
+}
+
+// curlHeader converts a header as passed to curl to the format for --header.
+// curl removes a header if the value is empty ("Name:") and sends an empty
+// header for "Name;".
+func curlHeader(s string) string {
+	if strings.HasSuffix(s, ";") && !strings.Contains(s, ":") {
+		return strings.TrimSuffix(s, ";") + ":"
+	}
+
+	data := strings.SplitN(s, ":", 2)
+	if len(data) == 2 && strings.TrimSpace(data[1]) == "" {
+		return strings.TrimSpace(data[0])
+	}
+
+	return s
+}
+
+// curlURLEncode encodes data like curl's --data-urlencode.
+func curlURLEncode(s string) (string, error) {
+	i := strings.IndexAny(s, "=@")
+	if i < 0 {
+		return url.QueryEscape(s), nil
+	}
+
+	if s[i] == '@' {
+		return "", fmt.Errorf("--data-urlencode: reading data from a file is not supported: %q", s)
+	}
+
+	if i == 0 {
+		return url.QueryEscape(s[1:]), nil
+	}
+
+	return s[:i] + "=" + url.QueryEscape(s[i+1:]), nil
+}
+
+// ParseCurl parses a curl command line into the URL and a list of values for
+// the flags of a request.
+func ParseCurl(cmd string) (targetURL string, flags map[string][]string, err error) {
+	args, err := splitCurl(cmd)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse curl command: %v", err)
+	}
+
+	if args[0] == "curl" {
+		args = args[1:]
+	}
+	args = expandCurlArgs(args)
+
+	flags = make(map[string][]string)
+	var (
+		method    string
+		data      []string
+		get, head bool
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if targetURL != "" {
+				return "", nil, fmt.Errorf("curl command contains more than one URL: %q", arg)
+			}
+			targetURL = arg
+			continue
+		}
+
+		if name, ok := curlBoolOptions[arg]; ok {
+			switch name {
+			case "--insecure":
+				flags["insecure"] = []string{"true"}
+			case "--http1.1":
+				flags["disable-http2"] = []string{"true"}
+			case "--get":
+				get = true
+			case "--head":
+				head = true
+			}
+			continue
+		}
+
+		name, ok := curlArgOptions[arg]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported curl option %q", arg)
+		}
+
+		if i+1 >= len(args) {
+			return "", nil, fmt.Errorf("curl option %v needs an argument", arg)
+		}
+		i++
+		value := args[i]
+
+		switch name {
+		case "--request":
+			method = value
+		case "--header":
+			flags["header"] = append(flags["header"], curlHeader(value))
+		case "--user-agent":
+			flags["header"] = append(flags["header"], "User-Agent: "+value)
+		case "--referer":
+			flags["header"] = append(flags["header"], "Referer: "+value)
+		case "--cookie":
+			if !strings.Contains(value, "=") {
+				return "", nil, fmt.Errorf("curl option %v: reading cookies from a file is not supported", arg)
+			}
+			flags["header"] = append(flags["header"], "Cookie: "+value)
+		case "--user":
+			flags["user"] = []string{value}
+		case "--url":
+			if targetURL != "" {
+				return "", nil, fmt.Errorf("curl command contains more than one URL: %q", value)
+			}
+			targetURL = value
+		case "--data", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				return "", nil, fmt.Errorf("curl option %v: reading data from a file is not supported", arg)
+			}
+			if name == "--data" {
+				value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "--data-urlencode":
+			value, err = curlURLEncode(value)
+			if err != nil {
+				return "", nil, err
+			}
+			data = append(data, value)
+		}
+	}
+
+	if targetURL == "" {
+		return "", nil, errors.New("curl command does not contain a URL")
+	}
+
+	switch {
+	case get && len(data) > 0:
+		sep := "?"
+		if strings.Contains(targetURL, "?") {
+			sep = "&"
+		}
+		targetURL += sep + strings.Join(data, "&")
+	case len(data) > 0:
+		flags["data"] = []string{strings.Join(data, "&")}
+		if method == "" {
+			method = "POST"
+		}
+		if !hasHeader(flags["header"], "Content-Type") {
+			flags["header"] = append(flags["header"], "Content-Type: application/x-www-form-urlencoded")
+		}
+	}
+
+	if head && method == "" {
+		method = "HEAD"
+	}
+
+	if method != "" {
+		flags["method"] = []string{method}
+	}
+
+	return targetURL, flags, nil
+}
+
+func hasHeader(list []string, name string) bool {
+	for _, s := range list {
+		n := strings.SplitN(s, ":", 2)[0]
+		if textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(n)) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyCurl parses the curl command line and sets the flags in fs which have
+// not been set explicitly on the command line. Headers passed via --header are
+// added to the ones from the curl command, replacing headers with the same
+// name. The URL from the curl command is returned.
+func ApplyCurl(r *Request, fs *pflag.FlagSet, cmd string) (string, error) {
+	targetURL, flags, err := ParseCurl(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	for name, values := range flags {
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+
+		if f.Changed {
+			if f.Value != pflag.Value(r.Header) {
+				continue
+			}
+
+			// keep the headers from the command line, they have priority
+			cmdline := r.Header.GetSlice()
+			var list []string
+			for _, h := range values {
+				n := strings.TrimSpace(strings.SplitN(h, ":", 2)[0])
+				if !hasHeader(cmdline, textproto.CanonicalMIMEHeaderKey(n)) {
+					list = append(list, h)
+				}
+			}
+			values = append(list, cmdline...)
+		}
+
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			err = v.Replace(values)
+		} else {
+			err = f.Value.Set(values[len(values)-1])
+		}
+		if err != nil {
+			return "", fmt.Errorf("curl: invalid value for flag %v: %v", name, err)
+		}
+
+		f.Changed = true
+	}
+
+	return targetURL, nil
+}
@@ -0,0 +1,276 @@
+package request
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitCurl(t *testing.T) {
+	var tests = []struct {
+		cmd  string
+		want []string
+	}{
+		{"curl 'http://x/' -H 'A: b'", []string{"curl", "http://x/", "-H", "A: b"}},
+		{"curl \\\n  -k \\\r\n  foo", []string{"curl", "-k", "foo"}},
+		{`curl "a \"b\" \$c \d"`, []string{"curl", `a "b" $c \d`}},
+		{`curl a\ b 'it'\''s'`, []string{"curl", "a b", "it's"}},
+		{`curl $'{"a":"ü\n\x41\'"}'`, []string{"curl", "{\"a\":\"ü\nA'\"}"}},
+		{`curl ''`, []string{"curl", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			got, err := splitCurl(test.cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(test.want, got) {
+				t.Error(cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestCurl(t *testing.T) {
+	var tests = []struct {
+		name   string
+		cmd    string
+		args   []string
+		method string
+		url    string
+		header http.Header
+		body   string
+	}{
+		{
+			name: "chrome",
+			cmd: `curl 'https://www.example.com/api/search?q=FUZZ' \
+  -H 'authority: www.example.com' \
+  -H 'accept: application/json' \
+  -H 'content-type: application/json' \
+  -H 'cookie: session=abc; theme=dark' \
+  -H 'user-agent: Mozilla/5.0 (X11; Linux x86_64) Chrome/118.0.0.0' \
+  --data-raw $'{"query":"FUZZ","note":"it\'s"}' \
+  --compressed`,
+			method: "POST",
+			url:    "https://www.example.com/api/search?q=xxx",
+			header: http.Header{
+				"Authority":    []string{"www.example.com"},
+				"Accept":       []string{"application/json"},
+				"Content-Type": []string{"application/json"},
+				"Cookie":       []string{"session=abc; theme=dark"},
+				"User-Agent":   []string{"Mozilla/5.0 (X11; Linux x86_64) Chrome/118.0.0.0"},
+			},
+			body: `{"query":"xxx","note":"it's"}`,
+		},
+		{
+			name:   "firefox",
+			cmd:    `curl 'http://www.example.com/login' -X POST -H 'User-Agent: Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0' -H 'Accept: text/html' -H 'Content-Type: application/x-www-form-urlencoded' -H 'Origin: http://www.example.com' -H 'Connection: keep-alive' -H 'Referer: http://www.example.com/login' --data-raw 'user=admin&password=FUZZ'`,
+			method: "POST",
+			url:    "http://www.example.com/login",
+			header: http.Header{
+				"User-Agent":   []string{"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0"},
+				"Accept":       []string{"text/html"},
+				"Content-Type": []string{"application/x-www-form-urlencoded"},
+				"Origin":       []string{"http://www.example.com"},
+				"Connection":   []string{"keep-alive"},
+				"Referer":      []string{"http://www.example.com/login"},
+			},
+			body: "user=admin&password=xxx",
+		},
+		{
+			name:   "flags",
+			cmd:    `curl -sk -XPUT -A agent -e http://ref/ -b a=b --user admin:FUZZ -d x=1 --data-urlencode 'y=a b' http://www.example.com/`,
+			method: "PUT",
+			url:    "http://www.example.com/",
+			header: http.Header{
+				"Accept":        []string{"*/*"},
+				"User-Agent":    []string{"agent"},
+				"Referer":       []string{"http://ref/"},
+				"Cookie":        []string{"a=b"},
+				"Content-Type":  []string{"application/x-www-form-urlencoded"},
+				"Authorization": []string{"Basic YWRtaW46eHh4"},
+			},
+			body: "x=1&y=a+b",
+		},
+		{
+			name:   "get",
+			cmd:    `curl -G -d a=FUZZ 'http://www.example.com/?b=1'`,
+			method: "GET",
+			url:    "http://www.example.com/?b=1&a=xxx",
+			header: http.Header{
+				"Accept":     []string{"*/*"},
+				"User-Agent": []string{"monsoon"},
+			},
+		},
+		{
+			name:   "command-line-overrides",
+			cmd:    `curl http://www.example.com/ -X POST -H 'Accept: text/html' -H 'X-Foo: bar' -d foo`,
+			args:   []string{"--method", "PATCH", "--header", "X-Foo: baz", "--header", "Content-Type"},
+			method: "PATCH",
+			url:    "http://www.example.com/",
+			header: http.Header{
+				"Accept":     []string{"text/html"},
+				"User-Agent": []string{"monsoon"},
+				"X-Foo":      []string{"baz"},
+			},
+			body: "foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := append([]string{"--curl", test.cmd}, test.args...)
+			r, fs := newTestFlags(t, args)
+
+			args, err := ProcessConfig(r, fs, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(args) != 1 {
+				t.Fatalf("wrong args returned: %v", args)
+			}
+			r.URL = args[0]
+
+			req, err := r.Apply("xxx")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Method != test.method {
+				t.Errorf("wrong method, want %q, got %q", test.method, req.Method)
+			}
+
+			if req.URL.String() != test.url {
+				t.Errorf("wrong URL, want %q, got %q", test.url, req.URL.String())
+			}
+
+			if !cmp.Equal(test.header, req.Header) {
+				t.Error(cmp.Diff(test.header, req.Header))
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.body {
+				t.Errorf("wrong body, want %q, got %q", test.body, buf)
+			}
+		})
+	}
+}
+
+func TestCurlUnsupported(t *testing.T) {
+	var tests = []string{
+		"curl --proxy http://proxy:8080 http://www.example.com",
+		"curl -d @data.txt http://www.example.com",
+		"curl -b cookies.txt http://www.example.com",
+		"curl --data-urlencode name@file http://www.example.com",
+		"curl -X",
+		"curl -k",
+		"curl http://a/ http://b/",
+		"curl 'http://www.example.com",
+	}
+
+	for _, cmd := range tests {
+		t.Run("", func(t *testing.T) {
+			_, _, err := ParseCurl(cmd)
+			if err == nil {
+				t.Fatalf("expected error not returned for %q", cmd)
+			}
+		})
+	}
+}
+
+func TestCurlCompat(t *testing.T) {
+	var tests = []struct {
+		args        []string
+		method      string
+		contentType []string
+	}{
+		// default: no implicit method or Content-Type
+		{
+			args:   []string{"--data", "foo=bar"},
+			method: "GET",
+		},
+		{
+			args:        []string{"--curl-compat", "--data", "foo=bar"},
+			method:      "POST",
+			contentType: []string{"application/x-www-form-urlencoded"},
+		},
+		{
+			args:        []string{"--curl-compat", "--data", "foo=bar", "--method", "PUT", "--header", "Content-Type: text/plain"},
+			method:      "PUT",
+			contentType: []string{"text/plain"},
+		},
+		{
+			args:   []string{"--curl-compat", "--data", "foo=bar", "--header", "Content-Type"},
+			method: "POST",
+		},
+		{
+			// without data, nothing changes
+			args:   []string{"--curl-compat"},
+			method: "GET",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r, _ := newTestFlags(t, test.args)
+			r.URL = "http://www.example.com/"
+
+			req, err := r.Apply("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Method != test.method {
+				t.Errorf("wrong method, want %q, got %q", test.method, req.Method)
+			}
+
+			ct := req.Header[http.CanonicalHeaderKey("content-type")]
+			if len(ct) != len(test.contentType) || (len(ct) > 0 && ct[0] != test.contentType[0]) {
+				t.Errorf("wrong Content-Type, want %q, got %q", test.contentType, ct)
+			}
+		})
+	}
+}
+
+func TestCurlFile(t *testing.T) {
+	filename, cleanup := writeTempFile(t, `curl 'http://www.example.com/login' \
+  -X 'PUT' \
+  -H 'Accept: */*' \
+  -b 'session=FUZZ' \
+  --data-raw 'user=admin'
+`)
+	defer cleanup()
+
+	r, fs := newTestFlags(t, []string{"--curl-file", filename})
+
+	args, err := ProcessConfig(r, fs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(args) != 1 || args[0] != "http://www.example.com/login" {
+		t.Fatalf("wrong args returned: %v", args)
+	}
+	r.URL = args[0]
+
+	want := "PUT /login HTTP/1.1\r\nHost: www.example.com\r\nUser-Agent: monsoon\r\nContent-Length: 10\r\nAccept: */*\r\nContent-Type: application/x-www-form-urlencoded\r\nCookie: session=xxx\r\nAccept-Encoding: gzip\r\n\r\nuser=admin"
+	got := dumpRequest(t, r, "xxx")
+	if got != want {
+		t.Errorf("wrong request, want:\n  %q\ngot:\n  %q", want, got)
+	}
+
+	r, fs = newTestFlags(t, []string{"--curl-file", filename, "--curl", "curl http://www.example.com"})
+	_, err = ProcessConfig(r, fs, nil)
+	if err == nil {
+		t.Error("expected error not returned for --curl and --curl-file")
+	}
+}
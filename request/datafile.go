@@ -0,0 +1,165 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// replaceReader replaces placeholders in the data read from src while it is
+// read, so the data does not need to be buffered completely. The pairs are
+// matched like strings.Replacer does it, in the order given.
+type replaceReader struct {
+	src    io.Reader
+	pairs  []string
+	maxLen int
+
+	in  []byte // data read from src which has not been processed yet
+	out []byte // processed data which has not been returned yet
+	eof bool
+	buf []byte
+}
+
+func newReplaceReader(src io.Reader, pairs []string) *replaceReader {
+	rd := &replaceReader{src: src, buf: make([]byte, 32*1024)}
+	for i := 0; i < len(pairs); i += 2 {
+		if pairs[i] == "" {
+			continue
+		}
+		rd.pairs = append(rd.pairs, pairs[i], pairs[i+1])
+		if len(pairs[i]) > rd.maxLen {
+			rd.maxLen = len(pairs[i])
+		}
+	}
+	return rd
+}
+
+func (rd *replaceReader) Read(p []byte) (int, error) {
+	for len(rd.out) == 0 {
+		if rd.eof && len(rd.in) == 0 {
+			return 0, io.EOF
+		}
+
+		if !rd.eof {
+			n, err := rd.src.Read(rd.buf)
+			rd.in = append(rd.in, rd.buf[:n]...)
+			if err == io.EOF {
+				rd.eof = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+
+		rd.process()
+	}
+
+	n := copy(p, rd.out)
+	rd.out = rd.out[n:]
+	return n, nil
+}
+
+// process replaces the placeholders in rd.in. Unless the end of the input has
+// been reached, the data at the end which may be the start of a placeholder
+// is kept.
+func (rd *replaceReader) process() {
+	limit := len(rd.in)
+	if !rd.eof {
+		limit -= rd.maxLen - 1
+	}
+
+	start, i := 0, 0
+	for i < limit {
+		name, value, ok := rd.match(rd.in[i:])
+		if !ok {
+			i++
+			continue
+		}
+
+		rd.out = append(rd.out, rd.in[start:i]...)
+		rd.out = append(rd.out, value...)
+		i += len(name)
+		start = i
+	}
+
+	if i > limit {
+		// a placeholder extended beyond the limit
+		limit = i
+	}
+	if limit < start {
+		limit = start
+	}
+
+	rd.out = append(rd.out, rd.in[start:limit]...)
+	rd.in = append(rd.in[:0], rd.in[limit:]...)
+}
+
+func (rd *replaceReader) match(buf []byte) (name, value string, ok bool) {
+	for i := 0; i < len(rd.pairs); i += 2 {
+		if bytes.HasPrefix(buf, []byte(rd.pairs[i])) {
+			return rd.pairs[i], rd.pairs[i+1], true
+		}
+	}
+	return "", "", false
+}
+
+// openDataFile opens the data file and returns a reader which replaces the
+// placeholders while the file is read.
+func (r *Request) openDataFile(pairs []string) (io.ReadCloser, error) {
+	f, err := os.Open(r.DataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{newReplaceReader(f, pairs), f}, nil
+}
+
+// dataFileBody sets the body of req to the contents of the data file, the
+// placeholders are replaced while the body is sent. The file is read once
+// in advance to determine the length of the body.
+func (r *Request) dataFileBody(req *http.Request, value string) error {
+	err := r.checkDataFile()
+	if err != nil {
+		return err
+	}
+
+	pairs := r.replacePairs(value)
+
+	rd, err := r.openDataFile(pairs)
+	if err != nil {
+		return err
+	}
+
+	length, err := io.Copy(ioutil.Discard, rd)
+	_ = rd.Close()
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = length
+	req.GetBody = func() (io.ReadCloser, error) {
+		return r.openDataFile(pairs)
+	}
+
+	req.Body, err = req.GetBody()
+	return err
+}
+
+// checkDataFile returns an error if the data file is combined with another
+// source for the body.
+func (r *Request) checkDataFile() error {
+	if r.DataFile == "" {
+		return nil
+	}
+
+	if r.Body != "" || r.JSON != "" || r.GraphQLQuery != "" || r.JSONBodyFile != "" || len(r.Form) > 0 || len(r.FormFile) > 0 {
+		return errors.New("--data-file cannot be used together with --data, --json, --graphql-query, --json-body or --form")
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package request
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReplaceReader(t *testing.T) {
+	pairs := []string{"FUZZFUZZ", "double", "FUZZ", "value", "TOKEN", ""}
+
+	var tests = []string{
+		"",
+		"no placeholder",
+		"FUZZ",
+		"FUZZFUZZFUZZ",
+		"FUZ",
+		"FUZFUZZ TOKEN FUZ",
+		"a FUZZ b TOKENFUZZ c FUZZFUZ",
+		strings.Repeat("x FUZZ y ", 10000),
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			want := strings.NewReplacer(pairs...).Replace(test)
+
+			for _, rd := range []*replaceReader{
+				newReplaceReader(strings.NewReader(test), pairs),
+				newReplaceReader(iotest.OneByteReader(strings.NewReader(test)), pairs),
+			} {
+				buf, err := ioutil.ReadAll(iotest.HalfReader(rd))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(buf) != want {
+					t.Errorf("wrong result, want %q, got %q", want, buf)
+				}
+			}
+		})
+	}
+}
+
+func TestDataFile(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "user=FUZZ&token=TOKEN")
+	defer cleanup()
+
+	r := New("")
+	r.URL = "http://www.example.com/"
+	r.Method = "POST"
+	r.DataFile = filename
+	r.Vars = NewVars()
+	r.Vars.Set("TOKEN", "secret")
+
+	want := "POST / HTTP/1.1\r\nHost: www.example.com\r\nUser-Agent: monsoon\r\nContent-Length: 23\r\nAccept: */*\r\nAccept-Encoding: gzip\r\n\r\nuser=admin&token=secret"
+	got := dumpRequest(t, r, "admin")
+	if got != want {
+		t.Errorf("wrong request, want:\n  %q\ngot:\n  %q", want, got)
+	}
+
+	r.Body = "foo"
+	_, err := r.Apply("admin")
+	if err == nil {
+		t.Error("expected error not returned for --data and --data-file")
+	}
+}
+
+func TestDataFileOtherBody(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "user=FUZZ")
+	defer cleanup()
+
+	var tests = []func(r *Request){
+		func(r *Request) { r.Body = "foo" },
+		func(r *Request) { r.JSON = `{"user": "FUZZ"}` },
+		func(r *Request) { r.GraphQLQuery = "{ user(name: \"FUZZ\") { id } }" },
+		func(r *Request) { r.JSONBodyFile = filename },
+		func(r *Request) { r.Form = []string{"user=FUZZ"} },
+	}
+
+	for _, setup := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/"
+			r.DataFile = filename
+			setup(r)
+
+			err := r.Prepare(context.Background())
+			if err == nil {
+				t.Fatal("expected error not returned by Prepare")
+			}
+
+			_, err = r.Apply("admin")
+			if err == nil {
+				t.Fatal("expected error not returned by Apply")
+			}
+		})
+	}
+}
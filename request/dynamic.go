@@ -0,0 +1,130 @@
+package request
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxRandomStringLength is the maximal length for {{RANDSTR:n}}.
+const maxRandomStringLength = 4096
+
+const randomStringChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// valueReplacer replaces the placeholders like strings.Replacer does it, and
+// additionally expands the dynamic placeholders {{RANDSTR:n}}, {{UUID}} and
+// {{TIMESTAMP}}. Each dynamic placeholder is expanded once, so all
+// occurrences in the strings passed to Replace receive the same value. It is
+// not safe for concurrent use.
+type valueReplacer struct {
+	pairs    []string
+	replacer *strings.Replacer
+	rnd      *lockedRand
+
+	dynamic map[string]string // expanded dynamic placeholders
+}
+
+func newValueReplacer(pairs []string, rnd *lockedRand) *valueReplacer {
+	return &valueReplacer{
+		pairs:    pairs,
+		replacer: strings.NewReplacer(pairs...),
+		rnd:      rnd,
+		dynamic:  make(map[string]string),
+	}
+}
+
+// with returns a replacer for pairs which shares the expanded dynamic
+// placeholders with v.
+func (v *valueReplacer) with(pairs []string) *valueReplacer {
+	return &valueReplacer{
+		pairs:    pairs,
+		replacer: strings.NewReplacer(pairs...),
+		rnd:      v.rnd,
+		dynamic:  v.dynamic,
+	}
+}
+
+// Replace returns s with all placeholders replaced in a single pass.
+func (v *valueReplacer) Replace(s string) string {
+	tokens := dynamicPlaceholders(s)
+	if len(tokens) == 0 {
+		return v.replacer.Replace(s)
+	}
+
+	pairs := make([]string, 0, 2*len(tokens)+len(v.pairs))
+	for _, token := range tokens {
+		value, ok := v.dynamic[token]
+		if !ok {
+			value = v.expand(token)
+			v.dynamic[token] = value
+		}
+		pairs = append(pairs, token, value)
+	}
+	pairs = append(pairs, v.pairs...)
+
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// dynamicPlaceholders returns the valid dynamic placeholders in s.
+func dynamicPlaceholders(s string) (list []string) {
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			return list
+		}
+		s = s[start:]
+
+		end := strings.Index(s, "}}")
+		if end < 0 {
+			return list
+		}
+
+		token := s[:end+2]
+		if validDynamicPlaceholder(token[2:end]) {
+			list = append(list, token)
+			s = s[end+2:]
+		} else {
+			s = s[2:]
+		}
+	}
+}
+
+func validDynamicPlaceholder(name string) bool {
+	switch name {
+	case "UUID", "TIMESTAMP":
+		return true
+	}
+
+	if strings.HasPrefix(name, "RANDSTR:") {
+		n, err := strconv.Atoi(strings.TrimPrefix(name, "RANDSTR:"))
+		return err == nil && n > 0 && n <= maxRandomStringLength
+	}
+
+	return false
+}
+
+// expand returns a new value for the dynamic placeholder token.
+func (v *valueReplacer) expand(token string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(token, "{{"), "}}")
+
+	switch name {
+	case "UUID":
+		// random UUID (version 4, RFC 4122)
+		var buf [16]byte
+		for i := range buf {
+			buf[i] = byte(v.rnd.Intn(256))
+		}
+		buf[6] = buf[6]&0x0f | 0x40
+		buf[8] = buf[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:])
+	case "TIMESTAMP":
+		return strconv.FormatInt(now().Unix(), 10)
+	}
+
+	n, _ := strconv.Atoi(strings.TrimPrefix(name, "RANDSTR:"))
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = randomStringChars[v.rnd.Intn(len(randomStringChars))]
+	}
+	return string(buf)
+}
@@ -0,0 +1,63 @@
+package request
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestDynamicPlaceholders(t *testing.T) {
+	now = func() time.Time {
+		return time.Unix(1500000000, 0)
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	r := New("FUZZ")
+	r.URL = "http://www.example.com/FUZZ?cb={{RANDSTR:8}}&ts={{TIMESTAMP}}"
+	r.Body = "id={{UUID}}&other={{RANDSTR:0}}{{.Method}}"
+	r.Seed = 1
+	err := r.Header.Set("Idempotency-Key: {{UUID}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for i := 0; i < 2; i++ {
+		// the value is inserted as it is
+		req, err := r.Apply("{{UUID}}")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !regexp.MustCompile(`^/%7B%7BUUID%7D%7D$`).MatchString(req.URL.EscapedPath()) {
+			t.Errorf("wrong path %q", req.URL.EscapedPath())
+		}
+
+		if !regexp.MustCompile(`^cb=[a-zA-Z0-9]{8}&ts=1500000000$`).MatchString(req.URL.RawQuery) {
+			t.Errorf("wrong query %q", req.URL.RawQuery)
+		}
+
+		key := req.Header.Get("Idempotency-Key")
+		if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(key) {
+			t.Errorf("invalid UUID %q", key)
+		}
+		keys = append(keys, key)
+
+		body, err := readBody(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the same UUID is used in the body, invalid placeholders are kept
+		want := "id=" + key + "&other={{RANDSTR:0}}{{.Method}}"
+		if string(body) != want {
+			t.Errorf("wrong body, want %q, got %q", want, body)
+		}
+	}
+
+	if keys[0] == keys[1] {
+		t.Errorf("same UUID used for two requests")
+	}
+}
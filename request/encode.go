@@ -0,0 +1,263 @@
+package request
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+)
+
+// encoders contains the transformations which can be applied to a value
+// before it is inserted into the request.
+var encoders = map[string]func(string) (string, error){
+	"urldecode":    urlDecode,
+	"urlencode":    urlEncode,
+	"base64":       base64Encode,
+	"base64decode": base64Decode,
+	"hex":          hexEncode,
+	"md5":          hashHex(md5.New),
+	"sha1":         hashHex(sha1.New),
+	"sha256":       hashHex(sha256.New),
+}
+
+// urlDecode decodes percent-encoded sequences. A plus sign is kept as is.
+func urlDecode(s string) (string, error) {
+	res, err := url.PathUnescape(s)
+	if err != nil {
+		return "", fmt.Errorf("urldecode %q: %v", s, err)
+	}
+	return res, nil
+}
+
+// urlEncode percent-encodes all bytes except for the unreserved characters
+// from RFC 3986 (letters, digits, "-", ".", "_" and "~").
+func urlEncode(s string) (string, error) {
+	return percentEncode(s), nil
+}
+
+// percentEncode is urlEncode without the error.
+func percentEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func base64Encode(s string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(s)), nil
+}
+
+func base64Decode(s string) (string, error) {
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("base64decode %q: %v", s, err)
+	}
+	return string(buf), nil
+}
+
+func hexEncode(s string) (string, error) {
+	return hex.EncodeToString([]byte(s)), nil
+}
+
+// hashHex returns a transformation which hashes the value and returns the
+// hex encoded digest.
+func hashHex(newHash func() hash.Hash) func(string) (string, error) {
+	return func(s string) (string, error) {
+		h := newHash()
+		_, _ = h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+}
+
+// encodeValue applies all transformations from r.Encode to value in order.
+// If r.Placeholders is set, they are applied to the value for each
+// placeholder.
+func (r *Request) encodeValue(value string) (string, error) {
+	if len(r.Placeholders) > 0 {
+		values, err := r.splitValues(value)
+		if err != nil {
+			return "", err
+		}
+
+		for i := range values {
+			values[i], err = r.encode(values[i])
+			if err != nil {
+				return "", err
+			}
+		}
+
+		return JoinValues(values), nil
+	}
+
+	return r.encode(value)
+}
+
+// encode applies all transformations from r.Encode to a single value.
+func (r *Request) encode(value string) (string, error) {
+	for _, name := range r.Encode {
+		encode, ok := encoders[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return "", fmt.Errorf("unknown encoding %q", name)
+		}
+
+		var err error
+		value, err = encode(value)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return value, nil
+}
+
+// queryReplacer returns the replacer for the query string, which
+// percent-encodes the value if r.EncodeQuery is set.
+func (r *Request) queryReplacer(replacer *valueReplacer, value string) *valueReplacer {
+	if !r.EncodeQuery {
+		return replacer
+	}
+	return r.escapedReplacer(replacer, value, percentEncode)
+}
+
+// headerReplacer returns the replacer for the headers, which percent-encodes
+// the value if r.EncodeHeader is set.
+func (r *Request) headerReplacer(replacer *valueReplacer, value string) *valueReplacer {
+	if !r.EncodeHeader {
+		return replacer
+	}
+	return r.escapedReplacer(replacer, value, percentEncode)
+}
+
+// insertURL replaces the placeholders in rawURL, insertQuery is used for the
+// query string.
+func insertURL(rawURL string, insertValue, insertQuery func(string) string) string {
+	i := strings.IndexByte(rawURL, '?')
+	if i < 0 {
+		return insertValue(rawURL)
+	}
+	return insertValue(rawURL[:i]) + "?" + insertQuery(rawURL[i+1:])
+}
+
+// insertTemplate replaces the placeholders in the template file, insertQuery
+// is used for the query string in the request line and insertHeader for the
+// header lines.
+func insertTemplate(buf []byte, insertValue, insertQuery, insertHeader func(string) string) []byte {
+	s := string(buf)
+
+	end := strings.IndexByte(s, '\n')
+	if end < 0 {
+		end = len(s)
+	}
+	line, rest := s[:end], s[end:]
+
+	// the query string ends at the last space before the protocol version
+	if q, sp := strings.IndexByte(line, '?'), strings.LastIndexByte(line, ' '); q >= 0 && sp > q {
+		line = insertValue(line[:q]) + "?" + insertQuery(line[q+1:sp]) + insertValue(line[sp:])
+	} else {
+		line = insertValue(line)
+	}
+
+	header, body := rest, ""
+	for _, sep := range []string{"\n\r\n", "\n\n"} {
+		if i := strings.Index(rest, sep); i >= 0 && i < len(header) {
+			header, body = rest[:i+1], rest[i+1:]
+		}
+	}
+
+	return []byte(line + insertHeader(header) + insertValue(body))
+}
+
+// splitPath splits rawURL into the URL without the path and the path, which
+// ends at the first "?". The path is empty if rawURL does not contain one,
+// or if it starts with "//" (Go would send such a path as an absolute URL).
+func splitPath(rawURL string) (withoutPath, path string) {
+	i := strings.Index(rawURL, "://")
+	if i < 0 {
+		return rawURL, ""
+	}
+	start := i + 3
+
+	i = strings.IndexAny(rawURL[start:], "/?#")
+	if i < 0 || rawURL[start+i] != '/' {
+		return rawURL, ""
+	}
+	start += i
+
+	end := len(rawURL)
+	if i := strings.IndexByte(rawURL[start:], '?'); i >= 0 {
+		end = start + i
+	}
+
+	if strings.HasPrefix(rawURL[start:end], "//") {
+		return rawURL, ""
+	}
+
+	return rawURL[:start] + rawURL[end:], rawURL[start:end]
+}
+
+// setVerbatimPath configures u so that path is sent unchanged in the request
+// line.
+func setVerbatimPath(u *url.URL, path string) {
+	u.Opaque = path
+	u.Path = path
+	u.RawPath = ""
+}
+
+// cutTemplatePath replaces the path in the request line of the template file
+// by "/" and returns it. The path may contain spaces, it is everything between
+// the method and the protocol version up to the first "?".
+func cutTemplatePath(buf []byte) ([]byte, string) {
+	s := string(buf)
+
+	end := strings.IndexByte(s, '\n')
+	if end < 0 {
+		end = len(s)
+	}
+	line := s[:end]
+
+	start, stop := strings.IndexByte(line, ' '), strings.LastIndexByte(line, ' ')
+	if start < 0 || stop <= start {
+		return buf, ""
+	}
+
+	path, query := line[start+1:stop], ""
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path, query = path[:i], path[i:]
+	}
+
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") {
+		return buf, ""
+	}
+
+	return []byte(line[:start+1] + "/" + query + line[stop:] + s[end:]), path
+}
+
+// URLString returns u as a string like u.String() does, a path which is sent
+// verbatim (see EncodePath) is included unchanged.
+func URLString(u *url.URL) string {
+	if !strings.HasPrefix(u.Opaque, "/") {
+		return u.String()
+	}
+
+	s := (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}).String() + u.Opaque
+	if u.ForceQuery || u.RawQuery != "" {
+		s += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		s += "#" + u.EscapedFragment()
+	}
+	return s
+}
@@ -0,0 +1,221 @@
+package request
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestEncodeValue(t *testing.T) {
+	var tests = []struct {
+		encode []string
+		value  string
+		want   string
+		err    bool
+	}{
+		{
+			value: "a%20b",
+			want:  "a%20b",
+		},
+		{
+			encode: []string{"urldecode"},
+			value:  "a%20b%2Fc%3d",
+			want:   "a b/c=",
+		},
+		{
+			encode: []string{"urldecode"},
+			value:  "a+b",
+			want:   "a+b",
+		},
+		{
+			// decode twice
+			encode: []string{"urldecode", "urldecode"},
+			value:  "%2527",
+			want:   "'",
+		},
+		{
+			encode: []string{"urldecode"},
+			value:  "100%",
+			err:    true,
+		},
+		{
+			encode: []string{"urldecode"},
+			value:  "%zz",
+			err:    true,
+		},
+		{
+			encode: []string{"urlencode"},
+			value:  "a b/c=ä~_",
+			want:   "a%20b%2Fc%3D%C3%A4~_",
+		},
+		{
+			encode: []string{"base64", "urlencode"},
+			value:  "<?>",
+			want:   "PD8%2B",
+		},
+		{
+			encode: []string{"base64decode"},
+			value:  "Zm9vYmFy",
+			want:   "foobar",
+		},
+		{
+			encode: []string{"base64decode"},
+			value:  "Zm9v!!",
+			err:    true,
+		},
+		{
+			encode: []string{"hex"},
+			value:  "foo",
+			want:   "666f6f",
+		},
+		{
+			encode: []string{"md5"},
+			value:  "foo",
+			want:   "acbd18db4cc2f85cedef654fccc4a4d8",
+		},
+		{
+			encode: []string{"sha1"},
+			value:  "foo",
+			want:   "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33",
+		},
+		{
+			encode: []string{" SHA256 "},
+			value:  "foo",
+			want:   "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		},
+		{
+			encode: []string{"rot13"},
+			value:  "foo",
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/"
+			r.Method = "POST"
+			r.Body = "value=FUZZ"
+			r.Encode = test.encode
+
+			req, err := r.Apply(test.value)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error not returned for %q", test.value)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := "value=" + test.want
+			if string(buf) != want {
+				t.Errorf("wrong body, want %q, got %q", want, buf)
+			}
+		})
+	}
+}
+
+func TestEncodeLocation(t *testing.T) {
+	var tests = []struct {
+		setup   func(*Request)
+		value   string
+		wantURI string
+		wantURL string
+		header  string
+		body    string
+	}{
+		{
+			// Go encodes the path again, which decodes "%2e"
+			value:   "a b/%2e%2e",
+			wantURI: "/a%20b/..?q=a b/%2e%2e",
+			wantURL: "http://www.example.com/a%20b/..?q=a b/%2e%2e",
+			header:  "a b/%2e%2e",
+		},
+		{
+			setup: func(r *Request) {
+				r.EncodePath = false
+			},
+			value:   "%zz/a b",
+			wantURI: "/%zz/a b?q=%zz/a b",
+			wantURL: "http://www.example.com/%zz/a b?q=%zz/a b",
+			header:  "%zz/a b",
+		},
+		{
+			setup: func(r *Request) {
+				r.EncodeQuery = true
+			},
+			value:   "a&b=c",
+			wantURI: "/a&b=c?q=a%26b%3Dc",
+			wantURL: "http://www.example.com/a&b=c?q=a%26b%3Dc",
+			header:  "a&b=c",
+		},
+		{
+			setup: func(r *Request) {
+				r.URL = "http://www.example.com/"
+				r.EncodeHeader = true
+			},
+			value:   "x\r\ny: z",
+			wantURI: "/",
+			header:  "x%0D%0Ay%3A%20z",
+		},
+		{
+			setup: func(r *Request) {
+				r.URL = "http://www.example.com"
+				r.TemplateData = []byte("POST /FUZZ/x?q=FUZZ HTTP/1.1\r\nHost: www.example.com\r\nX-Test: FUZZ\r\n\r\nFUZZ")
+				r.Header = NewHeader(nil)
+				r.EncodePath = false
+				r.EncodeQuery = true
+				r.EncodeHeader = true
+			},
+			value:   "%zz a",
+			wantURI: "/%zz a/x?q=%25zz%20a",
+			wantURL: "http://www.example.com/%zz a/x?q=%25zz%20a",
+			header:  "%25zz%20a",
+			body:    "%zz a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/FUZZ?q=FUZZ"
+			r.Header.Set("X-Test: FUZZ")
+			if test.setup != nil {
+				test.setup(r)
+			}
+
+			req, err := r.Apply(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.URL.RequestURI() != test.wantURI {
+				t.Errorf("wrong request URI, want %q, got %q", test.wantURI, req.URL.RequestURI())
+			}
+
+			if test.wantURL != "" && URLString(req.URL) != test.wantURL {
+				t.Errorf("wrong URL, want %q, got %q", test.wantURL, URLString(req.URL))
+			}
+
+			if req.Header.Get("X-Test") != test.header {
+				t.Errorf("wrong header, want %q, got %q", test.header, req.Header.Get("X-Test"))
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.body {
+				t.Errorf("wrong body, want %q, got %q", test.body, buf)
+			}
+		})
+	}
+}
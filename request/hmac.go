@@ -0,0 +1,152 @@
+package request
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// DefaultHMACStringToSign is the template used to build the string to sign
+// when no other template is configured.
+const DefaultHMACStringToSign = "{{.Method}}\n{{.URI}}\n{{.BodySHA256}}\n{{.Timestamp}}"
+
+// now returns the current time, it is a variable so tests can replace it.
+var now = time.Now
+
+// HMACFields are the fields of a request which can be used in the template
+// for the string to sign. The placeholder is not replaced in the template
+// itself, the value is available as .Value instead.
+type HMACFields struct {
+	Method     string
+	Host       string
+	Path       string
+	Query      string
+	URI        string // path and query string
+	Body       string
+	BodySHA256 string // hex encoded SHA256 hash of the body
+	Timestamp  string // seconds since the epoch
+	Value      string // the value inserted into the request
+
+	header       http.Header
+	placeholders map[string]string
+}
+
+// Header returns the first value of the HTTP request header name.
+func (f HMACFields) Header(name string) string {
+	return f.header.Get(name)
+}
+
+// Placeholder returns the value inserted for the placeholder or variable
+// name, or the empty string if there is no such placeholder.
+func (f HMACFields) Placeholder(name string) string {
+	return f.placeholders[name]
+}
+
+// hmacTemplate parses the template for the string to sign. It is only parsed
+// once, the values must not be inserted into the template source.
+func (r *Request) hmacTemplate() (*template.Template, error) {
+	r.hmacOnce.Do(func() {
+		stringToSign := r.HMACStringToSign
+		if stringToSign == "" {
+			stringToSign = DefaultHMACStringToSign
+		}
+
+		r.hmacTmpl, r.hmacErr = template.New("").Parse(stringToSign)
+		if r.hmacErr != nil {
+			r.hmacErr = fmt.Errorf("parse HMAC string to sign: %v", r.hmacErr)
+		}
+	})
+
+	return r.hmacTmpl, r.hmacErr
+}
+
+func hmacHash(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256", "":
+		return sha256.New, nil
+	default:
+		return nil, fmt.Errorf("unknown HMAC algorithm %q", algorithm)
+	}
+}
+
+func hmacEncode(encoding string, sig []byte) (string, error) {
+	switch encoding {
+	case "hex", "":
+		return hex.EncodeToString(sig), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(sig), nil
+	default:
+		return "", fmt.Errorf("unknown HMAC encoding %q", encoding)
+	}
+}
+
+// signHMAC computes the HMAC signature over the string built from the template
+// and the request and sets the configured header. The body is the final body
+// of the request. The placeholders in the secret are replaced by insertValue,
+// so the key itself can be fuzzed.
+func (r *Request) signHMAC(req *http.Request, body []byte, value string, insertValue func(string) string) error {
+	newHash, err := hmacHash(r.HMACAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := r.hmacTemplate()
+	if err != nil {
+		return err
+	}
+
+	bodyHash := sha256.Sum256(body)
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	fields := HMACFields{
+		Method:     req.Method,
+		Host:       host,
+		Path:       req.URL.EscapedPath(),
+		Query:      req.URL.RawQuery,
+		URI:        req.URL.RequestURI(),
+		Body:       string(body),
+		BodySHA256: hex.EncodeToString(bodyHash[:]),
+		Timestamp:  strconv.FormatInt(now().Unix(), 10),
+		Value:      value,
+		header:     req.Header,
+	}
+
+	// all placeholders and variables, r.mergeVars adds the variables to own
+	fields.placeholders = r.ownValues(value)
+	r.mergeVars(fields.placeholders)
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, fields)
+	if err != nil {
+		return fmt.Errorf("build HMAC string to sign: %v", err)
+	}
+
+	mac := hmac.New(newHash, []byte(insertValue(r.HMACSecret)))
+	_, _ = mac.Write(buf.Bytes())
+
+	sig, err := hmacEncode(r.HMACEncoding, mac.Sum(nil))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(r.HMACHeader, sig)
+	if r.HMACTimestampHeader != "" {
+		req.Header.Set(r.HMACTimestampHeader, fields.Timestamp)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package request
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestHMACSign(t *testing.T) {
+	var tests = []struct {
+		setup  func(*Request)
+		value  string
+		header string
+		want   string
+	}{
+		{
+			setup: func(r *Request) {
+				r.URL = "http://www.example.com/foo?x=FUZZ"
+				r.Method = "POST"
+				r.Body = "data=FUZZ"
+				r.HMACHeader = "X-Signature"
+				r.HMACSecret = "secret"
+				r.HMACStringToSign = "{{.Method}} {{.URI}} {{.BodySHA256}}"
+			},
+			value:  "bar",
+			header: "X-Signature",
+			want:   "3b75cbfaa8da9b8dac23377df241c4ec8a0aaac8864f7eb60945cd35991896e6",
+		},
+		{
+			setup: func(r *Request) {
+				r.URL = "http://www.example.com"
+				r.HMACHeader = "X-Signature"
+				r.HMACAlgorithm = "sha1"
+				r.HMACEncoding = "base64"
+				r.HMACSecret = "k3y"
+				r.HMACTimestampHeader = "X-Timestamp"
+			},
+			header: "X-Signature",
+			want:   "Y0ArPyGTFkJMcOD3vdzeMxShmgA=",
+		},
+		{
+			setup: func(r *Request) {
+				r.URL = "http://www.example.com"
+				r.HMACHeader = "X-Signature"
+				r.HMACTimestampHeader = "X-Timestamp"
+			},
+			header: "X-Timestamp",
+			want:   "1500000000",
+		},
+	}
+
+	now = func() time.Time {
+		return time.Unix(1500000000, 0)
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			test.setup(r)
+
+			req, err := r.Apply(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := req.Header.Get(test.header)
+			if got != test.want {
+				t.Fatalf("wrong value for header %v, want %q, got %q", test.header, test.want, got)
+			}
+		})
+	}
+}
+
+func TestHMACSignTemplateValue(t *testing.T) {
+	sign := func(secret, s string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var tests = []struct {
+		stringToSign string
+		secret       string
+		value        string
+		want         string
+	}{
+		{
+			stringToSign: "{{.Value}}",
+			secret:       "secret",
+			value:        "{{7*7}}",
+			want:         sign("secret", "{{7*7}}"),
+		},
+		{
+			stringToSign: "{{.Method}} {{.Value}}",
+			secret:       "secret",
+			value:        "{{.Body}}",
+			want:         sign("secret", "POST {{.Body}}"),
+		},
+		{
+			stringToSign: "FUZZ {{.Placeholder \"FUZZ\"}}",
+			secret:       "secret",
+			value:        "foo",
+			want:         sign("secret", "FUZZ foo"),
+		},
+		{
+			stringToSign: "{{.Method}}",
+			secret:       "key-FUZZ",
+			value:        "{{.Body}}",
+			want:         sign("key-{{.Body}}", "POST"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/"
+			r.Method = "POST"
+			r.Body = "data=FUZZ"
+			r.HMACHeader = "X-Signature"
+			r.HMACSecret = test.secret
+			r.HMACStringToSign = test.stringToSign
+
+			err := r.Prepare(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := r.Apply(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := req.Header.Get("X-Signature")
+			if got != test.want {
+				t.Fatalf("wrong signature, want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestHMACSignInvalidTemplate(t *testing.T) {
+	r := New("")
+	r.URL = "http://www.example.com/"
+	r.HMACHeader = "X-Signature"
+	r.HMACStringToSign = "{{.Method"
+
+	err := r.Prepare(context.Background())
+	if err == nil {
+		t.Fatal("expected error for invalid template not found")
+	}
+}
@@ -0,0 +1,193 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+)
+
+// hostPattern matches a host by network (CIDR or a single IP address) or by
+// name, where "*" matches any sequence of characters except dots.
+type hostPattern struct {
+	network *net.IPNet
+	name    string
+}
+
+func parseHostPattern(s string) (hostPattern, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return hostPattern{}, fmt.Errorf("empty host pattern")
+	}
+
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return hostPattern{}, fmt.Errorf("invalid host pattern %q: %v", s, err)
+		}
+		return hostPattern{network: network}, nil
+	}
+
+	if ip := net.ParseIP(strings.Trim(s, "[]")); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		return hostPattern{network: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}}, nil
+	}
+
+	name := strings.ToLower(s)
+	// check the syntax of the pattern
+	_, err := path.Match(strings.Replace(name, ".", "/", -1), "")
+	if err != nil {
+		return hostPattern{}, fmt.Errorf("invalid host pattern %q: %v", s, err)
+	}
+
+	return hostPattern{name: name}, nil
+}
+
+// match returns true if the pattern matches the host name or one of the
+// addresses returned by lookup, which is only called for networks.
+func (p hostPattern) match(host string, lookup func() ([]net.IP, error)) (bool, error) {
+	if p.network != nil {
+		addrs, err := lookup()
+		if err != nil {
+			return false, err
+		}
+
+		for _, ip := range addrs {
+			if p.network.Contains(ip) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	// match the labels separately so that "*" does not match dots
+	ok, _ := path.Match(strings.Replace(p.name, ".", "/", -1), strings.Replace(strings.ToLower(host), ".", "/", -1))
+	return ok, nil
+}
+
+func parseHostPatterns(list []string) ([]hostPattern, error) {
+	patterns := make([]hostPattern, 0, len(list))
+	for _, s := range list {
+		p, err := parseHostPattern(s)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// Resolver looks up the addresses of a host, e.g. *net.Resolver.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// HostFilter checks the hosts requests are sent to against the patterns from
+// AllowHost and DenyHost.
+type HostFilter struct {
+	allow, deny []hostPattern
+	resolver    Resolver
+}
+
+// NewHostFilter parses the patterns in AllowHost and DenyHost. Host names are
+// looked up via resolver, which should be the resolver used for connections,
+// so that the addresses which are checked are the ones connections are made
+// to. If resolver is nil, the system resolver is used. If no patterns are
+// set, nil is returned, which allows all hosts.
+func (r *Request) NewHostFilter(resolver Resolver) (*HostFilter, error) {
+	if len(r.AllowHost) == 0 && len(r.DenyHost) == 0 {
+		return nil, nil
+	}
+
+	allow, err := parseHostPatterns(r.AllowHost)
+	if err != nil {
+		return nil, err
+	}
+
+	deny, err := parseHostPatterns(r.DenyHost)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &HostFilter{allow: allow, deny: deny, resolver: resolver}, nil
+}
+
+// CheckTarget returns an error if one of hosts (the host from the URL and the
+// host connections are made to) is not allowed. Host names are resolved to
+// check the networks. A nil filter allows all hosts.
+func (f *HostFilter) CheckTarget(ctx context.Context, hosts ...string) error {
+	if f == nil {
+		return nil
+	}
+
+	for _, host := range hosts {
+		host = strings.TrimSuffix(host, ".")
+
+		// resolve the host name at most once, and only if needed
+		var addrs []net.IP
+		var lookupErr error
+		var resolved bool
+		lookup := func() ([]net.IP, error) {
+			if resolved {
+				return addrs, lookupErr
+			}
+			resolved = true
+
+			if ip := net.ParseIP(host); ip != nil {
+				addrs = []net.IP{ip}
+				return addrs, nil
+			}
+
+			res, err := f.resolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				lookupErr = fmt.Errorf("resolve %v for checking the target: %v", host, err)
+				return nil, lookupErr
+			}
+			for _, addr := range res {
+				addrs = append(addrs, addr.IP)
+			}
+			return addrs, nil
+		}
+
+		for _, p := range f.deny {
+			match, err := p.match(host, lookup)
+			if err != nil {
+				return err
+			}
+			if match {
+				return fmt.Errorf("target host %v is denied", host)
+			}
+		}
+
+		if len(f.allow) == 0 {
+			continue
+		}
+
+		var allowed bool
+		for _, p := range f.allow {
+			match, err := p.match(host, lookup)
+			if err != nil {
+				return err
+			}
+			if match {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("target host %v is not allowed", host)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestCheckTarget(t *testing.T) {
+	var tests = []struct {
+		allow, deny []string
+		hosts       []string
+		err         bool
+	}{
+		// no restrictions
+		{hosts: []string{"www.example.com"}},
+		// allowed
+		{allow: []string{"10.0.0.0/8"}, hosts: []string{"10.1.2.3"}},
+		{allow: []string{"*.test.example.com"}, hosts: []string{"API.test.example.com"}},
+		{allow: []string{"www.example.com", "192.0.2.1"}, hosts: []string{"www.example.com", "192.0.2.1"}},
+		{deny: []string{"*.example.com"}, hosts: []string{"a.b.example.com"}},
+		{allow: []string{"::1"}, hosts: []string{"::1"}},
+		// not allowed
+		{allow: []string{"10.0.0.0/8"}, hosts: []string{"192.168.1.1"}, err: true},
+		{allow: []string{"*.test.example.com"}, hosts: []string{"test.example.com"}, err: true},
+		// the dial target is checked as well
+		{allow: []string{"*.test.example.com"}, hosts: []string{"www.test.example.com", "www.example.com"}, err: true},
+		// denied by CIDR
+		{deny: []string{"192.168.0.0/16"}, hosts: []string{"192.168.10.1"}, err: true},
+		{deny: []string{"fd00::/8"}, hosts: []string{"fd12::1"}, err: true},
+		{deny: []string{"127.0.0.0/8", "::1/128"}, hosts: []string{"localhost"}, err: true},
+		{allow: []string{"10.0.0.0/8"}, deny: []string{"10.1.0.0/16"}, hosts: []string{"10.1.2.3"}, err: true},
+		// denied by wildcard
+		{deny: []string{"*.prod.example.com"}, hosts: []string{"api.prod.example.com"}, err: true},
+		{deny: []string{"db*.example.com"}, hosts: []string{"test.example.com", "db01.example.com"}, err: true},
+		// invalid patterns
+		{allow: []string{"10.0.0.0/33"}, hosts: []string{"10.0.0.1"}, err: true},
+		{deny: []string{"[.example.com"}, hosts: []string{"www.example.com"}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.AllowHost = test.allow
+			r.DenyHost = test.deny
+
+			f, err := r.NewHostFilter(nil)
+			if err == nil {
+				err = f.CheckTarget(context.Background(), test.hosts...)
+			}
+
+			if test.err && err == nil {
+				t.Fatalf("expected error not returned for %v", test.hosts)
+			}
+
+			if !test.err && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// fakeResolver returns the addresses from the map for each host name.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	list, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	var addrs []net.IPAddr
+	for _, s := range list {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(s)})
+	}
+	return addrs, nil
+}
+
+func TestCheckTargetResolver(t *testing.T) {
+	// the names are only known to the resolver passed to the filter
+	resolver := fakeResolver{
+		"intranet.monsoon-test.invalid": {"10.1.2.3"},
+		"dual.monsoon-test.invalid":     {"10.1.2.4", "192.168.1.1"},
+		"www.monsoon-test.invalid":      {"192.0.2.10"},
+	}
+
+	var tests = []struct {
+		allow, deny []string
+		host        string
+		err         string
+	}{
+		{allow: []string{"10.0.0.0/8"}, host: "intranet.monsoon-test.invalid"},
+		{allow: []string{"10.0.0.0/8"}, host: "dual.monsoon-test.invalid"},
+		{allow: []string{"10.0.0.0/8"}, host: "www.monsoon-test.invalid", err: "not allowed"},
+		{deny: []string{"192.168.0.0/16"}, host: "dual.monsoon-test.invalid", err: "denied"},
+		{deny: []string{"192.168.0.0/16"}, host: "intranet.monsoon-test.invalid"},
+		{allow: []string{"10.0.0.0/8"}, host: "unknown.monsoon-test.invalid", err: "no such host"},
+		// names are not resolved for name patterns
+		{allow: []string{"*.monsoon-test.invalid"}, host: "unknown.monsoon-test.invalid"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.AllowHost = test.allow
+			r.DenyHost = test.deny
+
+			f, err := r.NewHostFilter(resolver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = f.CheckTarget(context.Background(), test.host)
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("wrong error, want %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// jsonPathElement is either a key of an object or an index of an array.
+type jsonPathElement struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func (e jsonPathElement) String() string {
+	if e.isKey {
+		return strconv.Quote(e.key)
+	}
+	return strconv.Itoa(e.index)
+}
+
+// parseJSONPath parses a simple JSONPath expression such as
+// "$.user.name", "$.items[0].id" or "$['key with spaces']".
+func parseJSONPath(s string) (path []jsonPathElement, err error) {
+	p := strings.TrimPrefix(s, "$")
+
+	for len(p) > 0 {
+		switch {
+		case p[0] == '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", s)
+			}
+			path = append(path, jsonPathElement{key: p[:end], isKey: true})
+			p = p[end:]
+
+		case strings.HasPrefix(p, "['") || strings.HasPrefix(p, `["`):
+			quote := p[1]
+			end := strings.IndexByte(p[2:], quote)
+			if end < 0 || !strings.HasPrefix(p[2+end+1:], "]") {
+				return nil, fmt.Errorf("invalid JSON path %q: unterminated key", s)
+			}
+			path = append(path, jsonPathElement{key: p[2 : 2+end], isKey: true})
+			p = p[2+end+2:]
+
+		case p[0] == '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: missing ]", s)
+			}
+			index, err := strconv.Atoi(p[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: invalid index %q", s, p[1:end])
+			}
+			path = append(path, jsonPathElement{index: index})
+			p = p[end+1:]
+
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q: unexpected %q", s, p)
+		}
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("invalid JSON path %q: the root cannot be replaced", s)
+	}
+
+	return path, nil
+}
+
+// setJSONPath sets the element at path in data to value. Keys which do not
+// exist yet are added to the last object, all other elements must exist.
+func setJSONPath(data interface{}, path []jsonPathElement, value interface{}) error {
+	for i, elem := range path {
+		last := i == len(path)-1
+
+		switch d := data.(type) {
+		case map[string]interface{}:
+			if !elem.isKey {
+				return fmt.Errorf("element %v is an object, not an array", pathString(path[:i]))
+			}
+
+			if last {
+				d[elem.key] = value
+				return nil
+			}
+
+			next, ok := d[elem.key]
+			if !ok {
+				return fmt.Errorf("key %v not found", pathString(path[:i+1]))
+			}
+			data = next
+
+		case []interface{}:
+			if elem.isKey {
+				return fmt.Errorf("element %v is an array, not an object", pathString(path[:i]))
+			}
+
+			if elem.index >= len(d) {
+				return fmt.Errorf("index %v out of range, the array has %d elements", pathString(path[:i+1]), len(d))
+			}
+
+			if last {
+				d[elem.index] = value
+				return nil
+			}
+			data = d[elem.index]
+
+		default:
+			return fmt.Errorf("element %v is neither an object nor an array", pathString(path[:i]))
+		}
+	}
+
+	return nil
+}
+
+// getJSONPath returns the element at path in data.
+func getJSONPath(data interface{}, path []jsonPathElement) (interface{}, error) {
+	for i, elem := range path {
+		switch d := data.(type) {
+		case map[string]interface{}:
+			if !elem.isKey {
+				return nil, fmt.Errorf("element %v is an object, not an array", pathString(path[:i]))
+			}
+
+			next, ok := d[elem.key]
+			if !ok {
+				return nil, fmt.Errorf("key %v not found", pathString(path[:i+1]))
+			}
+			data = next
+
+		case []interface{}:
+			if elem.isKey {
+				return nil, fmt.Errorf("element %v is an array, not an object", pathString(path[:i]))
+			}
+
+			if elem.index >= len(d) {
+				return nil, fmt.Errorf("index %v out of range, the array has %d elements", pathString(path[:i+1]), len(d))
+			}
+			data = d[elem.index]
+
+		default:
+			return nil, fmt.Errorf("element %v is neither an object nor an array", pathString(path[:i]))
+		}
+	}
+
+	return data, nil
+}
+
+// CheckJSONPath returns an error if path is not a valid JSONPath expression.
+func CheckJSONPath(path string) error {
+	_, err := parseJSONPath(path)
+	return err
+}
+
+// LookupJSON returns the element at the JSONPath expression path (e.g.
+// "$.data.token") in the JSON document buf. Numbers are returned as
+// json.Number.
+func LookupJSON(buf []byte, path string) (interface{}, error) {
+	p, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	var data interface{}
+	err = dec.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	return getJSONPath(data, p)
+}
+
+// ExtractJSON returns the element at the JSONPath expression path (e.g.
+// "$.data.token") in the JSON document buf. Strings are returned without
+// quotes, all other elements are returned as JSON.
+func ExtractJSON(buf []byte, path string) (string, error) {
+	v, err := LookupJSON(buf, path)
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	res, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+func pathString(path []jsonPathElement) string {
+	s := "$"
+	for _, elem := range path {
+		s += "[" + elem.String() + "]"
+	}
+	return s
+}
+
+// jsonValue converts value to the JSON type named by typ.
+func jsonValue(value, typ string) (interface{}, error) {
+	switch typ {
+	case "string", "":
+		return value, nil
+	case "number":
+		_, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a number", value)
+		}
+		return json.Number(value), nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a boolean", value)
+		}
+		return b, nil
+	case "json":
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
+		err := dec.Decode(&v)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not valid JSON: %v", value, err)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown JSON type %q", typ)
+	}
+}
+
+// rawJSONMarker is inserted into the JSON body for the type rawjson, it is
+// replaced by the value verbatim after the body has been encoded.
+const rawJSONMarker = "\x00monsoon-rawjson\x00"
+
+// jsonBody reads the JSON body file and injects value at all configured paths.
+func (r *Request) jsonBody(value string) ([]byte, error) {
+	if r.Body != "" {
+		return nil, errors.New("a JSON body file cannot be used together with --data")
+	}
+
+	if len(r.Placeholders) > 0 {
+		return nil, errors.New("a JSON body file cannot be used together with several placeholders")
+	}
+
+	if len(r.JSONInject) == 0 {
+		return nil, errors.New("no JSON path for injecting the value specified")
+	}
+
+	buf, err := ioutil.ReadFile(r.JSONBodyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	err = dec.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("parse JSON body file %v: %v", r.JSONBodyFile, err)
+	}
+
+	var v interface{} = rawJSONMarker
+	if r.JSONInjectType != "rawjson" {
+		v, err = jsonValue(value, r.JSONInjectType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range r.JSONInject {
+		path, err := parseJSONPath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		err = setJSONPath(data, path, v)
+		if err != nil {
+			return nil, fmt.Errorf("inject value at %v: %v", p, err)
+		}
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+
+	if r.JSONInjectType == "rawjson" {
+		// the marker is encoded as a JSON string, replace it including the quotes
+		marker, err := json.Marshal(rawJSONMarker)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.Replace(body, marker, []byte(value), -1)
+	}
+
+	if r.JSONValidate && !json.Valid(body) {
+		return nil, fmt.Errorf("JSON body is invalid after inserting value %q", value)
+	}
+
+	return body, nil
+}
+
+// jsonEscape returns s escaped for a JSON string, without the quotes.
+func jsonEscape(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// encoding a string cannot fail
+	_ = enc.Encode(s)
+
+	res := strings.TrimSuffix(buf.String(), "\n")
+	return res[1 : len(res)-1]
+}
+
+// jsonTemplateBody returns the body for --json, the values are escaped for
+// JSON strings before they are inserted.
+func (r *Request) jsonTemplateBody(value string) ([]byte, error) {
+	if r.Body != "" || r.JSONBodyFile != "" || r.DataFile != "" || len(r.Form) > 0 || len(r.FormFile) > 0 {
+		return nil, errors.New("--json cannot be used together with --data, --data-file, --json-body or --form")
+	}
+
+	pairs := r.replacePairs(value)
+	for i := 1; i < len(pairs); i += 2 {
+		pairs[i] = jsonEscape(pairs[i])
+	}
+
+	body := strings.NewReplacer(pairs...).Replace(r.JSON)
+
+	if r.JSONValidate && !json.Valid([]byte(body)) {
+		return nil, fmt.Errorf("JSON body is invalid after inserting value %q", value)
+	}
+
+	return []byte(body), nil
+}
+
+// graphQLBody returns the body for a GraphQL request. The placeholders are
+// replaced in the query, and in the variables with values escaped for JSON
+// strings.
+func (r *Request) graphQLBody(value string) ([]byte, error) {
+	if r.Body != "" || r.JSON != "" || r.JSONBodyFile != "" || r.DataFile != "" || len(r.Form) > 0 || len(r.FormFile) > 0 {
+		return nil, errors.New("--graphql-query cannot be used together with --data, --data-file, --json, --json-body or --form")
+	}
+
+	pairs := r.replacePairs(value)
+	query := strings.NewReplacer(pairs...).Replace(r.GraphQLQuery)
+
+	for i := 1; i < len(pairs); i += 2 {
+		pairs[i] = jsonEscape(pairs[i])
+	}
+
+	var data struct {
+		Query     string          `json:"query"`
+		Variables json.RawMessage `json:"variables,omitempty"`
+	}
+	data.Query = query
+
+	if r.GraphQLVariables != "" {
+		vars := strings.NewReplacer(pairs...).Replace(r.GraphQLVariables)
+		if !json.Valid([]byte(vars)) {
+			return nil, fmt.Errorf("GraphQL variables are invalid JSON after inserting value %q", value)
+		}
+		data.Variables = json.RawMessage(vars)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,359 @@
+package request
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+const testJSONBody = `{
+  "user": {"name": "admin", "roles": ["user", {"id": 1}]},
+  "items": [[1, 2], [3, 4]],
+  "comment": "<none>"
+}`
+
+func TestJSONBody(t *testing.T) {
+	filename, cleanup := writeTempFile(t, testJSONBody)
+	defer cleanup()
+
+	var tests = []struct {
+		paths []string
+		typ   string
+		value string
+		want  string
+	}{
+		{
+			paths: []string{"$.user.name"},
+			value: `FUZZ "quoted"`,
+			want:  `{"comment":"<none>","items":[[1,2],[3,4]],"user":{"name":"FUZZ \"quoted\"","roles":["user",{"id":1}]}}`,
+		},
+		{
+			paths: []string{"$.user.roles[1].id"},
+			typ:   "number",
+			value: "1337",
+			want:  `{"comment":"<none>","items":[[1,2],[3,4]],"user":{"name":"admin","roles":["user",{"id":1337}]}}`,
+		},
+		{
+			paths: []string{"$.items[1][0]", "$['user']['new key']"},
+			typ:   "bool",
+			value: "true",
+			want:  `{"comment":"<none>","items":[[1,2],[true,4]],"user":{"name":"admin","new key":true,"roles":["user",{"id":1}]}}`,
+		},
+		{
+			paths: []string{"$.user.roles[0]"},
+			typ:   "json",
+			value: `{"admin": null}`,
+			want:  `{"comment":"<none>","items":[[1,2],[3,4]],"user":{"name":"admin","roles":[{"admin":null},{"id":1}]}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/"
+			r.Method = "POST"
+			r.JSONBodyFile = filename
+			r.JSONInject = test.paths
+			r.JSONInjectType = test.typ
+
+			req, err := r.Apply(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.want {
+				t.Errorf("wrong body, want:\n  %s\ngot:\n  %s", test.want, buf)
+			}
+
+			if req.ContentLength != int64(len(test.want)) {
+				t.Errorf("wrong content length, want %v, got %v", len(test.want), req.ContentLength)
+			}
+
+			if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("wrong Content-Type, want %q, got %q", "application/json", ct)
+			}
+		})
+	}
+}
+
+func TestJSONBodyInvalid(t *testing.T) {
+	filename, cleanup := writeTempFile(t, testJSONBody)
+	defer cleanup()
+
+	var tests = []struct {
+		path  string
+		typ   string
+		value string
+	}{
+		{path: "$"},
+		{path: "$.user..name"},
+		{path: "$.items[x]"},
+		{path: "$.items[5]"},
+		{path: "$.user[0]"},
+		{path: "$.items.foo"},
+		{path: "$.missing.name"},
+		{path: "$.comment.foo"},
+		{path: "$['user"},
+		{path: "$.user.name", typ: "number", value: "abc"},
+		{path: "$.user.name", typ: "bool", value: "abc"},
+		{path: "$.user.name", typ: "json", value: "{"},
+		{path: "$.user.name", typ: "foo"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/"
+			r.JSONBodyFile = filename
+			r.JSONInject = []string{test.path}
+			r.JSONInjectType = test.typ
+
+			_, err := r.Apply(test.value)
+			if err == nil {
+				t.Fatalf("expected error not returned for %v", test.path)
+			}
+		})
+	}
+}
+
+func TestJSONBodyRaw(t *testing.T) {
+	filename, cleanup := writeTempFile(t, testJSONBody)
+	defer cleanup()
+
+	var tests = []struct {
+		value    string
+		validate bool
+		want     string
+		err      bool
+	}{
+		{
+			value: "1337",
+			want:  `{"comment":"<none>","items":[[1,2],[3,4]],"user":{"name":1337,"roles":["user",{"id":1}]}}`,
+		},
+		{
+			value:    `[1, "two", {"z": 1, "a": 2}]`,
+			validate: true,
+			want:     `{"comment":"<none>","items":[[1,2],[3,4]],"user":{"name":[1, "two", {"z": 1, "a": 2}],"roles":["user",{"id":1}]}}`,
+		},
+		{
+			value: `{"a":`,
+			want:  `{"comment":"<none>","items":[[1,2],[3,4]],"user":{"name":{"a":,"roles":["user",{"id":1}]}}`,
+		},
+		{
+			value:    `{"a":`,
+			validate: true,
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/"
+			r.Method = "POST"
+			r.JSONBodyFile = filename
+			r.JSONInject = []string{"$.user.name"}
+			r.JSONInjectType = "rawjson"
+			r.JSONValidate = test.validate
+
+			req, err := r.Apply(test.value)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.want {
+				t.Errorf("wrong body, want:\n  %s\ngot:\n  %s", test.want, buf)
+			}
+		})
+	}
+}
+
+func TestJSONTemplate(t *testing.T) {
+	var tests = []struct {
+		json  string
+		value string
+		want  string
+	}{
+		{
+			json:  `{"user":"FUZZ"}`,
+			value: `admin"}, "role": "admin`,
+			want:  `{"user":"admin\"}, \"role\": \"admin"}`,
+		},
+		{
+			json:  `{"user":"FUZZ", "note": "FUZZ"}`,
+			value: "a\nb\\c\t<>&",
+			want:  `{"user":"a\nb\\c\t<>&", "note": "a\nb\\c\t<>&"}`,
+		},
+		{
+			json:  `{"id": FUZZ}`,
+			value: "23",
+			want:  `{"id": 23}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/"
+			r.Method = "POST"
+			r.JSON = test.json
+			r.JSONValidate = true
+
+			req, err := r.Apply(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.want {
+				t.Errorf("wrong body, want:\n  %s\ngot:\n  %s", test.want, buf)
+			}
+
+			if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("wrong Content-Type, want %q, got %q", "application/json", ct)
+			}
+		})
+	}
+
+	r := New("")
+	r.URL = "http://www.example.com/"
+	r.JSON = `{"id": FUZZ}`
+	r.JSONValidate = true
+
+	_, err := r.Apply("abc")
+	if err == nil {
+		t.Error("expected error not returned for invalid JSON")
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	var tests = []struct {
+		query string
+		vars  string
+		value string
+		want  string
+		err   bool
+	}{
+		{
+			query: `query { user(name: "FUZZ") { id } }`,
+			value: "admin",
+			want:  `{"query":"query { user(name: \"admin\") { id } }"}`,
+		},
+		{
+			query: `query($id: ID!) { node(id: $id) { id } }`,
+			vars:  `{"id": "FUZZ"}`,
+			value: `1" }`,
+			want:  `{"query":"query($id: ID!) { node(id: $id) { id } }","variables":{"id":"1\" }"}}`,
+		},
+		{
+			query: "query { a }",
+			vars:  `{"id": FUZZ}`,
+			value: "x",
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/graphql"
+			r.GraphQLQuery = test.query
+			r.GraphQLVariables = test.vars
+
+			req, err := r.Apply(test.value)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Method != "POST" {
+				t.Errorf("wrong method %q", req.Method)
+			}
+
+			buf, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != test.want {
+				t.Errorf("wrong body, want:\n  %s\ngot:\n  %s", test.want, buf)
+			}
+
+			if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("wrong Content-Type, want %q, got %q", "application/json", ct)
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	var body = []byte(`{"data": {"csrf": "abc123", "id": 12345678901234567890, "list": [true, {"a": null}]}}`)
+
+	var tests = []struct {
+		path  string
+		value string
+		err   bool
+	}{
+		{path: "$.data.csrf", value: "abc123"},
+		{path: "$['data']['csrf']", value: "abc123"},
+		{path: "$.data.id", value: "12345678901234567890"},
+		{path: "$.data.list[0]", value: "true"},
+		{path: "$.data.list[1]", value: `{"a":null}`},
+		{path: "$.data.missing", err: true},
+		{path: "$.data.list[2]", err: true},
+		{path: "$.data.csrf.foo", err: true},
+		{path: "$.data[0]", err: true},
+		{path: "$", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			value, err := ExtractJSON(body, test.path)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error not returned for %v, got %q", test.path, value)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if value != test.value {
+				t.Errorf("wrong value for %v, want %q, got %q", test.path, test.value, value)
+			}
+		})
+	}
+
+	_, err := ExtractJSON([]byte("<html>"), "$.data")
+	if err == nil {
+		t.Fatal("expected error not returned for invalid JSON")
+	}
+}
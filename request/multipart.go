@@ -0,0 +1,143 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+)
+
+// formFile is a file for a multipart form, parsed from
+// "field=@path[;filename=name][;type=mime]".
+type formFile struct {
+	field, path, filename, contentType string
+}
+
+func parseFormFile(s string) (f formFile, err error) {
+	data := strings.SplitN(s, "=", 2)
+	if len(data) != 2 || data[0] == "" || !strings.HasPrefix(data[1], "@") {
+		return formFile{}, fmt.Errorf("invalid form file %q, expected field=@path", s)
+	}
+
+	f.field = data[0]
+	options := strings.Split(data[1][1:], ";")
+	f.path = options[0]
+	if f.path == "" {
+		return formFile{}, fmt.Errorf("invalid form file %q: no path specified", s)
+	}
+
+	for _, opt := range options[1:] {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return formFile{}, fmt.Errorf("invalid form file %q: invalid option %q", s, opt)
+		}
+
+		switch strings.TrimSpace(kv[0]) {
+		case "filename":
+			f.filename = kv[1]
+		case "type":
+			f.contentType = kv[1]
+		default:
+			return formFile{}, fmt.Errorf("invalid form file %q: unknown option %q", s, kv[0])
+		}
+	}
+
+	if f.filename == "" {
+		f.filename = filepath.Base(f.path)
+	}
+
+	if f.contentType == "" {
+		f.contentType = mime.TypeByExtension(filepath.Ext(f.path))
+	}
+	if f.contentType == "" {
+		f.contentType = "application/octet-stream"
+	}
+
+	return f, nil
+}
+
+// multipartBoundary returns a boundary for a multipart body, taken from the
+// source of pseudo-random numbers so that it is reproducible with a seed.
+func (r *Request) multipartBoundary() string {
+	const chars = "0123456789abcdef"
+	rnd := r.random()
+
+	buf := make([]byte, 32)
+	for i := range buf {
+		buf[i] = chars[rnd.Intn(len(chars))]
+	}
+
+	return "monsoon" + string(buf)
+}
+
+// multipartBody builds a multipart/form-data body from the form fields and
+// files, the placeholders are replaced in the field names, the values and the
+// file names (but not in the contents of the files). It returns the body and
+// the value for the Content-Type header.
+func (r *Request) multipartBody(insertValue func(string) string) (body []byte, contentType string, err error) {
+	if r.Body != "" || r.JSONBodyFile != "" {
+		return nil, "", errors.New("a multipart form cannot be used together with --data or --json-body")
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	err = w.SetBoundary(r.multipartBoundary())
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, field := range r.Form {
+		data := strings.SplitN(field, "=", 2)
+		if len(data) != 2 || data[0] == "" {
+			return nil, "", fmt.Errorf("invalid form field %q, expected name=value", field)
+		}
+
+		err = w.WriteField(insertValue(data[0]), insertValue(data[1]))
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, s := range r.FormFile {
+		f, err := parseFormFile(s)
+		if err != nil {
+			return nil, "", err
+		}
+
+		content, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return nil, "", err
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(insertValue(f.field)), quoteEscaper.Replace(insertValue(f.filename))))
+		h.Set("Content-Type", f.contentType)
+
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+
+		_, err = part.Write(content)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// quoteEscaper escapes quotes and backslashes like mime/multipart does for
+// the names in the Content-Disposition header.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
@@ -0,0 +1,116 @@
+package request
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"testing"
+)
+
+func TestMultipartForm(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "file FUZZ content")
+	defer cleanup()
+
+	r := New("")
+	r.URL = "http://www.example.com/upload"
+	r.Method = "POST"
+	r.Form = []string{"user=FUZZ", "FUZZ_name=a=b"}
+	r.FormFile = []string{"upload=@" + filename + ";filename=FUZZ.php;type=image/png", "other=@" + filename}
+
+	req, err := r.Apply(`x"y`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mediaType != "multipart/form-data" {
+		t.Fatalf("wrong media type %q", mediaType)
+	}
+
+	type part struct {
+		name, filename, contentType, content string
+	}
+
+	// the type for the extension depends on the system
+	txtType := mime.TypeByExtension(".txt")
+	if txtType == "" {
+		txtType = "application/octet-stream"
+	}
+
+	want := []part{
+		{name: "user", content: `x"y`},
+		{name: `x"y_name`, content: "a=b"},
+		{name: "upload", filename: `x"y.php`, contentType: "image/png", content: "file FUZZ content"},
+		{name: "other", filename: "file.txt", contentType: txtType, content: "file FUZZ content"},
+	}
+
+	rd := multipart.NewReader(req.Body, params["boundary"])
+	for i := 0; ; i++ {
+		p, err := rd.NextPart()
+		if err != nil {
+			if i != len(want) {
+				t.Fatalf("wrong number of parts, want %d, got %d (%v)", len(want), i, err)
+			}
+			break
+		}
+
+		if i >= len(want) {
+			t.Fatalf("too many parts")
+		}
+
+		buf, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := part{
+			name:        p.FormName(),
+			filename:    p.FileName(),
+			contentType: p.Header.Get("Content-Type"),
+			content:     string(buf),
+		}
+		if got.filename == "" {
+			got.contentType = ""
+		}
+
+		if got != want[i] {
+			t.Errorf("wrong part %d, want %+v, got %+v", i, want[i], got)
+		}
+	}
+}
+
+func TestMultipartFormInvalid(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "content")
+	defer cleanup()
+
+	var tests = []struct {
+		form, file []string
+		body       string
+	}{
+		{form: []string{"novalue"}},
+		{file: []string{"upload=" + filename}},
+		{file: []string{"upload=@"}},
+		{file: []string{"upload=@" + filename + ";foo=bar"}},
+		{file: []string{"upload=@/does/not/exist"}},
+		{form: []string{"a=b"}, body: "foo"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.URL = "http://www.example.com/upload"
+			r.Form = test.form
+			r.FormFile = test.file
+			r.Body = test.body
+
+			_, err := r.Apply("")
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+		})
+	}
+}
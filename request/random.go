@@ -0,0 +1,40 @@
+package request
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand is a source of pseudo-random numbers which is safe for
+// concurrent use.
+type lockedRand struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{rnd: rand.New(rand.NewSource(seed))}
+}
+
+// Intn returns a pseudo-random number in [0,n).
+func (r *lockedRand) Intn(n int) int {
+	r.mu.Lock()
+	v := r.rnd.Intn(n)
+	r.mu.Unlock()
+	return v
+}
+
+// random returns the source of pseudo-random numbers for building requests.
+// It is seeded from r.Seed, or from the current time if no seed is set.
+func (r *Request) random() *lockedRand {
+	r.rndOnce.Do(func() {
+		seed := r.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		r.rnd = newLockedRand(seed)
+	})
+
+	return r.rnd
+}
@@ -0,0 +1,56 @@
+package request
+
+import (
+	"context"
+	"net/http/httputil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSeed(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "a\nb\nc\nd\ne\nf\n")
+	defer cleanup()
+
+	build := func(seed string) (list []string) {
+		r, _ := newTestFlags(t, []string{
+			"--rotating-header", "X-Test:@" + filename,
+			"--rotating-header-mode", "random",
+			"--seed", seed,
+			"--data", "value=FUZZ",
+		})
+		r.URL = "http://www.example.com/FUZZ"
+
+		err := r.Prepare(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, value := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"} {
+			req, err := r.ApplyNext(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf, err := httputil.DumpRequestOut(req, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			list = append(list, string(buf))
+		}
+
+		return list
+	}
+
+	first := build("23")
+	second := build("23")
+	if !cmp.Equal(first, second) {
+		t.Errorf("requests built with the same seed differ:\n%s", cmp.Diff(first, second))
+	}
+
+	other := build("42")
+	if cmp.Equal(first, other) {
+		t.Errorf("requests built with different seeds are the same")
+	}
+}
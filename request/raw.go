@@ -0,0 +1,217 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// RawWriter returns true if the request needs to be written to the connection
+// as it is, without the modifications made by http.Transport.
+func (r *Request) RawWriter() bool {
+	return r.Raw || r.PreserveLength || r.RawRequestLine != "" || len(r.RawHeader) > 0 || r.PreserveHeaderOrder ||
+		r.OmitHost() || r.multipleUserAgents()
+}
+
+// OmitHost returns true if the Host header is removed with --header, the
+// request is then sent without the Host header.
+func (r *Request) OmitHost() bool {
+	for name := range r.Header.Remove {
+		if textproto.CanonicalMIMEHeaderKey(name) == "Host" {
+			return true
+		}
+	}
+	return false
+}
+
+// multipleUserAgents returns true if more than one User-Agent header is set
+// with --header.
+func (r *Request) multipleUserAgents() bool {
+	n := 0
+	for name, values := range r.Header.Header {
+		if textproto.CanonicalMIMEHeaderKey(name) == "User-Agent" {
+			n += len(values)
+		}
+	}
+	return n > 1
+}
+
+// templateBytes returns the contents of the template file.
+func (r *Request) templateBytes() ([]byte, error) {
+	if r.TemplateData != nil {
+		return r.TemplateData, nil
+	}
+
+	if r.TemplateFile == "" {
+		return nil, errors.New("--raw requires a template file")
+	}
+
+	return ioutil.ReadFile(r.TemplateFile)
+}
+
+// RawRequest returns the contents of the template file with all placeholders
+// replaced, in raw mode (Raw) it is sent as it is.
+func (r *Request) RawRequest(value string) ([]byte, error) {
+	value, err := r.encodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := r.templateBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(r.replacer(value).Replace(string(buf))), nil
+}
+
+// applyRaw returns a request which describes the raw request built from the
+// template file: it contains the target from targetURL, and the method and
+// the path from the request line if they can be parsed. The request itself
+// is not sent, RawRequest returns the data to send instead.
+func (r *Request) applyRaw(targetURL string, insertValue func(string) string) (*http.Request, error) {
+	if r.Body != "" || r.JSONBodyFile != "" {
+		return nil, errors.New("--raw cannot be used together with --data or --json-body, the body is taken from the template file")
+	}
+
+	buf, err := r.templateBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Path != "" && target.Path != "/" {
+		return nil, errors.New("URL must not contain a path, it's taken from the template file")
+	}
+
+	if target.RawQuery != "" {
+		return nil, errors.New("URL must not contain a query string, it's taken from the template file")
+	}
+
+	// parse the request line leniently, it may be malformed on purpose
+	line := string(buf)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	line = insertValue(strings.TrimSuffix(line, "\r"))
+	fields := strings.Fields(line)
+
+	u := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/", Fragment: target.Fragment}
+	if len(fields) > 1 {
+		if p, err := url.ParseRequestURI(fields[1]); err == nil && p.Host == "" {
+			u.Path = p.Path
+			u.RawPath = p.RawPath
+			u.RawQuery = p.RawQuery
+		}
+	}
+
+	method := http.MethodGet
+	if len(fields) > 0 {
+		method = fields[0]
+	}
+
+	req := &http.Request{
+		Method:     method,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Host:       target.Host,
+	}
+
+	return req, nil
+}
+
+// RawLines returns the request line (empty if it is not set) and the
+// additional header lines for the raw writer, the placeholder is replaced by
+// value.
+func (r *Request) RawLines(value string) (requestLine string, headers []string, err error) {
+	value, err = r.encodeValue(value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	insertValue := r.replacer(value).Replace
+
+	requestLine = insertValue(r.RawRequestLine)
+	if strings.ContainsAny(requestLine, "\r\n") {
+		return "", nil, errors.New("raw request line must not contain line breaks")
+	}
+
+	for _, h := range r.RawHeader {
+		h = insertValue(h)
+		if strings.ContainsAny(h, "\r\n") {
+			return "", nil, errors.New("raw header must not contain line breaks")
+		}
+		headers = append(headers, h)
+	}
+
+	return requestLine, headers, nil
+}
+
+// HeaderOrder returns the names of the headers in the order in which they
+// are written with PreserveHeaderOrder: first the headers from the template
+// file in the order of the file, then the ones set with --header in the order
+// of the command line. The names are returned as they were given, with the
+// placeholder replaced by value. Each name is returned only once.
+func (r *Request) HeaderOrder(value string) ([]string, error) {
+	value, err := r.encodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	replacer := r.replacer(value)
+	insertValue := r.headerReplacer(replacer, value).Replace
+
+	var names []string
+	if r.TemplateFile != "" || r.TemplateData != nil {
+		buf, err := r.templateBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		lines := strings.Split(string(buf), "\n")
+		for _, line := range lines[1:] {
+			line = strings.TrimSuffix(line, "\r")
+			if line == "" {
+				break
+			}
+
+			// continuation lines do not start a new header
+			if line[0] == ' ' || line[0] == '\t' {
+				continue
+			}
+
+			if i := strings.IndexByte(line, ':'); i > 0 {
+				names = append(names, insertValue(strings.TrimSpace(line[:i])))
+			}
+		}
+	}
+
+	for _, name := range r.Header.Names() {
+		names = append(names, insertValue(name))
+	}
+
+	seen := make(map[string]struct{}, len(names))
+	list := names[:0]
+	for _, name := range names {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		list = append(list, name)
+	}
+
+	return list, nil
+}
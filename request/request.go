@@ -4,6 +4,7 @@ package request
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,12 +14,17 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
 // Header is an HTTP header that implements the pflag.Value interface.
 type Header struct {
 	Header http.Header
 	Remove map[string]struct{} // entries are to be removed before sending the HTTP request
+
+	order map[string]int // position at which a header name was set first
 }
 
 func (h Header) String() (s string) {
@@ -62,6 +68,10 @@ func (h Header) Set(s string) error {
 	// use original name in case there's a string we need to replace later
 	h.Header[name] = append(h.Header[name], val)
 
+	if _, ok := h.order[name]; !ok && h.order != nil {
+		h.order[name] = len(h.order)
+	}
+
 	return nil
 }
 
@@ -70,6 +80,77 @@ func (h Header) Type() string {
 	return "name: value"
 }
 
+// GetSlice returns the headers which have been set (except for the default
+// values) as "name: value" strings in the order in which they were set,
+// headers to be removed are returned as "name". It implements the
+// pflag.SliceValue interface.
+func (h Header) GetSlice() (list []string) {
+	names := make([]string, 0, len(h.Header))
+	for name := range h.Header {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, okA := h.order[names[i]]
+		b, okB := h.order[names[j]]
+		if okA && okB {
+			return a < b
+		}
+		if okA != okB {
+			return okA
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		if headerDefaultValue(h, name) {
+			continue
+		}
+
+		for _, v := range h.Header[name] {
+			list = append(list, name+": "+v)
+		}
+	}
+
+	names = names[:0]
+	for name := range h.Remove {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return append(list, names...)
+}
+
+// Append sets the header s. It implements the pflag.SliceValue interface.
+func (h Header) Append(s string) error {
+	return h.Set(s)
+}
+
+// Replace resets the headers to the default values, then sets all headers in
+// list. It implements the pflag.SliceValue interface.
+func (h Header) Replace(list []string) error {
+	for name := range h.Header {
+		delete(h.Header, name)
+	}
+	for name := range h.Remove {
+		delete(h.Remove, name)
+	}
+	for name := range h.order {
+		delete(h.order, name)
+	}
+	for name, vs := range DefaultHeader {
+		h.Header[name] = vs
+	}
+
+	for _, s := range list {
+		err := h.Set(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // NewHeader initializes a Header.
 func NewHeader(defaults http.Header) *Header {
 	hdr := make(http.Header)
@@ -80,7 +161,18 @@ func NewHeader(defaults http.Header) *Header {
 	return &Header{
 		Header: hdr,
 		Remove: make(map[string]struct{}),
+		order:  make(map[string]int),
+	}
+}
+
+// Names returns the names of the headers in the order in which they were set
+// first.
+func (h Header) Names() []string {
+	names := make([]string, len(h.order))
+	for name, i := range h.order {
+		names[i] = name
 	}
+	return names
 }
 
 // Apply applies the values in h to the target http.Header. The function
@@ -159,16 +251,143 @@ type Request struct {
 	Header *Header
 	Body   string
 
-	UserPass string // user:password for HTTP basic auth
+	DataFile string // send the contents of this file as the body
 
-	TemplateFile string // used to read the request from a file
+	UserPass   string // user:password for HTTP basic auth
+	DigestAuth string // user:password for HTTP digest auth, handled by the transport
+	NTLMAuth   string // domain\user:password for NTLM auth, handled by the transport
+
+	JSON           string   // send this JSON body, inserted values are escaped for JSON strings
+	JSONBodyFile   string   // read the body from this JSON file
+	JSONInject     []string // paths in the JSON body where the value is inserted
+	JSONInjectType string   // JSON type of the inserted value
+	JSONValidate   bool     // return an error if the JSON body is invalid after inserting the value
+
+	GraphQLQuery     string // send a GraphQL request with this query
+	GraphQLVariables string // JSON object with the variables for the GraphQL query
 
-	Replace string // this string is being replaced by a value in a specific http request
+	Form     []string // name=value, send the body as a multipart form with these fields
+	FormFile []string // field=@path, add these files to the multipart form
+
+	TemplateFile string // used to read the request from a file
+	TemplateData []byte // if set, used instead of reading the template file
+
+	ConfigFile            string // read options from this file
+	SaveConfigFile        string // write options to this file
+	SaveConfigWithSecrets bool   // write secrets to the config file instead of referencing environment variables
+	Curl                  string // curl command line to build the request from
+	CurlFile              string // read the curl command line from this file
+	CurlCompat            bool   // mirror the defaults of curl for --data
+
+	Replace      string   // this string is being replaced by a value in a specific http request
+	Placeholders []string // if set, each value contains one value for each of these placeholders instead
+	Encode       []string // transformations applied to the value before it is inserted
+	EncodePath   bool     // let Go percent-encode invalid characters in the path, otherwise it is sent verbatim
+	EncodeQuery  bool     // percent-encode the value inserted into the query string
+	EncodeHeader bool     // percent-encode the value inserted into headers
+	Vars         *Vars    // values for additional placeholders
+
+	PreRequestCommand     string        // the output of this command is used as the value for PreRequestPlaceholder
+	PreRequestPlaceholder string        // name of the placeholder for the output of PreRequestCommand
+	PreRequestInterval    time.Duration // run PreRequestCommand again after this duration
+
+	PrimeRequestFile string        // template file for the prime request
+	PrimeExtract     string        // regexp for extracting the value from the prime response
+	PrimeExtractJSON string        // JSONPath for extracting the value from the prime response body
+	PrimePlaceholder string        // name of the placeholder for the extracted value
+	PrimeInterval    time.Duration // send the prime request again after this duration
+	PrimeEvery       int           // send the prime request again before every nth request
+
+	// OAuth 2.0 client credentials grant, the token is requested and sent by
+	// the transport
+	OAuthTokenURL     string
+	OAuthClientID     string
+	OAuthClientSecret string
+	OAuthScope        string
+
+	// conditional request headers
+	IfModifiedSince string
+	IfNoneMatch     string
+	IfMatch         string
+
+	// HMAC request signing, enabled when HMACHeader is set
+	HMACHeader          string // the signature is written to this header
+	HMACAlgorithm       string
+	HMACEncoding        string
+	HMACSecret          string // the placeholders are replaced in the secret
+	HMACStringToSign    string // template for the string to sign, the placeholders are not replaced
+	HMACTimestampHeader string // the timestamp used for signing is written to this header
+	hmacOnce            sync.Once
+	hmacTmpl            *template.Template
+	hmacErr             error
+
+	AWSSigV4 string // region/service for signing the request with AWS Signature Version 4
+
+	// TLS versions ("1.0" to "1.3") and cipher suites
+	TLSMinVersion string
+	TLSMaxVersion string
+	TLSCiphers    []string
+	TLSKeyLogFile string // append the TLS secrets in NSS key log format to this file
+
+	// TLS client certificate, either from PEM files or from a PKCS#12 file
+	TLSClientKeyFile        string // read the key from this file instead of TLSClientKeyCertFile
+	TLSClientPKCS12File     string
+	TLSClientPKCS12Password string
 
 	Insecure             bool
+	NoSNI                bool   // do not send the server name in the TLS handshake
+	SNI                  string // send this server name in the TLS handshake instead of the host
 	TLSClientKeyCertFile string
+	Resolve              []string // host:port:addr, use addr to connect to host and port
+	ConnectTo            []string // host1:port1:host2:port2, connect to host2:port2 instead
+	TargetIP             string   // connect to this address for all hosts not matched by Resolve
+	Proxy                string   // send all requests through this proxy (http://, https://, socks5://)
+	ProxyChain           []string // connect through these proxies in order
+	ProxyFile            string   // rotate through the proxies listed in this file
+	ProxyRotation        string   // round-robin or random
+	AllowHost            []string // only send requests to hosts matching these patterns
+	DenyHost             []string // never send requests to hosts matching these patterns
+	Resolver             string   // ip:port of the DNS server to use instead of the system resolver
+	DoHURL               string   // resolve host names via DNS over HTTPS with this URL
+	Interface            string   // local IP address to connect from
+	UnixSocket           string   // connect to this Unix domain socket instead of the host in the URL
+	LocalPort            string   // local port or port range (from-to) to connect from
 	DisableHTTP2         bool
+	HTTP2                bool   // send all requests via HTTP/2, with prior knowledge for http URLs
+	H2Authority          string // send this as the :authority pseudo-header for HTTP/2 requests
 	ForceChunkedEncoding bool
+	CompressBody         string        // compress the body with gzip or deflate
+	Raw                  bool          // send the template file verbatim, only the placeholders are replaced
+	PreserveLength       bool          // send the Content-Length header from the template file unchanged
+	RawRequestLine       string        // written instead of the request line
+	RawHeader            []string      // header lines written verbatim after the other headers
+	PreserveHeaderOrder  bool          // write the headers in the order of the template file and --header
+	ChunkSize            int           // split the chunked body into chunks of this size
+	ChunkDelay           time.Duration // wait between the chunks of the body
+	Trailer              []string      // "name: value", trailer headers sent after the chunked body
+	ConnectionClose      bool          // close the connection after each request
+
+	// WebSocket mode, enabled when WebSocketMessage is set: the messages are
+	// sent after the upgrade
+	WebSocketMessage []string
+	WebSocketBinary  bool          // send binary instead of text frames
+	WebSocketFrames  int           // stop waiting after this many response messages, 0 waits for the timeout
+	WebSocketTimeout time.Duration // wait this long for each response message
+
+	RotatingHeader     []string // "Name:@file", send one value from file with each request
+	RotatingHeaderMode string   // round-robin or random
+	UserAgentFile      string   // send one User-Agent from this file with each request
+	rotating           []*rotatingHeader
+
+	Seed    int64 // seed for all pseudo-random values, 0 means a random seed
+	rnd     *lockedRand
+	rndOnce sync.Once
+
+	// BodyMutator, if set, is called with the value and the body of each
+	// request after the placeholders have been replaced and returns the body
+	// to send. It may be called concurrently. Not available on the command
+	// line.
+	BodyMutator func(value string, body []byte) ([]byte, error)
 }
 
 // New returns a new request. If replace is the empty string, "FUZZ" is used.
@@ -177,8 +396,10 @@ func New(replace string) *Request {
 		replace = "FUZZ"
 	}
 	return &Request{
-		Header:  NewHeader(DefaultHeader),
-		Replace: replace,
+		Header:     NewHeader(DefaultHeader),
+		Replace:    replace,
+		Vars:       NewVars(),
+		EncodePath: true,
 	}
 }
 
@@ -190,21 +411,36 @@ func replaceTemplate(s, template, value string) string {
 	return strings.Replace(s, template, value, -1)
 }
 
-func readRequestFromFile(filename string, target *url.URL, replace func([]byte) []byte) (*http.Request, error) {
+func readRequestFromFile(filename string, target *url.URL, replace func([]byte) []byte, verbatimPath bool) (*http.Request, error) {
 	buf, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
+	return readRequest(buf, filename, target, replace, verbatimPath)
+}
+
+func readRequest(buf []byte, filename string, target *url.URL, replace func([]byte) []byte, verbatimPath bool) (*http.Request, error) {
 	// replace the placeholder in the file we just read
 	buf = replace(buf)
 
+	// the path is removed before parsing so that it is not changed, and may
+	// even contain invalid percent-encoded sequences
+	var path string
+	if verbatimPath {
+		buf, path = cutTemplatePath(buf)
+	}
+
 	rd := bufio.NewReader(bytes.NewReader(buf))
 	req, err := http.ReadRequest(rd)
 	if err != nil {
 		return nil, fmt.Errorf("error reading HTTP request from %v: %v", filename, err)
 	}
 
+	if path != "" {
+		setVerbatimPath(req.URL, path)
+	}
+
 	// append the rest of the file to the body
 	rest, err := ioutil.ReadAll(rd)
 	if err == io.EOF {
@@ -243,6 +479,11 @@ func readRequestFromFile(filename string, target *url.URL, replace func([]byte)
 	req.URL.Scheme = target.Scheme
 	req.URL.Host = target.Host
 
+	// the fragment is not sent, but kept for the records
+	if target.Fragment != "" {
+		req.URL.Fragment = target.Fragment
+	}
+
 	// RequestURI must be empty for client requests
 	req.RequestURI = ""
 
@@ -253,28 +494,153 @@ func readRequestFromFile(filename string, target *url.URL, replace func([]byte)
 	return req, nil
 }
 
+// applyAuth sets the Authorization header. The credentials passed via --user
+// take precedence over the user info in the URL, which in turn takes
+// precedence over an Authorization header from the template file. The header
+// from the template is kept if neither is set. Headers set with --header are
+// applied afterwards and override all of them.
+func (r *Request) applyAuth(req *http.Request, insertValue func(string) string) {
+	switch {
+	case r.UserPass != "":
+		req.SetBasicAuth(splitUserPass(insertValue(r.UserPass)))
+	case req.URL.User != nil:
+		u := req.URL.User.Username()
+		p, _ := req.URL.User.Password()
+		req.SetBasicAuth(u, p)
+	}
+
+	// the credentials are in the header now, remove them from the URL so the
+	// http.Client does not add them again when the header has been removed
+	// via --header
+	req.URL.User = nil
+}
+
+// splitUserPass splits s in the form "user:password", the password may be
+// empty.
+func splitUserPass(s string) (user, password string) {
+	data := strings.SplitN(s, ":", 2)
+	if len(data) > 1 {
+		return data[0], data[1]
+	}
+	return data[0], ""
+}
+
+// Credentials returns the user and password from s ("user:password") with
+// value inserted, like for the other fields of the request. It is used for
+// authentication schemes which are handled by the transport.
+func (r *Request) Credentials(s, value string) (user, password string, err error) {
+	value, err = r.encodeValue(value)
+	if err != nil {
+		return "", "", err
+	}
+
+	user, password = splitUserPass(r.replacer(value).Replace(s))
+	return user, password, nil
+}
+
 // Apply replaces the template with value in all fields of the request and
 // returns a new http.Request.
 func (r *Request) Apply(value string) (*http.Request, error) {
-	insertValue := func(s string) string {
-		return replaceTemplate(s, r.Replace, value)
+	return r.apply(value, "", nil)
+}
+
+// Prepare must be called once before requests are built with ApplyNext. It
+// checks the sources for the body, parses the template for HMAC signing,
+// loads the values for rotating headers and runs the pre-request command.
+func (r *Request) Prepare(ctx context.Context) error {
+	err := r.checkDataFile()
+	if err != nil {
+		return err
+	}
+
+	if r.HMACHeader != "" {
+		_, err = r.hmacTemplate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.rotating == nil && r.hasRotatingHeaders() {
+		err = r.loadRotatingHeaders()
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.RunPreRequestCommand(ctx)
+}
+
+// apply builds the request for value, the headers in extra are set last (but
+// before the request is signed). If method is not empty, it overrides the
+// configured method.
+func (r *Request) apply(value, method string, extra http.Header) (*http.Request, error) {
+	value, err := r.encodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	replacer := r.replacer(value)
+	insertValue := replacer.Replace
+	insertQuery := r.queryReplacer(replacer, value).Replace
+	insertHeader := r.headerReplacer(replacer, value).Replace
+
+	targetURL := insertURL(r.URL, insertValue, insertQuery)
+
+	if r.Raw {
+		return r.applyRaw(targetURL, insertValue)
 	}
 
-	targetURL := insertValue(r.URL)
 	body := []byte(insertValue(r.Body))
 
+	if r.JSONBodyFile != "" {
+		body, err = r.jsonBody(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.JSON != "" {
+		body, err = r.jsonTemplateBody(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.GraphQLQuery != "" {
+		body, err = r.graphQLBody(value)
+		if err != nil {
+			return nil, err
+		}
+	} else if r.GraphQLVariables != "" {
+		return nil, errors.New("--graphql-variables requires --graphql-query")
+	}
+
+	var formContentType string
+	if len(r.Form) > 0 || len(r.FormFile) > 0 {
+		body, formContentType, err = r.multipartBody(insertValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var req *http.Request
 
 	// if a template file is given, read the HTTP request from it as a basis
-	if r.TemplateFile != "" {
+	if r.TemplateFile != "" || r.TemplateData != nil {
 		target, err := url.Parse(targetURL)
 		if err != nil {
 			return nil, err
 		}
 
-		req, err = readRequestFromFile(r.TemplateFile, target, func(buf []byte) []byte {
-			return bytes.Replace(buf, []byte(r.Replace), []byte(value), -1)
-		})
+		replace := func(buf []byte) []byte {
+			return insertTemplate(buf, insertValue, insertQuery, insertHeader)
+		}
+
+		if r.TemplateData != nil {
+			req, err = readRequest(r.TemplateData, r.TemplateFile, target, replace, !r.EncodePath)
+		} else {
+			req, err = readRequestFromFile(r.TemplateFile, target, replace, !r.EncodePath)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -285,57 +651,137 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 			req.ContentLength = int64(len(body))
 		}
 
-		if r.Method != "" {
+		if method != "" {
+			req.Method = method
+		} else if r.Method != "" {
 			req.Method = insertValue(r.Method)
 		}
 
 	} else {
 		var err error
 
+		if r.PreserveLength {
+			return nil, errors.New("--preserve-length requires a template file")
+		}
+
+		if method == "" {
+			method = insertValue(r.Method)
+		}
+		if r.CurlCompat && method == "" && (r.Body != "" || r.DataFile != "" || r.JSON != "" || formContentType != "") {
+			// like curl, send data with POST by default
+			method = http.MethodPost
+		}
+		if method == "" && r.GraphQLQuery != "" {
+			// GraphQL queries are sent with POST
+			method = http.MethodPost
+		}
+
+		// the path is set after parsing the URL so that it is sent unchanged
+		var path string
+		if !r.EncodePath {
+			targetURL, path = splitPath(targetURL)
+		}
+
 		// create new request from scratch
-		req, err = http.NewRequest(insertValue(r.Method), targetURL, bytes.NewReader(body))
+		req, err = http.NewRequest(method, targetURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		if path != "" {
+			setVerbatimPath(req.URL, path)
+		}
+	}
+
+	if r.DataFile != "" {
+		err = r.dataFileBody(req, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.BodyMutator != nil {
+		err = r.mutateBody(req, value)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if r.CompressBody != "" {
+		err = r.compressBody(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.JSONBodyFile != "" || r.JSON != "" || r.GraphQLQuery != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if formContentType != "" {
+		req.Header.Set("Content-Type", formContentType)
+	}
+
+	if r.CurlCompat && r.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
 	if r.ForceChunkedEncoding {
+		if r.RawWriter() {
+			return nil, errors.New("--force-chunked-encoding cannot be used together with --preserve-length, --preserve-header-order, --raw-request-line, --raw-header, multiple User-Agent headers or a removed Host header")
+		}
 		req.ContentLength = -1
 	}
 
-	// if the URL has user and password, use that
-	if req.URL.User != nil {
-		u := req.URL.User.Username()
-		p, _ := req.URL.User.Password()
-		req.SetBasicAuth(u, p)
+	if len(r.Trailer) > 0 {
+		err = r.applyTrailer(req, insertHeader)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// but if an explicit user and pass is specified, override them again
-	if r.UserPass != "" {
-		data := strings.SplitN(strings.Replace(r.UserPass, r.Replace, value, -1), ":", 2)
-		u := data[0]
-		p := ""
-		if len(data) > 1 {
-			p = data[1]
+	if r.WebSocket() {
+		err = r.applyWebSocket(req)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		req.SetBasicAuth(u, p)
+	// close the connection after the request, Go sends "Connection: close"
+	if r.ConnectionClose {
+		req.Close = true
 	}
 
+	r.applyAuth(req, insertValue)
+
 	// make sure there's a valid path
 	if req.URL.Path == "" {
 		req.URL.Path = "/"
 	}
 
+	// set conditional headers, they can be overwritten by the template headers
+	err = r.applyConditional(req.Header, insertHeader)
+	if err != nil {
+		return nil, err
+	}
+
 	// apply template headers
-	r.Header.Apply(req.Header, insertValue)
+	r.Header.Apply(req.Header, insertHeader)
 
 	// special handling for the Host header, which needs to be set on the
 	// request field Host
 	for k, v := range r.Header.Header {
 		if textproto.CanonicalMIMEHeaderKey(k) == "Host" {
-			req.Host = insertValue(v[0])
+			req.Host = insertHeader(v[0])
+		}
+	}
+
+	for name, v := range extra {
+		if name == "Host" {
+			req.Host = v[0]
+			continue
 		}
+		req.Header[name] = v
 	}
 
 	for k := range r.Header.Remove {
@@ -348,19 +794,88 @@ func (r *Request) Apply(value string) (*http.Request, error) {
 			req.Header.Set("User-Agent", "")
 		}
 
-		// known limitation: due to the way the Go stdlib handles setting the
-		// user-agent header, it's currently not possible to send a request with
-		// multiple user-agent headers.
+		// the Go stdlib sends only the first User-Agent header and always adds
+		// the Host header, such requests are sent by the raw writer instead
+		// (see RawWriter)
+	}
 
-		// special handling if the Host header is to be removed
-		if name == "Host" {
-			return nil, errors.New("request without Host header is not supported")
+	// sign the request as the last step, when all data is final
+	if r.HMACHeader != "" {
+		body, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.signHMAC(req, body, value, insertValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.AWSSigV4 != "" {
+		body, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.signAWS(req, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.ChunkDelay > 0 || r.ChunkSize != 0 {
+		err = r.applyChunks(req)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return req, nil
 }
 
+// Authority returns the value for the :authority pseudo-header of HTTP/2
+// requests for value.
+func (r *Request) Authority(value string) string {
+	return r.replacer(value).Replace(r.H2Authority)
+}
+
+// readBody returns the body of req and replaces req.Body so it can be read again.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	return buf, nil
+}
+
+// mutateBody replaces the body of req with the one returned by BodyMutator.
+func (r *Request) mutateBody(req *http.Request, value string) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	body, err = r.BodyMutator(value, body)
+	if err != nil {
+		return fmt.Errorf("body mutator: %v", err)
+	}
+
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+
+	return nil
+}
+
 // Target returns the host and port for the request.
 func Target(req *http.Request) (host, port string, err error) {
 	port = req.URL.Port()
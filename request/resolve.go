@@ -0,0 +1,292 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// splitAddressList splits s at each colon, but keeps IPv6 addresses enclosed
+// in square brackets together. The brackets are removed.
+func splitAddressList(s string) (parts []string, err error) {
+	for len(s) > 0 {
+		var part string
+		if s[0] == '[' {
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing bracket in %q", s)
+			}
+			part = s[1:end]
+			s = s[end+1:]
+			if len(s) > 0 && s[0] != ':' {
+				return nil, fmt.Errorf("unexpected data after closing bracket: %q", s)
+			}
+		} else {
+			end := strings.IndexByte(s, ':')
+			if end < 0 {
+				end = len(s)
+			}
+			part = s[:end]
+			s = s[end:]
+		}
+
+		parts = append(parts, part)
+
+		if len(s) > 0 {
+			// remove the colon
+			s = s[1:]
+			if len(s) == 0 {
+				// trailing colon means the last part is empty
+				parts = append(parts, "")
+			}
+		}
+	}
+
+	return parts, nil
+}
+
+func validPort(s string) error {
+	port, err := strconv.Atoi(s)
+	if err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid port %q", s)
+	}
+	return nil
+}
+
+// dialOverride rewrites the address a connection is established to.
+type dialOverride struct {
+	host, port       string // empty host or port (or "*") matches all
+	newHost, newPort string // empty host or port means no change
+}
+
+func (d dialOverride) apply(host, port string) (string, string, bool) {
+	if d.host != "" && d.host != "*" && !strings.EqualFold(d.host, host) {
+		return host, port, false
+	}
+
+	if d.port != "" && d.port != port {
+		return host, port, false
+	}
+
+	if d.newPort != "" {
+		port = d.newPort
+	}
+
+	if d.newHost != "" {
+		host = d.newHost
+	}
+
+	return host, port, true
+}
+
+// parseResolve parses an entry for --resolve in the format host:port:addr.
+// Everything after the port is used as the address, so IPv6 addresses may be
+// specified with or without square brackets.
+func parseResolve(s string) (dialOverride, error) {
+	parts, err := splitAddressList(s)
+	if err != nil {
+		return dialOverride{}, fmt.Errorf("invalid resolve entry %q: %v", s, err)
+	}
+
+	if len(parts) < 3 {
+		return dialOverride{}, fmt.Errorf("invalid resolve entry %q, expected host:port:addr", s)
+	}
+
+	if err := validPort(parts[1]); err != nil {
+		return dialOverride{}, fmt.Errorf("invalid resolve entry %q: %v", s, err)
+	}
+
+	addr := strings.Join(parts[2:], ":")
+	if net.ParseIP(addr) == nil {
+		return dialOverride{}, fmt.Errorf("invalid resolve entry %q: %q is not an IP address", s, addr)
+	}
+
+	return dialOverride{host: parts[0], port: parts[1], newHost: addr}, nil
+}
+
+// parseConnectTo parses an entry for --connect-to in the format
+// host1:port1:host2:port2. Both host1 and port1 may be empty to match all
+// hosts or ports, and host2 or port2 may be empty to keep the original value.
+func parseConnectTo(s string) (dialOverride, error) {
+	parts, err := splitAddressList(s)
+	if err != nil {
+		return dialOverride{}, fmt.Errorf("invalid connect-to entry %q: %v", s, err)
+	}
+
+	if len(parts) != 4 {
+		return dialOverride{}, fmt.Errorf("invalid connect-to entry %q, expected host1:port1:host2:port2", s)
+	}
+
+	for _, port := range []string{parts[1], parts[3]} {
+		if port == "" {
+			continue
+		}
+		if err := validPort(port); err != nil {
+			return dialOverride{}, fmt.Errorf("invalid connect-to entry %q: %v", s, err)
+		}
+	}
+
+	if strings.ContainsAny(parts[2], "/ ") {
+		return dialOverride{}, fmt.Errorf("invalid connect-to entry %q: invalid host %q", s, parts[2])
+	}
+
+	return dialOverride{host: parts[0], port: parts[1], newHost: parts[2], newPort: parts[3]}, nil
+}
+
+// parseTargetIP parses the address for --target-ip, IPv6 addresses may be
+// enclosed in square brackets.
+func parseTargetIP(s string) (string, error) {
+	addr := strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if net.ParseIP(addr) == nil {
+		return "", fmt.Errorf("invalid target IP %q: not an IP address", s)
+	}
+	return addr, nil
+}
+
+// OverridesDial returns true if the address to connect to is changed by
+// --resolve, --connect-to or --target-ip.
+func (r *Request) OverridesDial() bool {
+	return len(r.Resolve) > 0 || len(r.ConnectTo) > 0 || r.TargetIP != ""
+}
+
+// DialAddress returns the address to connect to for addr (host:port) after
+// the entries for --connect-to and --resolve have been applied, with value
+// (and the other placeholders) inserted in the same way as for the request.
+// The entries for --connect-to are evaluated first, the first matching one is
+// used. The resulting address is then used for --resolve. If no entry for
+// --resolve matches, the host is replaced by the target IP (if set).
+func (r *Request) DialAddress(value, addr string) (string, error) {
+	replacer := r.replacer(value)
+	return r.dialAddress(addr, func(entry string) (string, bool) {
+		return replacer.Replace(entry), true
+	})
+}
+
+// StaticDialAddress returns the address to connect to for addr like
+// DialAddress, but only the entries which do not depend on a value are used.
+// It is used for requests which are not built for a value, e.g. the OAuth
+// token request.
+func (r *Request) StaticDialAddress(addr string) (string, error) {
+	pairs := r.replacePairs("")
+	return r.dialAddress(addr, func(entry string) (string, bool) {
+		return entry, !dependsOnValue(entry, pairs)
+	})
+}
+
+// dialAddress applies the entries for --connect-to, --resolve and
+// --target-ip to addr. The function use returns the entry with the
+// placeholders inserted and whether it is used at all.
+func (r *Request) dialAddress(addr string, use func(entry string) (string, bool)) (string, error) {
+	if !r.OverridesDial() {
+		return addr, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range r.ConnectTo {
+		entry, ok := use(entry)
+		if !ok {
+			continue
+		}
+
+		o, err := parseConnectTo(entry)
+		if err != nil {
+			return "", err
+		}
+
+		host, port, ok = o.apply(host, port)
+		if ok {
+			break
+		}
+	}
+
+	resolved := false
+	for _, entry := range r.Resolve {
+		entry, ok := use(entry)
+		if !ok {
+			continue
+		}
+
+		o, err := parseResolve(entry)
+		if err != nil {
+			return "", err
+		}
+
+		host, port, resolved = o.apply(host, port)
+		if resolved {
+			break
+		}
+	}
+
+	if !resolved && r.TargetIP != "" {
+		if entry, ok := use(r.TargetIP); ok {
+			host, err = parseTargetIP(entry)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// DialDependsOnValue returns true if a placeholder (for the value, one of the
+// values for --value, a variable or a dynamic placeholder) is used in the
+// entries for --resolve, --connect-to or --target-ip, so the address to
+// connect to may change for each request.
+func (r *Request) DialDependsOnValue() bool {
+	pairs := r.replacePairs("")
+	for _, list := range [][]string{r.Resolve, r.ConnectTo, {r.TargetIP}} {
+		for _, entry := range list {
+			if dependsOnValue(entry, pairs) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dependsOnValue returns true if entry contains a dynamic placeholder or one
+// of the placeholders in pairs (as returned by replacePairs).
+func dependsOnValue(entry string, pairs []string) bool {
+	if len(dynamicPlaceholders(entry)) > 0 {
+		return true
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		if pairs[i] != "" && strings.Contains(entry, pairs[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type contextKey int
+
+const valueKey contextKey = 0
+
+// NewContext returns a context which carries the value inserted into the
+// request. It is used by the transport to find the address to connect to.
+func NewContext(ctx context.Context, value string) context.Context {
+	return context.WithValue(ctx, valueKey, value)
+}
+
+// NewContextWithoutValue returns a context for requests which are not built
+// for a value (e.g. the OAuth token request), a value stored in ctx is
+// removed. The entries for --resolve, --connect-to and --target-ip which
+// depend on the value are not used for these requests.
+func NewContextWithoutValue(ctx context.Context) context.Context {
+	return context.WithValue(ctx, valueKey, nil)
+}
+
+// FromContext returns the value stored in ctx, if any.
+func FromContext(ctx context.Context) (value string, ok bool) {
+	value, ok = ctx.Value(valueKey).(string)
+	return value, ok
+}
@@ -0,0 +1,286 @@
+package request
+
+import "testing"
+
+func TestDialAddress(t *testing.T) {
+	var tests = []struct {
+		resolve   []string
+		connectTo []string
+		targetIP  string
+		value     string
+		addr      string
+		want      string
+		err       bool
+	}{
+		{
+			addr: "www.example.com:443",
+			want: "www.example.com:443",
+		},
+		{
+			resolve: []string{"www.example.com:443:192.168.1.1"},
+			addr:    "www.example.com:443",
+			want:    "192.168.1.1:443",
+		},
+		{
+			// port does not match
+			resolve: []string{"www.example.com:443:192.168.1.1"},
+			addr:    "www.example.com:80",
+			want:    "www.example.com:80",
+		},
+		{
+			resolve: []string{"*:443:[2001:db8::1]"},
+			addr:    "other.example.com:443",
+			want:    "[2001:db8::1]:443",
+		},
+		{
+			resolve: []string{"www.example.com:443:10.0.0.FUZZ"},
+			value:   "23",
+			addr:    "www.example.com:443",
+			want:    "10.0.0.23:443",
+		},
+		{
+			resolve: []string{"www.example.com:443:FUZZ"},
+			value:   "not-an-ip",
+			addr:    "www.example.com:443",
+			err:     true,
+		},
+		{
+			resolve: []string{"www.example.com:FUZZ:192.168.1.1"},
+			value:   "99999",
+			addr:    "www.example.com:443",
+			err:     true,
+		},
+		{
+			connectTo: []string{"www.example.com:443:backend:8443"},
+			addr:      "www.example.com:443",
+			want:      "backend:8443",
+		},
+		{
+			connectTo: []string{"::FUZZ:"},
+			value:     "backend-3.local",
+			addr:      "www.example.com:443",
+			want:      "backend-3.local:443",
+		},
+		{
+			// --resolve is evaluated after --connect-to
+			connectTo: []string{"www.example.com:443:backend:8443"},
+			resolve:   []string{"backend:8443:FUZZ"},
+			value:     "127.0.0.2",
+			addr:      "www.example.com:443",
+			want:      "127.0.0.2:8443",
+		},
+		{
+			connectTo: []string{"www.example.com:443:host with space:8443"},
+			addr:      "www.example.com:443",
+			err:       true,
+		},
+		{
+			connectTo: []string{"www.example.com:443"},
+			addr:      "www.example.com:443",
+			err:       true,
+		},
+		{
+			targetIP: "192.0.2.10",
+			addr:     "www.example.com:443",
+			want:     "192.0.2.10:443",
+		},
+		{
+			targetIP: "[2001:db8::1]",
+			addr:     "www.example.com:80",
+			want:     "[2001:db8::1]:80",
+		},
+		{
+			// --resolve takes precedence
+			resolve:  []string{"www.example.com:443:192.168.1.1"},
+			targetIP: "192.0.2.10",
+			addr:     "www.example.com:443",
+			want:     "192.168.1.1:443",
+		},
+		{
+			connectTo: []string{"www.example.com:443:backend:8443"},
+			targetIP:  "192.0.2.FUZZ",
+			value:     "7",
+			addr:      "www.example.com:443",
+			want:      "192.0.2.7:8443",
+		},
+		{
+			targetIP: "target.example.com",
+			addr:     "www.example.com:443",
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.Resolve = test.resolve
+			r.ConnectTo = test.connectTo
+			r.TargetIP = test.targetIP
+
+			got, err := r.DialAddress(test.value, test.addr)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error not found, got %q", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Fatalf("wrong address, want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestDialAddressValues(t *testing.T) {
+	var tests = []struct {
+		placeholders []string
+		resolve      []string
+		connectTo    []string
+		targetIP     string
+		value        string
+		want         string
+		dependsOn    bool
+	}{
+		{
+			// the placeholder for the value is not replaced by the
+			// combined values when --value is used
+			placeholders: []string{"HOST", "PORT"},
+			connectTo:    []string{"::HOST:PORT"},
+			value:        "backend" + ValueSeparator + "8443",
+			want:         "backend:8443",
+			dependsOn:    true,
+		},
+		{
+			placeholders: []string{"USER", "IP"},
+			targetIP:     "IP",
+			value:        "admin" + ValueSeparator + "192.0.2.23",
+			want:         "192.0.2.23:443",
+			dependsOn:    true,
+		},
+		{
+			placeholders: []string{"USER", "IP"},
+			resolve:      []string{"www.example.com:443:10.0.0.IP"},
+			value:        "admin" + ValueSeparator + "5",
+			want:         "10.0.0.5:443",
+			dependsOn:    true,
+		},
+		{
+			// variables are inserted as well
+			connectTo: []string{"::SHARD.internal:"},
+			value:     "foo",
+			want:      "shard-7.internal:443",
+			dependsOn: true,
+		},
+		{
+			placeholders: []string{"USER", "IP"},
+			targetIP:     "192.0.2.1",
+			value:        "admin" + ValueSeparator + "5",
+			want:         "192.0.2.1:443",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.Placeholders = test.placeholders
+			r.Resolve = test.resolve
+			r.ConnectTo = test.connectTo
+			r.TargetIP = test.targetIP
+			r.Vars.Set("SHARD", "shard-7")
+
+			got, err := r.DialAddress(test.value, "www.example.com:443")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("wrong address, want %q, got %q", test.want, got)
+			}
+
+			if r.DialDependsOnValue() != test.dependsOn {
+				t.Errorf("DialDependsOnValue returned %v, want %v", !test.dependsOn, test.dependsOn)
+			}
+		})
+	}
+}
+
+func TestDialDependsOnValue(t *testing.T) {
+	var tests = []struct {
+		resolve  []string
+		targetIP string
+		want     bool
+	}{
+		{want: false},
+		{targetIP: "192.0.2.1", want: false},
+		{targetIP: "192.0.2.FUZZ", want: true},
+		{resolve: []string{"www.example.com:443:192.0.2.1"}, want: false},
+		{resolve: []string{"FUZZ.example.com:443:192.0.2.1"}, want: true},
+		{resolve: []string{"www.example.com:443:192.0.2.{{RANDSTR:1}}"}, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.Resolve = test.resolve
+			r.TargetIP = test.targetIP
+
+			if r.DialDependsOnValue() != test.want {
+				t.Errorf("DialDependsOnValue returned %v, want %v", !test.want, test.want)
+			}
+		})
+	}
+}
+
+func TestStaticDialAddress(t *testing.T) {
+	var tests = []struct {
+		resolve   []string
+		connectTo []string
+		targetIP  string
+		want      string
+	}{
+		{want: "auth.example.com:443"},
+		{targetIP: "FUZZ", want: "auth.example.com:443"},
+		{targetIP: "192.0.2.1", want: "192.0.2.1:443"},
+		{
+			resolve:  []string{"auth.example.com:443:192.0.2.FUZZ"},
+			targetIP: "192.0.2.1",
+			want:     "192.0.2.1:443",
+		},
+		{
+			resolve:  []string{"auth.example.com:443:192.0.2.FUZZ", "auth.example.com:443:192.0.2.5"},
+			targetIP: "192.0.2.1",
+			want:     "192.0.2.5:443",
+		},
+		{
+			connectTo: []string{"::FUZZ.internal:", "auth.example.com::idp.internal:8443"},
+			want:      "idp.internal:8443",
+		},
+		{
+			connectTo: []string{"auth.example.com::{{RANDSTR:4}}.internal:"},
+			want:      "auth.example.com:443",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			r.Resolve = test.resolve
+			r.ConnectTo = test.connectTo
+			r.TargetIP = test.targetIP
+
+			got, err := r.StaticDialAddress("auth.example.com:443")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("wrong address, want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
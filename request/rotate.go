@@ -0,0 +1,160 @@
+package request
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+)
+
+// rotatingHeader is a header with a list of values, one of which is sent with
+// each request.
+type rotatingHeader struct {
+	name   string
+	values []string
+	random *lockedRand // if set, values are selected randomly
+
+	mu   sync.Mutex
+	next int
+}
+
+// pick returns the value for the next request.
+func (h *rotatingHeader) pick() string {
+	if h.random != nil {
+		return h.values[h.random.Intn(len(h.values))]
+	}
+
+	h.mu.Lock()
+	v := h.values[h.next]
+	h.next = (h.next + 1) % len(h.values)
+	h.mu.Unlock()
+
+	return v
+}
+
+// readLines returns all non-empty lines from filename.
+func readLines(filename string) (lines []string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	err = sc.Err()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return lines, f.Close()
+}
+
+// parseRotatingHeader parses a header in the form "Name:@file" and loads the
+// values from file.
+func parseRotatingHeader(s string, mode string, rnd *lockedRand) (*rotatingHeader, error) {
+	data := strings.SplitN(s, ":", 2)
+	if len(data) != 2 || !strings.HasPrefix(strings.TrimSpace(data[1]), "@") {
+		return nil, fmt.Errorf("invalid rotating header %q, format is \"Name:@file\"", s)
+	}
+
+	name := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(data[0]))
+	if name == "" {
+		return nil, fmt.Errorf("invalid rotating header %q: empty name", s)
+	}
+
+	h := &rotatingHeader{name: name}
+
+	switch mode {
+	case "round-robin", "":
+	case "random":
+		h.random = rnd
+	default:
+		return nil, fmt.Errorf("unknown rotating header mode %q", mode)
+	}
+
+	filename := strings.TrimPrefix(strings.TrimSpace(data[1]), "@")
+	values, err := readLines(filename)
+	if err != nil {
+		return nil, fmt.Errorf("rotating header %v: %v", name, err)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("rotating header %v: file %v does not contain any values", name, filename)
+	}
+	h.values = values
+
+	return h, nil
+}
+
+// hasRotatingHeaders returns true if rotating headers are configured.
+func (r *Request) hasRotatingHeaders() bool {
+	return len(r.RotatingHeader) > 0 || r.UserAgentFile != ""
+}
+
+// loadRotatingHeaders reads the files for the rotating headers. The values
+// from UserAgentFile are handled as a rotating User-Agent header.
+func (r *Request) loadRotatingHeaders() error {
+	headers := r.RotatingHeader
+	if r.UserAgentFile != "" {
+		headers = append(headers[:len(headers):len(headers)], "User-Agent:@"+r.UserAgentFile)
+	}
+
+	r.rotating = nil
+	for i, s := range headers {
+		h, err := parseRotatingHeader(s, r.RotatingHeaderMode, r.random())
+		if err != nil {
+			return err
+		}
+
+		if r.UserAgentFile != "" && i < len(r.RotatingHeader) && h.name == "User-Agent" {
+			return errors.New("--user-agent-file cannot be used together with a rotating User-Agent header")
+		}
+
+		r.rotating = append(r.rotating, h)
+	}
+
+	return nil
+}
+
+// nextRotatingHeaders returns the values of the rotating headers for the next
+// request. It is safe for concurrent use.
+func (r *Request) nextRotatingHeaders() http.Header {
+	if len(r.rotating) == 0 {
+		return nil
+	}
+
+	hdr := make(http.Header, len(r.rotating))
+	for _, h := range r.rotating {
+		hdr[h.name] = []string{h.pick()}
+	}
+
+	return hdr
+}
+
+// ApplyNext works like Apply, but also sets the next values for the rotating
+// headers. Prepare must have been called before.
+func (r *Request) ApplyNext(value string) (*http.Request, error) {
+	return r.ApplyNextMethod(value, "")
+}
+
+// ApplyNextMethod works like ApplyNext, but uses method instead of the method
+// configured for the request (or from the template file) unless it is empty.
+func (r *Request) ApplyNextMethod(value, method string) (*http.Request, error) {
+	if r.hasRotatingHeaders() && r.rotating == nil {
+		return nil, errors.New("rotating headers have not been loaded")
+	}
+
+	return r.apply(value, method, r.nextRotatingHeaders())
+}
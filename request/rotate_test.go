@@ -0,0 +1,179 @@
+package request
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func writeTempFile(t testing.TB, data string) (filename string, cleanup func()) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename = filepath.Join(tempdir, "file.txt")
+	err = ioutil.WriteFile(filename, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filename, func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRotatingHeader(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "10.0.0.1\r\n10.0.0.2\n\n10.0.0.3\n")
+	defer cleanup()
+
+	r, _ := newTestFlags(t, []string{
+		"--rotating-header", "x-forwarded-for:@" + filename,
+		"--header", "X-Forwarded-For: 127.0.0.1",
+		"--header", "X-Value: FUZZ",
+	})
+	r.URL = "http://www.example.com"
+
+	err := r.Prepare(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1", "10.0.0.2"}
+	for i, ip := range want {
+		value := string(rune('a' + i))
+		req, err := r.ApplyNext(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := req.Header["X-Forwarded-For"]; len(got) != 1 || got[0] != ip {
+			t.Errorf("request %d: wrong X-Forwarded-For header, want %q, got %q", i, ip, got)
+		}
+
+		if got := req.Header.Get("X-Value"); got != value {
+			t.Errorf("request %d: wrong X-Value header, want %q, got %q", i, value, got)
+		}
+	}
+}
+
+func TestRotatingHeaderConcurrent(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "a\nb\nc\nd\n")
+	defer cleanup()
+
+	for _, mode := range []string{"round-robin", "random"} {
+		t.Run(mode, func(t *testing.T) {
+			r, _ := newTestFlags(t, []string{
+				"--rotating-header", "X-Test:@" + filename,
+				"--rotating-header-mode", mode,
+			})
+			r.URL = "http://www.example.com"
+
+			err := r.Prepare(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var mu sync.Mutex
+			seen := make(map[string]int)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						req, err := r.ApplyNext("")
+						if err != nil {
+							t.Error(err)
+							return
+						}
+
+						mu.Lock()
+						seen[req.Header.Get("X-Test")]++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			for v, n := range seen {
+				switch v {
+				case "a", "b", "c", "d":
+				default:
+					t.Errorf("unexpected value %q", v)
+				}
+
+				if mode == "round-robin" && n != 100 {
+					t.Errorf("value %q used %d times, want 100", v, n)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingHeaderInvalid(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "\n\n")
+	defer cleanup()
+
+	var tests = [][]string{
+		{"--rotating-header", "X-Test: foo"},
+		{"--rotating-header", ":@" + filename},
+		{"--rotating-header", "X-Test:@" + filename},
+		{"--rotating-header", "X-Test:@" + filename + ".missing"},
+		{"--rotating-header", "X-Test:@" + filename, "--rotating-header-mode", "foo"},
+	}
+
+	for _, args := range tests {
+		t.Run("", func(t *testing.T) {
+			r, _ := newTestFlags(t, args)
+			err := r.Prepare(context.Background())
+			if err == nil {
+				t.Fatalf("expected error not returned for %v", args)
+			}
+		})
+	}
+}
+
+func TestUserAgentFile(t *testing.T) {
+	filename, cleanup := writeTempFile(t, "agent1\nagent2\n")
+	defer cleanup()
+
+	r, _ := newTestFlags(t, []string{
+		"--user-agent-file", filename,
+		"--header", "User-Agent: other",
+	})
+	r.URL = "http://www.example.com"
+
+	err := r.Prepare(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"agent1", "agent2", "agent1"}
+	for i, agent := range want {
+		req, err := r.ApplyNext("x")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := req.Header["User-Agent"]; len(got) != 1 || got[0] != agent {
+			t.Errorf("request %d: wrong User-Agent header, want %q, got %q", i, agent, got)
+		}
+	}
+
+	r, _ = newTestFlags(t, []string{
+		"--user-agent-file", filename,
+		"--rotating-header", "user-agent:@" + filename,
+	})
+	err = r.Prepare(context.Background())
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+}
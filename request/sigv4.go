@@ -0,0 +1,202 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// AWS Signature Version 4, see
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+
+const awsAlgorithm = "AWS4-HMAC-SHA256"
+
+// awsCredentials returns the credentials from the environment variables used
+// by the AWS CLI and SDKs.
+func awsCredentials() (accessKey, secretKey, sessionToken string, err error) {
+	accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return "", "", "", errors.New("AWS signing: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	return accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"), nil
+}
+
+// parseAWSScope parses "region/service".
+func parseAWSScope(s string) (region, service string, err error) {
+	data := strings.Split(s, "/")
+	if len(data) != 2 || data[0] == "" || data[1] == "" {
+		return "", "", fmt.Errorf("AWS signing: invalid region/service %q", s)
+	}
+	return data[0], data[1], nil
+}
+
+// awsEscape URI encodes s as required for the canonical request: all bytes
+// except the unreserved characters are percent-encoded. If keepSlash is set,
+// "/" is not encoded.
+func awsEscape(s string, keepSlash bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			buf.WriteByte(c)
+		case c == '/' && keepSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// awsCanonicalQuery returns the query string with all names and values
+// encoded and sorted.
+func awsCanonicalQuery(rawQuery string) string {
+	var params []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		data := strings.SplitN(pair, "=", 2)
+		name, err := url.QueryUnescape(data[0])
+		if err != nil {
+			name = data[0]
+		}
+
+		value := ""
+		if len(data) == 2 {
+			value, err = url.QueryUnescape(data[1])
+			if err != nil {
+				value = data[1]
+			}
+		}
+
+		params = append(params, awsEscape(name, false)+"="+awsEscape(value, false))
+	}
+
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsSignedHeader returns true if the header name is included in the
+// signature.
+func awsSignedHeader(name string) bool {
+	return name == "host" || name == "content-type" || strings.HasPrefix(name, "x-amz-")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signAWS signs the request with AWS Signature Version 4 for the region and
+// service configured in AWSSigV4 and sets the Authorization header. The body
+// is the final body of the request.
+func (r *Request) signAWS(req *http.Request, body []byte) error {
+	region, service, err := parseAWSScope(r.AWSSigV4)
+	if err != nil {
+		return err
+	}
+
+	accessKey, secretKey, sessionToken, err := awsCredentials()
+	if err != nil {
+		return err
+	}
+
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	bodyHash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(bodyHash[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	// collect the headers to sign
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if !awsSignedHeader(name) || name == "host" {
+			continue
+		}
+
+		trimmed := make([]string, 0, len(values))
+		for _, v := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(v), " "))
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// the path is encoded twice for all services except S3
+	path := req.URL.EscapedPath()
+	if service != "s3" {
+		path = awsEscape(path, true)
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		awsCanonicalQuery(req.URL.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		awsAlgorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsAlgorithm, accessKey, scope, signedHeaders, signature))
+
+	return nil
+}
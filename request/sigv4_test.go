@@ -0,0 +1,117 @@
+package request
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setenv(t testing.TB, name, value string) func() {
+	old, ok := os.LookupEnv(name)
+	err := os.Setenv(name, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		if ok {
+			_ = os.Setenv(name, old)
+		} else {
+			_ = os.Unsetenv(name)
+		}
+	}
+}
+
+func TestSignAWS(t *testing.T) {
+	var tests = []struct {
+		setup func(*Request)
+		value string
+		want  []string
+	}{
+		{
+			// get-vanilla from the AWS Signature Version 4 test suite
+			setup: func(r *Request) {
+				r.URL = "https://example.amazonaws.com/"
+				r.AWSSigV4 = "us-east-1/service"
+			},
+			want: []string{
+				"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request",
+				"SignedHeaders=host;x-amz-date",
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+			},
+		},
+		{
+			// get-vanilla-query-order-key-case, the value is inserted first
+			setup: func(r *Request) {
+				r.URL = "https://example.amazonaws.com/?Param2=value2&Param1=FUZZ"
+				r.AWSSigV4 = "us-east-1/service"
+			},
+			value: "value1",
+			want: []string{
+				"Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+			},
+		},
+		{
+			// example from the AWS documentation for IAM
+			setup: func(r *Request) {
+				r.URL = "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08"
+				r.AWSSigV4 = "us-east-1/iam"
+				r.Header.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+			},
+			want: []string{
+				"SignedHeaders=content-type;host;x-amz-date",
+				"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+			},
+		},
+	}
+
+	defer setenv(t, "AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")()
+	defer setenv(t, "AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")()
+	defer setenv(t, "AWS_SESSION_TOKEN", "")()
+
+	now = func() time.Time {
+		return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			r := New("")
+			test.setup(r)
+
+			req, err := r.Apply(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+				t.Errorf("wrong X-Amz-Date header %q", req.Header.Get("X-Amz-Date"))
+			}
+
+			auth := req.Header.Get("Authorization")
+			for _, want := range test.want {
+				if !strings.Contains(auth, want) {
+					t.Errorf("%q not found in header %q", want, auth)
+				}
+			}
+		})
+	}
+}
+
+func TestSignAWSErrors(t *testing.T) {
+	defer setenv(t, "AWS_ACCESS_KEY_ID", "")()
+
+	for _, scope := range []string{"us-east-1/s3", "us-east-1", "/s3"} {
+		r := New("")
+		r.URL = "https://s3.amazonaws.com/"
+		r.AWSSigV4 = scope
+
+		_, err := r.Apply("")
+		if err == nil {
+			t.Errorf("%q: expected error not returned", scope)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package request
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValueSeparator separates the values for the placeholders in
+// Request.Placeholders within a single value.
+const ValueSeparator = "\x00"
+
+// JoinValues returns a single value which contains all values, one for each
+// placeholder in Request.Placeholders.
+func JoinValues(values []string) string {
+	return strings.Join(values, ValueSeparator)
+}
+
+// DisplayValue returns value in a form suitable for printing it, several
+// values for different placeholders are separated by commas.
+func DisplayValue(value string) string {
+	return strings.Replace(value, ValueSeparator, ", ", -1)
+}
+
+// splitValues returns the values for all placeholders in r.Placeholders.
+func (r *Request) splitValues(value string) ([]string, error) {
+	values := strings.Split(value, ValueSeparator)
+	if len(values) != len(r.Placeholders) {
+		return nil, fmt.Errorf("expected %d values for the placeholders %v, got %d", len(r.Placeholders), r.Placeholders, len(values))
+	}
+
+	return values, nil
+}
+
+// PlaceholderValue returns a value which inserts the placeholders themselves,
+// so that the request built for it still contains all of them.
+func (r *Request) PlaceholderValue() string {
+	if len(r.Placeholders) > 0 {
+		return JoinValues(r.Placeholders)
+	}
+	return r.Replace
+}
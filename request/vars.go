@@ -0,0 +1,129 @@
+package request
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Vars contains values for additional placeholders, which may be updated
+// while requests are built (e.g. by a background goroutine). It is safe for
+// concurrent use.
+type Vars struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewVars returns a new empty set of variables.
+func NewVars() *Vars {
+	return &Vars{values: make(map[string]string)}
+}
+
+// Set sets the value for the placeholder name.
+func (v *Vars) Set(name, value string) {
+	v.mu.Lock()
+	v.values[name] = value
+	v.mu.Unlock()
+}
+
+// Get returns the value for the placeholder name.
+func (v *Vars) Get(name string) (value string, ok bool) {
+	v.mu.RLock()
+	value, ok = v.values[name]
+	v.mu.RUnlock()
+	return value, ok
+}
+
+// pairs returns a list of placeholder names and values suitable for
+// strings.NewReplacer. Longer names come first so that a placeholder which
+// contains another one as a prefix is replaced properly.
+func (v *Vars) pairs() []string {
+	if v == nil {
+		return nil
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return sortPairs(v.values)
+}
+
+// replacer returns a replacer which replaces the placeholder with value, all
+// placeholders in vars with their current values and the dynamic
+// placeholders in a single pass, so that inserted values are never replaced
+// again. If r.Placeholders is set, value contains one value for each of them
+// instead.
+func (r *Request) replacer(value string) *valueReplacer {
+	return newValueReplacer(r.replacePairs(value), r.random())
+}
+
+// escapedReplacer returns a replacer like replacer, but the values for the
+// placeholder (or r.Placeholders) are passed through escape before they are
+// inserted. The dynamic placeholders are expanded to the same values as for
+// replacer.
+func (r *Request) escapedReplacer(replacer *valueReplacer, value string, escape func(string) string) *valueReplacer {
+	own := r.ownValues(value)
+	for name, v := range own {
+		own[name] = escape(v)
+	}
+	return replacer.with(r.mergeVars(own))
+}
+
+// replacePairs returns the list of placeholders and values used by replacer,
+// longer placeholders come first.
+func (r *Request) replacePairs(value string) []string {
+	return r.mergeVars(r.ownValues(value))
+}
+
+// ownValues returns the values for the placeholder (or r.Placeholders).
+func (r *Request) ownValues(value string) map[string]string {
+	own := map[string]string{r.Replace: value}
+	if len(r.Placeholders) > 0 {
+		own = make(map[string]string, len(r.Placeholders))
+		values := strings.Split(value, ValueSeparator)
+		for i, name := range r.Placeholders {
+			if i < len(values) {
+				own[name] = values[i]
+			} else {
+				own[name] = ""
+			}
+		}
+	}
+
+	return own
+}
+
+// mergeVars adds the placeholders in r.Vars which are not in own and returns
+// the list of placeholders and values, longer placeholders come first.
+func (r *Request) mergeVars(own map[string]string) []string {
+	pairs := r.Vars.pairs()
+	for i := 0; i < len(pairs); i += 2 {
+		if _, ok := own[pairs[i]]; !ok {
+			own[pairs[i]] = pairs[i+1]
+		}
+	}
+
+	return sortPairs(own)
+}
+
+// sortPairs returns the names and values in m as a list suitable for
+// strings.NewReplacer, longer names come first.
+func sortPairs(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	list := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		list = append(list, name, m[name])
+	}
+
+	return list
+}
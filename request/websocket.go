@@ -0,0 +1,63 @@
+package request
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// WebSocket returns true if the request is a WebSocket handshake and the
+// messages are sent after the upgrade.
+func (r *Request) WebSocket() bool {
+	return len(r.WebSocketMessage) > 0
+}
+
+// applyWebSocket turns req into a WebSocket handshake (RFC 6455, section
+// 4.1). The URL may use the schemes ws and wss. The handshake headers can be
+// overwritten with --header.
+func (r *Request) applyWebSocket(req *http.Request) error {
+	if r.RawWriter() || r.HTTP2 || r.ForceChunkedEncoding || len(r.Trailer) > 0 || r.ConnectionClose {
+		return errors.New("--ws-message cannot be used together with raw requests, --http2, --force-chunked-encoding, --trailer or --connection-close")
+	}
+
+	switch req.URL.Scheme {
+	case "ws":
+		req.URL.Scheme = "http"
+	case "wss":
+		req.URL.Scheme = "https"
+	case "http", "https":
+	default:
+		return fmt.Errorf("invalid scheme %q for WebSocket URL", req.URL.Scheme)
+	}
+
+	var key [16]byte
+	for i := range key {
+		key[i] = byte(r.random().Intn(256))
+	}
+
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key[:]))
+
+	return nil
+}
+
+// WebSocketMessages returns the messages sent after the upgrade with the
+// placeholder replaced by value.
+func (r *Request) WebSocketMessages(value string) ([]string, error) {
+	value, err := r.encodeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	insertValue := r.replacer(value).Replace
+
+	msgs := make([]string, 0, len(r.WebSocketMessage))
+	for _, msg := range r.WebSocketMessage {
+		msgs = append(msgs, insertValue(msg))
+	}
+
+	return msgs, nil
+}
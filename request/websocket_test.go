@@ -0,0 +1,61 @@
+package request
+
+import "testing"
+
+func TestWebSocketHandshake(t *testing.T) {
+	r := New("")
+	r.URL = "wss://www.example.com/socket?id=FUZZ"
+	r.WebSocketMessage = []string{`{"id": "FUZZ"}`, "bye"}
+	r.Header.Set("Sec-WebSocket-Protocol: chat")
+
+	req, err := r.Apply("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL.String() != "https://www.example.com/socket?id=1" {
+		t.Errorf("wrong URL %q", req.URL.String())
+	}
+
+	for name, want := range map[string]string{
+		"Upgrade":                "websocket",
+		"Connection":             "Upgrade",
+		"Sec-WebSocket-Version":  "13",
+		"Sec-WebSocket-Protocol": "chat",
+	} {
+		if req.Header.Get(name) != want {
+			t.Errorf("wrong value for header %v, want %q, got %q", name, want, req.Header.Get(name))
+		}
+	}
+
+	if len(req.Header.Get("Sec-WebSocket-Key")) != 24 {
+		t.Errorf("invalid key %q", req.Header.Get("Sec-WebSocket-Key"))
+	}
+
+	msgs, err := r.WebSocketMessages("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 2 || msgs[0] != `{"id": "1"}` || msgs[1] != "bye" {
+		t.Errorf("wrong messages %q", msgs)
+	}
+}
+
+func TestWebSocketInvalid(t *testing.T) {
+	for _, setup := range []func(*Request){
+		func(r *Request) { r.URL = "ftp://www.example.com/" },
+		func(r *Request) { r.HTTP2 = true },
+		func(r *Request) { r.ConnectionClose = true },
+	} {
+		r := New("")
+		r.URL = "ws://www.example.com/"
+		r.WebSocketMessage = []string{"FUZZ"}
+		setup(r)
+
+		_, err := r.Apply("x")
+		if err == nil {
+			t.Errorf("expected error not returned")
+		}
+	}
+}
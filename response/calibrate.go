@@ -0,0 +1,208 @@
+package response
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// FilterCalibration hides responses which are similar to the responses for
+// bogus values sent during calibration (e.g. "soft 404" pages): Responses are
+// rejected if the body is identical to one of the calibration responses, or if
+// the status code is the same and the number of words and lines is within the
+// range seen during calibration. The number of bytes is not compared, since
+// the value is often reflected in the body.
+//
+// If Similarity is set, responses with the same status code are also
+// rejected if the body is at least that similar (between 0 and 1) to one of
+// the calibration responses, see BodySimilarity.
+type FilterCalibration struct {
+	StatusCodes        map[int]bool
+	MinWords, MaxWords int
+	MinLines, MaxLines int
+	BodyHashes         map[[sha256.Size]byte]bool
+	Similarity         float64
+
+	n        int // number of calibration responses
+	profiles []bodyProfile
+}
+
+// bodyProfile contains the number of occurrences of each pair of consecutive
+// words in a body.
+type bodyProfile struct {
+	pairs map[uint64]int
+	total int
+}
+
+// newBodyProfile returns the profile for body. The values used for the
+// request are removed, since they are often reflected in the body.
+func newBodyProfile(body []byte, value string) bodyProfile {
+	text := string(body)
+	for _, v := range strings.Split(value, request.ValueSeparator) {
+		if v != "" {
+			text = strings.Replace(text, v, "", -1)
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	p := bodyProfile{pairs: make(map[uint64]int)}
+	add := func(s string) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(s))
+		p.pairs[h.Sum64()]++
+		p.total++
+	}
+
+	if len(words) == 1 {
+		add(words[0])
+	}
+	for i := 1; i < len(words); i++ {
+		add(words[i-1] + " " + words[i])
+	}
+
+	return p
+}
+
+// similarity returns the Dice coefficient of the two profiles.
+func (p bodyProfile) similarity(other bodyProfile) float64 {
+	if p.total+other.total == 0 {
+		return 1
+	}
+
+	common := 0
+	for h, n := range p.pairs {
+		if m := other.pairs[h]; m < n {
+			common += m
+		} else {
+			common += n
+		}
+	}
+
+	return 2 * float64(common) / float64(p.total+other.total)
+}
+
+// BodySimilarity returns the similarity of the two bodies between 0 (nothing
+// in common) and 1 (identical). The bodies are compared by the pairs of
+// consecutive words they contain (ignoring the case and punctuation), values
+// are removed from the bodies before.
+func BodySimilarity(body1 []byte, value1 string, body2 []byte, value2 string) float64 {
+	return newBodyProfile(body1, value1).similarity(newBodyProfile(body2, value2))
+}
+
+// Reject decides if r is to be printed.
+func (f *FilterCalibration) Reject(r Response) bool {
+	if r.HTTPResponse == nil {
+		return false
+	}
+
+	if f.BodyHashes[sha256.Sum256(r.RawBody)] {
+		return true
+	}
+
+	if !f.StatusCodes[r.HTTPResponse.StatusCode] {
+		return false
+	}
+
+	if r.Body.Words >= f.MinWords && r.Body.Words <= f.MaxWords &&
+		r.Body.Lines >= f.MinLines && r.Body.Lines <= f.MaxLines {
+		return true
+	}
+
+	if f.Similarity > 0 {
+		profile := newBodyProfile(r.RawBody, r.Item)
+		for _, p := range f.profiles {
+			if profile.similarity(p) >= f.Similarity {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (f *FilterCalibration) add(r Response) {
+	if f.n == 0 || r.Body.Words < f.MinWords {
+		f.MinWords = r.Body.Words
+	}
+	if f.n == 0 || r.Body.Words > f.MaxWords {
+		f.MaxWords = r.Body.Words
+	}
+	if f.n == 0 || r.Body.Lines < f.MinLines {
+		f.MinLines = r.Body.Lines
+	}
+	if f.n == 0 || r.Body.Lines > f.MaxLines {
+		f.MaxLines = r.Body.Lines
+	}
+
+	f.StatusCodes[r.HTTPResponse.StatusCode] = true
+	f.BodyHashes[sha256.Sum256(r.RawBody)] = true
+	f.profiles = append(f.profiles, newBodyProfile(r.RawBody, r.Item))
+	f.n++
+}
+
+func (f *FilterCalibration) String() string {
+	var codes []string
+	for code := range f.StatusCodes {
+		codes = append(codes, fmt.Sprintf("%d", code))
+	}
+	sort.Strings(codes)
+
+	s := fmt.Sprintf("status %v, words %d-%d, lines %d-%d",
+		strings.Join(codes, ","), f.MinWords, f.MaxWords, f.MinLines, f.MaxLines)
+	if f.Similarity > 0 {
+		s += fmt.Sprintf(" or bodies at least %.0f%% similar", f.Similarity*100)
+	}
+	return s
+}
+
+// CalibrationValues returns n random values of different lengths which are
+// unlikely to exist on the server.
+func CalibrationValues(n int) ([]string, error) {
+	values := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 4*(i+2))
+		_, err := rand.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, hex.EncodeToString(buf))
+	}
+
+	return values, nil
+}
+
+// Calibrate sends a request for each of the values and returns a filter which
+// rejects similar responses.
+func (r *Runner) Calibrate(ctx context.Context, values []string) (*FilterCalibration, error) {
+	if len(values) == 0 {
+		return nil, errors.New("no values for calibration specified")
+	}
+
+	f := &FilterCalibration{
+		StatusCodes: make(map[int]bool),
+		BodyHashes:  make(map[[sha256.Size]byte]bool),
+	}
+
+	for _, value := range values {
+		res := r.request(ctx, value, "")
+		if res.Error != nil {
+			return nil, fmt.Errorf("calibration request for %q failed: %v", value, res.Error)
+		}
+
+		f.add(res)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,174 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestCalibrate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			fmt.Fprintf(w, "<html>\n<h1>Admin</h1>\n<p>Welcome to the admin area, please log in below.</p>\n</html>\n")
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, "<html>\n<p>The page %v does not exist</p>\n</html>\n", r.URL.Path)
+		default:
+			// soft 404, the path is reflected
+			fmt.Fprintf(w, "<html>\n<p>The page %v does not exist</p>\n</html>\n", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/FUZZ"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	values, err := CalibrationValues(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := NewRunner(tr, template, nil, nil).Calibrate(context.Background(), values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		value  string
+		reject bool
+	}{
+		{"admin", false},
+		{"forbidden", false},
+		{"index.php", true},
+		{"a-very-long-name-for-a-file-which-does-not-exist.html", true},
+	}
+
+	input := make(chan string, len(tests))
+	for _, test := range tests {
+		input <- test.value
+	}
+	close(input)
+
+	output := make(chan Response, len(tests))
+	NewRunner(tr, template, input, output).Run(context.Background())
+	close(output)
+
+	results := make(map[string]bool)
+	for res := range Mark(output, []Filter{filter}) {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		results[res.Item] = res.Hide
+	}
+
+	for _, test := range tests {
+		if results[test.value] != test.reject {
+			t.Errorf("wrong result for %v, want hide %v, got %v (filter %v)", test.value, test.reject, results[test.value], filter)
+		}
+	}
+}
+
+func TestCalibrateSimilarity(t *testing.T) {
+	text := strings.Repeat("The page you requested could not be found on this server, please check the address or use the search. ", 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			fmt.Fprintf(w, "<html>\n<h1>Admin</h1>\n<p>Welcome to the admin area, please log in below.</p>\n</html>\n")
+			return
+		}
+
+		// soft 404, the number of lines depends on the length of the path
+		fmt.Fprintf(w, "<html>\n<p>%v</p>\n<p>The page %v does not exist</p>\n", text, r.URL.Path)
+		for i := 0; i < len(r.URL.Path)/8; i++ {
+			fmt.Fprintf(w, "<br>\n")
+		}
+		fmt.Fprintf(w, "</html>\n")
+	}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/FUZZ"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	values, err := CalibrationValues(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		value      string
+		similarity float64
+		reject     bool
+	}{
+		{"admin", 0, false},
+		{"admin", 0.9, false},
+		{"a-very-long-name-for-a-file-which-does-not-exist-on-the-server.html", 0, false},
+		{"a-very-long-name-for-a-file-which-does-not-exist-on-the-server.html", 0.9, true},
+	}
+
+	for _, test := range tests {
+		filter, err := NewRunner(tr, template, nil, nil).Calibrate(context.Background(), values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filter.Similarity = test.similarity
+
+		input := make(chan string, 1)
+		input <- test.value
+		close(input)
+
+		output := make(chan Response, 1)
+		NewRunner(tr, template, input, output).Run(context.Background())
+		close(output)
+
+		for res := range Mark(output, []Filter{filter}) {
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.Hide != test.reject {
+				t.Errorf("wrong result for %v with similarity %v, want hide %v, got %v (filter %v)",
+					test.value, test.similarity, test.reject, res.Hide, filter)
+			}
+		}
+	}
+}
+
+func TestBodySimilarity(t *testing.T) {
+	var tests = []struct {
+		body1, value1 string
+		body2, value2 string
+		min, max      float64
+	}{
+		{"", "", "", "", 1, 1},
+		{"foo bar baz", "", "Foo, bar. Baz!", "", 1, 1},
+		{"the page foo does not exist", "foo", "the page barbaz does not exist", "barbaz", 1, 1},
+		{"one two three four", "", "five six seven eight", "", 0, 0},
+		{"a b c d e f g h i j", "", "a b c d e f g h i x", "", 0.8, 0.95},
+	}
+
+	for _, test := range tests {
+		s := BodySimilarity([]byte(test.body1), test.value1, []byte(test.body2), test.value2)
+		if s < test.min || s > test.max {
+			t.Errorf("wrong similarity for %q and %q: want %v-%v, got %v",
+				test.body1, test.body2, test.min, test.max, s)
+		}
+	}
+}
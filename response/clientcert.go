@@ -0,0 +1,85 @@
+package response
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/crypto/pkcs12"
+)
+
+// clientCertificate loads the TLS client certificate configured in template,
+// either from a file containing both the certificate and key (or only the
+// certificate when the key is read from a separate file), or from a PKCS#12
+// file. It returns nil if no client certificate is configured.
+func clientCertificate(template *request.Request) (*tls.Certificate, error) {
+	switch {
+	case template.TLSClientPKCS12File != "":
+		if template.TLSClientKeyCertFile != "" || template.TLSClientKeyFile != "" {
+			return nil, errors.New("--client-p12 cannot be used together with --client-cert or --client-key")
+		}
+
+		return readPKCS12(template.TLSClientPKCS12File, template.TLSClientPKCS12Password)
+
+	case template.TLSClientKeyFile != "":
+		if template.TLSClientKeyCertFile == "" {
+			return nil, errors.New("--client-key requires --client-cert")
+		}
+
+		crt, err := tls.LoadX509KeyPair(template.TLSClientKeyCertFile, template.TLSClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS client cert or key: %v", err)
+		}
+		return &crt, nil
+
+	case template.TLSClientKeyCertFile != "":
+		certs, key, err := readPEMCertKey(template.TLSClientKeyCertFile)
+		if err != nil {
+			return nil, err
+		}
+
+		crt, err := tls.X509KeyPair(certs, key)
+		if err != nil {
+			return nil, fmt.Errorf("parse TLS client cert or key: %v", err)
+		}
+		return &crt, nil
+	}
+
+	return nil, nil
+}
+
+// readPKCS12 reads the certificates and the private key from a PKCS#12 file.
+func readPKCS12(filename, password string) (*tls.Certificate, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile: %v", err)
+	}
+
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("parse PKCS#12 file %v: %v", filename, err)
+	}
+
+	var certs, key []byte
+	for _, block := range blocks {
+		// the attributes from the PKCS#12 file are not needed
+		block.Headers = nil
+
+		switch block.Type {
+		case "CERTIFICATE":
+			certs = append(certs, pem.EncodeToMemory(block)...)
+		case "PRIVATE KEY":
+			key = pem.EncodeToMemory(block)
+		}
+	}
+
+	crt, err := tls.X509KeyPair(certs, key)
+	if err != nil {
+		return nil, fmt.Errorf("parse PKCS#12 file %v: %v", filename, err)
+	}
+
+	return &crt, nil
+}
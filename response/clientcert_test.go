@@ -0,0 +1,122 @@
+package response
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestClientCertificate(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// PEM file containing both the certificate and the key
+	certFile := filepath.Join("testdata", "client.crt")
+	keyFile := filepath.Join("testdata", "client.key")
+	combined, cleanup := tempFileFrom(t, certFile, keyFile)
+	defer cleanup()
+
+	var tests = []struct {
+		setup  func(*request.Request)
+		status int
+		err    bool
+	}{
+		{setup: func(r *request.Request) {}, status: http.StatusForbidden},
+		{setup: func(r *request.Request) { r.TLSClientKeyCertFile = combined }, status: http.StatusOK},
+		{setup: func(r *request.Request) {
+			r.TLSClientKeyCertFile = certFile
+			r.TLSClientKeyFile = keyFile
+		}, status: http.StatusOK},
+		{setup: func(r *request.Request) {
+			r.TLSClientPKCS12File = filepath.Join("testdata", "client.p12")
+			r.TLSClientPKCS12Password = "secret"
+		}, status: http.StatusOK},
+		{setup: func(r *request.Request) {
+			r.TLSClientPKCS12File = filepath.Join("testdata", "client.p12")
+			r.TLSClientPKCS12Password = "wrong"
+		}, err: true},
+		{setup: func(r *request.Request) { r.TLSClientKeyFile = keyFile }, err: true},
+		{setup: func(r *request.Request) {
+			r.TLSClientPKCS12File = filepath.Join("testdata", "client.p12")
+			r.TLSClientKeyCertFile = combined
+		}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.Insecure = true
+			test.setup(template)
+
+			tr, err := NewTransport(template, 1)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr.CloseIdleConnections()
+
+			res := runSingle(t, template, "")
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.HTTPResponse.StatusCode != test.status {
+				t.Fatalf("wrong status code, want %v, got %v", test.status, res.HTTPResponse.StatusCode)
+			}
+
+			if test.status == http.StatusOK && string(res.RawBody) != "monsoon test client" {
+				t.Errorf("wrong certificate sent: %q", res.RawBody)
+			}
+		})
+	}
+}
+
+// tempFileFrom writes the contents of all files to a new temporary file.
+func tempFileFrom(t testing.TB, files ...string) (filename string, cleanup func()) {
+	f, err := ioutil.TempFile("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range files {
+		buf, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = f.Write(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f.Name(), func() {
+		_ = os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,142 @@
+package response
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PersistentJar is a cookie jar which can be shared by all runners, so that
+// cookies set in a response are sent with all following requests. The
+// cookies can be loaded from and saved to a file.
+type PersistentJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string]storedCookie // the cookies set so far, by URL and name
+	now     func() time.Time
+}
+
+// storedCookie is a cookie in the file, in the form of a Set-Cookie header and
+// the URL of the response which set it.
+type storedCookie struct {
+	URL       string `json:"url"`
+	SetCookie string `json:"set_cookie"`
+}
+
+// NewPersistentJar returns a new cookie jar. If filename is not empty and the
+// file exists, the cookies are loaded from it.
+func NewPersistentJar(filename string) (*PersistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &PersistentJar{
+		jar:     jar,
+		cookies: make(map[string]storedCookie),
+		now:     time.Now,
+	}
+
+	if filename == "" {
+		return j, nil
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(string(buf))) == 0 {
+		// an empty file contains no cookies
+		return j, nil
+	}
+
+	var list []storedCookie
+	err = json.Unmarshal(buf, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range list {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		res := http.Response{Header: http.Header{"Set-Cookie": {c.SetCookie}}}
+		j.SetCookies(u, res.Cookies())
+	}
+
+	return j, nil
+}
+
+// SetCookies stores the cookies set in a response for u.
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		c := *c
+
+		// Max-Age is relative to the time the cookie was set
+		if c.MaxAge > 0 {
+			c.Expires = j.now().Add(time.Duration(c.MaxAge) * time.Second)
+			c.MaxAge = 0
+		}
+
+		setCookie := c.String()
+		if setCookie == "" {
+			// invalid cookie
+			continue
+		}
+
+		key := strings.Join([]string{u.Scheme, u.Host, c.Domain, c.Path, c.Name}, "\x00")
+		j.cookies[key] = storedCookie{
+			URL:       (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			SetCookie: setCookie,
+		}
+	}
+}
+
+// Cookies returns the cookies to send in a request for u.
+func (j *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes all cookies set so far to filename, including expired ones. The
+// jar decides on loading which of them are still valid.
+func (j *PersistentJar) Save(filename string) error {
+	j.mu.Lock()
+	keys := make([]string, 0, len(j.cookies))
+	for key := range j.cookies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]storedCookie, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, j.cookies[key])
+	}
+	j.mu.Unlock()
+
+	buf, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// the file contains session cookies, so it should not be readable for others
+	return ioutil.WriteFile(filename, append(buf, '\n'), 0600)
+}
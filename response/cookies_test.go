@@ -0,0 +1,94 @@
+package response
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestPersistentJar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret", Path: "/", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "old", Value: "x", Path: "/", MaxAge: -1})
+		default:
+			c, err := r.Cookie("session")
+			if err != nil {
+				_, _ = io.WriteString(w, "no session")
+				return
+			}
+			_, _ = io.WriteString(w, c.Value)
+		}
+	}))
+	defer srv.Close()
+
+	filename, cleanup := writeTempFile(t, "")
+	defer cleanup()
+
+	jar, err := NewPersistentJar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := request.New("")
+	template.URL = srv.URL + "/FUZZ"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	input := make(chan string, 2)
+	input <- "login"
+	input <- "check"
+	close(input)
+	output := make(chan Response, 2)
+	runner := NewRunner(tr, template, input, output)
+	runner.Client.Jar = jar
+	runner.Run(context.Background())
+
+	<-output
+	res := <-output
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	if string(res.RawBody) != "secret" {
+		t.Fatalf("session cookie not sent, got %q", res.RawBody)
+	}
+
+	err = jar.Save(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// load the cookies again
+	jar, err = NewPersistentJar(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(srv.URL + "/check")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := jar.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "secret" {
+		t.Fatalf("wrong cookies loaded: %v", cookies)
+	}
+}
+
+func TestPersistentJarMissingFile(t *testing.T) {
+	_, err := NewPersistentJar("testdata/does-not-exist.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
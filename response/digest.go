@@ -0,0 +1,345 @@
+package response
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// digestChallenge is a challenge for HTTP Digest authentication (RFC 7616)
+// sent by the server in the WWW-Authenticate header.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       []string
+	stale     bool
+
+	nc uint32 // the number of requests sent with this nonce
+}
+
+// parseAuthParams parses the parameters of a challenge (name=value or
+// name="quoted value", separated by commas).
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+
+		i := strings.IndexAny(s, "=, \t")
+		if i < 0 || s[i] != '=' {
+			// a token without a value, e.g. the scheme of the next challenge
+			if i < 0 {
+				return params
+			}
+			s = s[i:]
+			continue
+		}
+
+		name := strings.ToLower(s[:i])
+		s = s[i+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var buf strings.Builder
+			j := 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				buf.WriteByte(s[j])
+			}
+			value = buf.String()
+			if j < len(s) {
+				j++
+			}
+			s = s[j:]
+		} else {
+			end := strings.IndexAny(s, ", \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			s = s[end:]
+		}
+
+		params[name] = value
+	}
+}
+
+// parseDigestChallenge returns the first Digest challenge in the values of
+// the WWW-Authenticate header.
+func parseDigestChallenge(values []string) (*digestChallenge, bool) {
+	for _, v := range values {
+		i := strings.Index(strings.ToLower(v), "digest ")
+		if i < 0 {
+			continue
+		}
+
+		params := parseAuthParams(v[i+len("digest "):])
+		if params["nonce"] == "" {
+			continue
+		}
+
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			stale:     strings.EqualFold(params["stale"], "true"),
+		}
+
+		if c.algorithm == "" {
+			c.algorithm = "MD5"
+		}
+
+		for _, qop := range strings.Split(params["qop"], ",") {
+			qop = strings.TrimSpace(qop)
+			if qop != "" {
+				c.qop = append(c.qop, qop)
+			}
+		}
+
+		return c, true
+	}
+
+	return nil, false
+}
+
+// digestHash returns the hash function for algorithm and whether it is a
+// session variant.
+func digestHash(algorithm string) (h func() hash.Hash, session bool, err error) {
+	alg := strings.ToUpper(algorithm)
+	if strings.HasSuffix(alg, "-SESS") {
+		session = true
+		alg = strings.TrimSuffix(alg, "-SESS")
+	}
+
+	switch alg {
+	case "MD5":
+		return md5.New, session, nil
+	case "SHA-256":
+		return sha256.New, session, nil
+	case "SHA-512-256":
+		return sha512.New512_256, session, nil
+	}
+
+	return nil, false, fmt.Errorf("unsupported digest algorithm %q", algorithm)
+}
+
+// authorization returns the value of the Authorization header for req.
+func (c *digestChallenge) authorization(req *http.Request, user, password, cnonce string, nc uint32) (string, error) {
+	newHash, session, err := digestHash(c.algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	h := func(data ...string) string {
+		hash := newHash()
+		_, _ = io.WriteString(hash, strings.Join(data, ":"))
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+
+	qop := ""
+	for _, q := range c.qop {
+		if q == "auth" {
+			qop = q
+			break
+		}
+		if q == "auth-int" && (req.Body == nil || req.GetBody != nil) {
+			qop = q
+		}
+	}
+	if len(c.qop) > 0 && qop == "" {
+		return "", fmt.Errorf("unsupported qop %v", c.qop)
+	}
+
+	uri := req.URL.RequestURI()
+
+	ha1 := h(user, c.realm, password)
+	if session {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+
+	ha2 := h(req.Method, uri)
+	if qop == "auth-int" {
+		var body []byte
+		if req.GetBody != nil {
+			rd, err := req.GetBody()
+			if err != nil {
+				return "", err
+			}
+			body, err = ioutil.ReadAll(rd)
+			_ = rd.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+		ha2 = h(req.Method, uri, h(string(body)))
+	}
+
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+
+	params := []string{
+		fmt.Sprintf(`username="%s"`, quote(user)),
+		fmt.Sprintf(`realm="%s"`, quote(c.realm)),
+		fmt.Sprintf(`nonce="%s"`, quote(c.nonce)),
+		fmt.Sprintf(`uri="%s"`, quote(uri)),
+		fmt.Sprintf(`algorithm=%s`, c.algorithm),
+	}
+
+	if qop == "" {
+		params = append(params, fmt.Sprintf(`response="%s"`, h(ha1, c.nonce, ha2)))
+	} else {
+		ncValue := fmt.Sprintf("%08x", nc)
+		params = append(params,
+			fmt.Sprintf(`response="%s"`, h(ha1, c.nonce, ncValue, cnonce, qop, ha2)),
+			fmt.Sprintf(`qop=%s`, qop),
+			fmt.Sprintf(`nc=%s`, ncValue),
+			fmt.Sprintf(`cnonce="%s"`, cnonce),
+		)
+	}
+
+	if c.opaque != "" {
+		params = append(params, fmt.Sprintf(`opaque="%s"`, quote(c.opaque)))
+	}
+
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// digestTransport handles HTTP Digest authentication: when the server sends
+// a challenge, the request is sent again with the credentials. The last
+// challenge for each host is kept and used for the following requests right
+// away, so the handshake is only needed once per nonce.
+type digestTransport struct {
+	next     http.RoundTripper
+	template *request.Request
+
+	mu         sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+func newDigestTransport(next http.RoundTripper, template *request.Request) *digestTransport {
+	return &digestTransport{
+		next:       next,
+		template:   template,
+		challenges: make(map[string]*digestChallenge),
+	}
+}
+
+func newCnonce() (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// authorize returns a copy of req with the Authorization header for the
+// challenge c.
+func (t *digestTransport) authorize(req *http.Request, c *digestChallenge) (*http.Request, error) {
+	value, _ := request.FromContext(req.Context())
+	user, password, err := t.template.Credentials(t.template.DigestAuth, value)
+	if err != nil {
+		return nil, err
+	}
+
+	cnonce, err := newCnonce()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	c.nc++
+	nc := c.nc
+	t.mu.Unlock()
+
+	auth, err := c.authorization(req, user, password, cnonce, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	newReq := req.Clone(req.Context())
+	if req.GetBody != nil {
+		newReq.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	newReq.Header.Set("Authorization", auth)
+
+	return newReq, nil
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	t.mu.Lock()
+	c := t.challenges[host]
+	t.mu.Unlock()
+
+	sent := req
+	if c != nil {
+		var err error
+		sent, err = t.authorize(req, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil && req.Body != nil {
+			// a new body has been obtained for the request
+			_ = req.Body.Close()
+		}
+	}
+
+	res, err := t.next.RoundTrip(sent)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	newChallenge, ok := parseDigestChallenge(res.Header["Www-Authenticate"])
+	if !ok {
+		return res, nil
+	}
+
+	// the credentials have been sent for this nonce and were not accepted
+	if c != nil && c.nonce == newChallenge.nonce && !newChallenge.stale {
+		return res, nil
+	}
+
+	// the body cannot be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+
+	t.mu.Lock()
+	t.challenges[host] = newChallenge
+	t.mu.Unlock()
+
+	retry, err := t.authorize(req, newChallenge)
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, err
+	}
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+	_ = res.Body.Close()
+
+	return t.next.RoundTrip(retry)
+}
@@ -0,0 +1,145 @@
+package response
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestDigestAuthorization(t *testing.T) {
+	// example from RFC 7616, section 3.9.1
+	var tests = []struct {
+		algorithm string
+		want      string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			c, ok := parseDigestChallenge([]string{
+				`Basic realm="other", Digest realm="http-auth@example.org", qop="auth, auth-int", ` +
+					`algorithm=` + test.algorithm + `, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", ` +
+					`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+			})
+			if !ok {
+				t.Fatal("challenge not found")
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "http://www.example.org/dir/index.html", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			auth, err := c.authorization(req, "Mufasa", "Circle of Life", "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range []string{
+				`Digest username="Mufasa"`,
+				`realm="http-auth@example.org"`,
+				`uri="/dir/index.html"`,
+				`response="` + test.want + `"`,
+				`qop=auth,`,
+				`nc=00000001`,
+				`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+			} {
+				if !strings.Contains(auth, want) {
+					t.Errorf("%q not found in header %q", want, auth)
+				}
+			}
+		})
+	}
+}
+
+// digestServer requires Digest authentication (MD5, without qop) for
+// user:password and counts the challenges sent.
+type digestServer struct {
+	user, password string
+
+	mu         sync.Mutex
+	challenges int
+}
+
+func (s *digestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const realm, nonce = "test", "abcdef"
+
+	h := func(data ...string) string {
+		sum := md5.Sum([]byte(strings.Join(data, ":")))
+		return hex.EncodeToString(sum[:])
+	}
+
+	params := parseAuthParams(strings.TrimPrefix(r.Header.Get("Authorization"), "Digest "))
+	want := h(h(s.user, realm, s.password), nonce, h(r.Method, r.URL.RequestURI()))
+
+	if params["username"] != s.user || params["response"] != want {
+		s.mu.Lock()
+		s.challenges++
+		s.mu.Unlock()
+
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="%s", nonce="%s"`, realm, nonce))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	_, _ = io.WriteString(w, "ok "+r.URL.Path)
+}
+
+func TestDigestAuth(t *testing.T) {
+	var tests = []struct {
+		auth       string
+		values     []string
+		status     []int
+		challenges int
+	}{
+		{
+			auth:       "admin:secret",
+			values:     []string{"a", "b", "c"},
+			status:     []int{200, 200, 200},
+			challenges: 1,
+		},
+		{
+			auth:       "admin:FUZZ",
+			values:     []string{"wrong", "secret", "other"},
+			status:     []int{401, 200, 401},
+			challenges: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			handler := &digestServer{user: "admin", password: "secret"}
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			template := request.New("")
+			template.URL = srv.URL + "/FUZZ"
+			template.DigestAuth = test.auth
+
+			responses := runValues(t, template, test.values...)
+			for i, res := range responses {
+				if res.Error != nil {
+					t.Fatal(res.Error)
+				}
+
+				if res.HTTPResponse.StatusCode != test.status[i] {
+					t.Errorf("request %d: wrong status, want %v, got %v", i, test.status[i], res.HTTPResponse.StatusCode)
+				}
+			}
+
+			if handler.challenges != test.challenges {
+				t.Errorf("wrong number of challenges, want %v, got %v", test.challenges, handler.challenges)
+			}
+		})
+	}
+}
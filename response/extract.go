@@ -5,6 +5,8 @@ import "regexp"
 // Extracter collects data from interesting (non-hidden) responses.
 type Extracter struct {
 	Pattern  []*regexp.Regexp
+	JSONPath []string
+	XPath    []*XPath
 	Commands [][]string
 	Error    func(error)
 }
@@ -31,6 +33,8 @@ func (e *Extracter) Run(in <-chan Response) <-chan Response {
 			}
 
 			res.ExtractBody(e.Pattern)
+			res.ExtractBodyJSON(e.JSONPath)
+			res.ExtractBodyXPath(e.XPath)
 
 			// forward response to next in chain
 			ch <- res
@@ -1,9 +1,13 @@
 package response
 
 import (
+	"fmt"
+	"net/http"
+	"net/textproto"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Filter decides whether to reject a Response.
@@ -124,32 +128,49 @@ func parseRangeFilterSpec(spec string) (func(int) bool, error) {
 	return f, nil
 }
 
-// FilterSize hides responses based on a size.
+// FilterSize hides responses based on a size, or the number of words or
+// lines in the body.
 type FilterSize struct {
 	headerBytes []func(int) bool
 	bodyBytes   []func(int) bool
+	bodyWords   []func(int) bool
+	bodyLines   []func(int) bool
 }
 
-// NewFilterSize returns an initialized FilterSize.
-func NewFilterSize(headerBytes, bodyBytes []string) (FilterSize, error) {
-	fs := FilterSize{}
-
-	for _, spec := range headerBytes {
+// parseRangeFilterSpecs parses all specs.
+func parseRangeFilterSpecs(specs []string) (list []func(int) bool, err error) {
+	for _, spec := range specs {
 		f, err := parseRangeFilterSpec(spec)
 		if err != nil {
-			return FilterSize{}, err
+			return nil, err
 		}
 
-		fs.headerBytes = append(fs.headerBytes, f)
+		list = append(list, f)
 	}
 
-	for _, spec := range bodyBytes {
-		f, err := parseRangeFilterSpec(spec)
-		if err != nil {
-			return FilterSize{}, err
-		}
+	return list, nil
+}
+
+// NewFilterSize returns an initialized FilterSize.
+func NewFilterSize(headerBytes, bodyBytes, bodyWords, bodyLines []string) (fs FilterSize, err error) {
+	fs.headerBytes, err = parseRangeFilterSpecs(headerBytes)
+	if err != nil {
+		return FilterSize{}, err
+	}
 
-		fs.bodyBytes = append(fs.bodyBytes, f)
+	fs.bodyBytes, err = parseRangeFilterSpecs(bodyBytes)
+	if err != nil {
+		return FilterSize{}, err
+	}
+
+	fs.bodyWords, err = parseRangeFilterSpecs(bodyWords)
+	if err != nil {
+		return FilterSize{}, err
+	}
+
+	fs.bodyLines, err = parseRangeFilterSpecs(bodyLines)
+	if err != nil {
+		return FilterSize{}, err
 	}
 
 	return fs, nil
@@ -169,6 +190,18 @@ func (f FilterSize) Reject(r Response) bool {
 		}
 	}
 
+	for _, f := range f.bodyWords {
+		if f(r.Body.Words) {
+			return true
+		}
+	}
+
+	for _, f := range f.bodyLines {
+		if f(r.Body.Lines) {
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -223,3 +256,258 @@ func (f FilterAcceptPattern) Reject(res Response) bool {
 
 	return true
 }
+
+// FilterWebSocketClose hides responses based on the close code sent by the
+// server over a WebSocket. Responses without a WebSocket are not filtered.
+type FilterWebSocketClose struct {
+	rejects []func(int) bool
+	accepts []func(int) bool
+}
+
+// NewFilterWebSocketClose returns a filter based on the WebSocket close code.
+func NewFilterWebSocketClose(rejects, accepts []string) (FilterWebSocketClose, error) {
+	filter := FilterWebSocketClose{}
+	for _, s := range rejects {
+		f, err := parseRangeFilterSpec(s)
+		if err != nil {
+			return FilterWebSocketClose{}, err
+		}
+
+		filter.rejects = append(filter.rejects, f)
+	}
+
+	for _, s := range accepts {
+		f, err := parseRangeFilterSpec(s)
+		if err != nil {
+			return FilterWebSocketClose{}, err
+		}
+
+		filter.accepts = append(filter.accepts, f)
+	}
+
+	return filter, nil
+}
+
+// Reject decides if r is to be printed.
+func (f FilterWebSocketClose) Reject(r Response) bool {
+	if r.WebSocket == nil {
+		return false
+	}
+
+	for _, f := range f.rejects {
+		if f(r.WebSocket.CloseCode) {
+			return true
+		}
+	}
+
+	for _, f := range f.accepts {
+		if !f(r.WebSocket.CloseCode) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// headerMatch matches a response header by name and optionally a regular
+// expression for the value.
+type headerMatch struct {
+	name  string
+	value *regexp.Regexp
+}
+
+// parseHeaderMatch parses a header filter like "Server: nginx" or "X-Debug".
+func parseHeaderMatch(s string) (headerMatch, error) {
+	data := strings.SplitN(s, ":", 2)
+	name := strings.TrimSpace(data[0])
+	if name == "" {
+		return headerMatch{}, fmt.Errorf("invalid header filter %q, header name is empty", s)
+	}
+
+	m := headerMatch{name: textproto.CanonicalMIMEHeaderKey(name)}
+	if len(data) == 2 {
+		re, err := regexp.Compile(strings.TrimSpace(data[1]))
+		if err != nil {
+			return headerMatch{}, fmt.Errorf("invalid header filter %q: %v", s, err)
+		}
+		m.value = re
+	}
+
+	return m, nil
+}
+
+// match returns true if the header is present and (if set) one of its values
+// matches the regular expression.
+func (m headerMatch) match(header http.Header) bool {
+	values, ok := header[m.name]
+	if !ok {
+		return false
+	}
+
+	if m.value == nil {
+		return true
+	}
+
+	for _, v := range values {
+		if m.value.MatchString(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterHeader hides responses based on the response header. Responses
+// without an HTTP response (e.g. for errors) are not filtered.
+type FilterHeader struct {
+	rejects []headerMatch
+	accepts []headerMatch
+}
+
+// NewFilterHeader returns a filter which hides responses with a header
+// matching one of rejects, and responses for which one of accepts does not
+// match. The filters have the form "Name: regex" or "Name" if only the
+// presence of the header is checked.
+func NewFilterHeader(rejects, accepts []string) (FilterHeader, error) {
+	filter := FilterHeader{}
+	for _, s := range rejects {
+		m, err := parseHeaderMatch(s)
+		if err != nil {
+			return FilterHeader{}, err
+		}
+
+		filter.rejects = append(filter.rejects, m)
+	}
+
+	for _, s := range accepts {
+		m, err := parseHeaderMatch(s)
+		if err != nil {
+			return FilterHeader{}, err
+		}
+
+		filter.accepts = append(filter.accepts, m)
+	}
+
+	return filter, nil
+}
+
+// Reject decides if r is to be printed.
+func (f FilterHeader) Reject(r Response) bool {
+	if r.HTTPResponse == nil {
+		return false
+	}
+
+	for _, m := range f.rejects {
+		if m.match(r.HTTPResponse.Header) {
+			return true
+		}
+	}
+
+	for _, m := range f.accepts {
+		if !m.match(r.HTTPResponse.Header) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTimeFilterSpec parses a filter for the duration of a request, like
+// ">2s", "<100ms", ">=1s", "<=500ms" or "1s-3s" (inclusive).
+func parseTimeFilterSpec(spec string) (func(time.Duration) bool, error) {
+	parse := func(s string) (time.Duration, error) {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("invalid time filter %q: %v", spec, err)
+		}
+		return d, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(spec, op) {
+			continue
+		}
+
+		d, err := parse(spec[len(op):])
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case ">=":
+			return func(v time.Duration) bool { return v >= d }, nil
+		case "<=":
+			return func(v time.Duration) bool { return v <= d }, nil
+		case ">":
+			return func(v time.Duration) bool { return v > d }, nil
+		default:
+			return func(v time.Duration) bool { return v < d }, nil
+		}
+	}
+
+	data := strings.SplitN(spec, "-", 2)
+	if len(data) != 2 {
+		return nil, fmt.Errorf("invalid time filter %q, use >d, <d, >=d, <=d or from-to", spec)
+	}
+
+	from, err := parse(data[0])
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parse(data[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(v time.Duration) bool { return v >= from && v <= to }, nil
+}
+
+// FilterTime hides responses based on the time it took to receive them.
+type FilterTime struct {
+	rejects []func(time.Duration) bool
+	accepts []func(time.Duration) bool
+}
+
+// NewFilterTime returns a filter which hides responses for which one of
+// rejects matches the duration, and those for which one of accepts does not
+// match.
+func NewFilterTime(rejects, accepts []string) (FilterTime, error) {
+	filter := FilterTime{}
+	for _, s := range rejects {
+		f, err := parseTimeFilterSpec(s)
+		if err != nil {
+			return FilterTime{}, err
+		}
+
+		filter.rejects = append(filter.rejects, f)
+	}
+
+	for _, s := range accepts {
+		f, err := parseTimeFilterSpec(s)
+		if err != nil {
+			return FilterTime{}, err
+		}
+
+		filter.accepts = append(filter.accepts, f)
+	}
+
+	return filter, nil
+}
+
+// Reject decides if r is to be printed.
+func (f FilterTime) Reject(r Response) bool {
+	for _, f := range f.rejects {
+		if f(r.Duration) {
+			return true
+		}
+	}
+
+	for _, f := range f.accepts {
+		if !f(r.Duration) {
+			return true
+		}
+	}
+
+	return false
+}
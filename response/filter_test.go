@@ -1,6 +1,10 @@
 package response
 
-import "testing"
+import (
+	"net/http"
+	"testing"
+	"time"
+)
 
 func TestFilterSize(t *testing.T) {
 	var tests = []struct {
@@ -41,3 +45,146 @@ func TestFilterSize(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterSizeWordsLines(t *testing.T) {
+	res := Response{Body: TextStats{Bytes: 1234, Words: 100, Lines: 20}}
+
+	var tests = []struct {
+		words, lines []string
+		result       bool
+	}{
+		{[]string{"100"}, nil, true},
+		{[]string{"99"}, nil, false},
+		{nil, []string{"10-30"}, true},
+		{nil, []string{"21-"}, false},
+		{[]string{"-50"}, []string{"5", "20"}, true},
+	}
+
+	for _, test := range tests {
+		f, err := NewFilterSize(nil, nil, test.words, test.lines)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := f.Reject(res)
+		if result != test.result {
+			t.Errorf("wrong result for words %v, lines %v: want %v, got %v",
+				test.words, test.lines, test.result, result)
+		}
+	}
+
+	_, err := NewFilterSize(nil, nil, []string{"x"}, nil)
+	if err == nil {
+		t.Errorf("no error for invalid word count")
+	}
+}
+
+func TestFilterWebSocketClose(t *testing.T) {
+	var tests = []struct {
+		hide, show []string
+		res        Response
+		result     bool
+	}{
+		{[]string{"1008"}, nil, Response{WebSocket: &WebSocketStats{CloseCode: 1008}}, true},
+		{[]string{"1008"}, nil, Response{WebSocket: &WebSocketStats{CloseCode: 1000}}, false},
+		{[]string{"1008"}, nil, Response{}, false},
+		{nil, []string{"1000-1003"}, Response{WebSocket: &WebSocketStats{CloseCode: 1006}}, true},
+		{nil, []string{"1000-1003"}, Response{WebSocket: &WebSocketStats{CloseCode: 1001}}, false},
+	}
+
+	for _, test := range tests {
+		f, err := NewFilterWebSocketClose(test.hide, test.show)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := f.Reject(test.res)
+		if result != test.result {
+			t.Errorf("wrong result for hide %v, show %v and %v: want %v, got %v",
+				test.hide, test.show, test.res.WebSocket, test.result, result)
+		}
+	}
+}
+
+func TestFilterHeader(t *testing.T) {
+	header := http.Header{
+		"Server":   []string{"nginx/1.18.0"},
+		"X-Cache":  []string{"HIT"},
+		"Location": []string{"/login"},
+	}
+	res := Response{HTTPResponse: &http.Response{Header: header}}
+
+	var tests = []struct {
+		hide, show []string
+		res        Response
+		result     bool
+	}{
+		{[]string{"X-Cache: HIT"}, nil, res, true},
+		{[]string{"x-cache: ^MISS$"}, nil, res, false},
+		{[]string{"X-Debug"}, nil, res, false},
+		{[]string{"Location"}, nil, res, true},
+		{[]string{"X-Cache: HIT"}, nil, Response{}, false},
+		{nil, []string{"Server: nginx"}, res, false},
+		{nil, []string{"Server: apache"}, res, true},
+		{nil, []string{"Server: nginx", "Location: ^/admin"}, res, true},
+		{nil, []string{"X-Debug"}, res, true},
+	}
+
+	for _, test := range tests {
+		f, err := NewFilterHeader(test.hide, test.show)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := f.Reject(test.res)
+		if result != test.result {
+			t.Errorf("wrong result for hide %v, show %v: want %v, got %v",
+				test.hide, test.show, test.result, result)
+		}
+	}
+
+	for _, spec := range []string{"", ": foo", "Server: ("} {
+		_, err := NewFilterHeader([]string{spec}, nil)
+		if err == nil {
+			t.Errorf("no error for invalid filter %q", spec)
+		}
+	}
+}
+
+func TestFilterTime(t *testing.T) {
+	var tests = []struct {
+		hide, show []string
+		duration   time.Duration
+		result     bool
+	}{
+		{[]string{"<100ms"}, nil, 50 * time.Millisecond, true},
+		{[]string{"<100ms"}, nil, 100 * time.Millisecond, false},
+		{[]string{"<=100ms"}, nil, 100 * time.Millisecond, true},
+		{nil, []string{">2s"}, 3 * time.Second, false},
+		{nil, []string{">2s"}, 2 * time.Second, true},
+		{nil, []string{">=2s"}, 2 * time.Second, false},
+		{nil, []string{"1s-3s"}, 1500 * time.Millisecond, false},
+		{nil, []string{"1s-3s"}, 4 * time.Second, true},
+		{[]string{"> 5s"}, []string{">1s"}, 6 * time.Second, true},
+	}
+
+	for _, test := range tests {
+		f, err := NewFilterTime(test.hide, test.show)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := f.Reject(Response{Duration: test.duration})
+		if result != test.result {
+			t.Errorf("wrong result for hide %v, show %v and %v: want %v, got %v",
+				test.hide, test.show, test.duration, test.result, result)
+		}
+	}
+
+	for _, spec := range []string{"2s", ">2", "a-3s", ">"} {
+		_, err := NewFilterTime([]string{spec}, nil)
+		if err == nil {
+			t.Errorf("no error for invalid filter %q", spec)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// FollowUp sends a second request for each interesting (non-hidden) response.
+// The placeholders of the main request are replaced by the value of the
+// response, the data extracted from the response is available at the
+// placeholders Placeholder1, Placeholder2 and so on.
+type FollowUp struct {
+	Request      *request.Request // the follow-up request
+	Client       *http.Client
+	Placeholders []string // the placeholders of the main request
+	Placeholder  string   // prefix for the placeholders of the extracted data
+}
+
+// NewFollowUp returns a FollowUp for the request template in filename, which
+// is sent via tr. Scheme and host are taken from the URL of template.
+func NewFollowUp(template *request.Request, filename, placeholder string, tr http.RoundTripper) (*FollowUp, error) {
+	if placeholder == "" {
+		return nil, errors.New("follow-up request: placeholder for the extracted data is empty")
+	}
+
+	target, err := url.Parse(template.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	followUp := request.New(template.Replace)
+	followUp.URL = target.Scheme + "://" + target.Host
+	followUp.TemplateFile = filename
+	followUp.Vars = template.Vars
+
+	placeholders := template.Placeholders
+	if len(placeholders) == 0 {
+		placeholders = []string{template.Replace}
+	}
+
+	for _, name := range placeholders {
+		if name == placeholder {
+			return nil, fmt.Errorf("follow-up request: placeholder %q is already used for the main request", placeholder)
+		}
+	}
+
+	f := &FollowUp{
+		Request: followUp,
+		Client: &http.Client{
+			Transport: tr,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Placeholders: placeholders,
+		Placeholder:  placeholder,
+	}
+
+	return f, nil
+}
+
+// send sends the follow-up request for res.
+func (f *FollowUp) send(ctx context.Context, res Response) (response Response) {
+	names := append([]string{}, f.Placeholders...)
+	values := []string{res.Item}
+	for i, data := range res.Extract {
+		names = append(names, f.Placeholder+strconv.Itoa(i+1))
+		values = append(values, data)
+	}
+
+	// the requests are built one after another, so the placeholders can be
+	// set for each response
+	f.Request.Placeholders = names
+
+	response = Response{Item: res.Item}
+
+	req, err := f.Request.Apply(request.JoinValues(values))
+	if err != nil {
+		response.Error = fmt.Errorf("follow-up request: %v", err)
+		return
+	}
+	response.URL = request.URLString(req.URL)
+
+	start := time.Now()
+	httpResponse, err := f.Client.Do(req.WithContext(request.NewContext(ctx, res.Item)))
+	if err != nil {
+		response.Duration = time.Since(start)
+		response.Error = fmt.Errorf("follow-up request: %v", err)
+		return
+	}
+	response.Duration = time.Since(start)
+
+	err = response.ReadBody(httpResponse.Body, DefaultBodyBufferSize)
+	if err != nil {
+		_ = httpResponse.Body.Close()
+		response.Error = fmt.Errorf("follow-up request: %v", err)
+		return
+	}
+
+	// compute the stats for the header
+	err = response.ExtractHeader(httpResponse, nil)
+	if err != nil {
+		_ = httpResponse.Body.Close()
+		response.Error = fmt.Errorf("follow-up request: %v", err)
+		return
+	}
+
+	err = httpResponse.Body.Close()
+	if err != nil {
+		response.Error = fmt.Errorf("follow-up request: %v", err)
+		return
+	}
+
+	response.HTTPResponse = httpResponse
+	return response
+}
+
+// Run sends the follow-up request for all interesting (non-hidden) responses
+// and stores the result in the FollowUp field. It is done in a separate
+// goroutine, which terminates when the input channel is closed.
+func (f *FollowUp) Run(ctx context.Context, in <-chan Response) <-chan Response {
+	ch := make(chan Response)
+
+	go func() {
+		defer close(ch)
+		for res := range in {
+			if !res.Hide && res.Error == nil {
+				followUp := f.send(ctx, res)
+				res.FollowUp = &followUp
+			}
+
+			// forward response to next in chain
+			ch <- res
+		}
+	}()
+
+	return ch
+}
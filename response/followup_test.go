@@ -0,0 +1,123 @@
+package response
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestFollowUp(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("code") != "42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"voucher": "v-123"}`))
+	})
+	mux.HandleFunc("/redeem", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("redeemed " + r.URL.Query().Get("code") + " " + r.URL.Query().Get("voucher")))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-followup-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	followUpFile := filepath.Join(tempdir, "redeem.txt")
+	err = ioutil.WriteFile(followUpFile, []byte("GET /redeem?code=FUZZ&voucher=DATA1 HTTP/1.1\r\n\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := request.New("")
+	template.URL = srv.URL + "/check?code=FUZZ"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	followUp, err := NewFollowUp(template, followUpFile, "DATA", tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := make(chan string, 3)
+	for _, value := range []string{"1", "42", "3"} {
+		input <- value
+	}
+	close(input)
+
+	output := make(chan Response, 3)
+	NewRunner(tr, template, input, output).Run(context.Background())
+	close(output)
+
+	filter, err := NewFilterStatusCode([]string{"404"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extracter := &Extracter{
+		Pattern: []*regexp.Regexp{regexp.MustCompile(`"voucher": "([^"]+)"`)},
+	}
+
+	responses := extracter.Run(Mark(output, []Filter{filter}))
+	for res := range followUp.Run(context.Background(), responses) {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if res.Hide {
+			if res.FollowUp != nil {
+				t.Errorf("follow-up request sent for hidden response %v", res.Item)
+			}
+			continue
+		}
+
+		if res.FollowUp == nil {
+			t.Fatalf("no follow-up request sent for %v", res.Item)
+		}
+
+		if res.FollowUp.Error != nil {
+			t.Fatal(res.FollowUp.Error)
+		}
+
+		want := "redeemed 42 v-123"
+		if string(res.FollowUp.RawBody) != want {
+			t.Errorf("wrong follow-up response, want %q, got %q", want, res.FollowUp.RawBody)
+		}
+
+		if !strings.Contains(res.String(), "follow-up: 200") {
+			t.Errorf("follow-up response not displayed: %v", res)
+		}
+	}
+}
+
+func TestFollowUpPlaceholder(t *testing.T) {
+	template := request.New("")
+	template.URL = "http://www.example.com"
+	template.Placeholders = []string{"USER", "PASS"}
+
+	_, err := NewFollowUp(template, "followup.txt", "PASS", http.DefaultTransport)
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+}
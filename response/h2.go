@@ -0,0 +1,165 @@
+package response
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/net/http2"
+)
+
+// authorityRoundTripper sets the :authority pseudo-header of HTTP/2 requests
+// to the value configured in the template.
+type authorityRoundTripper struct {
+	template *request.Request
+	rt       http.RoundTripper
+}
+
+// RoundTrip sends the request with the authority from the template. The HTTP/2
+// transport uses req.Host for :authority, so the request is cloned and the
+// field is replaced.
+func (a authorityRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	value, _ := request.FromContext(req.Context())
+
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Host = a.template.Authority(value)
+
+	return a.rt.RoundTrip(req2)
+}
+
+// overrideAuthority wraps the HTTP/2 round tripper configured for tr so that
+// the :authority pseudo-header is set from template.
+func overrideAuthority(template *request.Request, tr *http.Transport) {
+	next, ok := tr.TLSNextProto["h2"]
+	if !ok {
+		return
+	}
+
+	tr.TLSNextProto["h2"] = func(authority string, c *tls.Conn) http.RoundTripper {
+		return authorityRoundTripper{template: template, rt: next(authority, c)}
+	}
+}
+
+// h2Transport sends all requests via HTTP/2, for http URLs without TLS with
+// prior knowledge (h2c). Connections are established via tr.DialContext, so
+// the options for connecting (e.g. --resolve or --proxy-chain) still apply.
+// A connection is reused for all requests to the same target unless the
+// address to connect to depends on the value.
+type h2Transport struct {
+	tr       *http.Transport
+	template *request.Request
+	h2       *http2.Transport
+
+	mu    sync.Mutex
+	conns map[string]*h2Conn
+}
+
+type h2Conn struct {
+	mu sync.Mutex
+	cc *http2.ClientConn
+}
+
+func newH2Transport(template *request.Request, tr *http.Transport) *h2Transport {
+	return &h2Transport{
+		tr:       tr,
+		template: template,
+		h2:       &http2.Transport{AllowHTTP: true},
+		conns:    make(map[string]*h2Conn),
+	}
+}
+
+// RoundTrip sends req via HTTP/2.
+func (t *h2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.template.H2Authority != "" {
+		value, _ := request.FromContext(req.Context())
+
+		req2 := new(http.Request)
+		*req2 = *req
+		req2.Host = t.template.Authority(value)
+		req = req2
+	}
+
+	if t.template.DialDependsOnValue() {
+		cc, err := t.dial(req)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := cc.RoundTrip(req)
+		if err != nil {
+			_ = cc.Close()
+			return nil, err
+		}
+
+		res.Body = &closeBody{ReadCloser: res.Body, close: cc.Close}
+		return res, nil
+	}
+
+	cc, err := t.conn(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return cc.RoundTrip(req)
+}
+
+// conn returns a connection to the target of req which can take a new
+// request, a new connection is established if necessary.
+func (t *h2Transport) conn(req *http.Request) (*http2.ClientConn, error) {
+	host, port, err := request.Target(req)
+	if err != nil {
+		return nil, err
+	}
+	key := req.URL.Scheme + "://" + net.JoinHostPort(host, port)
+
+	t.mu.Lock()
+	c, ok := t.conns[key]
+	if !ok {
+		c = &h2Conn{}
+		t.conns[key] = c
+	}
+	t.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cc != nil && c.cc.CanTakeNewRequest() {
+		return c.cc, nil
+	}
+
+	cc, err := t.dial(req)
+	if err != nil {
+		return nil, err
+	}
+	c.cc = cc
+
+	return cc, nil
+}
+
+// dial establishes a new HTTP/2 connection to the target of req.
+func (t *h2Transport) dial(req *http.Request) (*http2.ClientConn, error) {
+	conn, err := dialRequest(req, t.tr, t.template, []string{"h2"})
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		proto := tlsConn.ConnectionState().NegotiatedProtocol
+		if proto != "h2" {
+			_ = conn.Close()
+			return nil, fmt.Errorf("server %v does not support HTTP/2 (negotiated protocol %q)", req.URL.Host, proto)
+		}
+	}
+
+	cc, err := t.h2.NewClientConn(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return cc, nil
+}
@@ -0,0 +1,217 @@
+package response
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+type authorityRecorder struct {
+	mu        sync.Mutex
+	authority string
+	proto     int
+}
+
+func (a *authorityRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.authority = r.Host
+	a.proto = r.ProtoMajor
+	a.mu.Unlock()
+}
+
+func (a *authorityRecorder) check(t testing.TB, want string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.proto != 2 {
+		t.Errorf("request was not sent via HTTP/2, got HTTP/%d", a.proto)
+	}
+
+	if a.authority != want {
+		t.Errorf("wrong authority, want %q, got %q", want, a.authority)
+	}
+}
+
+func TestAuthorityH2C(t *testing.T) {
+	rec := &authorityRecorder{}
+	srv := httptest.NewServer(h2c.NewHandler(rec, &http2.Server{}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/"
+	template.H2Authority = "FUZZ.internal.example.com"
+	template.Header.Set("Host: www.example.com")
+
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	defer tr.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: authorityRoundTripper{template: template, rt: tr},
+	}
+
+	req, err := template.ApplyNext("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req.WithContext(request.NewContext(req.Context(), "admin")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	rec.check(t, "admin.internal.example.com")
+}
+
+func TestAuthorityTLS(t *testing.T) {
+	rec := &authorityRecorder{}
+	srv := httptest.NewUnstartedServer(rec)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/"
+	template.Insecure = true
+	template.H2Authority = "other.example.com"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	input := make(chan string, 1)
+	input <- ""
+	close(input)
+	output := make(chan Response, 1)
+
+	runner := NewRunner(tr, template, input, output)
+	runner.Run(context.Background())
+
+	res := <-output
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	rec.check(t, "other.example.com")
+}
+
+// runValues sends a request for each value via a new runner and returns the
+// responses.
+func runValues(t testing.TB, template *request.Request, values ...string) []Response {
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	input := make(chan string, len(values))
+	for _, v := range values {
+		input <- v
+	}
+	close(input)
+	output := make(chan Response, len(values))
+
+	NewRunner(tr, template, input, output).Run(context.Background())
+	close(output)
+
+	var list []Response
+	for res := range output {
+		list = append(list, res)
+	}
+	return list
+}
+
+func TestHTTP2PriorKnowledge(t *testing.T) {
+	var mu sync.Mutex
+	conns := make(map[string]struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		conns[r.RemoteAddr] = struct{}{}
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(r.Proto + " " + r.Host))
+	})
+
+	srv := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/FUZZ"
+	template.HTTP2 = true
+	template.H2Authority = "FUZZ.example.com"
+
+	for _, res := range runValues(t, template, "a", "b", "c") {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		want := "HTTP/2.0 " + res.Item + ".example.com"
+		if string(res.RawBody) != want {
+			t.Errorf("wrong body, want %q, got %q", want, res.RawBody)
+		}
+	}
+
+	if len(conns) != 1 {
+		t.Errorf("connection was not reused, got %d connections", len(conns))
+	}
+}
+
+func TestHTTP2TLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+
+	var tests = []struct {
+		h2    bool
+		proto string
+	}{
+		{h2: true, proto: "HTTP/2.0"},
+		{h2: false},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(handler)
+			srv.EnableHTTP2 = test.h2
+			srv.StartTLS()
+			defer srv.Close()
+
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.Insecure = true
+			template.HTTP2 = true
+
+			res := runValues(t, template, "")[0]
+			if test.proto == "" {
+				if res.Error == nil {
+					t.Fatal("expected error not returned for server without HTTP/2")
+				}
+				return
+			}
+
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if string(res.RawBody) != test.proto {
+				t.Errorf("wrong protocol, want %q, got %q", test.proto, res.RawBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package response
+
+import (
+	"context"
+	"sync"
+)
+
+// HostLimiter limits the number of concurrent requests per target host (the
+// host and port from the URL). It can be shared between several runners.
+type HostLimiter struct {
+	max int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+// NewHostLimiter returns a limiter which allows at most max concurrent
+// requests to each host.
+func NewHostLimiter(max int) *HostLimiter {
+	return &HostLimiter{
+		max:   max,
+		hosts: make(map[string]chan struct{}),
+	}
+}
+
+// Acquire waits until a request to host may be sent. The returned function
+// must be called when the request is done. An error is only returned when ctx
+// is cancelled.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (release func(), err error) {
+	l.mu.Lock()
+	sem, ok := l.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, l.max)
+		l.hosts[host] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func() { <-sem }, nil
+}
@@ -0,0 +1,110 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// concurrencyCounter records the maximum number of concurrent requests.
+type concurrencyCounter struct {
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (c *concurrencyCounter) add(n int) {
+	c.mu.Lock()
+	c.current += n
+	if c.current > c.max {
+		c.max = c.current
+	}
+	c.mu.Unlock()
+}
+
+func (c *concurrencyCounter) Max() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.max
+}
+
+func TestHostLimiter(t *testing.T) {
+	const (
+		workers = 6
+		perHost = 2
+	)
+
+	var total concurrencyCounter
+	var servers []*httptest.Server
+	var counters []*concurrencyCounter
+
+	for i := 0; i < 2; i++ {
+		counter := &concurrencyCounter{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			counter.add(1)
+			total.add(1)
+			time.Sleep(50 * time.Millisecond)
+			counter.add(-1)
+			total.add(-1)
+		}))
+		defer srv.Close()
+
+		servers = append(servers, srv)
+		counters = append(counters, counter)
+	}
+
+	template := request.New("")
+	template.URL = "FUZZ/"
+
+	tr, err := NewTransport(template, workers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	input := make(chan string, 24)
+	for i := 0; i < 12; i++ {
+		for _, srv := range servers {
+			input <- srv.URL
+		}
+	}
+	close(input)
+
+	output := make(chan Response, 24)
+	limit := NewHostLimiter(perHost)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		runner := NewRunner(tr, template, input, output)
+		runner.HostLimit = limit
+
+		wg.Add(1)
+		go func() {
+			runner.Run(context.Background())
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	close(output)
+
+	for res := range output {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+	}
+
+	for i, counter := range counters {
+		if counter.Max() > perHost {
+			t.Errorf("server %d: too many concurrent requests, want at most %d, got %d", i, perHost, counter.Max())
+		}
+	}
+
+	if total.Max() != 2*perHost {
+		t.Errorf("wrong number of concurrent requests in total, want %d, got %d", 2*perHost, total.Max())
+	}
+}
@@ -0,0 +1,130 @@
+package response
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+)
+
+// readLine returns the next line from rd without the line ending, which may
+// be "\r\n" or "\n".
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, err
+}
+
+// readResponseLenient reads an HTTP response from rd like http.ReadResponse,
+// but accepts responses which do not comply to the protocol where possible:
+// the status line only needs a protocol and a numeric status code, header
+// lines without a colon are ignored, header names are not validated and an
+// invalid Content-Length is ignored, the body is then read until the
+// connection is closed. Interim responses (1xx except 101) are skipped.
+func readResponseLenient(rd *bufio.Reader, req *http.Request) (*http.Response, error) {
+	for {
+		res, err := readResponseHeaderLenient(rd, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode >= 100 && res.StatusCode < 200 && res.StatusCode != http.StatusSwitchingProtocols {
+			continue
+		}
+
+		var body io.Reader = rd
+		res.ContentLength = -1
+
+		te := strings.ToLower(strings.Join(res.Header["Transfer-Encoding"], ","))
+		length, lengthErr := strconv.ParseInt(strings.TrimSpace(res.Header.Get("Content-Length")), 10, 64)
+
+		switch {
+		case req.Method == http.MethodHead, res.StatusCode == http.StatusNoContent,
+			res.StatusCode == http.StatusNotModified, res.StatusCode == http.StatusSwitchingProtocols:
+			body = strings.NewReader("")
+			res.ContentLength = 0
+		case strings.Contains(te, "chunked"):
+			body = httputil.NewChunkedReader(rd)
+			res.TransferEncoding = []string{"chunked"}
+		case lengthErr == nil && length >= 0:
+			body = io.LimitReader(rd, length)
+			res.ContentLength = length
+		}
+
+		res.Body = ioutil.NopCloser(body)
+		return res, nil
+	}
+}
+
+// readResponseHeaderLenient reads the status line and the header of a
+// response.
+func readResponseHeaderLenient(rd *bufio.Reader, req *http.Request) (*http.Response, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("malformed HTTP status line %q", line)
+	}
+
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed HTTP status code in status line %q", line)
+	}
+
+	res := &http.Response{
+		Status:     strings.Join(fields[1:], " "),
+		StatusCode: code,
+		Proto:      fields[0],
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Request:    req,
+	}
+
+	if major, minor, ok := http.ParseHTTPVersion(fields[0]); ok {
+		res.ProtoMajor, res.ProtoMinor = major, minor
+	}
+
+	var last string
+	for {
+		line, err := readLine(rd)
+		if err == io.EOF {
+			// the connection was closed after the header
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "" {
+			return res, nil
+		}
+
+		// obsolete line folding, append to the previous header
+		if (line[0] == ' ' || line[0] == '\t') && last != "" {
+			values := res.Header[last]
+			values[len(values)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+
+		name := http.CanonicalHeaderKey(strings.TrimSpace(line[:i]))
+		res.Header[name] = append(res.Header[name], strings.TrimSpace(line[i+1:]))
+		last = name
+	}
+}
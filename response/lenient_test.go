@@ -0,0 +1,125 @@
+package response
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadResponseLenient(t *testing.T) {
+	var tests = []struct {
+		response string
+		method   string
+		status   int
+		header   http.Header
+		body     string
+		err      bool
+	}{
+		{
+			response: "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nokextra",
+			status:   200,
+			header:   http.Header{"Content-Length": {"2"}},
+			body:     "ok",
+		},
+		{
+			// line endings without \r, a header without a colon, spaces before the colon
+			response: "HTTP/1.0 404 Not  Found\nno colon\nX-Foo : bar\n\nbody until EOF",
+			status:   404,
+			header:   http.Header{"X-Foo": {"bar"}},
+			body:     "body until EOF",
+		},
+		{
+			// invalid header name and an invalid Content-Length
+			response: "HTTP/1.1 200\r\nX Bad: 1\r\nContent-Length: abc\r\n\r\nfoobar",
+			status:   200,
+			header:   http.Header{"X Bad": {"1"}, "Content-Length": {"abc"}},
+			body:     "foobar",
+		},
+		{
+			// folded header, unknown protocol version
+			response: "HTTP/9 500 Error\r\nX-Foo: a\r\n b\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nfoo\r\n0\r\n\r\n",
+			status:   500,
+			header:   http.Header{"X-Foo": {"a b"}, "Transfer-Encoding": {"chunked"}},
+			body:     "foo",
+		},
+		{
+			// interim responses are skipped
+			response: "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 204 No Content\r\n\r\n",
+			status:   204,
+			header:   http.Header{},
+		},
+		{
+			response: "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\n",
+			method:   "HEAD",
+			status:   200,
+			header:   http.Header{"Content-Length": {"3"}},
+		},
+		{
+			// header is not terminated
+			response: "HTTP/1.1 302 Found\r\nLocation: /",
+			status:   302,
+			header:   http.Header{"Location": {"/"}},
+		},
+		{
+			response: "garbage\r\n\r\n",
+			err:      true,
+		},
+		{
+			response: "HTTP/1.1 abc OK\r\n\r\n",
+			err:      true,
+		},
+		{
+			response: "",
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = "GET"
+			}
+			req, err := http.NewRequest(method, "http://www.example.com", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := readResponseLenient(bufio.NewReader(strings.NewReader(test.response)), req)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != test.status {
+				t.Errorf("wrong status code, want %v, got %v", test.status, res.StatusCode)
+			}
+
+			if len(res.Header) != len(test.header) {
+				t.Errorf("wrong header, want %v, got %v", test.header, res.Header)
+			}
+			for name, values := range test.header {
+				if strings.Join(res.Header[name], "|") != strings.Join(values, "|") {
+					t.Errorf("wrong values for header %q, want %q, got %q", name, values, res.Header[name])
+				}
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != test.body {
+				t.Errorf("wrong body, want %q, got %q", test.body, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// localDialer establishes connections from a local IP address and optionally a
+// port from a range.
+type localDialer struct {
+	dialer   *net.Dialer
+	ip       net.IP
+	from, to int // port range, zero if the port is chosen by the OS
+
+	next uint32 // offset of the port tried first for the next connection
+}
+
+// newLocalDialer returns a dialer which binds connections to the address,
+// which must belong to a local interface, and a port from portRange (e.g.
+// "40000-40100" or "40000").
+func newLocalDialer(dialer *net.Dialer, address, portRange string) (*localDialer, error) {
+	if address == "" {
+		return nil, errors.New("a local port can only be used together with --interface")
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid local address %q, not an IP address", address)
+	}
+
+	err := checkLocalAddress(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &localDialer{dialer: dialer, ip: ip}
+
+	if portRange != "" {
+		d.from, d.to, err = parsePortRange(portRange)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// checkLocalAddress returns an error if ip does not belong to a local interface.
+func checkLocalAddress(ip net.IP) error {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("list local addresses: %v", err)
+	}
+
+	for _, addr := range addrs {
+		if n, ok := addr.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("local address %v does not belong to any interface", ip)
+}
+
+func parsePortRange(s string) (from, to int, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+
+	from, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid local port range %q: %v", s, err)
+	}
+
+	to, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid local port range %q: %v", s, err)
+	}
+
+	if from < 1 || to > 65535 || from > to {
+		return 0, 0, fmt.Errorf("invalid local port range %q", s)
+	}
+
+	return from, to, nil
+}
+
+func (d *localDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to addr. If a port range is configured, the ports are
+// tried in turn until one is available.
+func (d *localDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.from == 0 {
+		dialer := *d.dialer
+		dialer.LocalAddr = &net.TCPAddr{IP: d.ip}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	ports := d.to - d.from + 1
+	offset := int(atomic.AddUint32(&d.next, 1) - 1)
+
+	var err error
+	for i := 0; i < ports; i++ {
+		dialer := *d.dialer
+		dialer.LocalAddr = &net.TCPAddr{IP: d.ip, Port: d.from + (offset+i)%ports}
+
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+
+		if !errors.Is(err, syscall.EADDRINUSE) && !errors.Is(err, syscall.EADDRNOTAVAIL) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("no free local port in range %d-%d: %v", d.from, d.to, err)
+}
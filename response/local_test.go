@@ -0,0 +1,190 @@
+package response
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// externalAddress returns a local IPv4 address which is not a loopback address.
+func externalAddress(t testing.TB) string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, addr := range addrs {
+		n, ok := addr.(*net.IPNet)
+		if ok && n.IP.To4() != nil && !n.IP.IsLoopback() && !n.IP.IsLinkLocalUnicast() {
+			return n.IP.String()
+		}
+	}
+
+	t.Skip("no non-loopback IPv4 address found")
+	return ""
+}
+
+// freePort returns a local TCP port which is not in use.
+func freePort(t testing.TB) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestLocalAddress(t *testing.T) {
+	remote := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote <- r.RemoteAddr
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	port := freePort(t)
+
+	var tests = []struct {
+		iface    string
+		ports    string
+		external bool // use a local address which is not a loopback address
+	}{
+		{external: true},
+		{iface: "127.0.0.1", ports: strconv.Itoa(port)},
+		{iface: "127.0.0.1", ports: strconv.Itoa(port) + "-" + strconv.Itoa(port+2)},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			iface := test.iface
+			if test.external {
+				iface = externalAddress(t)
+			}
+
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.Interface = iface
+			template.LocalPort = test.ports
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			input := make(chan string, 1)
+			input <- ""
+			close(input)
+			output := make(chan Response, 1)
+			NewRunner(tr, template, input, output).Run(context.Background())
+
+			res := <-output
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			host, p, err := net.SplitHostPort(<-remote)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if host != iface {
+				t.Errorf("connection originates from wrong address, want %v, got %v", iface, host)
+			}
+
+			if test.ports != "" {
+				from, to, err := parsePortRange(test.ports)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				port, err := strconv.Atoi(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if port < from || port > to {
+					t.Errorf("connection originates from port %v, which is not in range %v", port, test.ports)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalAddressInvalid(t *testing.T) {
+	var tests = []struct {
+		iface string
+		ports string
+	}{
+		{iface: "foo"},
+		{iface: "192.0.2.1"},
+		{ports: "40000"},
+		{iface: "127.0.0.1", ports: "x"},
+		{iface: "127.0.0.1", ports: "0"},
+		{iface: "127.0.0.1", ports: "40100-40000"},
+		{iface: "127.0.0.1", ports: "40000-70000"},
+	}
+
+	for _, test := range tests {
+		template := request.New("")
+		template.Interface = test.iface
+		template.LocalPort = test.ports
+
+		_, err := NewTransport(template, 1)
+		if err == nil {
+			t.Errorf("expected error not returned for %q, %q", test.iface, test.ports)
+		}
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	socket := filepath.Join(tempdir, "test.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unable to listen on Unix domain socket: %v", err)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host+" "+r.URL.Path)
+	})}
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = "http://docker/v1.40/FUZZ"
+	template.UnixSocket = socket
+
+	res := runSingle(t, template, "version")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	want := "docker /v1.40/version"
+	if string(res.RawBody) != want {
+		t.Errorf("wrong response, want %q, got %q", want, res.RawBody)
+	}
+
+	template.Resolve = []string{"docker:80:127.0.0.1"}
+	_, err = NewTransport(template, 1)
+	if err == nil {
+		t.Error("expected error for --unix-socket together with --resolve not returned")
+	}
+}
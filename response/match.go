@@ -0,0 +1,184 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// splitMatch splits a match expression like "$.error == null" into the path,
+// the operator (== or !=) and the value. The operator is empty if the
+// expression only consists of the path.
+func splitMatch(expr string) (path, op, value string) {
+	i := strings.Index(expr, "==")
+	j := strings.Index(expr, "!=")
+	if j >= 0 && (i < 0 || j < i) {
+		i = j
+	}
+
+	if i < 0 {
+		return strings.TrimSpace(expr), "", ""
+	}
+
+	return strings.TrimSpace(expr[:i]), expr[i : i+2], strings.TrimSpace(expr[i+2:])
+}
+
+// normalizeJSON converts v to the types used by encoding/json without
+// json.Number, so that values can be compared.
+func normalizeJSON(v interface{}) interface{} {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	var res interface{}
+	err = json.Unmarshal(buf, &res)
+	if err != nil {
+		return v
+	}
+	return res
+}
+
+// JSONMatch checks an element of a JSON response body.
+type JSONMatch struct {
+	expr  string
+	path  string
+	op    string
+	value interface{}
+}
+
+// ParseJSONMatch parses a match expression for a JSON body, which consists of
+// a JSONPath expression, optionally followed by == or != and a JSON value
+// (e.g. "$.error == null" or "$.user.role != 'admin'"). Values which are not
+// valid JSON are used as strings, quotes are removed. Without an operator,
+// the element must exist and not be null. Missing elements are equal to null.
+func ParseJSONMatch(expr string) (JSONMatch, error) {
+	path, op, value := splitMatch(expr)
+	err := request.CheckJSONPath(path)
+	if err != nil {
+		return JSONMatch{}, fmt.Errorf("invalid JSON match %q: %v", expr, err)
+	}
+
+	m := JSONMatch{expr: expr, path: path, op: op}
+	if op != "" {
+		var v interface{}
+		err = json.Unmarshal([]byte(value), &v)
+		if err != nil {
+			v = unquote(value)
+		}
+		m.value = v
+	}
+
+	return m, nil
+}
+
+// Match returns true if the body matches.
+func (m JSONMatch) Match(body []byte) bool {
+	if !json.Valid(body) {
+		return false
+	}
+
+	v, err := request.LookupJSON(body, m.path)
+	if err != nil {
+		// the element does not exist
+		v = nil
+	}
+	v = normalizeJSON(v)
+
+	switch m.op {
+	case "==":
+		return reflect.DeepEqual(v, m.value)
+	case "!=":
+		return !reflect.DeepEqual(v, m.value)
+	}
+	return v != nil
+}
+
+// unquote removes single or double quotes around s.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// XPathMatch checks the values selected by an XPath expression in an XML or
+// HTML response body.
+type XPathMatch struct {
+	expr  string
+	xpath *XPath
+	op    string
+	value string
+}
+
+// ParseXPathMatch parses a match expression for an XML body, which consists
+// of an XPath expression, optionally followed by == or != and a value (e.g.
+// "//status == 'ok'"). Without an operator, the expression must select
+// something. With ==, one of the selected values must be equal to the value,
+// with != none of them.
+func ParseXPathMatch(expr string) (XPathMatch, error) {
+	path, op, value := splitMatch(expr)
+	x, err := ParseXPath(path)
+	if err != nil {
+		return XPathMatch{}, err
+	}
+
+	return XPathMatch{expr: expr, xpath: x, op: op, value: unquote(value)}, nil
+}
+
+// Match returns true if the body matches.
+func (m XPathMatch) Match(body []byte) bool {
+	values, err := m.xpath.Eval(body)
+	if err != nil {
+		return false
+	}
+
+	if m.op == "" {
+		return len(values) > 0
+	}
+
+	found := false
+	for _, v := range values {
+		if v == m.value {
+			found = true
+			break
+		}
+	}
+
+	if m.op == "!=" {
+		return !found
+	}
+	return found
+}
+
+// FilterMatch shows only responses whose body matches all JSON and XPath
+// expressions. Responses without an HTTP response (e.g. for errors) are not
+// filtered.
+type FilterMatch struct {
+	JSON  []JSONMatch
+	XPath []XPathMatch
+}
+
+// Reject decides if r is to be printed.
+func (f FilterMatch) Reject(r Response) bool {
+	if r.HTTPResponse == nil {
+		return false
+	}
+
+	for _, m := range f.JSON {
+		if !m.Match(r.RawBody) {
+			return true
+		}
+	}
+
+	for _, m := range f.XPath {
+		if !m.Match(r.RawBody) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestJSONMatch(t *testing.T) {
+	var body = []byte(`{"error": null, "user": {"name": "admin", "id": 5, "roles": ["a", "b"]}}`)
+
+	var tests = []struct {
+		expr  string
+		match bool
+	}{
+		{"$.error == null", true},
+		{"$.error != null", false},
+		{"$.error", false},
+		{"$.user.name", true},
+		{"$.missing == null", true},
+		{"$.missing", false},
+		{"$.user.name == 'admin'", true},
+		{`$.user.name == "admin"`, true},
+		{"$.user.name == admin", true},
+		{"$.user.name != admin", false},
+		{"$.user.id == 5", true},
+		{"$.user.id == 5.0", true},
+		{"$.user.id == '5'", false},
+		{`$.user.roles == ["a","b"]`, true},
+		{"$.user.roles[1] == b", true},
+	}
+
+	for _, test := range tests {
+		m, err := ParseJSONMatch(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m.Match(body) != test.match {
+			t.Errorf("wrong result for %v, want %v", test.expr, test.match)
+		}
+	}
+
+	m, err := ParseJSONMatch("$.missing == null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Match([]byte("<html>")) {
+		t.Errorf("invalid JSON matched")
+	}
+
+	_, err = ParseJSONMatch("error == null")
+	if err == nil {
+		t.Errorf("no error for invalid path")
+	}
+}
+
+func TestXPathMatch(t *testing.T) {
+	var body = []byte(`<response><status>ok</status><item id="1"/><item id="2"/></response>`)
+
+	var tests = []struct {
+		expr  string
+		match bool
+	}{
+		{"//status", true},
+		{"//error", false},
+		{"//status == ok", true},
+		{"//status == 'ok'", true},
+		{"//status != ok", false},
+		{"//item/@id == 2", true},
+		{"//item/@id != 3", true},
+	}
+
+	for _, test := range tests {
+		m, err := ParseXPathMatch(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m.Match(body) != test.match {
+			t.Errorf("wrong result for %v, want %v", test.expr, test.match)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	jm, err := ParseJSONMatch("$.error == null")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := FilterMatch{JSON: []JSONMatch{jm}}
+	res := func(body string) Response {
+		return Response{HTTPResponse: &http.Response{}, RawBody: []byte(body)}
+	}
+
+	if f.Reject(res(`{"error": null}`)) {
+		t.Errorf("matching response rejected")
+	}
+
+	if !f.Reject(res(`{"error": "invalid password"}`)) {
+		t.Errorf("response with error not rejected")
+	}
+
+	if f.Reject(Response{}) {
+		t.Errorf("response without HTTP response rejected")
+	}
+}
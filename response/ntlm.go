@@ -0,0 +1,347 @@
+package response
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/crypto/md4"
+)
+
+// NTLM authentication (MS-NLMP) with NTLMv2 responses. Only the
+// authentication is implemented, messages are neither signed nor sealed.
+
+const (
+	ntlmNegotiateUnicode  = 0x00000001
+	ntlmRequestTarget     = 0x00000004
+	ntlmNegotiateNTLM     = 0x00000200
+	ntlmAlwaysSign        = 0x00008000
+	ntlmExtendedSecurity  = 0x00080000
+	ntlmNegotiateTarget   = 0x00800000
+	ntlmNegotiate128      = 0x20000000
+	ntlmNegotiate56       = 0x80000000
+	ntlmNegotiateDefaults = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateNTLM | ntlmAlwaysSign |
+		ntlmExtendedSecurity | ntlmNegotiateTarget | ntlmNegotiate128 | ntlmNegotiate56
+
+	ntlmAvEOL       = 0
+	ntlmAvTimestamp = 7
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmNegotiateMessage returns the first message of the handshake.
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateDefaults)
+	// the domain and workstation fields are empty
+	return msg
+}
+
+// ntlmChallenge is the second message of the handshake, sent by the server.
+type ntlmChallenge struct {
+	flags      uint32
+	challenge  [8]byte
+	targetInfo []byte
+}
+
+// ntlmField returns the contents of the field described at offset in msg.
+func ntlmField(msg []byte, offset int) ([]byte, error) {
+	if len(msg) < offset+8 {
+		return nil, errors.New("NTLM message too short")
+	}
+
+	length := int(binary.LittleEndian.Uint16(msg[offset:]))
+	start := int(binary.LittleEndian.Uint32(msg[offset+4:]))
+	if start+length > len(msg) {
+		return nil, errors.New("invalid field in NTLM message")
+	}
+
+	return msg[start : start+length], nil
+}
+
+func parseNTLMChallenge(msg []byte) (*ntlmChallenge, error) {
+	if len(msg) < 32 || !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return nil, errors.New("invalid NTLM challenge message")
+	}
+
+	c := &ntlmChallenge{flags: binary.LittleEndian.Uint32(msg[20:])}
+	copy(c.challenge[:], msg[24:32])
+
+	if len(msg) >= 48 {
+		info, err := ntlmField(msg, 40)
+		if err != nil {
+			return nil, err
+		}
+		c.targetInfo = info
+	}
+
+	return c, nil
+}
+
+// timestamp returns the timestamp from the target info, if present.
+func (c *ntlmChallenge) timestamp() ([]byte, bool) {
+	info := c.targetInfo
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		length := int(binary.LittleEndian.Uint16(info[2:]))
+		if id == ntlmAvEOL || len(info) < 4+length {
+			break
+		}
+		if id == ntlmAvTimestamp && length == 8 {
+			return info[4:12], true
+		}
+		info = info[4+length:]
+	}
+	return nil, false
+}
+
+func utf16le(s string) []byte {
+	codes := utf16.Encode([]rune(s))
+	buf := make([]byte, 2*len(codes))
+	for i, c := range codes {
+		binary.LittleEndian.PutUint16(buf[2*i:], c)
+	}
+	return buf
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		_, _ = mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// ntowfv2 returns the NTLMv2 hash for the credentials.
+func ntowfv2(user, password, domain string) []byte {
+	h := md4.New()
+	_, _ = h.Write(utf16le(password))
+	return hmacMD5(h.Sum(nil), utf16le(strings.ToUpper(user)+domain))
+}
+
+// ntlmv2Response computes the NTLMv2 and LMv2 responses for the challenge.
+func ntlmv2Response(hash []byte, c *ntlmChallenge, clientChallenge, timestamp []byte) (nt, lm []byte) {
+	var temp bytes.Buffer
+	temp.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	temp.Write(timestamp)
+	temp.Write(clientChallenge)
+	temp.Write([]byte{0, 0, 0, 0})
+	temp.Write(c.targetInfo)
+	temp.Write([]byte{0, 0, 0, 0})
+
+	proof := hmacMD5(hash, c.challenge[:], temp.Bytes())
+	nt = append(proof, temp.Bytes()...)
+
+	lm = append(hmacMD5(hash, c.challenge[:], clientChallenge), clientChallenge...)
+	return nt, lm
+}
+
+// ntlmAuthenticateMessage returns the last message of the handshake.
+func ntlmAuthenticateMessage(c *ntlmChallenge, user, password, domain string) ([]byte, error) {
+	clientChallenge := make([]byte, 8)
+	_, err := rand.Read(clientChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, fromServer := c.timestamp()
+	if !fromServer {
+		// Windows file time: 100ns intervals since 1601-01-01
+		timestamp = make([]byte, 8)
+		ft := uint64(time.Now().UnixNano()/100) + 116444736000000000
+		binary.LittleEndian.PutUint64(timestamp, ft)
+	}
+
+	nt, lm := ntlmv2Response(ntowfv2(user, password, domain), c, clientChallenge, timestamp)
+	if fromServer {
+		// the LMv2 response must not be sent when the server sent a timestamp
+		lm = make([]byte, 24)
+	}
+
+	fields := [][]byte{lm, nt, utf16le(domain), utf16le(user), nil, nil}
+
+	const headerLen = 64
+	msg := make([]byte, headerLen)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+
+	offset := headerLen
+	for i, f := range fields {
+		pos := 12 + 8*i
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(f)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(f)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(offset))
+		offset += len(f)
+	}
+
+	binary.LittleEndian.PutUint32(msg[60:], c.flags&ntlmNegotiateDefaults|ntlmNegotiateUnicode)
+
+	for _, f := range fields {
+		msg = append(msg, f...)
+	}
+
+	return msg, nil
+}
+
+// splitNTLMUser splits "domain\user" (or "user@domain") into the user name
+// and the domain.
+func splitNTLMUser(s string) (user, domain string) {
+	if i := strings.IndexByte(s, '\\'); i >= 0 {
+		return s[i+1:], s[:i]
+	}
+	if i := strings.LastIndexByte(s, '@'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// ntlmScheme returns the authentication scheme offered by the server which
+// can be used for NTLM: "NTLM" or "Negotiate" (with NTLM tokens, Kerberos is
+// not supported).
+func ntlmScheme(res *http.Response) string {
+	scheme := ""
+	for _, v := range res.Header["Www-Authenticate"] {
+		name := strings.TrimSpace(strings.SplitN(v, " ", 2)[0])
+		switch {
+		case strings.EqualFold(name, "NTLM"):
+			return "NTLM"
+		case strings.EqualFold(name, "Negotiate"):
+			scheme = "Negotiate"
+		}
+	}
+	return scheme
+}
+
+// ntlmToken returns the token sent by the server for scheme.
+func ntlmToken(res *http.Response, scheme string) ([]byte, bool) {
+	for _, v := range res.Header["Www-Authenticate"] {
+		data := strings.SplitN(strings.TrimSpace(v), " ", 2)
+		if len(data) != 2 || !strings.EqualFold(data[0], scheme) {
+			continue
+		}
+
+		buf, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data[1]))
+		if err != nil {
+			continue
+		}
+		return buf, true
+	}
+	return nil, false
+}
+
+// ntlmTransport handles NTLM authentication. The handshake authenticates the
+// connection, so next must use a single connection per host (and no
+// HTTP/2). The following requests are sent over the authenticated
+// connection without the handshake. If the credentials contain the
+// placeholder, the connection is closed after the handshake so that the next
+// request is authenticated with its own credentials.
+type ntlmTransport struct {
+	next     *http.Transport
+	template *request.Request
+}
+
+func newNTLMTransport(tr *http.Transport, template *request.Request) *ntlmTransport {
+	next := tr.Clone()
+	next.MaxConnsPerHost = 1
+	next.MaxIdleConnsPerHost = 1
+	next.ForceAttemptHTTP2 = false
+	next.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+
+	return &ntlmTransport{next: next, template: template}
+}
+
+// retry sends req again with the Authorization header auth. If last is set,
+// this is the last message of the handshake.
+func (t *ntlmTransport) retry(req *http.Request, auth string, last bool) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+
+	// the connection must not be used for the next request when it would be
+	// authenticated with different credentials
+	if last && strings.Contains(t.template.NTLMAuth, t.template.Replace) {
+		newReq.Close = true
+	}
+
+	if req.GetBody != nil {
+		var err error
+		newReq.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	newReq.Header.Set("Authorization", auth)
+
+	return t.next.RoundTrip(newReq)
+}
+
+// discard reads and closes the body of res so the connection can be reused
+// for the next message of the handshake.
+func discard(res *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+	_ = res.Body.Close()
+}
+
+func (t *ntlmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	scheme := ntlmScheme(res)
+	if scheme == "" {
+		return res, nil
+	}
+
+	// the body cannot be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+
+	value, _ := request.FromContext(req.Context())
+	userDomain, password, err := t.template.Credentials(t.template.NTLMAuth, value)
+	if err != nil {
+		discard(res)
+		return nil, err
+	}
+	user, domain := splitNTLMUser(userDomain)
+
+	discard(res)
+	res, err = t.retry(req, scheme+" "+base64.StdEncoding.EncodeToString(ntlmNegotiateMessage()), false)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	token, ok := ntlmToken(res, scheme)
+	if !ok {
+		return res, nil
+	}
+
+	challenge, err := parseNTLMChallenge(token)
+	if err != nil {
+		discard(res)
+		return nil, fmt.Errorf("NTLM: %v", err)
+	}
+
+	msg, err := ntlmAuthenticateMessage(challenge, user, password, domain)
+	if err != nil {
+		discard(res)
+		return nil, err
+	}
+
+	discard(res)
+	return t.retry(req, scheme+" "+base64.StdEncoding.EncodeToString(msg), true)
+}
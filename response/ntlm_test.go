@@ -0,0 +1,202 @@
+package response
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func unhex(t testing.TB, s string) []byte {
+	buf, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestNTLMv2Response(t *testing.T) {
+	// test vectors from MS-NLMP, section 4.2.4
+	hash := ntowfv2("User", "Password", "Domain")
+	want := unhex(t, "0c 86 8a 40 3b fd 7a 93 a3 00 1e f2 2e f0 2e 3f")
+	if !bytes.Equal(hash, want) {
+		t.Fatalf("wrong NTOWFv2, want %x, got %x", want, hash)
+	}
+
+	c := &ntlmChallenge{
+		targetInfo: unhex(t, "02 00 0c 00 44 00 6f 00 6d 00 61 00 69 00 6e 00"+
+			"01 00 0c 00 53 00 65 00 72 00 76 00 65 00 72 00 00 00 00 00"),
+	}
+	copy(c.challenge[:], unhex(t, "01 23 45 67 89 ab cd ef"))
+
+	clientChallenge := unhex(t, "aa aa aa aa aa aa aa aa")
+	nt, lm := ntlmv2Response(hash, c, clientChallenge, make([]byte, 8))
+
+	wantProof := unhex(t, "68 cd 0a b8 51 e5 1c 96 aa bc 92 7b eb ef 6a 1c")
+	if !bytes.Equal(nt[:16], wantProof) {
+		t.Errorf("wrong NTProofStr, want %x, got %x", wantProof, nt[:16])
+	}
+
+	wantLM := unhex(t, "86 c3 50 97 ac 9c ec 10 25 54 76 4a 57 cc cc 19 aa aa aa aa aa aa aa aa")
+	if !bytes.Equal(lm, wantLM) {
+		t.Errorf("wrong LMv2 response, want %x, got %x", wantLM, lm)
+	}
+}
+
+func TestSplitNTLMUser(t *testing.T) {
+	var tests = []struct {
+		s, user, domain string
+	}{
+		{`CORP\alice`, "alice", "CORP"},
+		{"alice@corp.example.com", "alice", "corp.example.com"},
+		{"alice", "alice", ""},
+	}
+
+	for _, test := range tests {
+		user, domain := splitNTLMUser(test.s)
+		if user != test.user || domain != test.domain {
+			t.Errorf("%q: want %q, %q, got %q, %q", test.s, test.user, test.domain, user, domain)
+		}
+	}
+}
+
+// ntlmServer requires NTLM authentication for a connection, it checks the
+// NTLMv2 response for the password.
+type ntlmServer struct {
+	password string
+
+	mu            sync.Mutex
+	authenticated map[string]string // remote address -> user
+	handshakes    int
+}
+
+// decodeUTF16 decodes a little endian UTF-16 string.
+func decodeUTF16(buf []byte) string {
+	codes := make([]uint16, len(buf)/2)
+	for i := range codes {
+		codes[i] = binary.LittleEndian.Uint16(buf[2*i:])
+	}
+	return string(utf16.Decode(codes))
+}
+
+func (s *ntlmServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	challenge := &ntlmChallenge{targetInfo: []byte{0, 0, 0, 0}}
+	copy(challenge.challenge[:], "12345678")
+
+	s.mu.Lock()
+	user, ok := s.authenticated[r.RemoteAddr]
+	s.mu.Unlock()
+	if ok {
+		_, _ = io.WriteString(w, "ok "+user)
+		return
+	}
+
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "NTLM ")
+	msg, _ := base64.StdEncoding.DecodeString(auth)
+
+	switch {
+	case len(msg) > 12 && msg[8] == 1:
+		// send the challenge
+		buf := make([]byte, 48)
+		copy(buf, ntlmSignature)
+		buf[8] = 2
+		binary.LittleEndian.PutUint32(buf[20:], ntlmNegotiateDefaults)
+		copy(buf[24:], challenge.challenge[:])
+		binary.LittleEndian.PutUint16(buf[40:], 4)
+		binary.LittleEndian.PutUint32(buf[44:], 48)
+		buf = append(buf, challenge.targetInfo...)
+
+		w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(buf))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+
+	case len(msg) > 12 && msg[8] == 3:
+		nt, _ := ntlmField(msg, 20)
+		domain, _ := ntlmField(msg, 28)
+		userField, _ := ntlmField(msg, 36)
+		user := decodeUTF16(userField)
+
+		s.mu.Lock()
+		s.handshakes++
+		s.mu.Unlock()
+
+		if len(nt) > 16 {
+			hash := ntowfv2(user, s.password, decodeUTF16(domain))
+			proof := hmacMD5(hash, challenge.challenge[:], nt[16:])
+			if bytes.Equal(proof, nt[:16]) {
+				s.mu.Lock()
+				s.authenticated[r.RemoteAddr] = user
+				s.mu.Unlock()
+
+				_, _ = io.WriteString(w, "ok "+user)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("WWW-Authenticate", "NTLM")
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func TestNTLMAuth(t *testing.T) {
+	var tests = []struct {
+		auth       string
+		values     []string
+		status     []int
+		handshakes int
+	}{
+		{
+			// the connection is authenticated once
+			auth:       `CORP\alice:secret`,
+			values:     []string{"a", "b", "c"},
+			status:     []int{200, 200, 200},
+			handshakes: 1,
+		},
+		{
+			auth:       `CORP\alice:FUZZ`,
+			values:     []string{"wrong", "secret", "other"},
+			status:     []int{401, 200, 401},
+			handshakes: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			handler := &ntlmServer{password: "secret", authenticated: make(map[string]string)}
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.NTLMAuth = test.auth
+
+			responses := runValues(t, template, test.values...)
+			for i, res := range responses {
+				if res.Error != nil {
+					t.Fatal(res.Error)
+				}
+
+				if res.HTTPResponse.StatusCode != test.status[i] {
+					t.Errorf("request %d: wrong status, want %v, got %v", i, test.status[i], res.HTTPResponse.StatusCode)
+				}
+
+				if test.status[i] == http.StatusOK && string(res.RawBody) != "ok alice" {
+					t.Errorf("request %d: wrong body %q", i, res.RawBody)
+				}
+			}
+
+			if handler.handshakes != test.handshakes {
+				t.Errorf("wrong number of handshakes, want %v, got %v", test.handshakes, handler.handshakes)
+			}
+		})
+	}
+}
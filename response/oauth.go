@@ -0,0 +1,322 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// oauthMinRefreshInterval is the minimal time between two token requests
+// triggered by responses with status 401, so that a server which rejects all
+// tokens does not receive a token request for each response.
+const oauthMinRefreshInterval = 10 * time.Second
+
+// OAuthClient requests an access token from the token endpoint with the OAuth
+// 2.0 client credentials grant (RFC 6749, section 4.4) and adds it as a bearer
+// token to the requests.
+type OAuthClient struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+	Client       *http.Client
+
+	// HostFilter checks the host of the token endpoint, may be nil
+	HostFilter *request.HostFilter
+
+	// Error is called when the token could not be refreshed after the server
+	// rejected it, may be nil.
+	Error func(error)
+
+	// template is the main request, used for the address to connect to
+	template *request.Request
+
+	// header is set when the Authorization header is set or removed via
+	// --header, the token is not sent in this case
+	header bool
+
+	// minimal time between two token requests after a 401 response
+	minRefresh time.Duration
+
+	fetch sync.Mutex // held while a token is requested
+
+	mu          sync.Mutex
+	token       string
+	expires     time.Time // zero if the lifetime of the token is unknown
+	lastRequest time.Time
+}
+
+// NewOAuthClient returns a client for the token endpoint configured in
+// template, the token is requested via tr. If no token endpoint is
+// configured, nil is returned.
+func NewOAuthClient(template *request.Request, tr http.RoundTripper) (*OAuthClient, error) {
+	if template.OAuthTokenURL == "" {
+		return nil, nil
+	}
+
+	if template.OAuthClientID == "" {
+		return nil, errors.New("OAuth: no client ID specified (--oauth-client-id)")
+	}
+
+	if template.UserPass != "" || template.DigestAuth != "" || template.NTLMAuth != "" {
+		return nil, errors.New("--oauth-token-url cannot be used together with --user, --auth-digest or --auth-ntlm")
+	}
+
+	u, err := url.Parse(template.OAuthTokenURL)
+	if err != nil {
+		return nil, fmt.Errorf("OAuth: invalid token URL: %v", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("OAuth: invalid token URL %q", template.OAuthTokenURL)
+	}
+
+	c := &OAuthClient{
+		TokenURL:     template.OAuthTokenURL,
+		ClientID:     template.OAuthClientID,
+		ClientSecret: template.OAuthClientSecret,
+		Scope:        template.OAuthScope,
+		minRefresh:   oauthMinRefreshInterval,
+		template:     template,
+		Client: &http.Client{
+			Transport: tr,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	for name := range template.Header.Header {
+		c.header = c.header || strings.EqualFold(name, "Authorization")
+	}
+	for name := range template.Header.Remove {
+		c.header = c.header || strings.EqualFold(name, "Authorization")
+	}
+
+	return c, nil
+}
+
+// oauthToken is the response of the token endpoint.
+type oauthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Run requests a new token from the token endpoint.
+func (c *OAuthClient) Run(ctx context.Context) error {
+	c.fetch.Lock()
+	defer c.fetch.Unlock()
+
+	return c.run(ctx)
+}
+
+func (c *OAuthClient) run(ctx context.Context) error {
+	form := url.Values{"grant_type": []string{"client_credentials"}}
+	if c.Scope != "" {
+		form.Set("scope", c.Scope)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("OAuth: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// the client credentials are form encoded first (RFC 6749, section 2.3.1)
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	c.mu.Lock()
+	c.lastRequest = time.Now()
+	c.mu.Unlock()
+
+	// the token endpoint is not the target, so the entries for --resolve,
+	// --connect-to and --target-ip which depend on the value are not used
+	req = req.WithContext(request.NewContextWithoutValue(ctx))
+	err = CheckTarget(c.template, c.HostFilter, req)
+	if err != nil {
+		return fmt.Errorf("OAuth: %v", err)
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OAuth: %v", err)
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(res.Body, 1024*1024))
+	_ = res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("OAuth: %v", err)
+	}
+
+	var token oauthToken
+	err = json.Unmarshal(buf, &token)
+
+	switch {
+	case token.Error != "" && token.ErrorDescription != "":
+		return fmt.Errorf("OAuth: token request failed (status %v): %v: %v", res.Status, token.Error, token.ErrorDescription)
+	case token.Error != "":
+		return fmt.Errorf("OAuth: token request failed (status %v): %v", res.Status, token.Error)
+	case res.StatusCode != http.StatusOK:
+		return fmt.Errorf("OAuth: token request failed (status %v)", res.Status)
+	case err != nil:
+		return fmt.Errorf("OAuth: invalid response from token endpoint: %v", err)
+	case token.AccessToken == "":
+		return errors.New("OAuth: no access token in response from token endpoint")
+	case token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer"):
+		return fmt.Errorf("OAuth: unsupported token type %q", token.TokenType)
+	}
+
+	c.mu.Lock()
+	c.token = token.AccessToken
+	c.expires = time.Time{}
+	if token.ExpiresIn > 0 {
+		c.expires = c.lastRequest.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Token returns the current access token.
+func (c *OAuthClient) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+// Authorize sets the Authorization header of req to the current token and
+// returns it. The header is not changed if it is set or removed with
+// --header.
+func (c *OAuthClient) Authorize(req *http.Request) (token string) {
+	token = c.Token()
+	if !c.header {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return token
+}
+
+// Unauthorized is called when the server rejected the token used for a
+// request. It requests a new token unless this has already happened since
+// the request was sent, and returns true if the request should be sent again
+// with the new token.
+func (c *OAuthClient) Unauthorized(ctx context.Context, used string) bool {
+	c.fetch.Lock()
+	defer c.fetch.Unlock()
+
+	c.mu.Lock()
+	token, last := c.token, c.lastRequest
+	c.mu.Unlock()
+
+	if token != used {
+		return true
+	}
+
+	if time.Since(last) < c.minRefresh {
+		return false
+	}
+
+	err := c.run(ctx)
+	if err != nil {
+		if c.Error != nil && ctx.Err() == nil {
+			c.Error(err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// Refresh requests a new token before the current one expires until ctx is
+// cancelled. Errors are passed to onError and the token is requested again
+// later. It returns immediately if the token endpoint did not send the
+// lifetime of the token.
+func (c *OAuthClient) Refresh(ctx context.Context, onError func(error)) {
+	for {
+		c.mu.Lock()
+		expires, last := c.expires, c.lastRequest
+		c.mu.Unlock()
+
+		if expires.IsZero() {
+			return
+		}
+
+		// refresh when 90% of the lifetime have passed
+		wait := time.Until(last.Add(expires.Sub(last) * 9 / 10))
+		if wait < c.minRefresh {
+			wait = c.minRefresh
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := c.Run(ctx)
+		if err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+}
+
+// Transport returns a round tripper which sends requests via next with the
+// token and sends a request again with a new token when the server rejects
+// the token with status 401.
+func (c *OAuthClient) Transport(next http.RoundTripper) http.RoundTripper {
+	return &oauthTransport{next: next, client: c}
+}
+
+type oauthTransport struct {
+	next   http.RoundTripper
+	client *OAuthClient
+}
+
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	token := t.client.Authorize(newReq)
+
+	res, err := t.next.RoundTrip(newReq)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || t.client.header {
+		return res, err
+	}
+
+	// the body cannot be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+
+	if !t.client.Unauthorized(req.Context(), token) {
+		return res, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			discard(res)
+			return nil, err
+		}
+	}
+	t.client.Authorize(retry)
+
+	discard(res)
+	return t.next.RoundTrip(retry)
+}
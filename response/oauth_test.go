@@ -0,0 +1,270 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// oauthServer issues tokens for the client credentials grant and accepts only
+// the last token issued for API requests.
+type oauthServer struct {
+	mu     sync.Mutex
+	issued int
+	token  string
+}
+
+func (s *oauthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "monsoon" || secret != "s3cret" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"error": "invalid_client"}`)
+			return
+		}
+
+		if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("scope") != "read write" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error": "invalid_request"}`)
+			return
+		}
+
+		s.issued++
+		s.token = fmt.Sprintf("token%d", s.issued)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer", "expires_in": 3600}`, s.token)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	_, _ = io.WriteString(w, "ok "+s.token)
+}
+
+// revoke invalidates the current token.
+func (s *oauthServer) revoke() {
+	s.mu.Lock()
+	s.token = "revoked"
+	s.mu.Unlock()
+}
+
+func TestOAuth(t *testing.T) {
+	handler := &oauthServer{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/api/FUZZ"
+	template.OAuthTokenURL = srv.URL + "/token"
+	template.OAuthClientID = "monsoon"
+	template.OAuthClientSecret = "s3cret"
+	template.OAuthScope = "read write"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	oauth, err := NewOAuthClient(template, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oauth.minRefresh = 0
+
+	err = oauth.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(value string) Response {
+		input := make(chan string, 1)
+		input <- value
+		close(input)
+		output := make(chan Response, 1)
+
+		runner := NewRunner(tr, template, input, output)
+		runner.Client.Transport = oauth.Transport(runner.Client.Transport)
+		runner.Run(context.Background())
+
+		res := <-output
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		return res
+	}
+
+	res := send("a")
+	if string(res.RawBody) != "ok token1" {
+		t.Fatalf("wrong response %q", res.RawBody)
+	}
+
+	// a new token is requested when the server rejects the token
+	handler.revoke()
+	res = send("b")
+	if string(res.RawBody) != "ok token2" {
+		t.Fatalf("request was not sent again with a new token, response %q", res.RawBody)
+	}
+
+	if handler.issued != 2 {
+		t.Errorf("wrong number of tokens issued, want 2, got %v", handler.issued)
+	}
+}
+
+func TestOAuthErrors(t *testing.T) {
+	handler := &oauthServer{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/api"
+	template.OAuthTokenURL = srv.URL + "/token"
+	template.OAuthClientID = "monsoon"
+	template.OAuthClientSecret = "wrong"
+
+	oauth, err := NewOAuthClient(template, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = oauth.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Fatalf("wrong error, got %v", err)
+	}
+
+	template.OAuthClientID = ""
+	_, err = NewOAuthClient(template, http.DefaultTransport)
+	if err == nil {
+		t.Fatal("missing client ID not detected")
+	}
+
+	template.OAuthClientID = "monsoon"
+	template.UserPass = "user:pass"
+	_, err = NewOAuthClient(template, http.DefaultTransport)
+	if err == nil {
+		t.Fatal("--user not detected")
+	}
+}
+
+func TestOAuthHostFilter(t *testing.T) {
+	handler := &oauthServer{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = "http://www.example.com/api"
+	template.OAuthTokenURL = srv.URL + "/token"
+	template.OAuthClientID = "monsoon"
+	template.OAuthClientSecret = "s3cret"
+	template.OAuthScope = "read write"
+	template.DenyHost = []string{"127.0.0.0/8"}
+
+	oauth, err := NewOAuthClient(template, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oauth.HostFilter, err = NewHostFilter(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = oauth.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "is denied") {
+		t.Fatalf("wrong error, got %v", err)
+	}
+
+	if handler.issued != 0 {
+		t.Errorf("token requested from a denied host")
+	}
+}
+
+func TestOAuthDialOverrides(t *testing.T) {
+	handler := &oauthServer{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := request.New("")
+	template.URL = "http://api.example.com:" + u.Port() + "/api/FUZZ"
+	template.OAuthTokenURL = "http://auth.example.com:" + u.Port() + "/token"
+	template.OAuthClientID = "monsoon"
+	template.OAuthClientSecret = "s3cret"
+	template.OAuthScope = "read write"
+
+	// the entry for --target-ip depends on the value and must not be used
+	// for the token request, but the static entry for --connect-to is
+	template.ConnectTo = []string{"auth.example.com::127.0.0.1:"}
+	template.TargetIP = "FUZZ"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	oauth, err := NewOAuthClient(template, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oauth.minRefresh = 0
+
+	err = oauth.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(value string) Response {
+		input := make(chan string, 1)
+		input <- value
+		close(input)
+		output := make(chan Response, 1)
+
+		runner := NewRunner(tr, template, input, output)
+		runner.Client.Transport = oauth.Transport(runner.Client.Transport)
+		runner.Run(context.Background())
+
+		res := <-output
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		return res
+	}
+
+	res := send("127.0.0.1")
+	if string(res.RawBody) != "ok token1" {
+		t.Fatalf("wrong response %q", res.RawBody)
+	}
+
+	// the token request triggered by the 401 response is sent with the
+	// context of the request, the value must not be used for it either
+	handler.revoke()
+	res = send("127.0.0.1")
+	if string(res.RawBody) != "ok token2" {
+		t.Fatalf("request was not sent again with a new token, response %q", res.RawBody)
+	}
+
+	if handler.issued != 2 {
+		t.Errorf("wrong number of tokens issued, want 2, got %v", handler.issued)
+	}
+}
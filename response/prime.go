@@ -0,0 +1,198 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// Primer sends the prime request, extracts a value from the response and
+// stores it as the value for the prime placeholder of the main request.
+type Primer struct {
+	Template *request.Request // the main request, receives the extracted value
+	Prime    *request.Request // the prime request
+	Client   *http.Client
+
+	Extract     *regexp.Regexp
+	ExtractJSON string // JSONPath, used instead of Extract if set
+	Placeholder string
+	Interval    time.Duration
+	Every       int // send the prime request again before every nth request
+
+	HostFilter *request.HostFilter // checks the host of the prime request, may be nil
+
+	mu    sync.Mutex // held while a request is built with Apply
+	count int        // number of requests built with Apply
+}
+
+// NewPrimer returns a primer for the prime request configured in template,
+// which is sent via tr. If no prime request is configured, nil is returned.
+func NewPrimer(template *request.Request, tr http.RoundTripper) (*Primer, error) {
+	if template.PrimeRequestFile == "" {
+		return nil, nil
+	}
+
+	if template.PrimeExtract == "" && template.PrimeExtractJSON == "" {
+		return nil, errors.New("prime request: no pattern for extracting the value specified (--prime-extract or --prime-extract-json)")
+	}
+
+	if template.PrimeExtract != "" && template.PrimeExtractJSON != "" {
+		return nil, errors.New("prime request: --prime-extract and --prime-extract-json cannot be used together")
+	}
+
+	if template.PrimeEvery < 0 {
+		return nil, fmt.Errorf("prime request: invalid value %d for --prime-every", template.PrimeEvery)
+	}
+
+	if template.PrimePlaceholder == "" || template.PrimePlaceholder == template.Replace {
+		return nil, fmt.Errorf("prime request: invalid placeholder %q", template.PrimePlaceholder)
+	}
+
+	var pattern *regexp.Regexp
+	if template.PrimeExtract != "" {
+		var err error
+		pattern, err = regexp.Compile(template.PrimeExtract)
+		if err != nil {
+			return nil, fmt.Errorf("prime request: regexp %q failed to compile: %v", template.PrimeExtract, err)
+		}
+	}
+
+	// only scheme and host are taken from the URL of the main request
+	target, err := url.Parse(template.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	prime := request.New(template.Replace)
+	prime.URL = target.Scheme + "://" + target.Host
+	prime.TemplateFile = template.PrimeRequestFile
+	prime.Vars = template.Vars
+
+	p := &Primer{
+		Template: template,
+		Prime:    prime,
+		Client: &http.Client{
+			Transport: tr,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Extract:     pattern,
+		ExtractJSON: template.PrimeExtractJSON,
+		Placeholder: template.PrimePlaceholder,
+		Interval:    template.PrimeInterval,
+		Every:       template.PrimeEvery,
+	}
+
+	return p, nil
+}
+
+// Run sends the prime request once and stores the extracted value.
+func (p *Primer) Run(ctx context.Context) error {
+	req, err := p.Prime.Apply("")
+	if err != nil {
+		return fmt.Errorf("prime request: %v", err)
+	}
+
+	req = req.WithContext(request.NewContext(ctx, ""))
+	err = CheckTarget(p.Template, p.HostFilter, req)
+	if err != nil {
+		return fmt.Errorf("prime request: %v", err)
+	}
+
+	res, err := p.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("prime request: %v", err)
+	}
+
+	var response Response
+	err = response.ReadBody(res.Body, DefaultBodyBufferSize)
+	if err != nil {
+		_ = res.Body.Close()
+		return fmt.Errorf("prime request: %v", err)
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("prime request: %v", err)
+	}
+
+	if p.ExtractJSON != "" {
+		value, err := request.ExtractJSON(response.RawBody, p.ExtractJSON)
+		if err != nil {
+			return fmt.Errorf("prime request: %v in response (status %v)", err, res.Status)
+		}
+
+		p.Template.Vars.Set(p.Placeholder, value)
+		return nil
+	}
+
+	err = response.ExtractHeader(res, []*regexp.Regexp{p.Extract})
+	if err != nil {
+		return fmt.Errorf("prime request: %v", err)
+	}
+	response.ExtractBody([]*regexp.Regexp{p.Extract})
+
+	if len(response.Extract) == 0 {
+		return fmt.Errorf("prime request: pattern %q not found in response (status %v)", p.Extract, res.Status)
+	}
+
+	p.Template.Vars.Set(p.Placeholder, response.Extract[0])
+
+	return nil
+}
+
+// Refresh sends the prime request every Interval until ctx is cancelled.
+// Errors are passed to onError, the previous value is kept in this case. It
+// returns immediately if no interval is configured.
+func (p *Primer) Refresh(ctx context.Context, onError func(error)) {
+	if p.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := p.Run(ctx)
+		if err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+}
+
+// Apply calls apply to build a request. If Every is set, the prime request is
+// sent again before every nth request, and the request is built with the new
+// value before any other request.
+func (p *Primer) Apply(ctx context.Context, apply func() (*http.Request, error)) (*http.Request, error) {
+	if p.Every <= 0 {
+		return apply()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the value from the first prime request (sent by Run) is used first
+	if p.count > 0 && p.count%p.Every == 0 {
+		err := p.Run(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.count++
+
+	return apply()
+}
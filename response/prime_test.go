@@ -0,0 +1,318 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestPrimeRequest(t *testing.T) {
+	var mu sync.Mutex
+	var tokens []string
+	var token = "abc123"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Prime") != "yes" {
+			t.Errorf("header from prime template not sent")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write([]byte(`<input type="hidden" name="csrf" value="` + token + `">`))
+	})
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tokens = append(tokens, r.Header.Get("X-CSRF-Token")+" "+r.URL.Query().Get("v"))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-prime-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	primeFile := filepath.Join(tempdir, "prime.txt")
+	err = ioutil.WriteFile(primeFile, []byte("GET /form HTTP/1.1\r\nX-Prime: yes\r\n\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := request.New("")
+	template.URL = srv.URL + "/submit?v=FUZZ"
+	template.Header.Set("X-CSRF-Token: PRIME")
+	template.PrimeRequestFile = primeFile
+	template.PrimeExtract = `name="csrf" value="([^"]+)"`
+	template.PrimePlaceholder = "PRIME"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primer, err := NewPrimer(template, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(value string) {
+		input := make(chan string, 1)
+		input <- value
+		close(input)
+
+		output := make(chan Response, 1)
+		NewRunner(tr, template, input, output).Run(context.Background())
+
+		res := <-output
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+	}
+
+	err = primer.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run("1")
+	run("2")
+
+	// refresh the token and make sure the new one is used
+	mu.Lock()
+	token = "def456"
+	mu.Unlock()
+
+	err = primer.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run("3")
+
+	want := []string{"abc123 1", "abc123 2", "def456 3"}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(tokens) != len(want) {
+		t.Fatalf("wrong number of requests, want %v, got %v", len(want), tokens)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("request %d: want %q, got %q", i, want[i], tokens[i])
+		}
+	}
+}
+
+func TestPrimeRequestNoMatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("nothing here"))
+	}))
+	defer srv.Close()
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-prime-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	primeFile := filepath.Join(tempdir, "prime.txt")
+	err = ioutil.WriteFile(primeFile, []byte("GET / HTTP/1.1\r\n\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := request.New("")
+	template.URL = srv.URL + "/"
+	template.PrimeRequestFile = primeFile
+	template.PrimeExtract = `token=(\w+)`
+	template.PrimePlaceholder = "PRIME"
+
+	primer, err := NewPrimer(template, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = primer.Run(context.Background())
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+}
+
+func TestPrimeRequestEvery(t *testing.T) {
+	var mu sync.Mutex
+	var primed int
+	var tokens []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		primed++
+		_, _ = fmt.Fprintf(w, `{"data": {"csrf": "t%d"}}`, primed)
+	})
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tokens = append(tokens, r.Header.Get("X-CSRF-Token")+" "+r.URL.Query().Get("v"))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-prime-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	primeFile := filepath.Join(tempdir, "prime.txt")
+	err = ioutil.WriteFile(primeFile, []byte("GET /token HTTP/1.1\r\n\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := request.New("")
+	template.URL = srv.URL + "/submit?v=FUZZ"
+	template.Header.Set("X-CSRF-Token: PRIME")
+	template.PrimeRequestFile = primeFile
+	template.PrimeExtractJSON = "$.data.csrf"
+	template.PrimePlaceholder = "PRIME"
+	template.PrimeEvery = 2
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primer, err := NewPrimer(template, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = primer.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := make(chan string, 5)
+	for i := 1; i <= 5; i++ {
+		input <- fmt.Sprintf("%d", i)
+	}
+	close(input)
+
+	output := make(chan Response, 5)
+	runner := NewRunner(tr, template, input, output)
+	runner.Primer = primer
+	runner.Run(context.Background())
+	close(output)
+
+	for res := range output {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+	}
+
+	want := []string{"t1 1", "t1 2", "t2 3", "t2 4", "t3 5"}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(tokens) != len(want) {
+		t.Fatalf("wrong number of requests, want %v, got %v", len(want), tokens)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("request %d: want %q, got %q", i, want[i], tokens[i])
+		}
+	}
+}
+
+func TestPrimeRequestExtractBoth(t *testing.T) {
+	template := request.New("")
+	template.PrimeRequestFile = "prime.txt"
+	template.PrimeExtract = `token=(\w+)`
+	template.PrimeExtractJSON = "$.token"
+	template.PrimePlaceholder = "PRIME"
+
+	_, err := NewPrimer(template, http.DefaultTransport)
+	if err == nil {
+		t.Fatal("expected error not returned")
+	}
+}
+
+func TestPrimeRequestHostFilter(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("token=foo"))
+	}))
+	defer srv.Close()
+
+	tempdir, err := ioutil.TempDir("", "monsoon-test-prime-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := os.RemoveAll(tempdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	primeFile := filepath.Join(tempdir, "prime.txt")
+	err = ioutil.WriteFile(primeFile, []byte("GET / HTTP/1.1\r\n\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := request.New("")
+	template.URL = srv.URL + "/"
+	template.PrimeRequestFile = primeFile
+	template.PrimeExtract = `token=(\w+)`
+	template.PrimePlaceholder = "PRIME"
+	template.AllowHost = []string{"10.0.0.0/8"}
+
+	primer, err := NewPrimer(template, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primer.HostFilter, err = NewHostFilter(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = primer.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "is not allowed") {
+		t.Fatalf("wrong error, got %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("prime request sent to a host which is not allowed")
+	}
+}
@@ -0,0 +1,166 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// contextDialer can establish connections with and without a context.
+type contextDialer interface {
+	proxy.Dialer
+	proxy.ContextDialer
+}
+
+// httpProxyDialer connects to addresses via an HTTP proxy using the CONNECT
+// method. The connection to the proxy is established via forward.
+type httpProxyDialer struct {
+	proxy   *url.URL
+	forward contextDialer
+}
+
+func (d httpProxyDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d httpProxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxy.Host
+	if d.proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(d.proxy.Hostname(), "8080")
+	}
+
+	conn, err := d.forward.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		auth := d.proxy.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	err = req.Write(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %v: %v", d.proxy.Host, err)
+	}
+
+	rd := bufio.NewReader(conn)
+	res, err := http.ReadResponse(rd, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %v: %v", d.proxy.Host, err)
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %v: CONNECT to %v failed: %v", d.proxy.Host, addr, res.Status)
+	}
+
+	if rd.Buffered() > 0 {
+		// the proxy already sent data from the target, keep it
+		return &bufferedConn{Conn: conn, rd: rd}, nil
+	}
+
+	return conn, nil
+}
+
+// bufferedConn returns data which has already been read into rd first.
+type bufferedConn struct {
+	net.Conn
+	rd *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.rd.Read(p)
+}
+
+// proxyChainDialer returns a dialer which connects through all proxies in
+// chain in order, the first proxy is connected to via dialer. Supported are
+// HTTP proxies (using CONNECT) and SOCKS5 proxies, both with optional
+// authentication.
+func proxyChainDialer(chain []string, dialer contextDialer) (contextDialer, error) {
+	for _, s := range chain {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %v", s, err)
+		}
+
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q: no host specified", s)
+		}
+
+		switch u.Scheme {
+		case "http":
+			dialer = httpProxyDialer{proxy: u, forward: dialer}
+		case "socks5", "socks5h":
+			d, err := proxy.FromURL(u, dialer)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy %q: %v", s, err)
+			}
+
+			cd, ok := d.(contextDialer)
+			if !ok {
+				return nil, errors.New("socks5 dialer does not support context")
+			}
+			dialer = cd
+		default:
+			return nil, fmt.Errorf("invalid proxy %q: unsupported scheme %q", s, u.Scheme)
+		}
+	}
+
+	return dialer, nil
+}
+
+// setProxy configures tr to send all requests through the proxy at proxyURL,
+// overriding the proxies configured via the environment. HTTP proxies are
+// used by tr directly, SOCKS5 proxies are connected to via dialer.
+func setProxy(tr *http.Transport, proxyURL string, dialer contextDialer) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy %q: %v", proxyURL, err)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxy %q: no host specified", proxyURL)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		tr.Proxy = http.ProxyURL(u)
+		tr.DialContext = dialer.DialContext
+	case "socks5", "socks5h":
+		d, err := proxyChainDialer([]string{proxyURL}, dialer)
+		if err != nil {
+			return err
+		}
+		tr.Proxy = nil
+		tr.DialContext = d.DialContext
+	default:
+		return fmt.Errorf("invalid proxy %q: unsupported scheme %q", proxyURL, u.Scheme)
+	}
+
+	return nil
+}
@@ -0,0 +1,409 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// stubProxy is a minimal proxy server for tests.
+type stubProxy struct {
+	listener net.Listener
+	serve    func(conn net.Conn) (target string, err error)
+
+	mu       sync.Mutex
+	targets  []string
+	failures int
+	wg       sync.WaitGroup
+}
+
+func startStubProxy(t testing.TB, serve func(net.Conn) (string, error)) *stubProxy {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &stubProxy{listener: l, serve: serve}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				p.handle(conn)
+			}()
+		}
+	}()
+
+	return p
+}
+
+func (p *stubProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+func (p *stubProxy) Close() {
+	_ = p.listener.Close()
+	p.wg.Wait()
+}
+
+func (p *stubProxy) handle(conn net.Conn) {
+	defer conn.Close()
+
+	target, err := p.serve(conn)
+	if err != nil {
+		p.mu.Lock()
+		p.failures++
+		p.mu.Unlock()
+		return
+	}
+
+	p.mu.Lock()
+	p.targets = append(p.targets, target)
+	p.mu.Unlock()
+}
+
+// pipe dials target and copies data between conn and the new connection.
+func pipe(conn net.Conn, target string, established func() error) error {
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return err
+	}
+	defer upstream.Close()
+
+	err = established()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		_ = upstream.(*net.TCPConn).CloseWrite()
+		close(done)
+	}()
+	_, _ = io.Copy(conn, upstream)
+	_ = conn.Close()
+	<-done
+
+	return nil
+}
+
+// serveSOCKS5 implements a SOCKS5 server which requires the username and
+// password to be user and pass.
+func serveSOCKS5(user, pass string) func(net.Conn) (string, error) {
+	return func(conn net.Conn) (string, error) {
+		rd := bufio.NewReader(conn)
+
+		// greeting: version, number of methods, methods
+		buf := make([]byte, 2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return "", err
+		}
+		methods := make([]byte, buf[1])
+		if _, err := io.ReadFull(rd, methods); err != nil {
+			return "", err
+		}
+
+		var found bool
+		for _, m := range methods {
+			if m == 0x02 {
+				found = true
+			}
+		}
+		if !found {
+			_, _ = conn.Write([]byte{0x05, 0xff})
+			return "", errors.New("client does not support username/password authentication")
+		}
+		_, _ = conn.Write([]byte{0x05, 0x02})
+
+		// username/password authentication (RFC 1929)
+		if _, err := io.ReadFull(rd, buf[:2]); err != nil {
+			return "", err
+		}
+		u := make([]byte, buf[1])
+		if _, err := io.ReadFull(rd, u); err != nil {
+			return "", err
+		}
+		if _, err := io.ReadFull(rd, buf[:1]); err != nil {
+			return "", err
+		}
+		pw := make([]byte, buf[0])
+		if _, err := io.ReadFull(rd, pw); err != nil {
+			return "", err
+		}
+
+		if string(u) != user || string(pw) != pass {
+			_, _ = conn.Write([]byte{0x01, 0x01})
+			return "", errors.New("invalid credentials")
+		}
+		_, _ = conn.Write([]byte{0x01, 0x00})
+
+		// request: version, command, reserved, address type
+		hdr := make([]byte, 4)
+		if _, err := io.ReadFull(rd, hdr); err != nil {
+			return "", err
+		}
+		if hdr[1] != 0x01 {
+			return "", errors.New("unsupported command")
+		}
+
+		var host string
+		switch hdr[3] {
+		case 0x01:
+			ip := make([]byte, 4)
+			if _, err := io.ReadFull(rd, ip); err != nil {
+				return "", err
+			}
+			host = net.IP(ip).String()
+		case 0x03:
+			if _, err := io.ReadFull(rd, buf[:1]); err != nil {
+				return "", err
+			}
+			name := make([]byte, buf[0])
+			if _, err := io.ReadFull(rd, name); err != nil {
+				return "", err
+			}
+			host = string(name)
+		default:
+			return "", errors.New("unsupported address type")
+		}
+
+		if _, err := io.ReadFull(rd, buf[:2]); err != nil {
+			return "", err
+		}
+		port := binary.BigEndian.Uint16(buf[:2])
+		target := net.JoinHostPort(host, strconv.Itoa(int(port)))
+
+		err := pipe(conn, target, func() error {
+			_, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+			return err
+		})
+
+		return target, err
+	}
+}
+
+// serveHTTPConnect implements an HTTP proxy which only supports CONNECT and
+// requires the Proxy-Authorization header to be auth.
+func serveHTTPConnect(auth string) func(net.Conn) (string, error) {
+	return func(conn net.Conn) (string, error) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return "", err
+		}
+
+		if req.Method != "CONNECT" {
+			return "", errors.New("unsupported method")
+		}
+
+		if req.Header.Get("Proxy-Authorization") != auth {
+			_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return "", errors.New("invalid credentials")
+		}
+
+		err = pipe(conn, req.Host, func() error {
+			_, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+			return err
+		})
+
+		return req.Host, err
+	}
+}
+
+func TestProxyChain(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	socks := startStubProxy(t, serveSOCKS5("user", "secret"))
+	defer socks.Close()
+
+	httpProxy := startStubProxy(t, serveHTTPConnect("Basic Zm9vOmJhcg=="))
+	defer httpProxy.Close()
+
+	target := srv.Listener.Addr().String()
+
+	var tests = []struct {
+		chain []string
+		err   bool
+	}{
+		{chain: []string{"socks5://user:secret@" + socks.Addr()}},
+		{chain: []string{"socks5://user:wrong@" + socks.Addr()}, err: true},
+		{chain: []string{"socks5://" + socks.Addr()}, err: true},
+		{chain: []string{"http://foo:bar@" + httpProxy.Addr()}},
+		{chain: []string{"http://foo:bar@" + httpProxy.Addr(), "socks5://user:secret@" + socks.Addr()}},
+		{chain: []string{"http://foo:wrong@" + httpProxy.Addr(), "socks5://user:secret@" + socks.Addr()}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.ProxyChain = test.chain
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			input := make(chan string, 1)
+			input <- ""
+			close(input)
+			output := make(chan Response, 1)
+			NewRunner(tr, template, input, output).Run(context.Background())
+
+			res := <-output
+			if test.err {
+				if res.Error == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if string(res.RawBody) != "ok" {
+				t.Errorf("wrong body returned: %q", res.RawBody)
+			}
+		})
+	}
+
+	socks.Close()
+	httpProxy.Close()
+
+	// the last proxy in the chain connects to the target, the HTTP proxy
+	// connects to the SOCKS5 proxy
+	for _, target := range socks.targets {
+		if target != srv.Listener.Addr().String() {
+			t.Errorf("SOCKS5 proxy connected to wrong target %v", target)
+		}
+	}
+
+	want := map[string]bool{target: true, socks.Addr(): true}
+	for _, addr := range httpProxy.targets {
+		if !want[addr] {
+			t.Errorf("HTTP proxy connected to wrong target %v", addr)
+		}
+	}
+
+	if len(socks.targets) != 2 || len(httpProxy.targets) != 2 {
+		t.Errorf("wrong number of proxied connections: socks5 %v, http %v", socks.targets, httpProxy.targets)
+	}
+}
+
+func TestProxyChainInvalid(t *testing.T) {
+	for _, chain := range [][]string{
+		{"ftp://proxy:21"},
+		{"socks5://"},
+		{"http://proxy:8080", "::invalid"},
+	} {
+		template := request.New("")
+		template.ProxyChain = chain
+
+		_, err := NewTransport(template, 1)
+		if err == nil {
+			t.Errorf("expected error not returned for %v", chain)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	socks := startStubProxy(t, serveSOCKS5("user", "secret"))
+	defer socks.Close()
+
+	// the HTTP proxy receives requests for http URLs in absolute form and
+	// just answers them
+	httpProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() || r.URL.Host != srv.Listener.Addr().String() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Header.Get("Proxy-Authorization") != "Basic Zm9vOmJhcg==" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	defer httpProxy.Close()
+
+	var tests = []struct {
+		proxy  string
+		chain  []string
+		want   string
+		status int
+		err    bool
+	}{
+		{proxy: "socks5://user:secret@" + socks.Addr(), want: "ok", status: http.StatusOK},
+		{proxy: "socks5://user:wrong@" + socks.Addr(), err: true},
+		{proxy: "http://foo:bar@" + httpProxy.Listener.Addr().String(), want: "proxied", status: http.StatusOK},
+		{proxy: "http://" + httpProxy.Listener.Addr().String(), status: http.StatusProxyAuthRequired},
+		{proxy: "ftp://proxy:21", err: true},
+		{proxy: "socks5://" + socks.Addr(), chain: []string{"socks5://" + socks.Addr()}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.Proxy = test.proxy
+			template.ProxyChain = test.chain
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				if !test.err {
+					t.Fatal(err)
+				}
+				return
+			}
+			tr.CloseIdleConnections()
+
+			res := runSingle(t, template, "")
+			if test.err {
+				if res.Error == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.HTTPResponse.StatusCode != test.status {
+				t.Fatalf("wrong status code, want %v, got %v", test.status, res.HTTPResponse.StatusCode)
+			}
+
+			if test.want != "" && string(res.RawBody) != test.want {
+				t.Errorf("wrong body returned: %q", res.RawBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// proxyMaxFailures is the number of consecutive failures after which a
+	// proxy is not used for proxyDisableDuration.
+	proxyMaxFailures     = 3
+	proxyDisableDuration = 30 * time.Second
+)
+
+// rotatingProxy is a proxy in the list for --proxy-file.
+type rotatingProxy struct {
+	url    string
+	dialer contextDialer
+
+	failures      int       // consecutive failures
+	disabledUntil time.Time // the proxy is not used before this time
+}
+
+// rotatingDialer connects through one of several proxies, which are selected
+// in turn or randomly for each connection. Proxies which fail repeatedly are
+// not used for some time.
+type rotatingDialer struct {
+	proxies []*rotatingProxy
+	rnd     *rand.Rand // if set, proxies are selected randomly
+	now     func() time.Time
+
+	mu   sync.Mutex
+	next int
+}
+
+// readProxyFile returns the proxy URLs from filename, one per line. Empty
+// lines and lines starting with "#" are ignored.
+func readProxyFile(filename string) (list []string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+
+	err = sc.Err()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return list, f.Close()
+}
+
+// newRotatingDialer returns a dialer which rotates through the proxies in
+// list, mode is either "round-robin" or "random". The proxies are connected
+// to via dialer.
+func newRotatingDialer(list []string, mode string, seed int64, dialer contextDialer) (*rotatingDialer, error) {
+	if len(list) == 0 {
+		return nil, errors.New("the proxy file does not contain any proxies")
+	}
+
+	d := &rotatingDialer{now: time.Now}
+
+	switch mode {
+	case "round-robin", "":
+	case "random":
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		d.rnd = rand.New(rand.NewSource(seed))
+	default:
+		return nil, fmt.Errorf("unknown proxy rotation mode %q", mode)
+	}
+
+	for _, s := range list {
+		pd, err := proxyChainDialer([]string{s}, dialer)
+		if err != nil {
+			return nil, err
+		}
+		d.proxies = append(d.proxies, &rotatingProxy{url: s, dialer: pd})
+	}
+
+	return d, nil
+}
+
+// pick returns the index of the next proxy to use, proxies in tried are
+// skipped. It returns false if no proxy is available.
+func (d *rotatingDialer) pick(tried map[int]bool) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	var available []int
+	for i := range d.proxies {
+		n := (d.next + i) % len(d.proxies)
+		if tried[n] || now.Before(d.proxies[n].disabledUntil) {
+			continue
+		}
+		available = append(available, n)
+	}
+
+	if len(available) == 0 {
+		return 0, false
+	}
+
+	if d.rnd != nil {
+		return available[d.rnd.Intn(len(available))], true
+	}
+
+	d.next = (available[0] + 1) % len(d.proxies)
+	return available[0], true
+}
+
+// report records the result of connecting through the proxy with index i.
+func (d *rotatingDialer) report(i int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p := d.proxies[i]
+	if err == nil {
+		p.failures = 0
+		return
+	}
+
+	p.failures++
+	if p.failures >= proxyMaxFailures {
+		p.failures = 0
+		p.disabledUntil = d.now().Add(proxyDisableDuration)
+	}
+}
+
+func (d *rotatingDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to addr through the next proxy. If that fails, the
+// other available proxies are tried in turn.
+func (d *rotatingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	tried := make(map[int]bool)
+	var lastErr error
+	for {
+		i, ok := d.pick(tried)
+		if !ok {
+			break
+		}
+		tried[i] = true
+
+		conn, err := d.proxies[i].dialer.DialContext(ctx, network, addr)
+		if ctx.Err() != nil {
+			// the request was cancelled, this is not the proxy's fault
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		d.report(i, err)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("connecting through all available proxies failed, last error: %v", lastErr)
+	}
+
+	return nil, errors.New("no proxy available, all proxies failed recently")
+}
@@ -0,0 +1,214 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// countingDialer counts the connections and fails if err is set.
+type countingDialer struct {
+	err   error
+	calls int
+}
+
+func (d *countingDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *countingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.calls++
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	c1, c2 := net.Pipe()
+	_ = c2.Close()
+	return c1, nil
+}
+
+// writeTempFile writes data to a new temporary file.
+func writeTempFile(t testing.TB, data string) (filename string, cleanup func()) {
+	f, err := ioutil.TempFile("", "monsoon-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.WriteString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f.Name(), func() {
+		_ = os.Remove(f.Name())
+	}
+}
+
+func TestRotatingDialer(t *testing.T) {
+	d, err := newRotatingDialer([]string{"socks5://one:1080", "socks5://two:1080", "socks5://three:1080"}, "round-robin", 0, &net.Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1000, 0)
+	d.now = func() time.Time { return now }
+
+	dialers := []*countingDialer{{}, {err: errors.New("proxy is down")}, {}}
+	for i, cd := range dialers {
+		d.proxies[i].dialer = cd
+	}
+
+	dial := func() {
+		conn, err := d.DialContext(context.Background(), "tcp", "target:80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.Close()
+	}
+
+	// the second proxy fails, so every other connection is made through
+	// the third one
+	for i := 0; i < 6; i++ {
+		dial()
+	}
+
+	if dialers[0].calls != 3 || dialers[1].calls != 3 || dialers[2].calls != 3 {
+		t.Fatalf("wrong number of connections: %d %d %d", dialers[0].calls, dialers[1].calls, dialers[2].calls)
+	}
+
+	// the second proxy has failed three times, so it is not used any more
+	for i := 0; i < 4; i++ {
+		dial()
+	}
+
+	if dialers[1].calls != 3 {
+		t.Fatalf("disabled proxy was used, %d connections", dialers[1].calls)
+	}
+
+	// after some time, the proxy is used again
+	now = now.Add(proxyDisableDuration)
+	dialers[1].err = nil
+	for i := 0; i < 3; i++ {
+		dial()
+	}
+
+	if dialers[1].calls != 4 {
+		t.Fatalf("proxy was not enabled again, %d connections", dialers[1].calls)
+	}
+
+	// when all proxies fail, an error is returned
+	for _, cd := range dialers {
+		cd.err = errors.New("proxy is down")
+	}
+
+	_, err = d.DialContext(context.Background(), "tcp", "target:80")
+	if err == nil || !strings.Contains(err.Error(), "proxy is down") {
+		t.Fatalf("wrong error returned: %v", err)
+	}
+}
+
+func TestProxyFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	socks := startStubProxy(t, serveSOCKS5("user", "secret"))
+	defer socks.Close()
+
+	httpProxy := startStubProxy(t, serveHTTPConnect(""))
+	defer httpProxy.Close()
+
+	proxyFile, cleanup := writeTempFile(t, strings.Join([]string{
+		"# proxies for testing",
+		"socks5://user:secret@" + socks.Addr(),
+		"",
+		"http://" + httpProxy.Addr(),
+	}, "\n"))
+	defer cleanup()
+
+	for _, mode := range []string{"round-robin", "random"} {
+		t.Run(mode, func(t *testing.T) {
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.ProxyFile = proxyFile
+			template.ProxyRotation = mode
+			template.Seed = 1
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			input := make(chan string, 4)
+			for i := 0; i < 4; i++ {
+				input <- ""
+			}
+			close(input)
+			output := make(chan Response, 4)
+			NewRunner(tr, template, input, output).Run(context.Background())
+			close(output)
+
+			for res := range output {
+				if res.Error != nil {
+					t.Fatal(res.Error)
+				}
+
+				if string(res.RawBody) != "ok" {
+					t.Errorf("wrong body returned: %q", res.RawBody)
+				}
+			}
+		})
+	}
+
+	socks.Close()
+	httpProxy.Close()
+
+	// round-robin alone uses each proxy for two of the four requests
+	if len(socks.targets) < 2 || len(httpProxy.targets) < 2 {
+		t.Errorf("wrong number of proxied connections: socks5 %v, http %v", socks.targets, httpProxy.targets)
+	}
+}
+
+func TestProxyFileInvalid(t *testing.T) {
+	var tests = []struct {
+		content string
+		mode    string
+	}{
+		{content: "# no proxies\n"},
+		{content: "ftp://proxy:21\n"},
+		{content: "socks5://proxy:1080\n", mode: "invalid"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			proxyFile, cleanup := writeTempFile(t, test.content)
+			defer cleanup()
+
+			template := request.New("")
+			template.ProxyFile = proxyFile
+			template.ProxyRotation = test.mode
+
+			_, err := NewTransport(template, 1)
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+		})
+	}
+}
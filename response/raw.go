@@ -0,0 +1,229 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// rawTransport sends requests over a new connection for each request and
+// writes them exactly as they are, without the checks and modifications of
+// http.Transport. The body is sent unmodified, the Content-Length header is
+// sent as it is set in the header for PreserveLength. The raw request line
+// and headers from the template are written as they are. In raw mode, the
+// template file is sent verbatim and the response is parsed leniently.
+// Connections are established via the dial function of tr, so the options
+// for the address and proxies (except for the environment variables
+// HTTP_PROXY and HTTPS_PROXY) are respected.
+type rawTransport struct {
+	tr       *http.Transport
+	template *request.Request
+}
+
+func (t *rawTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	value, _ := request.FromContext(req.Context())
+	buf, err := DumpRequest(t.template, value, req)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := t.dial(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure the connection is closed when the context is cancelled
+	done := make(chan struct{})
+	var once sync.Once
+	closeConn := func() error {
+		once.Do(func() { close(done) })
+		return conn.Close()
+	}
+
+	ctx := req.Context()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	if t.tr.ResponseHeaderTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(t.tr.ResponseHeaderTimeout))
+	}
+
+	_, err = conn.Write(buf)
+	if err != nil {
+		_ = closeConn()
+		return nil, err
+	}
+
+	readResponse := http.ReadResponse
+	if t.template.Raw {
+		readResponse = readResponseLenient
+	}
+
+	res, err := readResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		_ = closeConn()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	res.Body = &closeBody{ReadCloser: res.Body, close: closeConn}
+
+	return res, nil
+}
+
+// dial connects to the target of req, for https a TLS connection which only
+// supports HTTP/1.1 is returned.
+func (t *rawTransport) dial(req *http.Request) (net.Conn, error) {
+	return dialRequest(req, t.tr, t.template, []string{"http/1.1"})
+}
+
+// closeBody calls close when the body is closed.
+type closeBody struct {
+	io.ReadCloser
+	close func() error
+}
+
+func (b *closeBody) Close() error {
+	err := b.ReadCloser.Close()
+	cerr := b.close()
+	if err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// dumpRaw returns the request for value as it is sent by rawTransport. The
+// body of req is restored afterwards.
+func dumpRaw(template *request.Request, value string, req *http.Request) ([]byte, error) {
+	requestLine, rawHeaders, err := template.RawLines(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if requestLine == "" {
+		requestLine = fmt.Sprintf("%s %s HTTP/1.1", req.Method, req.URL.RequestURI())
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	// the headers from the template file and --header are written first in
+	// the order given, the Host header is written first unless it is listed
+	var order []string
+	if template.PreserveHeaderOrder {
+		order, err = template.HeaderOrder(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hostListed := false
+	for _, name := range order {
+		hostListed = hostListed || textproto.CanonicalMIMEHeaderKey(name) == "Host"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\r\n", requestLine)
+	if !hostListed && !template.OmitHost() {
+		fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	}
+
+	hdr := req.Header.Clone()
+	// the Host header set via --header is in req.Host, too
+	hdr.Del("Host")
+	if hdr.Get("User-Agent") == "" {
+		// an empty User-Agent means the header should not be sent
+		hdr.Del("User-Agent")
+	}
+
+	if !template.PreserveLength {
+		// a Content-Length header from the template file may be wrong now
+		hdr.Del("Content-Length")
+	}
+
+	_, hasLength := hdr["Content-Length"]
+	_, hasEncoding := hdr["Transfer-Encoding"]
+	if !hasLength && !hasEncoding && len(body) > 0 {
+		hdr.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	if req.Close && hdr.Get("Connection") == "" {
+		hdr.Set("Connection", "close")
+	}
+
+	// like http.Header.Write, newlines in values are replaced
+	newlineToSpace := strings.NewReplacer("\n", " ", "\r", " ")
+	for _, name := range order {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if key == "Host" {
+			if !template.OmitHost() {
+				fmt.Fprintf(&buf, "%s: %s\r\n", name, host)
+			}
+			continue
+		}
+
+		for _, v := range hdr[key] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", name, strings.TrimSpace(newlineToSpace.Replace(v)))
+		}
+		delete(hdr, key)
+	}
+
+	err = hdr.Write(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range rawHeaders {
+		buf.WriteString(h + "\r\n")
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	return buf.Bytes(), nil
+}
+
+// DumpRequest returns the request for value as it is sent for template.
+func DumpRequest(template *request.Request, value string, req *http.Request) ([]byte, error) {
+	if template.Raw {
+		return template.RawRequest(value)
+	}
+
+	if template.RawWriter() {
+		return dumpRaw(template, value, req)
+	}
+
+	return httputil.DumpRequestOut(req, true)
+}
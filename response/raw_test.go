@@ -0,0 +1,343 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// serveRawOnce accepts a single connection, reads the request header and
+// everything which is sent shortly afterwards, and responds with "ok".
+func serveRawOnce(t testing.TB, l net.Listener) <-chan []byte {
+	ch := make(chan []byte, 1)
+
+	go func() {
+		defer close(ch)
+
+		conn, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		var buf bytes.Buffer
+		rd := bufio.NewReader(io.TeeReader(conn, &buf))
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if line == "\r\n" {
+				break
+			}
+		}
+
+		// read the body regardless of the Content-Length header
+		_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		_, _ = ioutil.ReadAll(rd)
+
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		ch <- buf.Bytes()
+	}()
+
+	return ch
+}
+
+func runSingle(t testing.TB, template *request.Request, value string) Response {
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	input := make(chan string, 1)
+	input <- value
+	close(input)
+	output := make(chan Response, 1)
+	NewRunner(tr, template, input, output).Run(context.Background())
+
+	return <-output
+}
+
+func TestPreserveLength(t *testing.T) {
+	var tests = []struct {
+		template string
+		want     string
+	}{
+		{
+			// declared length is shorter than the body
+			template: "POST /FUZZ HTTP/1.1\r\nHost: target\r\nContent-Length: 5\r\n\r\nabcdefghij",
+			want:     "POST /foo HTTP/1.1\r\nHost: target\r\nContent-Length: 5\r\nUser-Agent: monsoon\r\n\r\nabcdefghij",
+		},
+		{
+			// declared length is longer than the body
+			template: "POST / HTTP/1.1\r\nHost: target\r\nContent-Length: 100\r\n\r\nFUZZ",
+			want:     "POST / HTTP/1.1\r\nHost: target\r\nContent-Length: 100\r\nUser-Agent: monsoon\r\n\r\nfoo",
+		},
+		{
+			// no declared length
+			template: "POST / HTTP/1.1\r\nHost: target\r\n\r\nabc",
+			want:     "POST / HTTP/1.1\r\nHost: target\r\nContent-Length: 3\r\nUser-Agent: monsoon\r\n\r\nabc",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			received := serveRawOnce(t, l)
+
+			template := request.New("")
+			template.URL = "http://" + l.Addr().String()
+			template.TemplateData = []byte(test.template)
+			template.Header = request.NewHeader(http.Header{"User-Agent": []string{"monsoon"}})
+			template.PreserveLength = true
+
+			res := runSingle(t, template, "foo")
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if string(res.RawBody) != "ok" {
+				t.Errorf("wrong body returned: %q", res.RawBody)
+			}
+
+			got := string(<-received)
+			if got != test.want {
+				t.Errorf("wrong request sent, want:\n  %q\ngot:\n  %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestPreserveLengthTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = io.WriteString(w, r.Proto+" "+r.Header.Get("Content-Length")+" "+string(body))
+	}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL
+	template.TemplateData = []byte("POST / HTTP/1.1\r\nContent-Length: 3\r\n\r\nFUZZ")
+	template.Insecure = true
+	template.PreserveLength = true
+
+	res := runSingle(t, template, "abcdef")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	want := "HTTP/1.1 3 abc"
+	if string(res.RawBody) != want {
+		t.Errorf("wrong response, want %q, got %q", want, res.RawBody)
+	}
+}
+
+func TestRawRequestLine(t *testing.T) {
+	var tests = []struct {
+		line    string
+		headers []string
+		want    string
+	}{
+		{
+			line: "GET /FUZZ  HTTP/1.1",
+			want: "GET /foo  HTTP/1.1\r\nHost: target\r\nUser-Agent: monsoon\r\n\r\n",
+		},
+		{
+			line:    "GET\t/\tHTTP/1.1",
+			headers: []string{"X-Foo :bar", "no colon FUZZ"},
+			want:    "GET\t/\tHTTP/1.1\r\nHost: target\r\nUser-Agent: monsoon\r\nX-Foo :bar\r\nno colon foo\r\n\r\n",
+		},
+		{
+			line: "GET /",
+			want: "GET /\r\nHost: target\r\nUser-Agent: monsoon\r\n\r\n",
+		},
+		{
+			// only raw headers, the request line is built from the method and URL
+			headers: []string{"Transfer-Encoding : chunked"},
+			want:    "GET /path HTTP/1.1\r\nHost: target\r\nUser-Agent: monsoon\r\nTransfer-Encoding : chunked\r\n\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			received := serveRawOnce(t, l)
+
+			template := request.New("")
+			template.URL = "http://" + l.Addr().String() + "/path"
+			template.Header = request.NewHeader(http.Header{"User-Agent": []string{"monsoon"}})
+			err = template.Header.Set("Host: target")
+			if err != nil {
+				t.Fatal(err)
+			}
+			template.RawRequestLine = test.line
+			template.RawHeader = test.headers
+
+			res := runSingle(t, template, "foo")
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			got := string(<-received)
+			if got != test.want {
+				t.Errorf("wrong request sent, want:\n  %q\ngot:\n  %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRaw(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := serveRawOnce(t, l)
+
+	template := request.New("")
+	template.URL = "http://" + l.Addr().String()
+	template.TemplateData = []byte("GET  /FUZZ?x=FUZZ HTTP/1.1\r\nHost: target\r\nno colon\r\nX-Foo :FUZZ\r\nContent-Length: 100\r\n\r\n")
+	template.Header = request.NewHeader(http.Header{"User-Agent": []string{"monsoon"}})
+	template.Raw = true
+
+	res := runSingle(t, template, "foo")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	if res.URL != "http://"+l.Addr().String()+"/foo?x=foo" {
+		t.Errorf("wrong URL for response: %v", res.URL)
+	}
+
+	want := "GET  /foo?x=foo HTTP/1.1\r\nHost: target\r\nno colon\r\nX-Foo :foo\r\nContent-Length: 100\r\n\r\n"
+	got := string(<-received)
+	if got != want {
+		t.Errorf("wrong request sent, want:\n  %q\ngot:\n  %q", want, got)
+	}
+}
+
+func TestPreserveHeaderOrder(t *testing.T) {
+	var tests = []struct {
+		template string
+		headers  []string
+		want     string
+	}{
+		{
+			headers: []string{"x-second: 2", "host: target", "X-First: FUZZ", "accept: */*"},
+			want:    "GET /path HTTP/1.1\r\nx-second: 2\r\nhost: target\r\nX-First: foo\r\naccept: */*\r\nUser-Agent: monsoon\r\n\r\n",
+		},
+		{
+			template: "GET /FUZZ HTTP/1.1\r\nuser-agent: browser\r\nHost: target\r\nX-B: b\r\nx-a: a\r\n\r\n",
+			headers:  []string{"X-C: c", "X-B: replaced"},
+			want:     "GET /foo HTTP/1.1\r\nuser-agent: browser\r\nHost: target\r\nX-B: replaced\r\nx-a: a\r\nX-C: c\r\n\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			received := serveRawOnce(t, l)
+
+			template := request.New("")
+			template.URL = "http://" + l.Addr().String() + "/path"
+			if test.template != "" {
+				template.URL = "http://" + l.Addr().String()
+				template.TemplateData = []byte(test.template)
+			}
+			template.Header = request.NewHeader(http.Header{"User-Agent": []string{"monsoon"}})
+			for _, h := range test.headers {
+				err = template.Header.Set(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			template.PreserveHeaderOrder = true
+
+			res := runSingle(t, template, "foo")
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			got := string(<-received)
+			if got != test.want {
+				t.Errorf("wrong request sent, want:\n  %q\ngot:\n  %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestUserAgentHost(t *testing.T) {
+	var tests = []struct {
+		headers []string
+		want    string
+	}{
+		{
+			headers: []string{"Host: target", "User-Agent: one", "User-Agent: two"},
+			want:    "GET /path HTTP/1.1\r\nHost: target\r\nUser-Agent: one\r\nUser-Agent: two\r\n\r\n",
+		},
+		{
+			headers: []string{"Host", "X-Foo: bar"},
+			want:    "GET /path HTTP/1.1\r\nUser-Agent: monsoon\r\nX-Foo: bar\r\n\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			received := serveRawOnce(t, l)
+
+			template := request.New("")
+			template.URL = "http://" + l.Addr().String() + "/path"
+			template.Header = request.NewHeader(http.Header{"User-Agent": []string{"monsoon"}})
+			for _, h := range test.headers {
+				err = template.Header.Set(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			res := runSingle(t, template, "foo")
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			got := string(<-received)
+			if got != test.want {
+				t.Errorf("wrong request sent, want:\n  %q\ngot:\n  %q", test.want, got)
+			}
+		})
+	}
+}
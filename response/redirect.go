@@ -0,0 +1,68 @@
+package response
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// trustedHeaders are sent again after a redirect to a different host when
+// the redirect target is trusted. The Go http.Client removes them otherwise.
+var trustedHeaders = []string{"Authorization", "Cookie"}
+
+// CheckRedirect returns a function for http.Client.CheckRedirect which
+// follows at most max redirects. If trusted is set, the Authorization and
+// Cookie headers of the first request are also sent to other hosts. If check
+// is not nil, it is called for each redirect target, which is not followed
+// if an error is returned.
+func CheckRedirect(max int, trusted bool, check func(*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > max {
+			return http.ErrUseLastResponse
+		}
+
+		if check != nil {
+			err := check(req)
+			if err != nil {
+				return err
+			}
+		}
+
+		if trusted && len(via) > 0 {
+			for _, name := range trustedHeaders {
+				if v, ok := via[0].Header[name]; ok {
+					req.Header[name] = v
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// CheckTarget returns an error if filter does not allow the host of req or
+// the host the connection is made to, which is the result of dialAddress for
+// the context of req.
+func CheckTarget(template *request.Request, filter *request.HostFilter, req *http.Request) error {
+	if filter == nil {
+		return nil
+	}
+
+	host, port, err := request.Target(req)
+	if err != nil {
+		return err
+	}
+
+	dialAddr, err := dialAddress(req.Context(), template, net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+
+	dialHost, _, err := net.SplitHostPort(dialAddr)
+	if err != nil {
+		return err
+	}
+
+	return filter.CheckTarget(req.Context(), host, dialHost)
+}
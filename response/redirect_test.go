@@ -0,0 +1,270 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckRedirectTrusted(t *testing.T) {
+	var auth, cookie string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		cookie = r.Header.Get("Cookie")
+	}))
+	defer target.Close()
+
+	// use a different host name for the redirect target so that the host
+	// changes with the redirect
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetURL.Host = "localhost:" + targetURL.Port()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, targetURL.String(), http.StatusFound)
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		trusted bool
+		auth    string
+		cookie  string
+	}{
+		{trusted: false},
+		{trusted: true, auth: "Bearer secret", cookie: "session=foo"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			auth, cookie = "", ""
+
+			client := &http.Client{
+				CheckRedirect: CheckRedirect(2, test.trusted, nil),
+			}
+
+			req, err := http.NewRequest("GET", srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("Cookie", "session=foo")
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = res.Body.Close()
+
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("redirect not followed, status %v", res.Status)
+			}
+
+			if auth != test.auth {
+				t.Errorf("wrong Authorization header, want %q, got %q", test.auth, auth)
+			}
+
+			if cookie != test.cookie {
+				t.Errorf("wrong Cookie header, want %q, got %q", test.cookie, cookie)
+			}
+		})
+	}
+}
+
+func TestCookieJar(t *testing.T) {
+	var mu sync.Mutex
+	var cookies []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		http.Redirect(w, r, "/home", http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		mu.Unlock()
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var tests = []struct {
+		jar  bool
+		want []string
+	}{
+		{jar: false, want: []string{"", ""}},
+		// the jar is reset for each request, so the second request does not
+		// carry the cookie
+		{jar: true, want: []string{"session=secret", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			cookies = nil
+
+			template := request.New("")
+			template.URL = srv.URL + "/FUZZ"
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			input := make(chan string, 2)
+			input <- "login"
+			input <- "home"
+			close(input)
+
+			output := make(chan Response, 2)
+			runner := NewRunner(tr, template, input, output)
+			runner.Client.CheckRedirect = CheckRedirect(2, false, nil)
+			runner.CookieJar = test.jar
+			runner.Run(context.Background())
+			close(output)
+
+			for res := range output {
+				if res.Error != nil {
+					t.Fatal(res.Error)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !cmp.Equal(test.want, cookies) {
+				t.Error(cmp.Diff(test.want, cookies))
+			}
+		})
+	}
+}
+
+func TestCheckRedirectHostFilter(t *testing.T) {
+	var requests int
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer target.Close()
+
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetURL.Host = "localhost:" + targetURL.Port()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, targetURL.String(), http.StatusFound)
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		allow, deny []string
+		followed    bool
+	}{
+		{followed: true},
+		{allow: []string{"127.0.0.1", "localhost"}, followed: true},
+		{deny: []string{"local*"}},
+		{deny: []string{"localhost"}},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			requests = 0
+
+			template := request.New("")
+			template.URL = srv.URL + "/FUZZ"
+			template.AllowHost = test.allow
+			template.DenyHost = test.deny
+
+			filter, err := NewHostFilter(template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			input := make(chan string, 1)
+			input <- "foo"
+			close(input)
+
+			output := make(chan Response, 1)
+			runner := NewRunner(tr, template, input, output)
+			runner.HostFilter = filter
+			runner.Client.CheckRedirect = CheckRedirect(2, false, func(req *http.Request) error {
+				return CheckTarget(template, filter, req)
+			})
+			runner.Run(context.Background())
+			close(output)
+
+			res := <-output
+			if test.followed {
+				if res.Error != nil {
+					t.Fatal(res.Error)
+				}
+				if requests != 1 {
+					t.Errorf("redirect not followed")
+				}
+				return
+			}
+
+			if res.Error == nil {
+				t.Fatal("redirect to a host which is not allowed did not return an error")
+			}
+			if requests != 0 {
+				t.Errorf("redirect to a host which is not allowed was followed")
+			}
+		})
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	var tests = []struct {
+		url     string
+		resolve []string
+		value   string
+		err     bool
+	}{
+		{url: "http://www.test.example.com/"},
+		{url: "http://www.example.com/", err: true},
+		// the address connections are made to is checked as well
+		{url: "http://www.test.example.com/", resolve: []string{"www.test.example.com:80:www.example.com:"}, err: true},
+		{url: "http://www.test.example.com/", resolve: []string{"www.test.example.com:80:FUZZ:"}, value: "db.test.example.com"},
+		{url: "http://www.test.example.com/", resolve: []string{"www.test.example.com:80:FUZZ:"}, value: "db.example.com", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			template := request.New("FUZZ")
+			template.AllowHost = []string{"*.test.example.com"}
+			template.ConnectTo = test.resolve
+
+			filter, err := NewHostFilter(template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest("GET", test.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(request.NewContext(context.Background(), test.value))
+
+			err = CheckTarget(template, filter, req)
+			if test.err && err == nil {
+				t.Fatal("expected error not returned")
+			}
+			if !test.err && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
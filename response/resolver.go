@@ -0,0 +1,173 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// newResolver returns a resolver which sends all DNS queries to the server at
+// addr (ip:port, the port defaults to 53) or, if dohURL is set, via DNS over
+// HTTPS (RFC 8484) to dohURL. If both are empty, nil is returned so that the
+// system resolver is used.
+func newResolver(addr, dohURL string) (*net.Resolver, error) {
+	switch {
+	case addr != "" && dohURL != "":
+		return nil, errors.New("--resolver and --doh-url cannot be used together")
+
+	case addr != "":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "53")
+		}
+
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resolver %q: %v", addr, err)
+		}
+		if net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("invalid resolver %q: not an IP address", addr)
+		}
+
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}, nil
+
+	case dohURL != "":
+		u, err := url.Parse(dohURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DoH URL: %v", err)
+		}
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return nil, fmt.Errorf("invalid DoH URL %q: unsupported scheme %q", dohURL, u.Scheme)
+		}
+
+		client := &http.Client{Timeout: 10 * time.Second}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return &dohConn{ctx: ctx, client: client, url: dohURL}, nil
+			},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// dohConn is passed to the resolver as a connection to a DNS server. It
+// speaks the framing used for DNS over TCP (each message is prefixed with
+// the length) and sends each query via DNS over HTTPS.
+type dohConn struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+
+	query    []byte // data written which has not been sent yet
+	response bytes.Buffer
+}
+
+func (c *dohConn) Write(p []byte) (int, error) {
+	c.query = append(c.query, p...)
+
+	for len(c.query) >= 2 {
+		length := int(binary.BigEndian.Uint16(c.query))
+		if len(c.query) < 2+length {
+			break
+		}
+
+		msg := c.query[2 : 2+length]
+		res, err := c.exchange(msg)
+		if err != nil {
+			return 0, err
+		}
+
+		var prefix [2]byte
+		binary.BigEndian.PutUint16(prefix[:], uint16(len(res)))
+		c.response.Write(prefix[:])
+		c.response.Write(res)
+
+		c.query = c.query[2+length:]
+	}
+
+	return len(p), nil
+}
+
+// exchange sends the DNS message msg to the server and returns the response.
+func (c *dohConn) exchange(msg []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(c.ctx)
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned unexpected status %v", res.Status)
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(res.Body, 65535+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > 65535 {
+		return nil, errors.New("DoH response is too large")
+	}
+
+	return buf, nil
+}
+
+func (c *dohConn) Read(p []byte) (int, error) {
+	if c.response.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.response.Read(p)
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr{} }
+func (c *dohConn) SetDeadline(t time.Time) error      { return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dohAddr struct{}
+
+func (dohAddr) Network() string { return "doh" }
+func (dohAddr) String() string  { return "doh" }
+
+// NewHostFilter returns the filter for the hosts allowed by template, host
+// names are resolved in the same way as for connections (--resolver and
+// --doh-url). If no hosts are restricted, nil is returned.
+func NewHostFilter(template *request.Request) (*request.HostFilter, error) {
+	resolver, err := newResolver(template.Resolver, template.DoHURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// do not pass a nil *net.Resolver as a non-nil interface
+	if resolver == nil {
+		return template.NewHostFilter(nil)
+	}
+
+	return template.NewHostFilter(resolver)
+}
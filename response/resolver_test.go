@@ -0,0 +1,151 @@
+package response
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// answerDNS returns the response to the DNS query in buf, the name
+// target.monsoon.test resolves to 127.0.0.1, all other names do not exist.
+func answerDNS(t testing.TB, buf []byte) []byte {
+	var msg dnsmessage.Message
+	err := msg.Unpack(buf)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+
+	msg.Header.Response = true
+	msg.Header.RecursionAvailable = true
+
+	for _, q := range msg.Questions {
+		if q.Name.String() != "target.monsoon.test." {
+			msg.Header.RCode = dnsmessage.RCodeNameError
+			continue
+		}
+
+		if q.Type == dnsmessage.TypeA {
+			msg.Answers = append(msg.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+			})
+		}
+	}
+
+	res, err := msg.Pack()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+
+	return res
+}
+
+func TestResolver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	srvURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dns, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := dns.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			_, _ = dns.WriteTo(answerDNS(t, buf[:n]), addr)
+		}
+	}()
+
+	doh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		buf, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(answerDNS(t, buf))
+	}))
+	defer doh.Close()
+
+	var tests = []struct {
+		host     string
+		resolver string
+		doh      string
+		err      bool
+	}{
+		{host: "target.monsoon.test", resolver: dns.LocalAddr().String()},
+		{host: "target.monsoon.test", doh: doh.URL + "/dns-query"},
+		{host: "other.monsoon.test", resolver: dns.LocalAddr().String(), err: true},
+		{host: "other.monsoon.test", doh: doh.URL + "/dns-query", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			template := request.New("")
+			template.URL = "http://" + test.host + ":" + srvURL.Port() + "/"
+			template.Resolver = test.resolver
+			template.DoHURL = test.doh
+
+			res := runSingle(t, template, "")
+			if test.err {
+				if res.Error == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if string(res.RawBody) != "ok" {
+				t.Errorf("unexpected body %q", res.RawBody)
+			}
+		})
+	}
+}
+
+func TestResolverInvalid(t *testing.T) {
+	var tests = []struct {
+		resolver, doh string
+	}{
+		{resolver: "dns.example.com:53"},
+		{resolver: "127.0.0.1:53", doh: "https://127.0.0.1/dns-query"},
+		{doh: "ftp://127.0.0.1/dns-query"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := newResolver(test.resolver, test.doh)
+			if err == nil {
+				t.Fatal("expected error not returned")
+			}
+		})
+	}
+}
@@ -16,14 +16,22 @@ import (
 	"strings"
 	"time"
 	"unicode"
+
+	"github.com/RedTeamPentesting/monsoon/request"
 )
 
 // Response is an HTTP response.
 type Response struct {
 	Item     string
+	Method   string // only set if the method has been overridden for the response
+	Seq      int    // sequence number of the item, only set if the runner reads Items
 	URL      string
 	Error    error
 	Duration time.Duration
+	Timing   *Timing // only set if the runner records the timing
+
+	WebSocket *WebSocketStats // only set if the connection was upgraded to a WebSocket
+	FollowUp  *Response       // only set if a follow-up request has been sent
 
 	Header, Body TextStats
 	Extract      []string
@@ -45,6 +53,15 @@ func quote(strs []string) []string {
 	return res
 }
 
+// value returns the item, prefixed by the method if it is set.
+func (r Response) value() string {
+	item := request.DisplayValue(r.Item)
+	if r.Method == "" {
+		return item
+	}
+	return r.Method + " " + item
+}
+
 func (r Response) String() string {
 	if r.Error != nil {
 		// don't print anything if the request has been cancelled
@@ -55,11 +72,11 @@ func (r Response) String() string {
 			return ""
 		}
 
-		return fmt.Sprintf("%7s %18s   %v", "error", r.Error, r.Item)
+		return fmt.Sprintf("%7s %18s   %v", "error", r.Error, r.value())
 	}
 
 	res := r.HTTPResponse
-	status := fmt.Sprintf("%7d %8d %8d   %-8v", res.StatusCode, r.Header.Bytes, r.Body.Bytes, r.Item)
+	status := fmt.Sprintf("%7d %8d %8d   %-8v", res.StatusCode, r.Header.Bytes, r.Body.Bytes, r.value())
 	if res.StatusCode >= 300 && res.StatusCode < 400 {
 		loc, ok := res.Header["Location"]
 		if ok {
@@ -69,9 +86,27 @@ func (r Response) String() string {
 	if len(r.Extract) > 0 {
 		status += " data: " + strings.Join(quote(r.Extract), ", ")
 	}
+	if r.WebSocket != nil {
+		status += " " + r.WebSocket.String()
+	}
+	if r.Timing != nil {
+		status += " timing: " + r.Timing.String()
+	}
+	if r.FollowUp != nil {
+		status += " follow-up: " + r.FollowUp.summary()
+	}
 	return status
 }
 
+// summary returns a short description of a follow-up response.
+func (r Response) summary() string {
+	if r.Error != nil {
+		return r.Error.Error()
+	}
+
+	return fmt.Sprintf("%d, %d/%d bytes", r.HTTPResponse.StatusCode, r.Header.Bytes, r.Body.Bytes)
+}
+
 func extractRegexp(buf []byte, targets []*regexp.Regexp) (data []string) {
 	for _, reg := range targets {
 		if !reg.Match(buf) {
@@ -141,6 +176,30 @@ func (r *Response) ExtractBody(targets []*regexp.Regexp) {
 	r.Extract = append(r.Extract, extractRegexp(r.RawBody, targets)...)
 }
 
+// ExtractBodyJSON extracts the elements at the JSONPath expressions from the
+// HTTP response body. Paths which are not found are ignored.
+func (r *Response) ExtractBodyJSON(paths []string) {
+	for _, path := range paths {
+		v, err := request.ExtractJSON(r.RawBody, path)
+		if err != nil {
+			continue
+		}
+		r.Extract = append(r.Extract, v)
+	}
+}
+
+// ExtractBodyXPath extracts the values selected by the XPath expressions from
+// the HTTP response body.
+func (r *Response) ExtractBodyXPath(xpaths []*XPath) {
+	for _, x := range xpaths {
+		values, err := x.Eval(r.RawBody)
+		if err != nil {
+			continue
+		}
+		r.Extract = append(r.Extract, values...)
+	}
+}
+
 // ExtractBodyCommand extracts data from the HTTP response body by running an external command.
 func (r *Response) ExtractBodyCommand(cmds [][]string) (err error) {
 	data, err := extractCommand(r.RawBody, cmds)
@@ -1,13 +1,17 @@
 package response
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"regexp"
@@ -25,6 +29,18 @@ type Runner struct {
 
 	BodyBufferSize int
 	Extract        []*regexp.Regexp
+	CookieJar      bool         // use a new cookie jar for each request
+	Timing         bool         // record the duration of the phases of each request
+	HostLimit      *HostLimiter // limit the concurrent requests per host, may be nil
+	Methods        []string     // send a request with each method for every value
+	Primer         *Primer      // sends the prime request again before requests, may be nil
+
+	// HostFilter checks the hosts requests are sent to, may be nil
+	HostFilter *request.HostFilter
+
+	// Items, if set, is read instead of the input channel, the sequence
+	// numbers of the items are set in the responses
+	Items <-chan Item
 
 	Client    *http.Client
 	Transport *http.Transport
@@ -33,12 +49,19 @@ type Runner struct {
 	output chan<- Response
 }
 
+// Item is a value with a sequence number, so that the responses for it can be
+// told apart from the responses for the same value sent at another position.
+type Item struct {
+	Value string
+	Seq   int
+}
+
 // DefaultBodyBufferSize is the default size for peeking at the body to extract strings via regexp.
 const DefaultBodyBufferSize = 5 * 1024 * 1024
 
-// NewTransport creates a new shared transport for clients to use.
-func NewTransport(insecure bool, TLSClientCertKeyFilename string,
-	disableHTTP2 bool, concurrentRequests int) (*http.Transport, error) {
+// NewTransport creates a new shared transport for clients to use, configured
+// from the transport options in template.
+func NewTransport(template *request.Request, concurrentRequests int) (*http.Transport, error) {
 	// for timeouts, see
 	// https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
 	tr := &http.Transport{
@@ -57,15 +80,30 @@ func NewTransport(insecure bool, TLSClientCertKeyFilename string,
 		KeepAlive: 30 * time.Second,
 	}
 
+	resolver, err := newResolver(template.Resolver, template.DoHURL)
+	if err != nil {
+		return nil, err
+	}
+	dialer.Resolver = resolver
+
+	var base contextDialer = dialer
+	if template.Interface != "" || template.LocalPort != "" {
+		local, err := newLocalDialer(dialer, template.Interface, template.LocalPort)
+		if err != nil {
+			return nil, err
+		}
+		base = local
+	}
+
 	noProxy := len(os.Getenv("NO_PROXY")) > 0 || len(os.Getenv("no_proxy")) > 0
 
 	socks5ProxyConfig := os.Getenv("FORCE_SOCKS5_PROXY")
 	if socks5ProxyConfig == "" || noProxy {
-		tr.DialContext = dialer.DialContext
+		tr.DialContext = base.DialContext
 	} else {
 		// configure a socks5 proxy that also forwards requests
 		// to loopback devices through the proxy connection
-		socks5Dialer, err := socks5ContextDialer(dialer, socks5ProxyConfig)
+		socks5Dialer, err := socks5ContextDialer(base, socks5ProxyConfig)
 		if err != nil {
 			return nil, fmt.Errorf("configure socks5 proxy: %v", err)
 		}
@@ -73,34 +111,179 @@ func NewTransport(insecure bool, TLSClientCertKeyFilename string,
 		tr.DialContext = socks5Dialer.DialContext
 	}
 
-	if insecure {
+	if len(template.ProxyChain) > 0 {
+		// the proxy chain replaces the proxies configured via the environment
+		chain, err := proxyChainDialer(template.ProxyChain, base)
+		if err != nil {
+			return nil, err
+		}
+
+		tr.Proxy = nil
+		tr.DialContext = chain.DialContext
+	}
+
+	if template.Proxy != "" {
+		if len(template.ProxyChain) > 0 {
+			return nil, errors.New("--proxy and --proxy-chain cannot be used together")
+		}
+
+		err := setProxy(tr, template.Proxy, base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if template.ProxyFile != "" {
+		if template.Proxy != "" || len(template.ProxyChain) > 0 {
+			return nil, errors.New("--proxy-file cannot be used together with --proxy or --proxy-chain")
+		}
+
+		list, err := readProxyFile(template.ProxyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		rotating, err := newRotatingDialer(list, template.ProxyRotation, template.Seed, base)
+		if err != nil {
+			return nil, err
+		}
+
+		// use a new connection (through the next proxy) for each request
+		tr.Proxy = nil
+		tr.DialContext = rotating.DialContext
+		tr.DisableKeepAlives = true
+	}
+
+	if template.UnixSocket != "" {
+		if template.Proxy != "" || len(template.ProxyChain) > 0 || template.ProxyFile != "" || template.OverridesDial() {
+			return nil, errors.New("--unix-socket cannot be used together with proxies, --resolve, --connect-to or --target-ip")
+		}
+
+		// connect to the socket for all requests, the URL is only used
+		// for the path and the Host header
+		path := template.UnixSocket
+		tr.Proxy = nil
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+	}
+
+	if template.OverridesDial() {
+		tr.DialContext = overrideDial(template, tr.DialContext)
+
+		// connections must not be reused when the address depends on the value
+		if template.DialDependsOnValue() {
+			tr.DisableKeepAlives = true
+		}
+	}
+
+	if template.Insecure {
 		tr.TLSClientConfig.InsecureSkipVerify = true
 	}
 
-	if !disableHTTP2 {
+	err = configureTLS(tr.TLSClientConfig, template)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case template.HTTP2:
+		if template.DisableHTTP2 {
+			return nil, errors.New("--http2 and --disable-http2 cannot be used together")
+		}
+
+		if template.RawWriter() {
+			return nil, errors.New("--http2 cannot be used together with --preserve-length, --preserve-header-order, --raw-request-line, --raw-header, multiple User-Agent headers or a removed Host header")
+		}
+
+		// send all requests via HTTP/2, for both http and https URLs
+		h2 := newH2Transport(template, tr)
+		tr.RegisterProtocol("http", h2)
+		tr.RegisterProtocol("https", h2)
+
+	case !template.DisableHTTP2:
 		// enable http2
 		err := http2.ConfigureTransport(tr)
 		if err != nil {
 			return nil, err
 		}
+
+		if template.H2Authority != "" {
+			overrideAuthority(template, tr)
+		}
 	}
 
-	if TLSClientCertKeyFilename != "" {
-		certs, key, err := readPEMCertKey(TLSClientCertKeyFilename)
+	crt, err := clientCertificate(template)
+	if err != nil {
+		return nil, err
+	}
+	if crt != nil {
+		tr.TLSClientConfig.Certificates = []tls.Certificate{*crt}
+	}
+
+	if template.DigestAuth != "" && template.UserPass != "" {
+		return nil, errors.New("--auth-digest and --user cannot be used together")
+	}
+
+	if template.NTLMAuth != "" {
+		switch {
+		case template.UserPass != "" || template.DigestAuth != "":
+			return nil, errors.New("--auth-ntlm cannot be used together with --user or --auth-digest")
+		case template.HTTP2 || template.RawWriter():
+			return nil, errors.New("--auth-ntlm requires HTTP/1.1 and cannot be used together with --http2 or raw requests")
+		}
+	}
+
+	if template.TLSKeyLogFile != "" {
+		// the file stays open as long as the process runs
+		f, err := os.OpenFile(template.TLSKeyLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
 		if err != nil {
 			return nil, err
 		}
+		tr.TLSClientConfig.KeyLogWriter = f
+	}
 
-		crt, err := tls.X509KeyPair(certs, key)
-		if err != nil {
-			return nil, fmt.Errorf("parse TLS client cert or key: %v", err)
+	if template.SNI != "" {
+		if template.NoSNI {
+			return nil, errors.New("--sni and --no-sni cannot be used together")
 		}
-		tr.TLSClientConfig.Certificates = []tls.Certificate{crt}
+		tr.TLSClientConfig.ServerName = template.SNI
+	}
+
+	if template.NoSNI {
+		tr.DialTLSContext = dialTLSNoSNI(tr, template.Insecure)
 	}
 
 	return tr, nil
 }
 
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// overrideDial returns a function which connects to the address returned by
+// dialAddress.
+func overrideDial(template *request.Request, dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		addr, err := dialAddress(ctx, template, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return dial(ctx, network, addr)
+	}
+}
+
+// dialAddress returns the address to connect to for addr, which is the result
+// of template.DialAddress for the value stored in ctx. If ctx does not carry a
+// value, only the entries which do not depend on it are used.
+func dialAddress(ctx context.Context, template *request.Request, addr string) (string, error) {
+	value, ok := request.FromContext(ctx)
+	if !ok {
+		return template.StaticDialAddress(addr)
+	}
+
+	return template.DialAddress(value, addr)
+}
+
 func socks5ContextDialer(dialer proxy.Dialer, socks5Conf string) (proxy.ContextDialer, error) {
 	socks5URL, err := url.Parse("socks5://" + socks5Conf)
 	if err != nil {
@@ -163,6 +346,22 @@ func NewRunner(tr *http.Transport, template *request.Request, input <-chan strin
 		},
 	}
 
+	// http.Transport would correct the Content-Length header and refuses
+	// invalid request lines and headers
+	if template.RawWriter() {
+		c.Transport = &rawTransport{tr: tr, template: template}
+	}
+
+	if template.DigestAuth != "" {
+		c.Transport = newDigestTransport(c.Transport, template)
+	}
+
+	// the NTLM handshake authenticates a connection, so each runner uses its
+	// own connection
+	if template.NTLMAuth != "" {
+		c.Transport = newNTLMTransport(tr, template)
+	}
+
 	return &Runner{
 		Template:       template,
 		Client:         c,
@@ -173,32 +372,121 @@ func NewRunner(tr *http.Transport, template *request.Request, input <-chan strin
 	}
 }
 
-func (r *Runner) request(ctx context.Context, item string) (response Response) {
-	req, err := r.Template.Apply(item)
+func (r *Runner) request(ctx context.Context, item, method string) (response Response) {
+	apply := func() (*http.Request, error) {
+		return r.Template.ApplyNextMethod(item, method)
+	}
+
+	var req *http.Request
+	var err error
+	if r.Primer != nil {
+		req, err = r.Primer.Apply(ctx, apply)
+	} else {
+		req, err = apply()
+	}
 	if err != nil {
 		response.Error = err
+		response.Method = method
 		return
 	}
 
 	response = Response{
-		URL:  req.URL.String(),
-		Item: item,
+		URL:    request.URLString(req.URL),
+		Item:   item,
+		Method: method,
+	}
+
+	// make sure the address to connect to is valid
+	host, port, err := request.Target(req)
+	if err != nil {
+		response.Error = err
+		return
 	}
 
+	dialAddr, err := r.Template.DialAddress(item, net.JoinHostPort(host, port))
+	if err != nil {
+		response.Error = err
+		return
+	}
+
+	dialHost, _, err := net.SplitHostPort(dialAddr)
+	if err != nil {
+		response.Error = err
+		return
+	}
+
+	err = r.HostFilter.CheckTarget(ctx, host, dialHost)
+	if err != nil {
+		response.Error = err
+		return
+	}
+
+	if r.HostLimit != nil {
+		release, err := r.HostLimit.Acquire(ctx, net.JoinHostPort(host, port))
+		if err != nil {
+			response.Error = err
+			return
+		}
+		defer release()
+	}
+
+	if r.CookieJar {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			response.Error = err
+			return
+		}
+		r.Client.Jar = jar
+	}
+
+	ctx = request.NewContext(ctx, item)
+
 	start := time.Now()
+	var trace *timingTrace
+	if r.Timing {
+		trace = newTimingTrace(start)
+		ctx = httptrace.WithClientTrace(ctx, trace.ClientTrace())
+	}
+
 	res, err := r.Client.Do(req.WithContext(ctx))
-	response.Duration = time.Since(start)
 	if err != nil {
+		response.Duration = time.Since(start)
 		response.Error = err
 		return
 	}
 
-	err = response.ReadBody(res.Body, r.BodyBufferSize)
+	body := res.Body
+	if r.Template.WebSocket() && res.StatusCode == http.StatusSwitchingProtocols {
+		msgs, err := r.Template.WebSocketMessages(item)
+		if err != nil {
+			_ = res.Body.Close()
+			response.Error = err
+			return
+		}
+
+		buf, n, code, err := r.webSocket(ctx, res, msgs)
+		if err != nil {
+			response.Duration = time.Since(start)
+			response.Error = err
+			return
+		}
+
+		response.WebSocket = &WebSocketStats{Messages: n, CloseCode: code}
+		body = ioutil.NopCloser(bytes.NewReader(buf))
+		res.Body = body
+	}
+	response.Duration = time.Since(start)
+
+	err = response.ReadBody(body, r.BodyBufferSize)
 	if err != nil {
 		response.Error = err
 		return
 	}
 
+	if trace != nil {
+		response.Timing = trace.Timing()
+	}
+
 	// dump the header and extract data now so the stats about the header are
 	// present when the filter runs in the next step. We need to dump the header
 	// for that, so we can easily run data extraction in the same step.
@@ -219,15 +507,60 @@ func (r *Runner) request(ctx context.Context, item string) (response Response) {
 	return
 }
 
-// Run processes items read from ch and executes HTTP requests.
+// Warmup sends n requests for item one after another and discards the
+// responses, e.g. to establish a connection before the measured run begins.
+// The error of the last failed request is returned.
+func (r *Runner) Warmup(ctx context.Context, item string, n int) (err error) {
+	for i := 0; i < n; i++ {
+		res := r.request(ctx, item, "")
+		if res.Error != nil {
+			err = res.Error
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
+
+// Run processes items read from ch and executes HTTP requests. If Methods is
+// set, one request is sent with each method for each item.
 func (r *Runner) Run(ctx context.Context) {
+	methods := r.Methods
+	if len(methods) == 0 {
+		methods = []string{""}
+	}
+
+	if r.Items != nil {
+		for item := range r.Items {
+			if !r.send(ctx, item, methods) {
+				return
+			}
+		}
+		return
+	}
+
 	for item := range r.input {
-		res := r.request(ctx, item)
+		if !r.send(ctx, Item{Value: item}, methods) {
+			return
+		}
+	}
+}
+
+// send sends the requests for item with all methods. It returns false if the
+// context has been cancelled.
+func (r *Runner) send(ctx context.Context, item Item, methods []string) bool {
+	for _, method := range methods {
+		res := r.request(ctx, item.Value, method)
+		res.Seq = item.Seq
 
 		select {
 		case <-ctx.Done():
-			return
+			return false
 		case r.output <- res:
 		}
 	}
+	return true
 }
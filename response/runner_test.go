@@ -0,0 +1,317 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOverrideDial(t *testing.T) {
+	template := request.New("")
+	template.URL = "http://www.example.com/"
+	template.Resolve = []string{"www.example.com:80:FUZZ"}
+
+	var mu sync.Mutex
+	var dialed []string
+
+	errDialed := errors.New("dialed")
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !tr.DisableKeepAlives {
+		t.Errorf("keepalives are not disabled although address depends on the value")
+	}
+
+	tr.DialContext = overrideDial(template, func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+		return nil, errDialed
+	})
+
+	values := []string{"127.0.0.1", "10.0.0.1", "::1", "not-an-ip"}
+	input := make(chan string, len(values))
+	for _, v := range values {
+		input <- v
+	}
+	close(input)
+
+	output := make(chan Response, len(values))
+	runner := NewRunner(tr, template, input, output)
+	runner.Run(context.Background())
+	close(output)
+
+	var buildErrors int
+	for res := range output {
+		if res.Error == nil {
+			t.Errorf("expected error not found for %v", res.Item)
+			continue
+		}
+
+		if !errors.Is(res.Error, errDialed) {
+			buildErrors++
+		}
+	}
+
+	if buildErrors != 1 {
+		t.Errorf("wrong number of build errors, want 1, got %v", buildErrors)
+	}
+
+	want := []string{"127.0.0.1:80", "10.0.0.1:80", "[::1]:80"}
+	if !cmp.Equal(want, dialed) {
+		t.Error(cmp.Diff(want, dialed))
+	}
+}
+
+func TestConnectionClose(t *testing.T) {
+	var mu sync.Mutex
+	var conns int
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	var tests = []struct {
+		close bool
+		conns int
+	}{
+		{close: false, conns: 1},
+		{close: true, conns: 3},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			mu.Lock()
+			conns = 0
+			mu.Unlock()
+
+			template := request.New("")
+			template.URL = srv.URL + "/FUZZ"
+			template.ConnectionClose = test.close
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			values := []string{"a", "b", "c"}
+			input := make(chan string)
+			output := make(chan Response)
+			runner := NewRunner(tr, template, input, output)
+			go runner.Run(context.Background())
+
+			// send the requests one after another, so an idle connection
+			// can be reused
+			for _, v := range values {
+				input <- v
+				res := <-output
+				if res.Error != nil {
+					t.Fatal(res.Error)
+				}
+			}
+			close(input)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if conns != test.conns {
+				t.Errorf("wrong number of connections, want %v, got %v", test.conns, conns)
+			}
+		})
+	}
+}
+
+func TestTemplateSchemeFromValue(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			_, _ = io.WriteString(w, "https "+r.URL.Path)
+			return
+		}
+		_, _ = io.WriteString(w, "http "+r.URL.Path)
+	})
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	// the whole base URL is taken from the value, path and method from the template
+	template := request.New("")
+	template.URL = "FUZZ"
+	template.TemplateData = []byte("GET /admin HTTP/1.1\r\nAccept: */*\r\n\r\n")
+	template.Insecure = true
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	values := []string{plain.URL, secure.URL, plain.URL, secure.URL}
+	input := make(chan string, len(values))
+	for _, v := range values {
+		input <- v
+	}
+	close(input)
+
+	output := make(chan Response, len(values))
+	NewRunner(tr, template, input, output).Run(context.Background())
+	close(output)
+
+	for res := range output {
+		if res.Error != nil {
+			t.Fatalf("request for %v failed: %v", res.Item, res.Error)
+		}
+
+		want := "http /admin"
+		if strings.HasPrefix(res.Item, "https://") {
+			want = "https /admin"
+		}
+
+		if string(res.RawBody) != want {
+			t.Errorf("wrong response for %v, want %q, got %q", res.Item, want, res.RawBody)
+		}
+	}
+}
+
+func TestRunnerMethods(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/FUZZ"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
+	values := []string{"foo", "bar", "baz"}
+
+	input := make(chan string, len(values))
+	for _, v := range values {
+		input <- v
+	}
+	close(input)
+
+	output := make(chan Response, len(values)*len(methods))
+	runner := NewRunner(tr, template, input, output)
+	runner.Methods = methods
+	runner.Run(context.Background())
+	close(output)
+
+	var want, built []string
+	for _, v := range values {
+		for _, m := range methods {
+			want = append(want, m+" /"+v)
+		}
+	}
+
+	for res := range output {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if res.HTTPResponse.Request.Method != res.Method {
+			t.Errorf("response tagged with method %v, but request was sent with %v", res.Method, res.HTTPResponse.Request.Method)
+		}
+
+		built = append(built, res.Method+" /"+res.Item)
+	}
+
+	sort.Strings(want)
+	sort.Strings(built)
+	sort.Strings(received)
+
+	if !cmp.Equal(want, built) {
+		t.Errorf("wrong responses returned:\n%v", cmp.Diff(want, built))
+	}
+
+	if !cmp.Equal(want, received) {
+		t.Errorf("wrong requests received:\n%v", cmp.Diff(want, received))
+	}
+}
+
+func TestRunnerWarmup(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL + "/FUZZ"
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	values := []string{"foo", "bar", "baz"}
+	input := make(chan string, len(values))
+	for _, v := range values {
+		input <- v
+	}
+	close(input)
+
+	output := make(chan Response, len(values))
+	runner := NewRunner(tr, template, input, output)
+
+	err = runner.Warmup(context.Background(), "foo", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner.Run(context.Background())
+	close(output)
+
+	var responses int
+	for res := range output {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		responses++
+	}
+
+	if responses != len(values) {
+		t.Errorf("wrong number of responses, want %d, got %d", len(values), responses)
+	}
+
+	if requests != len(values)+4 {
+		t.Errorf("wrong number of requests received, want %d, got %d", len(values)+4, requests)
+	}
+}
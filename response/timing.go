@@ -0,0 +1,112 @@
+package response
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing contains the duration of the phases of a request. Phases which did
+// not happen (e.g. DNS resolution for an IP address, or all connection phases
+// when a connection is reused) are zero.
+type Timing struct {
+	DNS     time.Duration // resolving the host name
+	Connect time.Duration // establishing the TCP connection
+	TLS     time.Duration // the TLS handshake
+	TTFB    time.Duration // from the start of the request until the first byte of the response
+	Total   time.Duration // from the start of the request until the body has been read
+}
+
+func (t Timing) String() string {
+	round := func(d time.Duration) time.Duration {
+		return d.Round(time.Microsecond)
+	}
+
+	return fmt.Sprintf("dns %v, connect %v, tls %v, ttfb %v, total %v",
+		round(t.DNS), round(t.Connect), round(t.TLS), round(t.TTFB), round(t.Total))
+}
+
+// timingTrace records the timing of a single request. The hooks may be called
+// from different goroutines (e.g. when several addresses are dialed in
+// parallel), so all access is synchronized.
+type timingTrace struct {
+	mu     sync.Mutex
+	start  time.Time
+	dns    time.Time
+	conn   time.Time
+	tls    time.Time
+	timing Timing
+}
+
+// newTimingTrace returns a trace which measures all durations from start.
+func newTimingTrace(start time.Time) *timingTrace {
+	return &timingTrace{start: start}
+}
+
+// since stores the duration since the time at *t in *d, if *t is set.
+func (tr *timingTrace) since(t *time.Time, d *time.Duration) {
+	tr.mu.Lock()
+	if !t.IsZero() {
+		*d = time.Since(*t)
+		*t = time.Time{}
+	}
+	tr.mu.Unlock()
+}
+
+// begin sets *t to the current time.
+func (tr *timingTrace) begin(t *time.Time) {
+	tr.mu.Lock()
+	*t = time.Now()
+	tr.mu.Unlock()
+}
+
+// ClientTrace returns the hooks which record the timing. When redirects are
+// followed, the connection phases are the ones of the last request.
+func (tr *timingTrace) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { tr.begin(&tr.dns) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { tr.since(&tr.dns, &tr.timing.DNS) },
+		ConnectStart: func(_, _ string) {
+			tr.mu.Lock()
+			// only the first of several parallel connection attempts counts
+			if tr.conn.IsZero() {
+				tr.conn = time.Now()
+			}
+			tr.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				tr.since(&tr.conn, &tr.timing.Connect)
+			}
+		},
+		TLSHandshakeStart: func() { tr.begin(&tr.tls) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { tr.since(&tr.tls, &tr.timing.TLS) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				return
+			}
+
+			tr.mu.Lock()
+			tr.timing.DNS, tr.timing.Connect, tr.timing.TLS = 0, 0, 0
+			tr.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tr.mu.Lock()
+			tr.timing.TTFB = time.Since(tr.start)
+			tr.mu.Unlock()
+		},
+	}
+}
+
+// Timing returns the recorded timing, the total duration is set to the time
+// since the start of the request.
+func (tr *timingTrace) Timing() *Timing {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	t := tr.timing
+	t.Total = time.Since(tr.start)
+	return &t
+}
@@ -0,0 +1,67 @@
+package response
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestTiming(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	template := request.New("")
+	// use a host name so that it needs to be resolved
+	template.URL = strings.Replace(srv.URL, "127.0.0.1", "localhost", 1) + "/FUZZ"
+	template.Insecure = true
+	template.DisableHTTP2 = true
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	input := make(chan string, 2)
+	input <- "first"
+	input <- "second"
+	close(input)
+	output := make(chan Response, 2)
+
+	runner := NewRunner(tr, template, input, output)
+	runner.Timing = true
+	runner.Run(context.Background())
+
+	first, second := <-output, <-output
+	for _, res := range []Response{first, second} {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if res.Timing == nil {
+			t.Fatalf("timing not recorded for %v", res.Item)
+		}
+
+		if res.Timing.TTFB <= 0 || res.Timing.Total < res.Timing.TTFB {
+			t.Errorf("invalid timing for %v: %v", res.Item, res.Timing)
+		}
+	}
+
+	timing := first.Timing
+	if timing.DNS <= 0 || timing.Connect <= 0 || timing.TLS <= 0 {
+		t.Errorf("phase durations for a new connection are not set: %v", timing)
+	}
+
+	// the second request reuses the connection
+	timing = second.Timing
+	if timing.DNS != 0 || timing.Connect != 0 || timing.TLS != 0 {
+		t.Errorf("phase durations for a reused connection are set: %v", timing)
+	}
+}
@@ -0,0 +1,227 @@
+package response
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// dialTLSNoSNI returns a function for http.Transport.DialTLSContext which
+// connects via tr.DialContext and runs the TLS handshake without sending a
+// server name. Unless insecure is set, the certificate is verified manually
+// for the host name in addr.
+func dialTLSNoSNI(tr *http.Transport, insecure bool) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := tr.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		// an empty server name is only accepted by crypto/tls if verification
+		// is disabled, so do it ourselves
+		cfg := tr.TLSClientConfig.Clone()
+		cfg.ServerName = ""
+		cfg.InsecureSkipVerify = true
+		if !insecure {
+			cfg.VerifyPeerCertificate = verifyCertificate(cfg.RootCAs, host)
+		}
+
+		if tr.TLSHandshakeTimeout > 0 {
+			_ = conn.SetDeadline(time.Now().Add(tr.TLSHandshakeTimeout))
+		}
+
+		tlsConn := tls.Client(conn, cfg)
+		err = tlsConn.Handshake()
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+
+		_ = conn.SetDeadline(time.Time{})
+
+		return tlsConn, nil
+	}
+}
+
+// verifyCertificate returns a function for tls.Config.VerifyPeerCertificate
+// which verifies the certificate chain presented by the server for host. If
+// roots is nil, the system pool is used.
+func verifyCertificate(roots *x509.CertPool, host string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server did not present a certificate")
+		}
+
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			DNSName:       host,
+			Intermediates: x509.NewCertPool(),
+		}
+
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+}
+
+// dialRequest connects to the target of req via tr.DialContext and runs the
+// TLS handshake for https URLs, offering the protocols in protos. The
+// options for TLS from template (e.g. NoSNI) are respected.
+func dialRequest(req *http.Request, tr *http.Transport, template *request.Request, protos []string) (net.Conn, error) {
+	host, port, err := request.Target(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := req.Context()
+	conn, err := tr.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL.Scheme != "https" {
+		return conn, nil
+	}
+
+	cfg := tr.TLSClientConfig.Clone()
+	cfg.NextProtos = protos
+	switch {
+	case template.NoSNI:
+		cfg.ServerName = ""
+		cfg.InsecureSkipVerify = true
+		if !template.Insecure {
+			cfg.VerifyPeerCertificate = verifyCertificate(cfg.RootCAs, host)
+		}
+	case cfg.ServerName == "":
+		cfg.ServerName = host
+	}
+
+	if tr.TLSHandshakeTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(tr.TLSHandshakeTimeout))
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	err = tlsConn.Handshake()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return tlsConn, nil
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSVersion returns the TLS version for s, e.g. "1.2".
+func parseTLSVersion(s string) (uint16, error) {
+	v, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(s), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q, supported are 1.0, 1.1, 1.2 and 1.3", s)
+	}
+	return v, nil
+}
+
+// parseCipherSuites returns the IDs of the cipher suites in names, which are
+// either the names used by crypto/tls (e.g.
+// "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA") or IDs in hex (e.g. "0xc013").
+// Cipher suites considered insecure are supported, too.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, list := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, cs := range list {
+			suites[cs.Name] = cs.ID
+		}
+	}
+
+	var ids []uint16
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+
+		if strings.HasPrefix(name, "0x") {
+			id, err := strconv.ParseUint(name[2:], 16, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cipher suite ID %q", name)
+			}
+			ids = append(ids, uint16(id))
+			continue
+		}
+
+		id, ok := suites[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// configureTLS sets the versions and cipher suites configured in template
+// for cfg.
+func configureTLS(cfg *tls.Config, template *request.Request) (err error) {
+	if template.TLSMinVersion != "" {
+		cfg.MinVersion, err = parseTLSVersion(template.TLSMinVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	if template.TLSMaxVersion != "" {
+		cfg.MaxVersion, err = parseTLSVersion(template.TLSMaxVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the default minimum is TLS 1.2, so allow older versions when the
+	// maximum is below that
+	if cfg.MinVersion == 0 && cfg.MaxVersion != 0 && cfg.MaxVersion < tls.VersionTLS12 {
+		cfg.MinVersion = tls.VersionTLS10
+	}
+
+	if cfg.MinVersion != 0 && cfg.MaxVersion != 0 && cfg.MinVersion > cfg.MaxVersion {
+		return errors.New("the minimal TLS version is larger than the maximal version")
+	}
+
+	if len(template.TLSCiphers) > 0 {
+		cfg.CipherSuites, err = parseCipherSuites(template.TLSCiphers)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package response
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+func TestNoSNI(t *testing.T) {
+	var mu sync.Mutex
+	var serverName string
+	var host string
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		host = r.Host
+		mu.Unlock()
+	}))
+	srv.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			serverName = hello.ServerName
+			mu.Unlock()
+			return nil, nil
+		},
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	srvURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := srvURL.Port()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	var tests = []struct {
+		host       string
+		noSNI      bool
+		sni        string
+		insecure   bool
+		serverName string
+		err        bool
+	}{
+		// the test certificate is valid for example.com
+		{host: "example.com", serverName: "example.com"},
+		{host: "example.com", noSNI: true, serverName: ""},
+		{host: "example.com", noSNI: true, insecure: true, serverName: ""},
+		{host: "www.example.org", noSNI: true, err: true},
+		{host: "www.example.org", noSNI: true, insecure: true, serverName: ""},
+		// the certificate is verified for the name sent via SNI
+		{host: "www.example.org", sni: "example.com", serverName: "example.com"},
+		{host: "example.com", sni: "www.example.org", err: true},
+		{host: "example.com", sni: "www.example.org", insecure: true, serverName: "www.example.org"},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			serverName, host = "invalid", ""
+
+			template := request.New("")
+			template.URL = "https://" + test.host + ":" + port + "/"
+			template.Resolve = []string{test.host + ":" + port + ":127.0.0.1"}
+			template.NoSNI = test.noSNI
+			template.SNI = test.sni
+			template.Insecure = test.insecure
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr.TLSClientConfig.RootCAs = roots
+
+			req, err := template.Apply("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{Transport: tr}
+			res, err := client.Do(req)
+			if test.err {
+				if err == nil {
+					_ = res.Body.Close()
+					t.Fatal("expected certificate verification error not returned")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = res.Body.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if serverName != test.serverName {
+				t.Errorf("wrong server name in ClientHello, want %q, got %q", test.serverName, serverName)
+			}
+
+			wantHost := test.host + ":" + port
+			if host != wantHost {
+				t.Errorf("wrong Host header, want %q, got %q", wantHost, host)
+			}
+		})
+	}
+}
+
+func TestTLSVersionCiphers(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%x %s", r.TLS.Version, tls.CipherSuiteName(r.TLS.CipherSuite))
+	}))
+	srv.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS10,
+		MaxVersion: tls.VersionTLS12,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	var tests = []struct {
+		min, max  string
+		ciphers   []string
+		want      string
+		err       bool
+		resultErr bool
+	}{
+		{want: "303 "},
+		{max: "1.1", want: "302 "},
+		{min: "1.0", max: "TLS1.0", want: "301 "},
+		{ciphers: []string{"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"}, want: "303 TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"},
+		{ciphers: []string{"0xc014"}, want: "303 TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA"},
+		{min: "1.3", resultErr: true},
+		{min: "1.2", max: "1.1", err: true},
+		{min: "1.4", err: true},
+		{ciphers: []string{"TLS_INVALID"}, err: true},
+		{ciphers: []string{"0xinvalid"}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			template := request.New("")
+			template.URL = srv.URL + "/"
+			template.Insecure = true
+			template.TLSMinVersion = test.min
+			template.TLSMaxVersion = test.max
+			template.TLSCiphers = test.ciphers
+
+			if test.err {
+				_, err := NewTransport(template, 1)
+				if err == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+
+			res := runSingle(t, template, "")
+			if test.resultErr {
+				if res.Error == nil {
+					t.Fatal("expected error not returned")
+				}
+				return
+			}
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if !strings.HasPrefix(string(res.RawBody), test.want) {
+				t.Errorf("wrong version or cipher suite, want %q, got %q", test.want, res.RawBody)
+			}
+		})
+	}
+}
+
+func TestTLSKeyLog(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	keylog, cleanup := tempFileFrom(t)
+	defer cleanup()
+
+	template := request.New("")
+	template.URL = srv.URL + "/"
+	template.Insecure = true
+	template.TLSKeyLogFile = keylog
+
+	res := runSingle(t, template, "")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	buf, err := ioutil.ReadFile(keylog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(buf), "CLIENT_TRAFFIC_SECRET_0 ") {
+		t.Errorf("key log does not contain the client traffic secret:\n%s", buf)
+	}
+}
@@ -0,0 +1,280 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes, see RFC 6455, section 5.2.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// WebSocket close codes which are never sent in a close frame (RFC 6455,
+// section 7.4.1).
+const (
+	wsNoStatus = 1005 // the close frame did not contain a code
+	wsAbnormal = 1006 // the connection was closed without a close frame
+)
+
+// defaultWebSocketTimeout is used if the template does not configure a
+// timeout.
+const defaultWebSocketTimeout = 2 * time.Second
+
+// WebSocketStats describes the messages received over a WebSocket.
+type WebSocketStats struct {
+	Messages  int
+	CloseCode int // sent by the server, 0 if the connection was still open
+}
+
+func (s WebSocketStats) String() string {
+	if s.CloseCode == 0 {
+		return fmt.Sprintf("ws: %d messages", s.Messages)
+	}
+	return fmt.Sprintf("ws: %d messages, close %d", s.Messages, s.CloseCode)
+}
+
+// wsFrame is a frame read from the server.
+type wsFrame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// wsConn is the connection after the upgrade.
+type wsConn struct {
+	rd *bufio.Reader
+
+	mu sync.Mutex // held while a frame is written
+	wr io.Writer
+}
+
+// writeFrame sends a single masked frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	var mask [4]byte
+	_, err := io.ReadFull(rand.Reader, mask[:])
+	if err != nil {
+		return err
+	}
+
+	buf := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		buf = append(buf, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		buf = append(buf, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(buf[2:], uint16(len(payload)))
+	default:
+		buf = append(buf, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[2:], uint64(len(payload)))
+	}
+	buf = append(buf, mask[:]...)
+
+	for i, b := range payload {
+		buf = append(buf, b^mask[i%4])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err = c.wr.Write(buf)
+	return err
+}
+
+// readFrame reads the next frame, the payload is limited to maxSize bytes.
+func (c *wsConn) readFrame(maxSize int) (wsFrame, error) {
+	var head [2]byte
+	_, err := io.ReadFull(c.rd, head[:])
+	if err != nil {
+		return wsFrame{}, err
+	}
+
+	f := wsFrame{
+		fin:    head[0]&0x80 != 0,
+		opcode: head[0] & 0x0f,
+	}
+
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.rd, ext[:])
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.rd, ext[:])
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return wsFrame{}, err
+	}
+
+	if size > uint64(maxSize) {
+		return wsFrame{}, fmt.Errorf("WebSocket frame too large (%d bytes)", size)
+	}
+
+	// the server must not mask frames, but accept it anyway
+	var mask [4]byte
+	masked := head[1]&0x80 != 0
+	if masked {
+		_, err = io.ReadFull(c.rd, mask[:])
+		if err != nil {
+			return wsFrame{}, err
+		}
+	}
+
+	f.payload = make([]byte, size)
+	_, err = io.ReadFull(c.rd, f.payload)
+	if err != nil {
+		return wsFrame{}, err
+	}
+
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+
+	return f, nil
+}
+
+// closePayload returns the payload for a close frame with code.
+func closePayload(code int) []byte {
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	return buf
+}
+
+// webSocket sends the messages over the connection from res, which must be
+// the response to the handshake, and returns the messages received from the
+// server (one per line), the number of messages and the close code sent by
+// the server.
+func (r *Runner) webSocket(ctx context.Context, res *http.Response, msgs []string) (body []byte, messages, closeCode int, err error) {
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		return nil, 0, 0, errors.New("WebSocket: connection cannot be written to after the upgrade")
+	}
+	defer rwc.Close()
+
+	conn := &wsConn{rd: bufio.NewReader(rwc), wr: rwc}
+
+	// read all frames in the background, pings are answered right away
+	frames := make(chan wsFrame)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			f, err := conn.readFrame(r.BodyBufferSize)
+			if err != nil {
+				close(frames)
+				return
+			}
+
+			if f.opcode == wsPing {
+				_ = conn.writeFrame(wsPong, f.payload)
+				continue
+			}
+
+			select {
+			case frames <- f:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// the connection is closed when ctx is cancelled, so the reader returns
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = rwc.Close()
+		case <-stop:
+		}
+	}()
+
+	timeout := r.Template.WebSocketTimeout
+	if timeout <= 0 {
+		timeout = defaultWebSocketTimeout
+	}
+
+	opcode := byte(wsText)
+	if r.Template.WebSocketBinary {
+		opcode = wsBinary
+	}
+
+	var received [][]byte
+	var partial []byte
+
+	for _, msg := range msgs {
+		err = conn.writeFrame(opcode, []byte(msg))
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("WebSocket: %v", err)
+		}
+
+		count := 0
+		timer := time.NewTimer(timeout)
+	wait:
+		for r.Template.WebSocketFrames <= 0 || count < r.Template.WebSocketFrames {
+			select {
+			case f, ok := <-frames:
+				if !ok {
+					timer.Stop()
+					if ctx.Err() != nil {
+						return nil, 0, 0, ctx.Err()
+					}
+					return bytes.Join(received, []byte("\n")), len(received), wsAbnormal, nil
+				}
+
+				switch f.opcode {
+				case wsClose:
+					timer.Stop()
+					closeCode = wsNoStatus
+					if len(f.payload) >= 2 {
+						closeCode = int(binary.BigEndian.Uint16(f.payload))
+					}
+					_ = conn.writeFrame(wsClose, f.payload[:0])
+					return bytes.Join(received, []byte("\n")), len(received), closeCode, nil
+				case wsText, wsBinary, wsContinuation:
+					partial = append(partial, f.payload...)
+					if f.fin {
+						received = append(received, partial)
+						partial = nil
+						count++
+					}
+				}
+
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(timeout)
+			case <-timer.C:
+				break wait
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, 0, 0, ctx.Err()
+			}
+		}
+		timer.Stop()
+	}
+
+	_ = conn.writeFrame(wsClose, closePayload(1000))
+
+	return bytes.Join(received, []byte("\n")), len(received), 0, nil
+}
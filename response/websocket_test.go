@@ -0,0 +1,194 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RedTeamPentesting/monsoon/request"
+)
+
+// writeServerFrame writes an unmasked frame as a server does.
+func writeServerFrame(wr *bufio.Writer, fin bool, opcode byte, payload []byte) {
+	if fin {
+		opcode |= 0x80
+	}
+	_ = wr.WriteByte(opcode)
+	_ = wr.WriteByte(byte(len(payload)))
+	_, _ = wr.Write(payload)
+	_ = wr.Flush()
+}
+
+// wsServer answers each text message with "echo: " and the message. For the
+// message "close" it sends a close frame with code 1008, for "drop" it closes
+// the connection.
+func wsServer(t testing.TB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		h := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		accept := base64.StdEncoding.EncodeToString(h[:])
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", accept)
+		_ = rw.Flush()
+
+		ws := &wsConn{rd: rw.Reader, wr: rw}
+		for {
+			f, err := ws.readFrame(1024)
+			if err != nil {
+				return
+			}
+
+			switch {
+			case f.opcode == wsClose:
+				return
+			case f.opcode == wsPong:
+				if string(f.payload) != "x" {
+					t.Errorf("wrong pong payload %q", f.payload)
+				}
+			case string(f.payload) == "close":
+				payload := make([]byte, 2)
+				binary.BigEndian.PutUint16(payload, 1008)
+				writeServerFrame(rw.Writer, true, wsClose, payload)
+				return
+			case string(f.payload) == "drop":
+				return
+			case string(f.payload) == "ping":
+				writeServerFrame(rw.Writer, true, wsPing, []byte("x"))
+				writeServerFrame(rw.Writer, true, wsText, []byte("pong"))
+			default:
+				// the answer is split into two frames
+				writeServerFrame(rw.Writer, false, wsText, []byte("echo: "))
+				writeServerFrame(rw.Writer, true, wsContinuation, f.payload)
+			}
+		}
+	})
+}
+
+func TestWebSocket(t *testing.T) {
+	srv := httptest.NewServer(wsServer(t))
+	defer srv.Close()
+
+	var tests = []struct {
+		messages []string
+		value    string
+		body     string
+		stats    WebSocketStats
+	}{
+		{
+			messages: []string{"hello FUZZ"},
+			value:    "world",
+			body:     "echo: hello world",
+			stats:    WebSocketStats{Messages: 1},
+		},
+		{
+			messages: []string{"a", "FUZZ", "c"},
+			value:    "ping",
+			body:     "echo: a\npong\necho: c",
+			stats:    WebSocketStats{Messages: 3},
+		},
+		{
+			messages: []string{"a", "FUZZ", "c"},
+			value:    "close",
+			body:     "echo: a",
+			stats:    WebSocketStats{Messages: 1, CloseCode: 1008},
+		},
+		{
+			messages: []string{"FUZZ"},
+			value:    "drop",
+			stats:    WebSocketStats{CloseCode: 1006},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			template := request.New("")
+			template.URL = strings.Replace(srv.URL, "http://", "ws://", 1) + "/socket"
+			template.WebSocketMessage = test.messages
+			template.WebSocketFrames = 1
+			template.WebSocketTimeout = time.Second
+
+			tr, err := NewTransport(template, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			input := make(chan string, 1)
+			input <- test.value
+			close(input)
+			output := make(chan Response, 1)
+
+			runner := NewRunner(tr, template, input, output)
+			runner.Run(context.Background())
+
+			res := <-output
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+
+			if res.HTTPResponse.StatusCode != http.StatusSwitchingProtocols {
+				t.Fatalf("wrong status code %v", res.HTTPResponse.StatusCode)
+			}
+
+			if string(res.RawBody) != test.body {
+				t.Errorf("wrong body, want %q, got %q", test.body, res.RawBody)
+			}
+
+			if res.WebSocket == nil || *res.WebSocket != test.stats {
+				t.Errorf("wrong stats, want %+v, got %+v", test.stats, res.WebSocket)
+			}
+		})
+	}
+}
+
+func TestWebSocketRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, "no")
+	}))
+	defer srv.Close()
+
+	template := request.New("")
+	template.URL = srv.URL
+	template.WebSocketMessage = []string{"FUZZ"}
+
+	tr, err := NewTransport(template, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := make(chan string, 1)
+	input <- "x"
+	close(input)
+	output := make(chan Response, 1)
+
+	NewRunner(tr, template, input, output).Run(context.Background())
+
+	res := <-output
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	if res.HTTPResponse.StatusCode != http.StatusForbidden || string(res.RawBody) != "no" || res.WebSocket != nil {
+		t.Errorf("wrong response %v %q %v", res.HTTPResponse.StatusCode, res.RawBody, res.WebSocket)
+	}
+}
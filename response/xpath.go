@@ -0,0 +1,326 @@
+package response
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xmlNode is an element or a text node of a parsed XML (or HTML) document.
+type xmlNode struct {
+	name     string // local name, empty for text nodes and the document
+	text     string // for text nodes
+	attrs    []xml.Attr
+	children []*xmlNode
+}
+
+// parseXML parses buf into a tree of nodes. The parser is lenient, so most
+// HTML documents can be parsed as well.
+func parseXML(buf []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(buf))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	doc := &xmlNode{}
+	stack := []*xmlNode{doc}
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %v", err)
+		}
+
+		cur := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: t.Attr}
+			cur.children = append(cur.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			cur.children = append(cur.children, &xmlNode{text: string(t)})
+		}
+	}
+
+	if len(doc.children) == 0 {
+		return nil, fmt.Errorf("invalid XML: no elements found")
+	}
+
+	return doc, nil
+}
+
+// textContent returns the text of n and all its descendants.
+func (n *xmlNode) textContent() string {
+	if n.name == "" && n.children == nil {
+		return n.text
+	}
+
+	var s strings.Builder
+	for _, child := range n.children {
+		s.WriteString(child.textContent())
+	}
+	return s.String()
+}
+
+// descendants appends all element nodes below n to list.
+func (n *xmlNode) descendants(list []*xmlNode) []*xmlNode {
+	for _, child := range n.children {
+		if child.name != "" {
+			list = append(list, child)
+			list = child.descendants(list)
+		}
+	}
+	return list
+}
+
+// xpathStep is a single step of an XPath expression.
+type xpathStep struct {
+	descendant bool   // the step follows "//"
+	name       string // element name, "*", "@attr", "@*" or "text()"
+
+	// predicates
+	position  int    // [n], starting at 1
+	attr      string // [@attr] or [@attr='value']
+	attrValue *string
+}
+
+// XPath is a parsed XPath expression. Only a subset is supported: absolute
+// paths with the child (/) and descendant (//) axis, element names and *,
+// attributes (@name, @*) and text() as the last step, and the predicates
+// [n], [@name] and [@name='value']. Namespaces are ignored.
+type XPath struct {
+	expr  string
+	steps []xpathStep
+}
+
+func (x *XPath) String() string {
+	return x.expr
+}
+
+// ParseXPath parses the XPath expression expr.
+func ParseXPath(expr string) (*XPath, error) {
+	x := &XPath{expr: expr}
+
+	if !strings.HasPrefix(expr, "/") {
+		return nil, fmt.Errorf("XPath %q: only absolute paths (starting with /) are supported", expr)
+	}
+
+	rest := expr
+	for rest != "" {
+		var step xpathStep
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			step.descendant = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("XPath %q: expected / before %q", expr, rest)
+		}
+
+		// find the end of the step, slashes in predicates are allowed
+		end := len(rest)
+		depth := 0
+		for i, c := range rest {
+			if c == '[' {
+				depth++
+			}
+			if c == ']' {
+				depth--
+			}
+			if c == '/' && depth == 0 {
+				end = i
+				break
+			}
+		}
+		text := rest[:end]
+		rest = rest[end:]
+
+		if i := strings.Index(text, "["); i >= 0 {
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("XPath %q: unterminated predicate in %q", expr, text)
+			}
+
+			err := step.parsePredicate(text[i+1 : len(text)-1])
+			if err != nil {
+				return nil, fmt.Errorf("XPath %q: %v", expr, err)
+			}
+			text = text[:i]
+		}
+
+		if text == "" {
+			return nil, fmt.Errorf("XPath %q: empty step", expr)
+		}
+		step.name = text
+
+		if (strings.HasPrefix(text, "@") || text == "text()") && rest != "" {
+			return nil, fmt.Errorf("XPath %q: %v must be the last step", expr, text)
+		}
+
+		x.steps = append(x.steps, step)
+	}
+
+	if len(x.steps) == 0 {
+		return nil, fmt.Errorf("XPath %q: no steps", expr)
+	}
+
+	return x, nil
+}
+
+// parsePredicate parses the predicate p (without brackets).
+func (s *xpathStep) parsePredicate(p string) error {
+	p = strings.TrimSpace(p)
+
+	if n, err := strconv.Atoi(p); err == nil {
+		if n < 1 {
+			return fmt.Errorf("invalid position %d", n)
+		}
+		s.position = n
+		return nil
+	}
+
+	if !strings.HasPrefix(p, "@") {
+		return fmt.Errorf("unsupported predicate [%v]", p)
+	}
+
+	data := strings.SplitN(p[1:], "=", 2)
+	s.attr = strings.TrimSpace(data[0])
+	if s.attr == "" {
+		return fmt.Errorf("unsupported predicate [%v]", p)
+	}
+
+	if len(data) == 2 {
+		v := strings.TrimSpace(data[1])
+		if len(v) < 2 || (v[0] != '\'' && v[0] != '"') || v[len(v)-1] != v[0] {
+			return fmt.Errorf("value in predicate [%v] must be quoted", p)
+		}
+		v = v[1 : len(v)-1]
+		s.attrValue = &v
+	}
+
+	return nil
+}
+
+// matchesAttr returns true if the attribute predicate of the step matches n.
+func (s *xpathStep) matchesAttr(n *xmlNode) bool {
+	if s.attr == "" {
+		return true
+	}
+
+	for _, attr := range n.attrs {
+		if attr.Name.Local == s.attr {
+			return s.attrValue == nil || attr.Value == *s.attrValue
+		}
+	}
+
+	return false
+}
+
+// elements returns the elements matched by the step for the context node n.
+func (s *xpathStep) elements(n *xmlNode) []*xmlNode {
+	candidates := n.children
+	if s.descendant {
+		candidates = n.descendants(nil)
+	}
+
+	var list []*xmlNode
+	for _, c := range candidates {
+		if c.name == "" || (s.name != "*" && c.name != s.name) || !s.matchesAttr(c) {
+			continue
+		}
+		list = append(list, c)
+	}
+
+	if s.position > 0 {
+		if s.position > len(list) {
+			return nil
+		}
+		return list[s.position-1 : s.position]
+	}
+
+	return list
+}
+
+// values returns the attribute values or texts for the last step.
+func (s *xpathStep) values(n *xmlNode) (list []string) {
+	nodes := []*xmlNode{n}
+	if s.descendant {
+		nodes = n.descendants(nodes)
+	}
+
+	for _, node := range nodes {
+		if s.name == "text()" {
+			for _, c := range node.children {
+				if c.name == "" {
+					list = append(list, c.text)
+				}
+			}
+			continue
+		}
+
+		for _, attr := range node.attrs {
+			if s.name == "@*" || attr.Name.Local == s.name[1:] {
+				list = append(list, attr.Value)
+			}
+		}
+	}
+
+	return list
+}
+
+// Eval returns the values selected by the expression in the document buf:
+// the text content for elements, the values for attributes and the text for
+// text(). Values of elements and text are trimmed, empty texts are skipped.
+func (x *XPath) Eval(buf []byte) ([]string, error) {
+	doc, err := parseXML(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*xmlNode{doc}
+	for _, step := range x.steps {
+		if strings.HasPrefix(step.name, "@") || step.name == "text()" {
+			var list []string
+			for _, n := range nodes {
+				list = append(list, step.values(n)...)
+			}
+			if step.name == "text()" {
+				texts := list[:0]
+				for _, s := range list {
+					if s = strings.TrimSpace(s); s != "" {
+						texts = append(texts, s)
+					}
+				}
+				list = texts
+			}
+			return list, nil
+		}
+
+		var next []*xmlNode
+		seen := make(map[*xmlNode]bool)
+		for _, n := range nodes {
+			for _, e := range step.elements(n) {
+				if !seen[e] {
+					seen[e] = true
+					next = append(next, e)
+				}
+			}
+		}
+		nodes = next
+	}
+
+	list := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		list = append(list, strings.TrimSpace(n.textContent()))
+	}
+	return list, nil
+}
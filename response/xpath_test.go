@@ -0,0 +1,81 @@
+package response
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXPath(t *testing.T) {
+	var doc = []byte(`<?xml version="1.0"?>
+<users xmlns="urn:example">
+  <user id="1" role="admin"><name>Alice</name><mail>alice@example.com</mail></user>
+  <user id="2" role="user"><name>Bob</name></user>
+  <group><user id="3"><name>Eve <b>X</b></name></user></group>
+</users>`)
+
+	var tests = []struct {
+		expr   string
+		values []string
+	}{
+		{"/users/user/@id", []string{"1", "2"}},
+		{"//user/@id", []string{"1", "2", "3"}},
+		{"//@role", []string{"admin", "user"}},
+		{"/users/user/name", []string{"Alice", "Bob"}},
+		{"//user[@role='admin']/mail", []string{"alice@example.com"}},
+		{"//user[@role]/@id", []string{"1", "2"}},
+		{"/users/user[2]/name", []string{"Bob"}},
+		{"/users/user[5]/name", nil},
+		{"//group/*/name", []string{"Eve X"}},
+		{"//name/text()", []string{"Alice", "Bob", "Eve"}},
+		{"/users/missing", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			x, err := ParseXPath(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := x.Eval(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(values) == 0 && len(test.values) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(values, test.values) {
+				t.Errorf("wrong values for %v, want %q, got %q", test.expr, test.values, values)
+			}
+		})
+	}
+}
+
+func TestXPathHTML(t *testing.T) {
+	var doc = []byte(`<html><body><form><input type="hidden" name="csrf" value="abc123"><br><input name="user"></form></body></html>`)
+
+	x, err := ParseXPath("//input[@name='csrf']/@value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := x.Eval(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, []string{"abc123"}) {
+		t.Errorf("wrong values %q", values)
+	}
+}
+
+func TestParseXPathInvalid(t *testing.T) {
+	for _, expr := range []string{"", "user", "/users/@id/name", "//user[", "//user[last()]", "//user[@id=1]", "//user[0]", "/users//"} {
+		_, err := ParseXPath(expr)
+		if err == nil {
+			t.Errorf("no error for invalid XPath %q", expr)
+		}
+	}
+}